@@ -0,0 +1,20 @@
+package arbitrage
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+)
+
+type Router struct {
+	Service  *ArbitrageService
+	UserRepo *user.UserRepository
+	Parser   *helpers.JWTParser
+}
+
+func (router *Router) SetArbitrageRouter(fiberRouter *fiber.App) {
+	group := fiberRouter.Group("/arbitrage")
+	group.Use(middleware.JWTAuthMiddleware(*router.UserRepo, router.Parser))
+	group.Get("/opportunities", router.Service.GetOpportunities)
+}
@@ -0,0 +1,85 @@
+package arbitrage
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/domain/arbitrage"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFeedRange = 24 * time.Hour
+	defaultFeedLimit = 100
+	maxFeedLimit     = 1000
+)
+
+// ArbitrageService serves the opportunities recorded by the arbitrage detector
+type ArbitrageService struct {
+	ArbitrageRepo *arbitrage.ArbitrageRepository
+	ExchangeRepo  *exchange.ExchangeRepository
+}
+
+// GetOpportunities returns the opportunities seen since the given time, most recent first
+func (service *ArbitrageService) GetOpportunities(c *fiber.Ctx) error {
+	var request arbitrage.OpportunitiesRequest
+	if err := c.QueryParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	filter := arbitrage.OpportunityFilter{
+		Since: time.Now().Add(-defaultFeedRange),
+		Limit: request.Limit,
+	}
+	if request.Symbol != "" {
+		base, quote, err := symbols.Parse(request.Symbol)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: err.Error()})
+		}
+		filter.Symbol = symbols.Canonical(base, quote)
+	}
+	if request.Since != "" {
+		since, err := time.Parse(time.RFC3339, request.Since)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "since must be an RFC3339 time"})
+		}
+		filter.Since = since
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultFeedLimit
+	}
+	if filter.Limit > maxFeedLimit {
+		filter.Limit = maxFeedLimit
+	}
+	if request.Exchange != "" {
+		exchangeModel, err := service.ExchangeRepo.GetByName(c.Context(), strings.ToLower(request.Exchange))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: "exchange not found"})
+		}
+		filter.ExchangeID = &exchangeModel.ID
+	}
+
+	opportunities, err := service.ArbitrageRepo.List(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	response := make([]arbitrage.OpportunityResponse, 0, len(opportunities))
+	for _, opportunity := range opportunities {
+		response = append(response, arbitrage.OpportunityResponse{
+			ID:              opportunity.ID,
+			Symbol:          opportunity.Symbol,
+			BuyExchange:     opportunity.BuyExchange.Name,
+			SellExchange:    opportunity.SellExchange.Name,
+			BuyPrice:        opportunity.BuyPrice,
+			SellPrice:       opportunity.SellPrice,
+			Quantity:        opportunity.Quantity,
+			BuyFee:          opportunity.BuyFee,
+			SellFee:         opportunity.SellFee,
+			ProfitRate:      opportunity.ProfitRate,
+			EstimatedProfit: opportunity.EstimatedProfit,
+			DetectedAt:      opportunity.DetectedAt,
+			LastSeenAt:      opportunity.LastSeenAt,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,20 @@
+package balance
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+)
+
+type Router struct {
+	Service  *BalanceService
+	UserRepo *user.UserRepository
+	Parser   *helpers.JWTParser
+}
+
+func (router *Router) SetBalanceRouter(fiberRouter *fiber.App) {
+	group := fiberRouter.Group("/balances")
+	group.Use(middleware.JWTAuthMiddleware(*router.UserRepo, router.Parser))
+	group.Get("/history", router.Service.GetHistory)
+}
@@ -0,0 +1,102 @@
+package balance
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/balance"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHistoryRange = 30 * 24 * time.Hour
+	defaultMaxPoints    = 500
+	maxMaxPoints        = 5000
+)
+
+// BalanceService serves the stored balance snapshots
+type BalanceService struct {
+	BalanceRepo  *balance.BalanceSnapshotRepository
+	ExchangeRepo *exchange.ExchangeRepository
+}
+
+// GetHistory returns the balance series of every asset and exchange in the range. Long ranges are downsampled to
+// the last snapshot of evenly sized buckets so no series has more than max_points points
+func (service *BalanceService) GetHistory(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request balance.BalanceHistoryRequest
+	if err := c.QueryParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	to := time.Now()
+	if request.To != "" {
+		parsed, err := time.Parse(time.RFC3339, request.To)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "to must be an RFC3339 time"})
+		}
+		to = parsed
+	}
+	from := to.Add(-defaultHistoryRange)
+	if request.From != "" {
+		parsed, err := time.Parse(time.RFC3339, request.From)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "from must be an RFC3339 time"})
+		}
+		from = parsed
+	}
+	if !to.After(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "to must be after from"})
+	}
+	maxPoints := request.MaxPoints
+	if maxPoints <= 0 {
+		maxPoints = defaultMaxPoints
+	}
+	if maxPoints > maxMaxPoints {
+		maxPoints = maxMaxPoints
+	}
+
+	var exchangeID *uuid.UUID
+	if request.Exchange != "" {
+		exchangeModel, err := service.ExchangeRepo.GetByName(c.Context(), request.Exchange)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: "exchange not found"})
+		}
+		exchangeID = &exchangeModel.ID
+	}
+	bucket := to.Sub(from) / time.Duration(maxPoints)
+	snapshots, err := service.BalanceRepo.GetDownsampledHistory(c.Context(), userId, exchangeID, request.Asset,
+		from, to, bucket)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+
+	exchangeNames := make(map[uuid.UUID]string)
+	seriesIndex := make(map[string]int)
+	series := make([]balance.BalanceSeries, 0)
+	for _, snapshot := range snapshots {
+		name, found := exchangeNames[snapshot.ExchangeID]
+		if !found {
+			exchangeModel, err := service.ExchangeRepo.GetByID(c.Context(), snapshot.ExchangeID)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+			}
+			name = exchangeModel.Name
+			exchangeNames[snapshot.ExchangeID] = name
+		}
+		asset := strings.ToUpper(snapshot.Currency)
+		key := name + "/" + asset
+		index, found := seriesIndex[key]
+		if !found {
+			index = len(series)
+			seriesIndex[key] = index
+			series = append(series, balance.BalanceSeries{Exchange: name, Asset: asset})
+		}
+		series[index].Points = append(series[index].Points, balance.BalancePoint{
+			Time:      snapshot.SnapshotTime,
+			Total:     snapshot.Total,
+			Available: snapshot.Available,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(series)
+}
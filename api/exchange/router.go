@@ -0,0 +1,31 @@
+package exchange
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+)
+
+type Router struct {
+	Service  *ExchangeService
+	UserRepo *user.UserRepository
+	Parser   *helpers.JWTParser
+}
+
+func (router *Router) SetExchangeRouter(fiberRouter *fiber.App) {
+	fiberRouter.Get("/exchanges", router.Service.ListExchanges)
+	group := fiberRouter.Group("/exchanges/:name")
+	group.Use(middleware.JWTAuthMiddleware(*router.UserRepo, router.Parser))
+	group.Use(middleware.CredentialSelector())
+	group.Post("/order", router.Service.PlaceOrder)
+	group.Delete("/order/:orderId", router.Service.CancelOrder)
+	group.Get("/order/:orderId", router.Service.GetOrder)
+	group.Get("/orders", router.Service.ListOrders)
+	group.Post("/orders/cancel-old", router.Service.CancelOldOrders)
+	group.Get("/orderBook/:symbol", router.Service.GetOrderBook)
+	group.Get("/ticker/:symbol", router.Service.GetTicker)
+	group.Get("/balance", router.Service.GetBalance)
+	group.Get("/balance/:symbol", router.Service.GetBalance)
+	group.Post("/renew", router.Service.RenewAccessToken)
+}
@@ -0,0 +1,337 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/balance"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/exchange/papertrade"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/marketCache"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExchangeService serves every registered exchange adapter through the same handlers
+type ExchangeService struct {
+	Registry               *registry.ExchangeRegistry
+	BalanceRepo            *balance.BalanceSnapshotRepository
+	ExchangeCredentialRepo *exchangeCredentials.ExchangeCredentialRepository
+	ExchangeRepo           *exchange.ExchangeRepository
+	OrderRepo              *order.OrderRepository
+	Cache                  *marketCache.OrderBookCache // optional, order books are read from the exchange every time without it
+}
+
+func (service *ExchangeService) ListExchanges(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(service.Registry.ListSupportedExchanges())
+}
+
+func (service *ExchangeService) GetBalance(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	var symbol *string
+	if asset := c.Params("symbol", c.Query("symbol")); asset != "" {
+		symbol = &asset
+	}
+
+	ctx := middleware.CredentialContext(c)
+	balanceSnapshots, err := exchangeAdapter.GetBalance(ctx, userId, symbol)
+	if err != nil {
+		return c.Status(adapterErrorStatus(err)).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+
+	if len(balanceSnapshots) > 0 && service.wholeExchange(ctx, userId, balanceSnapshots[0].ExchangeID) {
+		for i := range balanceSnapshots {
+			balanceSnapshots[i].Currency = strings.ToUpper(balanceSnapshots[i].Currency)
+		}
+		if err := service.BalanceRepo.BulkCreate(c.Context(), &balanceSnapshots); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	balances := make([]balance.StandardBalanceResponse, 0, len(balanceSnapshots))
+	for _, balanceIns := range balanceSnapshots {
+		available := balanceIns.Available
+		total := balanceIns.Total
+		frozen := total.Sub(available)
+
+		balances = append(balances, balance.StandardBalanceResponse{
+			Asset:  strings.ToUpper(balanceIns.Currency),
+			Free:   available,
+			Locked: frozen,
+			Total:  total,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(balances)
+}
+
+// GetOrderBook serves the order book from the cache while it is fresh, the Age header and cache_age_ms tell how long
+// ago it was read from the exchange
+func (service *ExchangeService) GetOrderBook(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request orderBook.StandardOrderBookRequest
+	if err := c.ParamsParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	book, err := service.Cache.OrderBook(c.Context(), exchangeAdapter.Name(), request.Symbol,
+		service.orderBookLoader(exchangeAdapter, request.Symbol, userId))
+	if err != nil {
+		return c.Status(adapterErrorStatus(err)).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	c.Set(fiber.HeaderAge, strconv.Itoa(int(book.Age().Seconds())))
+	history := orderBook.StandardOrderBookResponse{
+		Symbol:     book.Snapshot.Symbol,
+		Bids:       book.Snapshot.Bids,
+		Asks:       book.Snapshot.Asks,
+		Timestamp:  book.FetchedAt.Format(time.RFC850),
+		Cached:     book.Cached,
+		CacheAgeMs: book.Age().Milliseconds(),
+	}
+	return c.Status(fiber.StatusOK).JSON(history)
+}
+
+// GetTicker returns the best bid and ask of a symbol, from the cache while it is fresh
+func (service *ExchangeService) GetTicker(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request orderBook.StandardOrderBookRequest
+	if err := c.ParamsParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	ticker, err := service.Cache.Ticker(c.Context(), exchangeAdapter.Name(), request.Symbol,
+		service.orderBookLoader(exchangeAdapter, request.Symbol, userId))
+	if err != nil {
+		return c.Status(adapterErrorStatus(err)).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	c.Set(fiber.HeaderAge, strconv.FormatInt(ticker.CacheAgeMs/1000, 10))
+	return c.Status(fiber.StatusOK).JSON(ticker)
+}
+
+// wholeExchange reports whether a balance read with ctx covers every active credential of the user on the exchange.
+// Snapshots hold one balance per exchange, the balance of one sub account must not be stored as the exchange's
+func (service *ExchangeService) wholeExchange(ctx context.Context, userId, exchangeID uuid.UUID) bool {
+	if service.ExchangeCredentialRepo == nil {
+		return true
+	}
+	creds, err := service.ExchangeCredentialRepo.ListActiveByUser(ctx, userId)
+	if err != nil {
+		return false
+	}
+	count := 0
+	for _, cred := range creds {
+		if cred.ExchangeID == exchangeID {
+			count++
+		}
+	}
+	return count <= 1
+}
+
+// adapterErrorStatus answers 429 when the call was held back by the exchange rate limit, 503 while the exchange
+// circuit breaker is open or paper trading has no recent book, and 400 otherwise
+func adapterErrorStatus(err error) int {
+	if errors.Is(err, helpers.ErrRateLimited) {
+		return fiber.StatusTooManyRequests
+	}
+	if errors.Is(err, helpers.ErrCircuitOpen) || errors.Is(err, papertrade.ErrStaleOrderBook) {
+		return fiber.StatusServiceUnavailable
+	}
+	return fiber.StatusBadRequest
+}
+
+// orderBookLoader reads a book on a cache miss. The cache is shared by every user, so public books are read without
+// credentials and stored as snapshots, as the refresher does, and a hit and a miss answer the same user alike. It may
+// run after the request is done, so it keeps its own copy of the symbol instead of the request buffer
+func (service *ExchangeService) orderBookLoader(exchangeAdapter registry.IExchange, symbol string,
+	userId uuid.UUID) marketCache.Loader {
+	symbol = strings.Clone(symbol)
+	return func(ctx context.Context) (*models.OrderBookSnapshot, error) {
+		if fetcher, public := exchangeAdapter.(registry.IOrderBookFetcher); public {
+			return service.Cache.Fetch(ctx, fetcher, symbol)
+		}
+		return exchangeAdapter.GetOrderBook(ctx, symbol, userId)
+	}
+}
+
+func (service *ExchangeService) PlaceOrder(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request order.StandardOrderRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	if request.ClientOrderId == "" {
+		request.ClientOrderId = c.Get("Idempotency-Key")
+	}
+	orderHistory, err := exchangeAdapter.PlaceOrder(middleware.CredentialContext(c), &request, userId)
+	if errors.Is(err, order.ErrPlacementInProgress) {
+		return c.Status(fiber.StatusConflict).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	var validationErr *order.ValidationError
+	if errors.As(err, &validationErr) {
+		status := fiber.StatusUnprocessableEntity
+		if validationErr.Code == order.CodeClientOrderIDInUse {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(exchange.ErrorResponse{
+			Error: validationErr.Message, Code: string(validationErr.Code), Field: validationErr.Field})
+	}
+	if err != nil {
+		return c.Status(adapterErrorStatus(err)).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(order.NewStandardOrderResponse(orderHistory))
+}
+
+func (service *ExchangeService) CancelOrder(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request order.CancelOrderRequest
+	if err := c.ParamsParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format missing orderId as url param"})
+	}
+
+	resultErr := exchangeAdapter.CancelOrder(middleware.CredentialContext(c), &request.OrderId, userId)
+	if resultErr != nil {
+		return c.Status(adapterErrorStatus(resultErr)).JSON(exchange.ErrorResponse{Error: resultErr.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(map[string]string{"message": "success"})
+}
+
+// CancelOldOrders cancels every order of a market placed more than the given hours ago, on exchanges supporting it
+func (service *ExchangeService) CancelOldOrders(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	canceller, ok := exchangeAdapter.(registry.IBulkCanceller)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{
+			Error: exchangeAdapter.Name() + " does not cancel orders by age"})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request order.CancelOldOrdersRequest
+	if err := c.BodyParser(&request); err != nil || request.Symbol == "" || request.Hours <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{
+			Error: "symbol and a positive hours are required"})
+	}
+	if err := canceller.CancelOldOrders(middleware.CredentialContext(c), &request, userId); err != nil {
+		return c.Status(adapterErrorStatus(err)).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(map[string]string{"message": "success"})
+}
+
+func (service *ExchangeService) RenewAccessToken(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	renewer, ok := exchangeAdapter.(registry.ITokenRenewer)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{
+			Error: exchangeAdapter.Name() + " does not use renewable access tokens"})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	creds, err := renewer.RenewAccessToken(middleware.CredentialContext(c), userId)
+	if err != nil {
+		return c.Status(adapterErrorStatus(err)).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	response := exchangeCredentials.RenewAccessTokenResponse{AccessToken: creds.AccessKey}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func (service *ExchangeService) GetOrder(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request order.GetOrderRequest
+	if err := c.ParamsParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format missing orderId as url param"})
+	}
+	orderResponse, err := exchangeAdapter.GetOrder(middleware.CredentialContext(c), request.OrderId, userId)
+	if err != nil {
+		return c.Status(adapterErrorStatus(err)).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(orderResponse)
+}
+
+func (service *ExchangeService) ListOrders(c *fiber.Ctx) error {
+	exchangeAdapter, err := service.Registry.Get(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request order.ListOrdersRequest
+	if err := c.QueryParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	if request.Status != "" && request.Status != "open" {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "only status=open is supported"})
+	}
+	ctx := middleware.CredentialContext(c)
+	orders, err := exchangeAdapter.ListOpenOrders(ctx, request.Symbol, userId)
+	if err != nil {
+		return c.Status(adapterErrorStatus(err)).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	stored, err := service.storedOpenOrders(ctx, exchangeAdapter.Name(), request.Symbol, userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(order.ReconcileOpenOrders(orders, stored))
+}
+
+// storedOpenOrders returns the orders stored as open for the credential ctx selects on the exchange, only those of
+// symbol when one is given
+func (service *ExchangeService) storedOpenOrders(ctx context.Context, exchangeName, symbol string,
+	userId uuid.UUID) ([]models.OrderHistory, error) {
+	exchangeModel, err := service.ExchangeRepo.GetByName(ctx, exchangeName)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := service.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchangeModel.ID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := service.OrderRepo.GetOpenOrders(ctx, userId, creds.ID)
+	if err != nil || symbol == "" {
+		return stored, err
+	}
+	canonical := strings.ToUpper(symbol)
+	if baseAsset, quoteAsset, err := symbols.Parse(symbol); err == nil {
+		canonical = symbols.Canonical(baseAsset, quoteAsset)
+	}
+	filtered := stored[:0]
+	for _, storedOrder := range stored {
+		if strings.EqualFold(storedOrder.TradingPair.Symbol, symbol) ||
+			symbols.Canonical(storedOrder.TradingPair.BaseAsset, storedOrder.TradingPair.QuoteAsset) == canonical {
+			filtered = append(filtered, storedOrder)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,22 @@
+package market
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+)
+
+type Router struct {
+	Service  *MarketService
+	UserRepo *user.UserRepository
+	Parser   *helpers.JWTParser
+}
+
+func (router *Router) SetMarketRouter(fiberRouter *fiber.App) {
+	group := fiberRouter.Group("/market")
+	group.Use(middleware.JWTAuthMiddleware(*router.UserRepo, router.Parser))
+	group.Get("/symbols", router.Service.ListSymbols)
+	group.Get("/:symbol/book", router.Service.GetConsolidatedBook)
+	group.Get("/:exchange/:symbol/candles", router.Service.GetCandles)
+}
@@ -0,0 +1,124 @@
+package market
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/domain/candle"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/market"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBookDepth    = 50
+	maxBookDepth        = 500
+	defaultCandlesLimit = 500
+	maxCandlesLimit     = 1000
+)
+
+// MarketService serves market data merged across exchanges
+type MarketService struct {
+	Consolidator   *market.Consolidator
+	SymbolRepo     *symbols.SymbolRepository
+	SymbolRegistry *symbols.SymbolRegistry
+	ExchangeRepo   *exchange.ExchangeRepository
+	CandleRepo     *candle.CandleRepository
+}
+
+// ListSymbols returns every canonical symbol with the symbol each exchange listing it uses natively
+func (service *MarketService) ListSymbols(c *fiber.Ctx) error {
+	canonicalSymbols, err := service.SymbolRepo.List(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	response := make([]symbols.SymbolResponse, 0, len(canonicalSymbols))
+	for _, canonicalSymbol := range canonicalSymbols {
+		response = append(response, symbols.NewSymbolResponse(canonicalSymbol))
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetConsolidatedBook returns the order book of a canonical symbol merged across every exchange listing it
+func (service *MarketService) GetConsolidatedBook(c *fiber.Ctx) error {
+	var request market.ConsolidatedBookRequest
+	if err := c.QueryParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	if request.Depth <= 0 {
+		request.Depth = defaultBookDepth
+	}
+	if request.Depth > maxBookDepth {
+		request.Depth = maxBookDepth
+	}
+	response, err := service.Consolidator.Book(c.Context(), c.Params("symbol"), request.Depth)
+	switch {
+	case errors.Is(err, symbols.ErrInvalidSymbol):
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, market.ErrUnlistedMarket):
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetCandles returns the candles of a symbol on one exchange, the most recent limit candles of the range oldest first
+func (service *MarketService) GetCandles(c *fiber.Ctx) error {
+	var request candle.CandlesRequest
+	if err := c.QueryParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	interval, err := candle.ParseInterval(request.Interval)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	filter := candle.CandleFilter{Interval: interval, Limit: request.Limit}
+	if request.From != "" {
+		if filter.From, err = time.Parse(time.RFC3339, request.From); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "from must be an RFC3339 time"})
+		}
+	}
+	if request.To != "" {
+		if filter.To, err = time.Parse(time.RFC3339, request.To); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "to must be an RFC3339 time"})
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "to must be after from"})
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultCandlesLimit
+	}
+	if filter.Limit > maxCandlesLimit {
+		filter.Limit = maxCandlesLimit
+	}
+
+	exchangeModel, err := service.ExchangeRepo.GetByName(c.Context(), strings.ToLower(c.Params("exchange")))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: "exchange not found"})
+	}
+	pair, err := service.SymbolRegistry.Resolve(c.Context(), exchangeModel.ID, c.Params("symbol"))
+	switch {
+	case errors.Is(err, symbols.ErrUnlistedSymbol):
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	filter.TradingPairID = pair.ID
+	candles, err := service.CandleRepo.List(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	response := candle.CandlesResponse{
+		Exchange: exchangeModel.Name,
+		Symbol:   symbols.Canonical(pair.BaseAsset, pair.QuoteAsset),
+		Interval: interval,
+		Candles:  make([]candle.CandleResponse, 0, len(candles)),
+	}
+	for _, bar := range candles {
+		response.Candles = append(response.Candles, candle.NewCandleResponse(bar))
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
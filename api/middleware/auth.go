@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rzabhd80/eye-on/domain/user"
 	"github.com/rzabhd80/eye-on/internal/helpers"
@@ -10,6 +11,10 @@ import (
 func JWTAuthMiddleware(userRepo user.UserRepository, jwtParser *helpers.JWTParser) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
+		// browsers cannot set headers on WebSocket handshakes, so those may pass the token as a query parameter
+		if authHeader == "" && websocket.IsWebSocketUpgrade(c) && c.Query("token") != "" {
+			authHeader = "Bearer " + c.Query("token")
+		}
 		if authHeader == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(user.ErrorResponse{
 				Error: "Authorization header required",
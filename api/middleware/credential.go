@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/user"
+)
+
+const credentialSelectorKey = "credential_selector"
+
+// CredentialSelector reads the optional credential_id or credential_label query parameter, naming which of the
+// user's credentials the exchange calls of the request use instead of the default one
+func CredentialSelector() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		selector, err := exchangeCredentials.ParseSelector(c.Query("credential_id"), c.Query("credential_label"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(user.ErrorResponse{Error: err.Error()})
+		}
+		if !selector.IsZero() {
+			c.Locals(credentialSelectorKey, selector)
+		}
+		return c.Next()
+	}
+}
+
+// CredentialContext is the context to call exchanges with, carrying the credential CredentialSelector read
+func CredentialContext(c *fiber.Ctx) context.Context {
+	selector, ok := c.Locals(credentialSelectorKey).(exchangeCredentials.Selector)
+	if !ok {
+		return c.Context()
+	}
+	return exchangeCredentials.WithSelector(c.Context(), selector)
+}
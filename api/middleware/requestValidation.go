@@ -24,9 +24,9 @@ func ValidateOrderRequest(req *order.StandardOrderRequest) error {
 	}
 
 	// Must have either Quantity OR (BaseAmount/QuoteAmount)
-	hasQuantity := req.Quantity != nil && *req.Quantity > 0
-	hasBaseAmount := req.BaseAmount != nil && *req.BaseAmount > 0
-	hasQuoteAmount := req.QuoteAmount != nil && *req.QuoteAmount > 0
+	hasQuantity := req.Quantity != nil && req.Quantity.IsPositive()
+	hasBaseAmount := req.BaseAmount != nil && req.BaseAmount.IsPositive()
+	hasQuoteAmount := req.QuoteAmount != nil && req.QuoteAmount.IsPositive()
 
 	if !hasQuantity && !hasBaseAmount && !hasQuoteAmount {
 		return fmt.Errorf("must specify either quantity or base_amount/quote_amount")
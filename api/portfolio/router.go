@@ -0,0 +1,21 @@
+package portfolio
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+)
+
+type Router struct {
+	Service  *PortfolioService
+	UserRepo *user.UserRepository
+	Parser   *helpers.JWTParser
+}
+
+func (router *Router) SetPortfolioRouter(fiberRouter *fiber.App) {
+	group := fiberRouter.Group("/portfolio")
+	group.Use(middleware.JWTAuthMiddleware(*router.UserRepo, router.Parser))
+	group.Get("/", router.Service.GetPortfolio)
+	group.Get("/history", router.Service.GetHistory)
+}
@@ -0,0 +1,84 @@
+package portfolio
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/portfolio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHistoryRange    = 7 * 24 * time.Hour
+	defaultHistoryInterval = time.Hour
+)
+
+// PortfolioService serves the user's holdings and their value across every exchange
+type PortfolioService struct {
+	Aggregator *portfolio.Aggregator
+}
+
+func (service *PortfolioService) GetPortfolio(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	response, err := service.Aggregator.Collect(c.Context(), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func (service *PortfolioService) GetHistory(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request portfolio.HistoryRequest
+	if err := c.QueryParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	to := time.Now()
+	if request.To != "" {
+		parsed, err := time.Parse(time.RFC3339, request.To)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "to must be an RFC3339 time"})
+		}
+		to = parsed
+	}
+	from := to.Add(-defaultHistoryRange)
+	if request.From != "" {
+		parsed, err := time.Parse(time.RFC3339, request.From)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "from must be an RFC3339 time"})
+		}
+		from = parsed
+	}
+	interval := defaultHistoryInterval
+	if request.Interval != "" {
+		parsed, err := ParseInterval(request.Interval)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: err.Error()})
+		}
+		interval = parsed
+	}
+	series, err := service.Aggregator.History(c.Context(), userId, from, to, interval)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(series)
+}
+
+// ParseInterval parses Go durations such as 15m or 4h, and whole days such as 1d
+func ParseInterval(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		count, err := strconv.Atoi(days)
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("invalid interval %s", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid interval %s", value)
+	}
+	return interval, nil
+}
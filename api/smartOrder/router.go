@@ -0,0 +1,23 @@
+package smartOrder
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+)
+
+type Router struct {
+	Service  *SmartOrderService
+	UserRepo *user.UserRepository
+	Parser   *helpers.JWTParser
+}
+
+func (router *Router) SetSmartOrderRouter(fiberRouter *fiber.App) {
+	group := fiberRouter.Group("/orders/smart")
+	group.Use(middleware.JWTAuthMiddleware(*router.UserRepo, router.Parser))
+	group.Use(middleware.CredentialSelector())
+	group.Post("/", router.Service.PlaceSmartOrder)
+	group.Get("/:id", router.Service.GetSmartOrder)
+	group.Delete("/:id", router.Service.CancelSmartOrder)
+}
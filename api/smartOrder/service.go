@@ -0,0 +1,83 @@
+package smartOrder
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/smartOrder"
+)
+
+// SmartOrderService places, tracks and cancels orders split across exchanges
+type SmartOrderService struct {
+	Router *smartOrder.SmartRouter
+}
+
+func (service *SmartOrderService) PlaceSmartOrder(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	var request order.StandardOrderRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "Bad Request Format"})
+	}
+	if err := middleware.ValidateOrderRequest(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	if request.ClientOrderId == "" {
+		request.ClientOrderId = c.Get("Idempotency-Key")
+	}
+	response, err := service.Router.Route(middleware.CredentialContext(c), &request, userId)
+	if err != nil {
+		return service.routingError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func (service *SmartOrderService) GetSmartOrder(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "malformed order id"})
+	}
+	response, err := service.Router.Get(c.Context(), id, userId)
+	if err != nil {
+		return service.routingError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// CancelSmartOrder cancels every open child order, children that could not be cancelled are listed in failures
+func (service *SmartOrderService) CancelSmartOrder(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(exchange.ErrorResponse{Error: "malformed order id"})
+	}
+	response, err := service.Router.Cancel(middleware.CredentialContext(c), id, userId)
+	if err != nil {
+		return service.routingError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func (service *SmartOrderService) routingError(c *fiber.Ctx, err error) error {
+	var validationErr *order.ValidationError
+	switch {
+	case errors.As(err, &validationErr) && validationErr.Code == order.CodeClientOrderIDInUse:
+		return c.Status(fiber.StatusConflict).JSON(exchange.ErrorResponse{
+			Error: validationErr.Message, Code: string(validationErr.Code), Field: validationErr.Field})
+	case errors.As(err, &validationErr):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(exchange.ErrorResponse{
+			Error: validationErr.Message, Code: string(validationErr.Code), Field: validationErr.Field})
+	case errors.Is(err, order.ErrPlacementInProgress):
+		return c.Status(fiber.StatusConflict).JSON(exchange.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, smartOrder.ErrRoutedOrderNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, smartOrder.ErrNoVenues):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(exchange.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, smartOrder.ErrNoOrderBooks):
+		return c.Status(fiber.StatusBadGateway).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(exchange.ErrorResponse{Error: err.Error()})
+}
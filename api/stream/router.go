@@ -0,0 +1,22 @@
+package stream
+
+import (
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rzabhd80/eye-on/api/middleware"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+)
+
+type Router struct {
+	Service  *StreamService
+	UserRepo *user.UserRepository
+	Parser   *helpers.JWTParser
+}
+
+func (router *Router) SetStreamRouter(fiberRouter *fiber.App) {
+	group := fiberRouter.Group("/ws")
+	group.Use(middleware.JWTAuthMiddleware(*router.UserRepo, router.Parser))
+	group.Get("/orderbook/:exchange/:symbol", router.Service.UpgradeOrderBook,
+		websocket.New(router.Service.StreamOrderBook))
+}
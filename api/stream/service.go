@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/orderBookStream"
+	"strings"
+)
+
+// StreamService pushes live order books to WebSocket clients
+type StreamService struct {
+	Hub *orderBookStream.Hub
+}
+
+// UpgradeOrderBook rejects plain HTTP requests and unsupported exchanges before the connection is upgraded
+func (service *StreamService) UpgradeOrderBook(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(exchange.ErrorResponse{Error: "WebSocket upgrade required"})
+	}
+	if _, err := service.Hub.Registry.Get(strings.ToLower(c.Params("exchange"))); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(exchange.ErrorResponse{Error: err.Error()})
+	}
+	return c.Next()
+}
+
+// StreamOrderBook writes every change of the order book to the connection until the client disconnects
+func (service *StreamService) StreamOrderBook(conn *websocket.Conn) {
+	userId := conn.Locals("user_id").(uuid.UUID)
+	subscription, err := service.Hub.Subscribe(conn.Params("exchange"), conn.Params("symbol"), userId)
+	if err != nil {
+		_ = conn.WriteJSON(exchange.ErrorResponse{Error: err.Error()})
+		return
+	}
+	defer subscription.Close()
+
+	// clients only send control frames, reading is how a closed connection is noticed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-closed:
+			return
+		case update, ok := <-subscription.Updates:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(update); err != nil {
+				return
+			}
+		}
+	}
+}
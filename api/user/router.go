@@ -15,8 +15,16 @@ func (router *Router) SetUserRouter(fiberRouter *fiber.App) {
 	groupRouter := fiberRouter.Group("/user")
 	groupRouter.Post("/register", router.Service.Register)
 	groupRouter.Post("/login", router.Service.Login)
+	groupRouter.Get("/exchangeCredentials", middleware.JWTAuthMiddleware(
+		*router.Service.User.UserRepo, router.Parser), router.Service.ListExchangeCredentials)
+	groupRouter.Get("/exchangeCredentials/:id", middleware.JWTAuthMiddleware(
+		*router.Service.User.UserRepo, router.Parser), router.Service.GetExchangeCredential)
 	groupRouter.Post("/exchangeCredentials", middleware.JWTAuthMiddleware(
 		*router.Service.User.UserRepo, router.Parser), router.Service.CreateExchangeCredential)
 	groupRouter.Put("/exchangeCredentials", middleware.JWTAuthMiddleware(
 		*router.Service.User.UserRepo, router.Parser), router.Service.UpdateExchangeCredentials)
+	groupRouter.Put("/exchangeCredentials/:id", middleware.JWTAuthMiddleware(
+		*router.Service.User.UserRepo, router.Parser), router.Service.UpdateExchangeCredentials)
+	groupRouter.Delete("/exchangeCredentials/:id", middleware.JWTAuthMiddleware(
+		*router.Service.User.UserRepo, router.Parser), router.Service.DeleteExchangeCredential)
 }
@@ -47,15 +47,71 @@ func (service *UserAuthService) CreateExchangeCredential(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(response)
 }
 
+func (service *UserAuthService) ListExchangeCredentials(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	response, err := service.User.ListExchangeCredentials(c.Context(), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func (service *UserAuthService) GetExchangeCredential(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	credentialId, parseErr := uuid.Parse(c.Params("id"))
+	if parseErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(user.ErrorResponse{Error: "malformed credential id"})
+	}
+	response, err := service.User.GetExchangeCredential(c.Context(), userId, credentialId)
+	if err != nil {
+		return c.Status(credentialErrorStatus(err)).JSON(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// UpdateExchangeCredentials updates the credential in the path, or without one the credential named by the
+// exchange and label of the body
 func (service *UserAuthService) UpdateExchangeCredentials(c *fiber.Ctx) error {
 	userId := c.Locals("user_id").(uuid.UUID)
+	var credentialId uuid.UUID
+	if id := c.Params("id"); id != "" {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(user.ErrorResponse{Error: "malformed credential id"})
+		}
+		credentialId = parsed
+	}
 	var requestBody user.ExchangeCredentialUpdateRequest = user.ExchangeCredentialUpdateRequest{}
 	if err := c.BodyParser(&requestBody); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(user.ErrorResponse{Error: "Bad Request Format"})
 	}
-	response, err := service.User.UpdateExchangeCredential(c.Context(), requestBody, userId)
+	response, err := service.User.UpdateExchangeCredential(c.Context(), requestBody, userId, credentialId)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(err)
+		return c.Status(credentialErrorStatus(err)).JSON(err)
 	}
 	return c.Status(fiber.StatusOK).JSON(response)
 }
+
+func (service *UserAuthService) DeleteExchangeCredential(c *fiber.Ctx) error {
+	userId := c.Locals("user_id").(uuid.UUID)
+	credentialId, parseErr := uuid.Parse(c.Params("id"))
+	if parseErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(user.ErrorResponse{Error: "malformed credential id"})
+	}
+	if err := service.User.DeleteExchangeCredential(c.Context(), userId, credentialId); err != nil {
+		return c.Status(credentialErrorStatus(err)).JSON(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(user.MessageResponse{Message: "success"})
+}
+
+// credentialErrorStatus answers 404 for a credential the user does not hold, 409 for one that still has open orders
+// and 400 otherwise
+func credentialErrorStatus(err *user.ErrorResponse) int {
+	if err == user.ErrCredentialNotFound {
+		return fiber.StatusNotFound
+	}
+	if err == user.ErrCredentialHasOpenOrders {
+		return fiber.StatusConflict
+	}
+	return fiber.StatusBadRequest
+}
@@ -5,18 +5,17 @@ import (
 	"fmt"
 	redis2 "github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
-	"github.com/rzabhd80/eye-on/api/bitpin"
-	"github.com/rzabhd80/eye-on/api/nobitex"
+	arbitrageService "github.com/rzabhd80/eye-on/api/arbitrage"
+	balanceService "github.com/rzabhd80/eye-on/api/balance"
+	exchangeService "github.com/rzabhd80/eye-on/api/exchange"
+	marketService "github.com/rzabhd80/eye-on/api/market"
+	portfolioService "github.com/rzabhd80/eye-on/api/portfolio"
+	smartOrderService "github.com/rzabhd80/eye-on/api/smartOrder"
+	streamService "github.com/rzabhd80/eye-on/api/stream"
 	userService "github.com/rzabhd80/eye-on/api/user"
-	"github.com/rzabhd80/eye-on/domain/balance"
-	"github.com/rzabhd80/eye-on/domain/exchange"
-	bitpinEntity "github.com/rzabhd80/eye-on/domain/exchange/bitpin"
-	nobitexEntity "github.com/rzabhd80/eye-on/domain/exchange/nobitex"
-	"github.com/rzabhd80/eye-on/domain/exchange/registry"
-	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
-	"github.com/rzabhd80/eye-on/domain/order"
-	"github.com/rzabhd80/eye-on/domain/orderBook"
-	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/domain/marketCache"
+	"github.com/rzabhd80/eye-on/domain/orderBookStream"
+	"github.com/rzabhd80/eye-on/domain/smartOrder"
 	"github.com/rzabhd80/eye-on/domain/user"
 	db "github.com/rzabhd80/eye-on/internal/database"
 	"github.com/rzabhd80/eye-on/internal/envConfig"
@@ -45,6 +44,7 @@ func apiService(cntx *cli.Context, logger *zap.Logger) error {
 	appRedisClient := redisConn.NewRedisClient()
 	jwtParser := helpers.JWTParser{EnvConf: devConf}
 	request := helpers.NewRequest(10 * time.Second)
+	request.Limiter.Client = appRedisClient
 
 	defer func(redisCLient *redis2.Client) {
 		err := redisCLient.Close()
@@ -68,98 +68,142 @@ func apiService(cntx *cli.Context, logger *zap.Logger) error {
 	if err != nil {
 		return err
 	}
-	exchangeRepo := exchange.NewExchangeRepository(psqlDb.GormDb)
-
-	tradingPairRepo := traidingPair.TradingPairRepository{DB: psqlDb.GormDb}
-
-	exchangeCredRepo := exchangeCredentials.NewExchangeCredentialRepository(psqlDb.GormDb, devConf)
-	orderRepo := order.NewOrderHistoryRepository(psqlDb.GormDb)
-	orderBookRepo := orderBook.NewOrderBookSnapshotRepository(psqlDb.GormDb)
-	balanceRepo := balance.NewBalanceSnapshotRepository(psqlDb.GormDb)
-
-	exchangeRegistery := registry.NewRegistry(exchangeRepo, &tradingPairRepo, exchangeCredRepo, psqlDb.GormDb)
-
-	registry.SetDefaultRegistry(exchangeRegistery)
-
-	bitpinSymbolRegistry := bitpinEntity.BitpinSymbolRegistry{}
-	NobitexSymbolRegistry := nobitexEntity.NobitexSymbolRegistry{}
-	bitpinExchange, err := registry.GetOrCreateExchange(ctx, registry.ExchangeConfig{
-		Name:          "bitpin",
-		DisplayName:   "bitpin",
-		BaseURL:       "https://api.bitpin.ir",
-		RateLimit:     0,
-		Features:      nil,
-		SymbolFactory: &bitpinSymbolRegistry,
-	})
-
-	nobitexExchange, err := registry.GetOrCreateExchange(ctx, registry.ExchangeConfig{
-		Name:          "nobitex",
-		DisplayName:   "nobitex",
-		BaseURL:       "https://apiv2.nobitex.ir",
-		RateLimit:     0,
-		Features:      nil,
-		SymbolFactory: &NobitexSymbolRegistry,
-	})
+	repos := newRepositories(psqlDb.GormDb, devConf)
+	exchangeRegistery, err := registerExchanges(ctx, psqlDb.GormDb, repos, devConf, request)
 	if err != nil {
 		return err
 	}
-
-	userRepo := user.NewUserRepository(psqlDb.GormDb)
+	// orders are validated against the stored filters, refresh them before serving rather than wait for the worker.
+	// A failing exchange only keeps the filters it had
+	if devConf.SymbolSyncInterval > 0 {
+		newSymbolSyncer(exchangeRegistery, repos, devConf, logger).SyncLogged(ctx)
+	}
 
 	app := fiber.New()
 
 	userRouter := userService.Router{
 		Service: &userService.UserAuthService{User: &user.User{
-			UserRepo:         userRepo,
-			ExchangeRepo:     exchangeRepo,
-			ExchangeCredRepo: exchangeCredRepo,
+			UserRepo:         repos.userRepo,
+			ExchangeRepo:     repos.exchangeRepo,
+			ExchangeCredRepo: repos.exchangeCredRepo,
+			OrderRepo:        repos.orderRepo,
 			JwtParser:        &jwtParser,
 			EnvConf:          devConf,
 		}},
 		Parser: &jwtParser,
 	}
 
-	nobitexRouter := nobitex.Router{
-		Service: &nobitex.NobitexService{
-			Exchange: &nobitexEntity.NobitexExchange{
-				NobitexExchangeModel:   nobitexExchange.Exchange,
-				ExchangeRepo:           exchangeRepo,
-				ExchangeCredentialRepo: exchangeCredRepo,
-				UserRepo:               userRepo,
-				TradingPairRepo:        &tradingPairRepo,
-				OrderRepo:              orderRepo,
-				OrderBookRepo:          orderBookRepo,
-				BalanceRepo:            balanceRepo,
-				Request:                request,
-			},
+	orderBookCache := &marketCache.OrderBookCache{
+		Client:    appRedisClient,
+		TTL:       devConf.OrderBookCacheTTL,
+		Stale:     devConf.OrderBookCacheStale,
+		Snapshots: repos.orderBookRepo,
+		Logger:    logger,
+	}
+	exchangeRouter := exchangeService.Router{
+		Service: &exchangeService.ExchangeService{
+			Registry:               exchangeRegistery,
+			BalanceRepo:            repos.balanceRepo,
+			ExchangeCredentialRepo: repos.exchangeCredRepo,
+			ExchangeRepo:           repos.exchangeRepo,
+			OrderRepo:              repos.orderRepo,
+			Cache:                  orderBookCache,
 		},
-		Parser: &jwtParser,
+		UserRepo: repos.userRepo,
+		Parser:   &jwtParser,
+	}
+
+	portfolioRouter := portfolioService.Router{
+		Service:  &portfolioService.PortfolioService{Aggregator: newPortfolioAggregator(exchangeRegistery, repos)},
+		UserRepo: repos.userRepo,
+		Parser:   &jwtParser,
 	}
-	bitpinRouter := bitpin.Router{
-		Service: &bitpin.BitpinService{
-			Exchange: &bitpinEntity.BitpinExchange{
-				BitpinExchangeModel:    bitpinExchange.Exchange,
-				ExchangeRepo:           exchangeRepo,
-				ExchangeCredentialRepo: exchangeCredRepo,
-				UserRepo:               userRepo,
-				TradingPairRepo:        &tradingPairRepo,
-				OrderRepo:              orderRepo,
-				OrderBookRepo:          orderBookRepo,
-				BalanceRepo:            balanceRepo,
-				Request:                request,
-				EnvConf:                devConf,
-			},
+
+	balanceRouter := balanceService.Router{
+		Service: &balanceService.BalanceService{
+			BalanceRepo:  repos.balanceRepo,
+			ExchangeRepo: repos.exchangeRepo,
 		},
-		Parser: &jwtParser,
+		UserRepo: repos.userRepo,
+		Parser:   &jwtParser,
+	}
+
+	if devConf.OrderBookStreamInterval <= 0 {
+		return fmt.Errorf("ORDERBOOK_STREAM_INTERVAL must be positive, got %s", devConf.OrderBookStreamInterval)
+	}
+	orderBookHub := &orderBookStream.Hub{
+		Registry: exchangeRegistery,
+		Interval: devConf.OrderBookStreamInterval,
+		Logger:   logger,
+	}
+	defer orderBookHub.Close()
+	streamRouter := streamService.Router{
+		Service:  &streamService.StreamService{Hub: orderBookHub},
+		UserRepo: repos.userRepo,
+		Parser:   &jwtParser,
+	}
+
+	marketRouter := marketService.Router{
+		Service: &marketService.MarketService{
+			Consolidator:   newConsolidator(exchangeRegistery, repos),
+			SymbolRepo:     repos.symbolRepo,
+			SymbolRegistry: repos.symbolRegistry,
+			ExchangeRepo:   repos.exchangeRepo,
+			CandleRepo:     repos.candleRepo,
+		},
+		UserRepo: repos.userRepo,
+		Parser:   &jwtParser,
+	}
+
+	arbitrageRouter := arbitrageService.Router{
+		Service: &arbitrageService.ArbitrageService{
+			ArbitrageRepo: repos.arbitrageRepo,
+			ExchangeRepo:  repos.exchangeRepo,
+		},
+		UserRepo: repos.userRepo,
+		Parser:   &jwtParser,
+	}
+
+	smartOrderRouter := smartOrderService.Router{
+		Service: &smartOrderService.SmartOrderService{Router: &smartOrder.SmartRouter{
+			Registry:               exchangeRegistery,
+			ExchangeCredentialRepo: repos.exchangeCredRepo,
+			TradingPairRepo:        repos.tradingPairRepo,
+			RoutedOrderRepo:        repos.routedOrderRepo,
+		}},
+		UserRepo: repos.userRepo,
+		Parser:   &jwtParser,
 	}
 
 	//Register your routes here
 	userRouter.SetUserRouter(app)
-	bitpinRouter.SetUserRouter(app)
-	nobitexRouter.SetUserRouter(app)
+	exchangeRouter.SetExchangeRouter(app)
+	portfolioRouter.SetPortfolioRouter(app)
+	balanceRouter.SetBalanceRouter(app)
+	streamRouter.SetStreamRouter(app)
+	marketRouter.SetMarketRouter(app)
+	arbitrageRouter.SetArbitrageRouter(app)
+	smartOrderRouter.SetSmartOrderRouter(app)
 
 	ctx, stp := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 
+	if devConf.OrderBookCacheTTL > 0 && devConf.OrderBookCacheRefreshInterval > 0 {
+		refresher := &marketCache.Refresher{
+			Cache:     orderBookCache,
+			Registry:  exchangeRegistery,
+			Interval:  devConf.OrderBookCacheRefreshInterval,
+			HotWindow: devConf.OrderBookCacheHotWindow,
+			Logger:    logger,
+		}
+		go func() {
+			logger.Info("Starting order book cache refresher",
+				zap.Duration("interval", devConf.OrderBookCacheRefreshInterval))
+			if err := refresher.Run(ctx); err != nil {
+				logger.Error("order book cache refresher stopped", zap.Error(err))
+			}
+		}()
+	}
+
 	go func() {
 		logger.Info("Starting server on port %s", zap.String("port", devConf.PORT))
 		if err := app.Listen(":" + devConf.PORT); err != nil {
@@ -24,6 +24,36 @@ func main() {
 				}
 				return nil
 			}},
+			{Name: "worker", Usage: "run background order sync worker", Action: func(ctx *cli.Context) error {
+				err := workerService(ctx, logger)
+				if err != nil {
+					logger.Fatal("failed to run worker", zap.Error(err))
+					return err
+				}
+				return nil
+			}},
+			{Name: "symbols", Usage: "manage the trading pairs of the exchanges", Subcommands: []*cli.Command{
+				{Name: "sync", Usage: "sync trading pairs and their filters from the exchange market listings",
+					Action: func(ctx *cli.Context) error {
+						err := symbolsSyncService(ctx, logger)
+						if err != nil {
+							logger.Error("symbol sync failed", zap.Error(err))
+							return err
+						}
+						return nil
+					}},
+			}},
+			{Name: "maintenance", Usage: "keep the stored snapshots in check", Subcommands: []*cli.Command{
+				{Name: "prune", Usage: "downsample and expire order book and balance snapshots",
+					Action: func(ctx *cli.Context) error {
+						err := maintenancePruneService(ctx, logger)
+						if err != nil {
+							logger.Error("snapshot prune failed", zap.Error(err))
+							return err
+						}
+						return nil
+					}},
+			}},
 		},
 	}
 	er := app.Run(os.Args)
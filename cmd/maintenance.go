@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	db "github.com/rzabhd80/eye-on/internal/database"
+	"github.com/rzabhd80/eye-on/internal/envConfig"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+// maintenancePruneService applies the snapshot retention policies once and prints what was removed
+func maintenancePruneService(cntx *cli.Context, logger *zap.Logger) error {
+	ctx := cntx.Context
+	devConf, err := envCofig.LoadConfig()
+	if err != nil {
+		return err
+	}
+	psqlDb, err := db.NewDatabase(devConf)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := psqlDb.Close(); err != nil {
+			logger.Error("failed to close database", zap.Error(err))
+		}
+	}()
+	if err := psqlDb.Migrate(); err != nil {
+		return err
+	}
+	repos := newRepositories(psqlDb.GormDb, devConf)
+
+	results, err := newPruner(repos, devConf, logger).PruneAll(ctx)
+	for _, result := range results {
+		fmt.Printf("%-22s downsampled %d, expired %d\n", result.Table, result.Downsampled, result.Expired)
+	}
+	return err
+}
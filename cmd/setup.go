@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/rzabhd80/eye-on/domain/arbitrage"
+	"github.com/rzabhd80/eye-on/domain/balance"
+	"github.com/rzabhd80/eye-on/domain/candle"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	bitpinEntity "github.com/rzabhd80/eye-on/domain/exchange/bitpin"
+	nobitexEntity "github.com/rzabhd80/eye-on/domain/exchange/nobitex"
+	papertradeEntity "github.com/rzabhd80/eye-on/domain/exchange/papertrade"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/maintenance"
+	"github.com/rzabhd80/eye-on/domain/market"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/portfolio"
+	"github.com/rzabhd80/eye-on/domain/smartOrder"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/envConfig"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// repositories groups the repositories shared by the api server and the background workers
+type repositories struct {
+	exchangeRepo     *exchange.ExchangeRepository
+	tradingPairRepo  *traidingPair.TradingPairRepository
+	exchangeCredRepo *exchangeCredentials.ExchangeCredentialRepository
+	orderRepo        *order.OrderRepository
+	orderBookRepo    *orderBook.OrderBookSnapshotRepository
+	balanceRepo      *balance.BalanceSnapshotRepository
+	arbitrageRepo    *arbitrage.ArbitrageRepository
+	routedOrderRepo  *smartOrder.RoutedOrderRepository
+	symbolRepo       *symbols.SymbolRepository
+	symbolRegistry   *symbols.SymbolRegistry
+	candleRepo       *candle.CandleRepository
+	userRepo         *user.UserRepository
+}
+
+func newRepositories(gormDb *gorm.DB, devConf *envCofig.AppConfig) *repositories {
+	repos := &repositories{
+		exchangeRepo:     exchange.NewExchangeRepository(gormDb),
+		tradingPairRepo:  &traidingPair.TradingPairRepository{DB: gormDb},
+		exchangeCredRepo: exchangeCredentials.NewExchangeCredentialRepository(gormDb, devConf),
+		orderRepo:        order.NewOrderHistoryRepository(gormDb),
+		orderBookRepo:    newOrderBookRepository(gormDb, devConf),
+		balanceRepo:      balance.NewBalanceSnapshotRepository(gormDb),
+		arbitrageRepo:    arbitrage.NewArbitrageRepository(gormDb),
+		routedOrderRepo:  smartOrder.NewRoutedOrderRepository(gormDb),
+		symbolRepo:       symbols.NewSymbolRepository(gormDb),
+		candleRepo:       candle.NewCandleRepository(gormDb),
+		userRepo:         user.NewUserRepository(gormDb),
+	}
+	repos.symbolRegistry = &symbols.SymbolRegistry{Repo: repos.symbolRepo, TradingPairRepo: repos.tradingPairRepo}
+	return repos
+}
+
+// newOrderBookRepository applies the configured snapshot depth and keyframe interval
+func newOrderBookRepository(gormDb *gorm.DB, devConf *envCofig.AppConfig) *orderBook.OrderBookSnapshotRepository {
+	orderBookRepo := orderBook.NewOrderBookSnapshotRepository(gormDb)
+	orderBookRepo.Depth = devConf.OrderBookSnapshotDepth
+	orderBookRepo.KeyframeInterval = devConf.OrderBookKeyframeInterval
+	return orderBookRepo
+}
+
+// newPortfolioAggregator wires the balance aggregator shared by the portfolio api and the balance snapshot worker
+func newPortfolioAggregator(exchangeRegistery *registry.ExchangeRegistry, repos *repositories) *portfolio.Aggregator {
+	return &portfolio.Aggregator{
+		Registry:               exchangeRegistery,
+		ExchangeRepo:           repos.exchangeRepo,
+		ExchangeCredentialRepo: repos.exchangeCredRepo,
+		BalanceRepo:            repos.balanceRepo,
+		Valuator: &portfolio.Valuator{
+			TradingPairRepo: repos.tradingPairRepo,
+			OrderBookRepo:   repos.orderBookRepo,
+		},
+	}
+}
+
+// newConsolidator wires the multi-exchange order book shared by the market api and the arbitrage detector
+func newConsolidator(exchangeRegistery *registry.ExchangeRegistry, repos *repositories) *market.Consolidator {
+	return &market.Consolidator{
+		Registry:        exchangeRegistery,
+		TradingPairRepo: repos.tradingPairRepo,
+	}
+}
+
+// newBitpinTokenRefresher wires the worker renewing bitpin access tokens before they expire
+func newBitpinTokenRefresher(exchangeRegistery *registry.ExchangeRegistry, devConf *envCofig.AppConfig,
+	logger *zap.Logger) (*bitpinEntity.TokenRefresher, error) {
+	adapter, err := exchangeRegistery.Get("bitpin")
+	if err != nil {
+		return nil, err
+	}
+	bitpinAdapter, ok := adapter.(*bitpinEntity.BitpinExchange)
+	if !ok {
+		return nil, fmt.Errorf("bitpin adapter has unexpected type %T", adapter)
+	}
+	return &bitpinEntity.TokenRefresher{
+		Exchange: bitpinAdapter,
+		Interval: devConf.BitpinTokenRefreshInterval,
+		Logger:   logger,
+	}, nil
+}
+
+// newArbitrageDetector wires the arbitrage detector with the configured taker fees and profit threshold
+func newArbitrageDetector(exchangeRegistery *registry.ExchangeRegistry, repos *repositories,
+	devConf *envCofig.AppConfig, logger *zap.Logger) (*arbitrage.Detector, error) {
+	minProfitRate, err := decimal.NewFromString(devConf.ArbitrageMinProfitRate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ARBITRAGE_MIN_PROFIT_RATE: %w", err)
+	}
+	defaultTakerFee, err := decimal.NewFromString(devConf.ArbitrageDefaultTakerFee)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ARBITRAGE_DEFAULT_TAKER_FEE: %w", err)
+	}
+	takerFees := make(map[string]decimal.Decimal, len(devConf.ArbitrageTakerFees))
+	for exchangeName, fee := range devConf.ArbitrageTakerFees {
+		parsed, err := decimal.NewFromString(fee)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARBITRAGE_TAKER_FEES fee for %s: %w", exchangeName, err)
+		}
+		takerFees[strings.ToLower(exchangeName)] = parsed
+	}
+	return &arbitrage.Detector{
+		Consolidator:    newConsolidator(exchangeRegistery, repos),
+		TradingPairRepo: repos.tradingPairRepo,
+		ArbitrageRepo:   repos.arbitrageRepo,
+		TakerFees:       takerFees,
+		DefaultTakerFee: defaultTakerFee,
+		MinProfitRate:   minProfitRate,
+		Interval:        devConf.ArbitrageInterval,
+		Logger:          logger,
+	}, nil
+}
+
+// newSymbolSyncer wires the trading pair sync shared by the worker and the symbols sync command
+func newSymbolSyncer(exchangeRegistery *registry.ExchangeRegistry, repos *repositories, devConf *envCofig.AppConfig,
+	logger *zap.Logger) *symbols.SymbolSyncer {
+	return &symbols.SymbolSyncer{
+		Registry:        exchangeRegistery,
+		ExchangeRepo:    repos.exchangeRepo,
+		TradingPairRepo: repos.tradingPairRepo,
+		Symbols:         repos.symbolRegistry,
+		Interval:        devConf.SymbolSyncInterval,
+		Logger:          logger,
+	}
+}
+
+// newCandleBuilder wires the candle builder run by the worker
+func newCandleBuilder(repos *repositories, devConf *envCofig.AppConfig, logger *zap.Logger) *candle.Builder {
+	return &candle.Builder{
+		Repo:            repos.candleRepo,
+		TradingPairRepo: repos.tradingPairRepo,
+		Interval:        devConf.CandleBuildInterval,
+		Backfill:        devConf.CandleBackfill,
+		Logger:          logger,
+	}
+}
+
+// newPruner wires the snapshot retention shared by the worker and the maintenance prune command
+func newPruner(repos *repositories, devConf *envCofig.AppConfig, logger *zap.Logger) *maintenance.Pruner {
+	return &maintenance.Pruner{
+		Tables: []maintenance.Table{
+			{
+				Name:  "order_book_snapshots",
+				Store: repos.orderBookRepo,
+				Policy: maintenance.RetentionPolicy{
+					DownsampleAfter:  devConf.OrderBookDownsampleAfter,
+					DownsampleBucket: devConf.OrderBookDownsampleBucket,
+					Retention:        devConf.OrderBookRetention,
+				},
+			},
+			{
+				Name:  "balance_snapshots",
+				Store: repos.balanceRepo,
+				Policy: maintenance.RetentionPolicy{
+					DownsampleAfter:  devConf.BalanceDownsampleAfter,
+					DownsampleBucket: devConf.BalanceDownsampleBucket,
+					Retention:        devConf.BalanceRetention,
+				},
+			},
+		},
+		Interval: devConf.MaintenanceInterval,
+		Logger:   logger,
+	}
+}
+
+// registerExchanges creates the exchange records and registers every supported adapter in the default registry
+func registerExchanges(ctx context.Context, gormDb *gorm.DB, repos *repositories, devConf *envCofig.AppConfig,
+	request *helpers.Request) (*registry.ExchangeRegistry, error) {
+	exchangeRegistery := registry.NewRegistry(repos.exchangeRepo, repos.tradingPairRepo, repos.exchangeCredRepo, gormDb)
+
+	registry.SetDefaultRegistry(exchangeRegistery)
+	symbolRegistry := repos.symbolRegistry
+
+	bitpinSymbolRegistry := bitpinEntity.BitpinSymbolRegistry{Request: request}
+	NobitexSymbolRegistry := nobitexEntity.NobitexSymbolRegistry{Request: request}
+	bitpinExchange, err := registry.GetOrCreateExchange(ctx, registry.ExchangeConfig{
+		Name:          "bitpin",
+		DisplayName:   "bitpin",
+		BaseURL:       "https://api.bitpin.ir",
+		RateLimit:     devConf.ExchangeRateLimits["bitpin"],
+		Features:      nil,
+		SymbolFactory: &bitpinSymbolRegistry,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := registerSymbols(ctx, symbolRegistry, bitpinExchange.Exchange, &bitpinSymbolRegistry); err != nil {
+		return nil, err
+	}
+
+	nobitexExchange, err := registry.GetOrCreateExchange(ctx, registry.ExchangeConfig{
+		Name:          "nobitex",
+		DisplayName:   "nobitex",
+		BaseURL:       "https://apiv2.nobitex.ir",
+		RateLimit:     devConf.ExchangeRateLimits["nobitex"],
+		Features:      nil,
+		SymbolFactory: &NobitexSymbolRegistry,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := registerSymbols(ctx, symbolRegistry, nobitexExchange.Exchange, &NobitexSymbolRegistry); err != nil {
+		return nil, err
+	}
+	request.Limiter.MaxWait = devConf.RateLimitMaxWait
+	for _, exchangeModel := range []*models.Exchange{bitpinExchange.Exchange, nobitexExchange.Exchange} {
+		request.Limiter.SetLimit(exchangeModel.BaseURL, helpers.RateLimit{
+			Exchange:            exchangeModel.Name,
+			PerMinute:           exchangeModel.RateLimit,
+			PerCredentialMinute: devConf.CredentialRateLimits[exchangeModel.Name],
+		})
+	}
+
+	nobitexAdapter := &nobitexEntity.NobitexExchange{
+		NobitexExchangeModel:   nobitexExchange.Exchange,
+		ExchangeRepo:           repos.exchangeRepo,
+		ExchangeCredentialRepo: repos.exchangeCredRepo,
+		UserRepo:               repos.userRepo,
+		TradingPairRepo:        repos.tradingPairRepo,
+		OrderRepo:              repos.orderRepo,
+		OrderBookRepo:          repos.orderBookRepo,
+		BalanceRepo:            repos.balanceRepo,
+		Symbols:                symbolRegistry,
+		Request:                request,
+	}
+	bitpinAdapter := &bitpinEntity.BitpinExchange{
+		BitpinExchangeModel:    bitpinExchange.Exchange,
+		ExchangeRepo:           repos.exchangeRepo,
+		ExchangeCredentialRepo: repos.exchangeCredRepo,
+		UserRepo:               repos.userRepo,
+		TradingPairRepo:        repos.tradingPairRepo,
+		OrderRepo:              repos.orderRepo,
+		OrderBookRepo:          repos.orderBookRepo,
+		BalanceRepo:            repos.balanceRepo,
+		Symbols:                symbolRegistry,
+		Request:                request,
+		EnvConf:                devConf,
+		Redis:                  request.Limiter.Client,
+		TokenRefreshBefore:     devConf.BitpinTokenRefreshBefore,
+	}
+	request.Retry = helpers.RetryPolicy{
+		MaxAttempts: devConf.RetryMaxAttempts,
+		BaseDelay:   devConf.RetryBaseDelay,
+		MaxDelay:    devConf.RetryMaxDelay,
+	}
+	request.Breakers.Threshold = devConf.BreakerFailureThreshold
+	request.Breakers.Cooldown = devConf.BreakerCooldown
+	request.Breakers.SetProbe(nobitexExchange.Exchange.BaseURL, nobitexExchange.Exchange.Name, nobitexAdapter.Ping)
+	request.Breakers.SetProbe(bitpinExchange.Exchange.BaseURL, bitpinExchange.Exchange.Name, bitpinAdapter.Ping)
+	exchangeRegistery.Register(nobitexAdapter)
+	exchangeRegistery.Register(bitpinAdapter)
+	if err := registerPaperTrade(ctx, gormDb, exchangeRegistery, symbolRegistry, repos, devConf); err != nil {
+		return nil, err
+	}
+	return exchangeRegistery, nil
+}
+
+// registerSymbols stores the asset mappings an exchange declares and links its trading pairs to canonical symbols
+func registerSymbols(ctx context.Context, symbolRegistry *symbols.SymbolRegistry, exchangeModel *models.Exchange,
+	symbolFactory registry.ISymbolFactory) error {
+	var assets []models.ExchangeAsset
+	if assetMapper, ok := symbolFactory.(symbols.IAssetMapper); ok {
+		assets = assetMapper.ExchangeAssets(exchangeModel)
+	}
+	if err := symbolRegistry.Register(ctx, exchangeModel.ID, assets); err != nil {
+		return fmt.Errorf("%s symbols: %w", exchangeModel.Name, err)
+	}
+	return nil
+}
+
+// registerPaperTrade registers the simulated exchange on top of the configured source exchange
+func registerPaperTrade(ctx context.Context, gormDb *gorm.DB, exchangeRegistery *registry.ExchangeRegistry,
+	symbolRegistry *symbols.SymbolRegistry, repos *repositories, devConf *envCofig.AppConfig) error {
+	sourceAdapter, err := exchangeRegistery.Get(devConf.PaperTradeSourceExchange)
+	if err != nil {
+		return fmt.Errorf("paper trade source: %w", err)
+	}
+	sourceExchange, err := repos.exchangeRepo.GetByName(ctx, sourceAdapter.Name())
+	if err != nil {
+		return err
+	}
+	feeRate, err := decimal.NewFromString(devConf.PaperTradeFeeRate)
+	if err != nil {
+		return fmt.Errorf("invalid PAPERTRADE_FEE_RATE: %w", err)
+	}
+	initialBalances := make(map[string]decimal.Decimal, len(devConf.PaperTradeInitialBalances))
+	for currency, amount := range devConf.PaperTradeInitialBalances {
+		parsed, err := decimal.NewFromString(amount)
+		if err != nil {
+			return fmt.Errorf("invalid PAPERTRADE_INITIAL_BALANCES amount for %s: %w", currency, err)
+		}
+		initialBalances[strings.ToUpper(currency)] = parsed
+	}
+
+	if devConf.PaperTradeMaxBookAge <= 0 {
+		return fmt.Errorf("PAPERTRADE_MAX_BOOK_AGE must be positive, got %s", devConf.PaperTradeMaxBookAge)
+	}
+	// the live book is read from the public api, the user's credentials on the source exchange are not needed
+	marketData, _ := sourceAdapter.(registry.IOrderBookFetcher)
+
+	paperSymbolRegistry := papertradeEntity.PaperTradeSymbolRegistry{}
+	paperExchange, err := registry.GetOrCreateExchange(ctx, registry.ExchangeConfig{
+		Name:          "papertrade",
+		DisplayName:   "paper trading",
+		BaseURL:       "local://papertrade",
+		RateLimit:     0,
+		Features:      map[string]interface{}{"simulated": true, "source": sourceExchange.Name},
+		SymbolFactory: &paperSymbolRegistry,
+	})
+	if err != nil {
+		return err
+	}
+	if err := registerSymbols(ctx, symbolRegistry, paperExchange.Exchange, &paperSymbolRegistry); err != nil {
+		return err
+	}
+	exchangeRegistery.Register(&papertradeEntity.PaperTradeExchange{
+		PaperTradeExchangeModel: paperExchange.Exchange,
+		SourceExchangeModel:     sourceExchange,
+		MarketData:              marketData,
+		MaxBookAge:              devConf.PaperTradeMaxBookAge,
+		ExchangeCredentialRepo:  repos.exchangeCredRepo,
+		TradingPairRepo:         repos.tradingPairRepo,
+		OrderRepo:               repos.orderRepo,
+		OrderBookRepo:           repos.orderBookRepo,
+		PaperRepo:               papertradeEntity.NewPaperTradeRepository(gormDb),
+		Symbols:                 symbolRegistry,
+		Engine:                  papertradeEntity.MatchingEngine{FeeRate: feeRate},
+		InitialBalances:         initialBalances,
+	})
+	return nil
+}
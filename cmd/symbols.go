@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	db "github.com/rzabhd80/eye-on/internal/database"
+	"github.com/rzabhd80/eye-on/internal/envConfig"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+	"time"
+)
+
+// symbolsSyncService syncs the trading pairs of every exchange that can list its markets once and prints the changes
+func symbolsSyncService(cntx *cli.Context, logger *zap.Logger) error {
+	ctx := cntx.Context
+	devConf, err := envCofig.LoadConfig()
+	if err != nil {
+		return err
+	}
+	psqlDb, err := db.NewDatabase(devConf)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := psqlDb.Close(); err != nil {
+			logger.Error("failed to close database", zap.Error(err))
+		}
+	}()
+	if err := psqlDb.Migrate(); err != nil {
+		return err
+	}
+	request := helpers.NewRequest(10 * time.Second)
+	repos := newRepositories(psqlDb.GormDb, devConf)
+	exchangeRegistery, err := registerExchanges(ctx, psqlDb.GormDb, repos, devConf, request)
+	if err != nil {
+		return err
+	}
+
+	results, err := newSymbolSyncer(exchangeRegistery, repos, devConf, logger).SyncAll(ctx)
+	for _, result := range results {
+		fmt.Printf("%-12s created %d, updated %d, deactivated %d\n", result.Exchange, result.Created, result.Updated,
+			result.Deactivated)
+	}
+	return err
+}
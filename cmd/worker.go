@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"github.com/rzabhd80/eye-on/domain/balanceSync"
+	"github.com/rzabhd80/eye-on/domain/orderSync"
+	db "github.com/rzabhd80/eye-on/internal/database"
+	"github.com/rzabhd80/eye-on/internal/envConfig"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/rzabhd80/eye-on/internal/redis"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func workerService(cntx *cli.Context, logger *zap.Logger) error {
+	ctx, cancel := context.WithCancel(cntx.Context)
+	defer cancel()
+	devConf, err := envCofig.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	psqlDb, err := db.NewDatabase(devConf)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := psqlDb.Close(); err != nil {
+			logger.Error("failed to close database", zap.Error(err))
+		}
+	}()
+	err = psqlDb.Migrate()
+	if err != nil {
+		return err
+	}
+	redisConn := redis.RedisConnection{EnvConf: devConf}
+	redisClient := redisConn.NewRedisClient()
+	defer func() {
+		if err := redisClient.Close(); err != nil {
+			logger.Error("failed to close redis", zap.Error(err))
+		}
+	}()
+	request := helpers.NewRequest(10 * time.Second)
+	// shares the exchange and credential budgets with the api, falling back to local ones while redis is down
+	request.Limiter.Client = redisClient
+	repos := newRepositories(psqlDb.GormDb, devConf)
+	exchangeRegistery, err := registerExchanges(ctx, psqlDb.GormDb, repos, devConf, request)
+	if err != nil {
+		return err
+	}
+
+	orderSyncWorker := orderSync.OrderSyncWorker{
+		Registry:           exchangeRegistery,
+		OrderRepo:          repos.orderRepo,
+		Interval:           devConf.OrderSyncInterval,
+		ReservationTimeout: devConf.OrderReservationTimeout,
+		Logger:             logger,
+	}
+
+	balanceSnapshotWorker := balanceSync.BalanceSnapshotWorker{
+		Aggregator:             newPortfolioAggregator(exchangeRegistery, repos),
+		ExchangeCredentialRepo: repos.exchangeCredRepo,
+		Interval:               devConf.BalanceSnapshotInterval,
+		Logger:                 logger,
+	}
+
+	arbitrageDetector, err := newArbitrageDetector(exchangeRegistery, repos, devConf, logger)
+	if err != nil {
+		return err
+	}
+
+	symbolSyncer := newSymbolSyncer(exchangeRegistery, repos, devConf, logger)
+	candleBuilder := newCandleBuilder(repos, devConf, logger)
+	pruner := newPruner(repos, devConf, logger)
+	tokenRefresher, err := newBitpinTokenRefresher(exchangeRegistery, devConf, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, stp := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stp()
+
+	var wg sync.WaitGroup
+	if devConf.BalanceSnapshotInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Starting balance snapshot worker",
+				zap.Duration("interval", devConf.BalanceSnapshotInterval))
+			if err := balanceSnapshotWorker.Run(ctx); err != nil {
+				logger.Error("balance snapshot worker stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	if devConf.SymbolSyncInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Starting trading pair sync", zap.Duration("interval", devConf.SymbolSyncInterval))
+			if err := symbolSyncer.Run(ctx); err != nil {
+				logger.Error("trading pair sync stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	if devConf.CandleBuildInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Starting candle builder", zap.Duration("interval", devConf.CandleBuildInterval))
+			if err := candleBuilder.Run(ctx); err != nil {
+				logger.Error("candle builder stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	if devConf.MaintenanceInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Starting snapshot pruner", zap.Duration("interval", devConf.MaintenanceInterval))
+			if err := pruner.Run(ctx); err != nil {
+				logger.Error("snapshot pruner stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	if devConf.BitpinTokenRefreshInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Starting bitpin token refresher", zap.Duration("interval", devConf.BitpinTokenRefreshInterval))
+			if err := tokenRefresher.Run(ctx); err != nil {
+				logger.Error("bitpin token refresher stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	if devConf.ArbitrageInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Starting arbitrage detector", zap.Duration("interval", devConf.ArbitrageInterval))
+			if err := arbitrageDetector.Run(ctx); err != nil {
+				logger.Error("arbitrage detector stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	if devConf.OrderSyncInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Starting order sync worker", zap.Duration("interval", devConf.OrderSyncInterval))
+			if err := orderSyncWorker.Run(ctx); err != nil {
+				logger.Error("order sync worker stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+	logger.Info("Worker shutdown complete")
+	return nil
+}
@@ -0,0 +1,154 @@
+package arbitrage
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/market"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// Detector compares the top of book of every market listed on more than one exchange and records the ones that
+// can be bought on one exchange and sold on another at a profit after taker fees
+type Detector struct {
+	Consolidator    *market.Consolidator
+	TradingPairRepo *traidingPair.TradingPairRepository
+	ArbitrageRepo   *ArbitrageRepository
+	TakerFees       map[string]decimal.Decimal // by lower case exchange name
+	DefaultTakerFee decimal.Decimal            // for exchanges missing from TakerFees
+	MinProfitRate   decimal.Decimal            // net of fees, e.g. 0.002 for 0.2%
+	Interval        time.Duration
+	Logger          *zap.Logger
+
+	// open opportunities of the previous pass, updated instead of recorded again while they last
+	open map[opportunityKey]*models.ArbitrageOpportunity
+}
+
+type opportunityKey struct {
+	symbol string
+	buy    uuid.UUID
+	sell   uuid.UUID
+}
+
+// Run detects opportunities every Interval until ctx is cancelled
+func (detector *Detector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(detector.Interval)
+	defer ticker.Stop()
+	for {
+		detector.DetectAll(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// DetectAll scans every shared market once
+func (detector *Detector) DetectAll(ctx context.Context) {
+	markets, err := detector.TradingPairRepo.GetSharedMarkets(ctx)
+	if err != nil {
+		detector.Logger.Error("failed to load shared markets", zap.Error(err))
+		return
+	}
+	seen := make(map[opportunityKey]*models.ArbitrageOpportunity)
+	for _, sharedMarket := range markets {
+		if ctx.Err() != nil {
+			return
+		}
+		symbol := symbols.Canonical(sharedMarket.BaseAsset, sharedMarket.QuoteAsset)
+		book, err := detector.Consolidator.Book(ctx, symbol, 1)
+		if err != nil {
+			detector.Logger.Warn("failed to read market", zap.String("symbol", symbol), zap.Error(err))
+			continue
+		}
+		for _, opportunity := range detector.Detect(book) {
+			key := opportunityKey{symbol: opportunity.Symbol, buy: opportunity.BuyExchangeID, sell: opportunity.SellExchangeID}
+			seen[key] = detector.record(ctx, key, opportunity)
+		}
+	}
+	detector.open = seen
+}
+
+// record stores a new opportunity, or refreshes the stored one when it was already open in the previous pass
+func (detector *Detector) record(ctx context.Context, key opportunityKey,
+	opportunity models.ArbitrageOpportunity) *models.ArbitrageOpportunity {
+	if previous, found := detector.open[key]; found {
+		opportunity.BaseModel = previous.BaseModel
+		opportunity.DetectedAt = previous.DetectedAt
+		if err := detector.ArbitrageRepo.Update(ctx, &opportunity); err != nil {
+			detector.Logger.Warn("failed to update arbitrage opportunity", zap.String("symbol", key.symbol),
+				zap.Error(err))
+			return previous
+		}
+		return &opportunity
+	}
+	opportunity.ID = uuid.New()
+	if err := detector.ArbitrageRepo.Create(ctx, &opportunity); err != nil {
+		detector.Logger.Warn("failed to record arbitrage opportunity", zap.String("symbol", key.symbol),
+			zap.Error(err))
+		return nil
+	}
+	detector.Logger.Info("arbitrage opportunity",
+		zap.String("symbol", opportunity.Symbol),
+		zap.String("buy", opportunity.BuyExchangeID.String()),
+		zap.String("sell", opportunity.SellExchangeID.String()),
+		zap.String("profit_rate", opportunity.ProfitRate.String()))
+	return &opportunity
+}
+
+// Detect returns every pair of exchanges of book where buying at the best ask of one and selling at the best bid of
+// the other returns at least MinProfitRate after both taker fees
+func (detector *Detector) Detect(book *market.ConsolidatedBookResponse) []models.ArbitrageOpportunity {
+	now := time.Now()
+	one := decimal.NewFromInt(1)
+	var opportunities []models.ArbitrageOpportunity
+	for _, buy := range book.Venues {
+		if buy.BestAsk == nil {
+			continue
+		}
+		buyFee := detector.takerFee(buy.Exchange)
+		cost := buy.BestAsk.Price.Mul(one.Add(buyFee))
+		for _, sell := range book.Venues {
+			if sell.ExchangeID == buy.ExchangeID || sell.BestBid == nil {
+				continue
+			}
+			sellFee := detector.takerFee(sell.Exchange)
+			proceeds := sell.BestBid.Price.Mul(one.Sub(sellFee))
+			profitRate := proceeds.Sub(cost).DivRound(cost, 10)
+			if profitRate.LessThan(detector.MinProfitRate) || !profitRate.IsPositive() {
+				continue
+			}
+			quantity := decimal.Min(buy.BestAsk.Quantity, sell.BestBid.Quantity)
+			opportunities = append(opportunities, models.ArbitrageOpportunity{
+				Symbol:          book.Symbol,
+				BaseAsset:       book.BaseAsset,
+				QuoteAsset:      book.QuoteAsset,
+				BuyExchangeID:   buy.ExchangeID,
+				SellExchangeID:  sell.ExchangeID,
+				BuyPrice:        buy.BestAsk.Price,
+				SellPrice:       sell.BestBid.Price,
+				Quantity:        quantity,
+				BuyFee:          buyFee,
+				SellFee:         sellFee,
+				ProfitRate:      profitRate,
+				EstimatedProfit: proceeds.Sub(cost).Mul(quantity),
+				DetectedAt:      now,
+				LastSeenAt:      now,
+			})
+		}
+	}
+	return opportunities
+}
+
+func (detector *Detector) takerFee(exchangeName string) decimal.Decimal {
+	if fee, found := detector.TakerFees[strings.ToLower(exchangeName)]; found {
+		return fee
+	}
+	return detector.DefaultTakerFee
+}
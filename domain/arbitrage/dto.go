@@ -0,0 +1,40 @@
+package arbitrage
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// OpportunityFilter narrows the opportunity feed, Symbol and ExchangeID are optional
+type OpportunityFilter struct {
+	Symbol     string
+	ExchangeID *uuid.UUID
+	Since      time.Time
+	Limit      int
+}
+
+// OpportunitiesRequest is the query of the opportunity feed, every field is optional
+type OpportunitiesRequest struct {
+	Symbol   string `query:"symbol"`
+	Exchange string `query:"exchange"`
+	Since    string `query:"since"`
+	Limit    int    `query:"limit"`
+}
+
+// OpportunityResponse is one detected opportunity: buy Quantity on BuyExchange, sell it on SellExchange
+type OpportunityResponse struct {
+	ID              uuid.UUID       `json:"id"`
+	Symbol          string          `json:"symbol"`
+	BuyExchange     string          `json:"buy_exchange"`
+	SellExchange    string          `json:"sell_exchange"`
+	BuyPrice        decimal.Decimal `json:"buy_price"`
+	SellPrice       decimal.Decimal `json:"sell_price"`
+	Quantity        decimal.Decimal `json:"quantity"`
+	BuyFee          decimal.Decimal `json:"buy_fee"`
+	SellFee         decimal.Decimal `json:"sell_fee"`
+	ProfitRate      decimal.Decimal `json:"profit_rate"`
+	EstimatedProfit decimal.Decimal `json:"estimated_profit"`
+	DetectedAt      time.Time       `json:"detected_at"`
+	LastSeenAt      time.Time       `json:"last_seen_at"`
+}
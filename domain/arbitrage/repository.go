@@ -0,0 +1,45 @@
+package arbitrage
+
+import (
+	"context"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"gorm.io/gorm"
+)
+
+type IArbitrageRepository interface {
+	Create(ctx context.Context, opportunity *models.ArbitrageOpportunity) error
+	Update(ctx context.Context, opportunity *models.ArbitrageOpportunity) error
+	List(ctx context.Context, filter OpportunityFilter) ([]models.ArbitrageOpportunity, error)
+}
+
+type ArbitrageRepository struct {
+	db *gorm.DB
+}
+
+func NewArbitrageRepository(db *gorm.DB) *ArbitrageRepository {
+	return &ArbitrageRepository{db: db}
+}
+
+func (r *ArbitrageRepository) Create(ctx context.Context, opportunity *models.ArbitrageOpportunity) error {
+	return r.db.WithContext(ctx).Omit("BuyExchange", "SellExchange").Create(opportunity).Error
+}
+
+func (r *ArbitrageRepository) Update(ctx context.Context, opportunity *models.ArbitrageOpportunity) error {
+	return r.db.WithContext(ctx).Omit("BuyExchange", "SellExchange").Save(opportunity).Error
+}
+
+// List returns the opportunities last seen at or after Since, most recent first, with their exchanges
+func (r *ArbitrageRepository) List(ctx context.Context, filter OpportunityFilter) (
+	[]models.ArbitrageOpportunity, error) {
+	query := r.db.WithContext(ctx).Preload("BuyExchange").Preload("SellExchange").
+		Where("last_seen_at >= ?", filter.Since)
+	if filter.Symbol != "" {
+		query = query.Where("symbol = ?", filter.Symbol)
+	}
+	if filter.ExchangeID != nil {
+		query = query.Where("buy_exchange_id = ? OR sell_exchange_id = ?", *filter.ExchangeID, *filter.ExchangeID)
+	}
+	var opportunities []models.ArbitrageOpportunity
+	err := query.Order("last_seen_at DESC").Limit(filter.Limit).Find(&opportunities).Error
+	return opportunities, err
+}
@@ -1,12 +1,40 @@
 package balance
 
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
 type GetBalanceRequest struct {
 	Asset string `json:"symbol,omitempty"`
 }
 
 type StandardBalanceResponse struct {
-	Asset  string  `json:"asset"`
-	Free   float64 `json:"free"`
-	Locked float64 `json:"locked"`
-	Total  float64 `json:"total"`
+	Asset  string          `json:"asset"`
+	Free   decimal.Decimal `json:"free"`
+	Locked decimal.Decimal `json:"locked"`
+	Total  decimal.Decimal `json:"total"`
+}
+
+// BalanceHistoryRequest filters the balance history, every field is optional
+type BalanceHistoryRequest struct {
+	Asset     string `query:"asset"`
+	Exchange  string `query:"exchange"`
+	From      string `query:"from"`
+	To        string `query:"to"`
+	MaxPoints int    `query:"max_points"`
+}
+
+// BalancePoint is one stored balance of a series
+type BalancePoint struct {
+	Time      time.Time       `json:"time"`
+	Total     decimal.Decimal `json:"total"`
+	Available decimal.Decimal `json:"available"`
+}
+
+// BalanceSeries is the balance history of one asset on one exchange
+type BalanceSeries struct {
+	Exchange string         `json:"exchange"`
+	Asset    string         `json:"asset"`
+	Points   []BalancePoint `json:"points"`
 }
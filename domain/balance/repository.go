@@ -12,7 +12,12 @@ type IBalanceSnapshotRepository interface {
 	Create(ctx context.Context, snapshot *models.BalanceSnapshot) error
 	GetLatestByUserAndExchange(ctx context.Context, userID, exchangeID uuid.UUID) (*[]models.BalanceSnapshot, error)
 	GetHistory(ctx context.Context, userID, exchangeID uuid.UUID, currency string, limit int) (*[]models.BalanceSnapshot, error)
-	DeleteOldSnapshots(ctx context.Context, olderThan time.Time) error
+	GetUserSnapshotsBetween(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]models.BalanceSnapshot, error)
+	GetUserLatestBefore(ctx context.Context, userID uuid.UUID, before time.Time) ([]models.BalanceSnapshot, error)
+	GetDownsampledHistory(ctx context.Context, userID uuid.UUID, exchangeID *uuid.UUID, currency string, from,
+		to time.Time, bucket time.Duration) ([]models.BalanceSnapshot, error)
+	DownsampleSnapshots(ctx context.Context, olderThan time.Time, bucket time.Duration) (int64, error)
+	DeleteOldSnapshots(ctx context.Context, olderThan time.Time) (int64, error)
 }
 type BalanceSnapshotRepository struct {
 	db *gorm.DB
@@ -51,6 +56,80 @@ func (r *BalanceSnapshotRepository) GetHistory(ctx context.Context, userID, exch
 	return snapshots, err
 }
 
-func (r *BalanceSnapshotRepository) DeleteOldSnapshots(ctx context.Context, olderThan time.Time) error {
-	return r.db.WithContext(ctx).Where("snapshot_time < ?", olderThan).Delete(&models.BalanceSnapshot{}).Error
+// GetUserSnapshotsBetween returns every balance snapshot of the user in [from, to], oldest first
+func (r *BalanceSnapshotRepository) GetUserSnapshotsBetween(ctx context.Context, userID uuid.UUID, from, to time.Time) (
+	[]models.BalanceSnapshot, error) {
+	var snapshots []models.BalanceSnapshot
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND snapshot_time BETWEEN ? AND ?", userID, from, to).
+		Order("snapshot_time ASC").
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+// GetUserLatestBefore returns the last snapshot of every exchange and currency of the user taken before the given time
+func (r *BalanceSnapshotRepository) GetUserLatestBefore(ctx context.Context, userID uuid.UUID, before time.Time) (
+	[]models.BalanceSnapshot, error) {
+	var snapshots []models.BalanceSnapshot
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT DISTINCT ON (exchange_id, upper(currency)) * FROM balance_snapshots
+			WHERE user_id = ? AND snapshot_time < ? AND deleted_at IS NULL
+			ORDER BY exchange_id, upper(currency), snapshot_time DESC`, userID, before).
+		Scan(&snapshots).Error
+	return snapshots, err
+}
+
+// GetDownsampledHistory returns the user's balance snapshots in [from, to] keeping only the last one of every bucket
+// per exchange and currency, oldest first. exchangeID and currency are optional filters
+func (r *BalanceSnapshotRepository) GetDownsampledHistory(ctx context.Context, userID uuid.UUID, exchangeID *uuid.UUID,
+	currency string, from, to time.Time, bucket time.Duration) ([]models.BalanceSnapshot, error) {
+	bucketSeconds := int64(bucket / time.Second)
+	if bucketSeconds < 1 {
+		bucketSeconds = 1
+	}
+	query := `SELECT * FROM (
+		SELECT DISTINCT ON (exchange_id, upper(currency), floor(extract(epoch FROM snapshot_time) / @bucket)) *
+		FROM balance_snapshots
+		WHERE user_id = @user AND snapshot_time BETWEEN @from AND @to AND deleted_at IS NULL`
+	args := map[string]interface{}{"user": userID, "from": from, "to": to, "bucket": bucketSeconds}
+	if exchangeID != nil {
+		query += " AND exchange_id = @exchange"
+		args["exchange"] = *exchangeID
+	}
+	if currency != "" {
+		query += " AND upper(currency) = upper(@currency)"
+		args["currency"] = currency
+	}
+	query += `
+		ORDER BY exchange_id, upper(currency), floor(extract(epoch FROM snapshot_time) / @bucket), snapshot_time DESC
+	) AS buckets ORDER BY snapshot_time ASC`
+	var snapshots []models.BalanceSnapshot
+	err := r.db.WithContext(ctx).Raw(query, args).Scan(&snapshots).Error
+	return snapshots, err
+}
+
+// DownsampleSnapshots keeps the last snapshot of every bucket per user, exchange and currency among the snapshots
+// older than olderThan and hard deletes the others
+func (r *BalanceSnapshotRepository) DownsampleSnapshots(ctx context.Context, olderThan time.Time,
+	bucket time.Duration) (int64, error) {
+	bucketSeconds := int64(bucket / time.Second)
+	if bucketSeconds < 1 {
+		bucketSeconds = 1
+	}
+	result := r.db.WithContext(ctx).Exec(`DELETE FROM balance_snapshots WHERE id IN (
+			SELECT id FROM (
+				SELECT id, row_number() OVER (
+					PARTITION BY user_id, exchange_id, upper(currency), floor(extract(epoch FROM snapshot_time) / @bucket)
+					ORDER BY snapshot_time DESC) AS position
+				FROM balance_snapshots
+				WHERE snapshot_time < @before
+			) ranked WHERE position > 1
+		)`, map[string]interface{}{"before": olderThan, "bucket": bucketSeconds})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteOldSnapshots hard deletes the snapshots taken before olderThan
+func (r *BalanceSnapshotRepository) DeleteOldSnapshots(ctx context.Context, olderThan time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().Where("snapshot_time < ?", olderThan).Delete(&models.BalanceSnapshot{})
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,56 @@
+package balanceSync
+
+import (
+	"context"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/portfolio"
+	"go.uber.org/zap"
+	"time"
+)
+
+// BalanceSnapshotWorker periodically stores the balances of every user with active credentials
+type BalanceSnapshotWorker struct {
+	Aggregator             *portfolio.Aggregator
+	ExchangeCredentialRepo *exchangeCredentials.ExchangeCredentialRepository
+	Interval               time.Duration
+	Logger                 *zap.Logger
+}
+
+// Run snapshots balances every Interval until ctx is cancelled
+func (worker *BalanceSnapshotWorker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(worker.Interval)
+	defer ticker.Stop()
+	for {
+		worker.SnapshotAll(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SnapshotAll snapshots the balances of every user once, a failing exchange does not stop the others
+func (worker *BalanceSnapshotWorker) SnapshotAll(ctx context.Context) {
+	userIDs, err := worker.ExchangeCredentialRepo.ListActiveUserIDs(ctx)
+	if err != nil {
+		worker.Logger.Error("failed to load users with credentials", zap.Error(err))
+		return
+	}
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		failures, err := worker.Aggregator.Snapshot(ctx, userID)
+		if err != nil {
+			worker.Logger.Error("failed to store balance snapshots", zap.String("user_id", userID.String()),
+				zap.Error(err))
+		}
+		for exchangeName, failure := range failures {
+			worker.Logger.Warn("failed to snapshot balances",
+				zap.String("user_id", userID.String()),
+				zap.String("exchange", exchangeName),
+				zap.Error(failure))
+		}
+	}
+}
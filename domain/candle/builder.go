@@ -0,0 +1,162 @@
+package candle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"sort"
+	"time"
+)
+
+// Builder turns order book snapshots and order fills into 1m candles and rolls those up into the longer intervals
+type Builder struct {
+	Repo            *CandleRepository
+	TradingPairRepo *traidingPair.TradingPairRepository
+	Interval        time.Duration
+	Backfill        time.Duration // how far back the first pass builds
+	Logger          *zap.Logger
+
+	// open time of the minute the previous pass stopped at, rebuilt by the next pass since it was still open
+	lastBuild time.Time
+}
+
+// Run builds candles right away and then every Interval until ctx is cancelled
+func (builder *Builder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(builder.Interval)
+	defer ticker.Stop()
+	for {
+		if err := builder.BuildAll(ctx); err != nil {
+			builder.Logger.Error("failed to build candles", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// BuildAll builds the candles of every pair with new data since the previous pass, a failing pair does not stop
+// the others
+func (builder *Builder) BuildAll(ctx context.Context) error {
+	now := time.Now().UTC()
+	from := builder.lastBuild
+	if from.IsZero() {
+		from = now.Add(-builder.Backfill)
+	}
+	from = Interval1m.OpenTime(from)
+	tradingPairIDs, err := builder.Repo.PairsWithDataSince(ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed to list pairs with new data: %w", err)
+	}
+	var failures []error
+	for _, tradingPairID := range tradingPairIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := builder.Build(ctx, tradingPairID, from); err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", tradingPairID, err))
+		}
+	}
+	if len(failures) == 0 {
+		builder.lastBuild = Interval1m.OpenTime(now)
+	}
+	return errors.Join(failures...)
+}
+
+// Build rebuilds the candles of one pair from the minute of from onwards
+func (builder *Builder) Build(ctx context.Context, tradingPairID uuid.UUID, from time.Time) error {
+	pair, err := builder.TradingPairRepo.GetByID(ctx, tradingPairID)
+	if err != nil {
+		return err
+	}
+	from = Interval1m.OpenTime(from)
+	midPrices, err := builder.Repo.MidPrices(ctx, tradingPairID, from)
+	if err != nil {
+		return fmt.Errorf("failed to load mid prices: %w", err)
+	}
+	fills, err := builder.Repo.Fills(ctx, tradingPairID, from)
+	if err != nil {
+		return fmt.Errorf("failed to load fills: %w", err)
+	}
+	points := append(midPrices, fills...)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	if err := builder.Repo.Upsert(ctx, aggregate(pair, points)); err != nil {
+		return fmt.Errorf("failed to store 1m candles: %w", err)
+	}
+
+	for _, interval := range rollupIntervals {
+		start := interval.OpenTime(from)
+		minutes, err := builder.Repo.List(ctx, CandleFilter{TradingPairID: tradingPairID, Interval: Interval1m, From: start})
+		if err != nil {
+			return fmt.Errorf("failed to load 1m candles: %w", err)
+		}
+		if err := builder.Repo.Upsert(ctx, rollup(interval, minutes)); err != nil {
+			return fmt.Errorf("failed to store %s candles: %w", interval, err)
+		}
+	}
+	return nil
+}
+
+// aggregate builds 1m candles from points sorted by time, only fills add to the volume and trade count
+func aggregate(pair *models.TradingPair, points []PricePoint) []models.Candle {
+	var candles []models.Candle
+	for _, point := range points {
+		openTime := Interval1m.OpenTime(point.Time)
+		if len(candles) == 0 || !candles[len(candles)-1].OpenTime.Equal(openTime) {
+			candles = append(candles, models.Candle{
+				BaseModel:     models.BaseModel{ID: uuid.New()},
+				ExchangeID:    pair.ExchangeID,
+				TradingPairID: pair.ID,
+				Interval:      string(Interval1m),
+				OpenTime:      openTime,
+				Open:          point.Price,
+				High:          point.Price,
+				Low:           point.Price,
+				Volume:        decimal.Zero,
+			})
+		}
+		current := &candles[len(candles)-1]
+		current.High = decimal.Max(current.High, point.Price)
+		current.Low = decimal.Min(current.Low, point.Price)
+		current.Close = point.Price
+		if point.Fill {
+			current.Volume = current.Volume.Add(point.Quantity)
+			current.TradeCount++
+		}
+	}
+	return candles
+}
+
+// rollup merges 1m candles sorted by open time into candles of a longer interval
+func rollup(interval Interval, minutes []models.Candle) []models.Candle {
+	var candles []models.Candle
+	for _, minute := range minutes {
+		openTime := interval.OpenTime(minute.OpenTime)
+		if len(candles) == 0 || !candles[len(candles)-1].OpenTime.Equal(openTime) {
+			candles = append(candles, models.Candle{
+				BaseModel:     models.BaseModel{ID: uuid.New()},
+				ExchangeID:    minute.ExchangeID,
+				TradingPairID: minute.TradingPairID,
+				Interval:      string(interval),
+				OpenTime:      openTime,
+				Open:          minute.Open,
+				High:          minute.High,
+				Low:           minute.Low,
+				Volume:        decimal.Zero,
+			})
+		}
+		current := &candles[len(candles)-1]
+		current.High = decimal.Max(current.High, minute.High)
+		current.Low = decimal.Min(current.Low, minute.Low)
+		current.Close = minute.Close
+		current.Volume = current.Volume.Add(minute.Volume)
+		current.TradeCount += minute.TradeCount
+	}
+	return candles
+}
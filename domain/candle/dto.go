@@ -0,0 +1,98 @@
+package candle
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+var ErrInvalidInterval = errors.New("invalid interval")
+
+// Interval is the length of a candle
+type Interval string
+
+const (
+	Interval1m Interval = "1m"
+	Interval5m Interval = "5m"
+	Interval1h Interval = "1h"
+	Interval1d Interval = "1d"
+)
+
+// rollupIntervals are built from the 1m candles rather than from the raw data
+var rollupIntervals = []Interval{Interval5m, Interval1h, Interval1d}
+
+var intervalDurations = map[Interval]time.Duration{
+	Interval1m: time.Minute,
+	Interval5m: 5 * time.Minute,
+	Interval1h: time.Hour,
+	Interval1d: 24 * time.Hour,
+}
+
+// ParseInterval validates an interval given by a client
+func ParseInterval(interval string) (Interval, error) {
+	if _, found := intervalDurations[Interval(interval)]; !found {
+		return "", fmt.Errorf("%w %q, expected one of 1m, 5m, 1h, 1d", ErrInvalidInterval, interval)
+	}
+	return Interval(interval), nil
+}
+
+func (interval Interval) Duration() time.Duration {
+	return intervalDurations[interval]
+}
+
+// OpenTime returns the open time of the candle containing t, days open at midnight UTC
+func (interval Interval) OpenTime(t time.Time) time.Time {
+	return t.UTC().Truncate(interval.Duration())
+}
+
+// CandleFilter selects the candles of one pair and interval, From, To and Limit are optional
+type CandleFilter struct {
+	TradingPairID uuid.UUID
+	Interval      Interval
+	From          time.Time
+	To            time.Time
+	Limit         int
+}
+
+// CandlesRequest is the query of the candles endpoint, From and To are RFC3339 times
+type CandlesRequest struct {
+	Interval string `query:"interval"`
+	From     string `query:"from"`
+	To       string `query:"to"`
+	Limit    int    `query:"limit"`
+}
+
+type CandleResponse struct {
+	OpenTime   time.Time       `json:"open_time"`
+	CloseTime  time.Time       `json:"close_time"`
+	Open       decimal.Decimal `json:"open"`
+	High       decimal.Decimal `json:"high"`
+	Low        decimal.Decimal `json:"low"`
+	Close      decimal.Decimal `json:"close"`
+	Volume     decimal.Decimal `json:"volume"`
+	TradeCount int             `json:"trade_count"`
+}
+
+// CandlesResponse lists candles oldest first
+type CandlesResponse struct {
+	Exchange string           `json:"exchange"`
+	Symbol   string           `json:"symbol"`
+	Interval Interval         `json:"interval"`
+	Candles  []CandleResponse `json:"candles"`
+}
+
+func NewCandleResponse(candle models.Candle) CandleResponse {
+	return CandleResponse{
+		OpenTime:   candle.OpenTime,
+		CloseTime:  candle.OpenTime.Add(Interval(candle.Interval).Duration()),
+		Open:       candle.Open,
+		High:       candle.High,
+		Low:        candle.Low,
+		Close:      candle.Close,
+		Volume:     candle.Volume,
+		TradeCount: candle.TradeCount,
+	}
+}
@@ -0,0 +1,120 @@
+package candle
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type ICandleRepository interface {
+	Upsert(ctx context.Context, candles []models.Candle) error
+	List(ctx context.Context, filter CandleFilter) ([]models.Candle, error)
+	PairsWithDataSince(ctx context.Context, since time.Time) ([]uuid.UUID, error)
+	MidPrices(ctx context.Context, tradingPairID uuid.UUID, since time.Time) ([]PricePoint, error)
+	Fills(ctx context.Context, tradingPairID uuid.UUID, since time.Time) ([]PricePoint, error)
+}
+
+type CandleRepository struct {
+	db *gorm.DB
+}
+
+func NewCandleRepository(db *gorm.DB) *CandleRepository {
+	return &CandleRepository{db: db}
+}
+
+// PricePoint is a price observed at Time, fills also carry the filled Quantity
+type PricePoint struct {
+	Time     time.Time
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+	Fill     bool
+}
+
+// Upsert stores the candles, replacing the values of candles that were already built
+func (r *CandleRepository) Upsert(ctx context.Context, candles []models.Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "trading_pair_id"}, {Name: "interval"}, {Name: "open_time"}},
+			DoUpdates: clause.AssignmentColumns([]string{"open", "high", "low", "close", "volume", "trade_count", "updated_at"}),
+		}).
+		CreateInBatches(&candles, 500).Error
+}
+
+// List returns the candles matching the filter oldest first, the most recent Limit when a limit is set
+func (r *CandleRepository) List(ctx context.Context, filter CandleFilter) ([]models.Candle, error) {
+	query := r.db.WithContext(ctx).
+		Where("trading_pair_id = ? AND interval = ?", filter.TradingPairID, string(filter.Interval))
+	if !filter.From.IsZero() {
+		query = query.Where("open_time >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("open_time < ?", filter.To)
+	}
+	var candles []models.Candle
+	if filter.Limit <= 0 {
+		err := query.Order("open_time").Find(&candles).Error
+		return candles, err
+	}
+	if err := query.Order("open_time DESC").Limit(filter.Limit).Find(&candles).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+	return candles, nil
+}
+
+// PairsWithDataSince returns the trading pairs with order book snapshots or fills at or after since
+func (r *CandleRepository) PairsWithDataSince(ctx context.Context, since time.Time) ([]uuid.UUID, error) {
+	var tradingPairIDs []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT trading_pair_id FROM order_book_snapshots WHERE snapshot_time >= ? AND deleted_at IS NULL
+			UNION
+			SELECT order_histories.trading_pair_id FROM order_events
+			JOIN order_histories ON order_histories.id = order_events.order_hist_id
+			WHERE order_events.event_time >= ? AND order_events.deleted_at IS NULL`, since, since).
+		Scan(&tradingPairIDs).Error
+	return tradingPairIDs, err
+}
+
+// MidPrices returns the mid price between the best bid and ask of every snapshot of a pair since the given time
+func (r *CandleRepository) MidPrices(ctx context.Context, tradingPairID uuid.UUID, since time.Time) (
+	[]PricePoint, error) {
+	var points []PricePoint
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT snapshot_time AS time, (best_bid + best_ask) / 2 AS price
+			FROM order_book_snapshots
+			WHERE trading_pair_id = ? AND snapshot_time >= ? AND deleted_at IS NULL
+			AND best_bid IS NOT NULL AND best_ask IS NOT NULL
+			ORDER BY snapshot_time`, tradingPairID, since).
+		Scan(&points).Error
+	return points, err
+}
+
+// Fills returns the fills of orders on a pair since the given time. Order events carry the cumulative filled
+// quantity, so each fill is the increase over the previous event of its order, priced at the order's average price
+func (r *CandleRepository) Fills(ctx context.Context, tradingPairID uuid.UUID, since time.Time) ([]PricePoint, error) {
+	var points []PricePoint
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT time, price, quantity, true AS fill FROM (
+				SELECT order_events.event_time AS time,
+					COALESCE(order_histories.executed_price, order_histories.price) AS price,
+					order_events.filled_qty - COALESCE(LAG(order_events.filled_qty) OVER (
+						PARTITION BY order_events.order_hist_id
+						ORDER BY order_events.event_time, order_events.recorded_at), 0) AS quantity
+				FROM order_events
+				JOIN order_histories ON order_histories.id = order_events.order_hist_id
+				WHERE order_histories.trading_pair_id = ? AND order_events.deleted_at IS NULL
+			) fills
+			WHERE time >= ? AND quantity > 0 AND price IS NOT NULL
+			ORDER BY time`, tradingPairID, since).
+		Scan(&points).Error
+	return points, err
+}
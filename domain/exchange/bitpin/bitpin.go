@@ -5,18 +5,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/rzabhd80/eye-on/domain/balance"
 	"github.com/rzabhd80/eye-on/domain/exchange"
 	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
 	"github.com/rzabhd80/eye-on/domain/order"
 	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
 	"github.com/rzabhd80/eye-on/domain/traidingPair"
 	"github.com/rzabhd80/eye-on/domain/user"
 	"github.com/rzabhd80/eye-on/internal/database/models"
 	envCofig "github.com/rzabhd80/eye-on/internal/envConfig"
 	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/shopspring/decimal"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -31,12 +35,30 @@ type BitpinExchange struct {
 	OrderRepo              *order.OrderRepository
 	OrderBookRepo          *orderBook.OrderBookSnapshotRepository
 	BalanceRepo            *balance.BalanceSnapshotRepository
+	Symbols                *symbols.SymbolRegistry
 	Request                *helpers.Request
 	EnvConf                *envCofig.AppConfig
+	// Redis makes instances renew a credential's token one at a time, nil leaves it to each instance
+	Redis *redis.Client
+	// TokenRefreshBefore renews access tokens expiring within it before sending a call, 0 only renews on 401
+	TokenRefreshBefore time.Duration
+}
+
+func (exchange *BitpinExchange) Name() string { return exchange.BitpinExchangeModel.Name }
+
+// Ping reads the public USDT/IRT order book, any answer below 500 means the exchange is up
+func (exchange *BitpinExchange) Ping(ctx context.Context) error {
+	respBody, _, err := exchange.Request.MakeRequest(ctx, "GET", "/api/v1/mth/orderbook/USDT_IRT/", nil, nil,
+		exchange.BitpinExchangeModel.BaseURL, false, false, helpers.ApiAccToken)
+	if err != nil {
+		return err
+	}
+	if respBody.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("response from %s: status %d", exchange.Name(), respBody.StatusCode)
+	}
+	return nil
 }
 
-func (exchange *BitpinExchange) Name() string                   { return exchange.BitpinExchangeModel.Name }
-func (exchange *BitpinExchange) Ping(ctx context.Context) error { return nil }
 func (exchange *BitpinExchange) GetBalance(ctx context.Context, userId uuid.UUID, sign *string) ([]models.BalanceSnapshot, error) {
 	creds, err := exchange.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchange.BitpinExchangeModel.ID)
 	if creds == nil {
@@ -45,14 +67,7 @@ func (exchange *BitpinExchange) GetBalance(ctx context.Context, userId uuid.UUID
 	if err != nil {
 		return nil, errors.New("Internal Server Error")
 	}
-	request := exchange.Request
-
-	respBody, body, err := request.MakeRequest(ctx, "GET", "/api/v1/wlt/wallets/", nil, &models.ExchangeCredential{
-		APIKey:    creds.APIKey,
-		SecretKey: creds.SecretKey,
-		AccessKey: creds.AccessKey,
-		IsTestnet: creds.IsTestnet,
-	}, exchange.BitpinExchangeModel.BaseURL, true, false, helpers.ApiAccToken)
+	respBody, body, err := exchange.authorizedRequest(ctx, "GET", "/api/v1/wlt/wallets/", nil, creds)
 	if err != nil {
 		return nil, err
 	}
@@ -74,20 +89,18 @@ func (exchange *BitpinExchange) GetBalance(ctx context.Context, userId uuid.UUID
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	balances := make([]balance.StandardBalanceResponse, 0, len(balanceResp))
 	balanceSnapshot := make([]models.BalanceSnapshot, 0, len(balanceResp))
 	for _, balanceIns := range balanceResp {
+		frozen, err := decimal.NewFromString(balanceIns.Frozen)
+		if err != nil {
+			return nil, fmt.Errorf("invalid frozen amount of %s: %w", balanceIns.Asset, err)
+		}
+		total, err := decimal.NewFromString(balanceIns.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid balance of %s: %w", balanceIns.Asset, err)
+		}
+		available := total.Sub(frozen)
 
-		frozen, _ := strconv.ParseFloat(balanceIns.Frozen, 64)
-		total, _ := strconv.ParseFloat(balanceIns.Balance, 64)
-		available := total - frozen
-
-		balances = append(balances, balance.StandardBalanceResponse{
-			Asset:  strings.ToUpper(balanceIns.Asset),
-			Free:   available,
-			Locked: frozen,
-			Total:  total,
-		})
 		balanceSnapshot = append(balanceSnapshot, models.BalanceSnapshot{
 			BaseModel:    models.BaseModel{ID: uuid.New()},
 			Currency:     strings.ToUpper(balanceIns.Asset),
@@ -109,22 +122,36 @@ func (exchange *BitpinExchange) GetOrderBook(ctx context.Context, symbol string,
 	if err != nil {
 		return nil, errors.New("Internal Server Error")
 	}
-	tradePair, err := exchange.TradingPairRepo.GetByExchangeAndSymbol(ctx, exchange.BitpinExchangeModel.ID, symbol)
-	if tradePair == nil {
-		return nil, fmt.Errorf("this symbol is not for this exchange ")
+	orderbookInstance, err := exchange.FetchOrderBook(ctx, symbol)
+	if err != nil {
+		return nil, err
 	}
+	err = exchange.OrderBookRepo.Create(ctx, orderbookInstance)
 	if err != nil {
-		return nil, errors.New("Internal Server Error")
+		return nil, err
+	}
+	return orderbookInstance, nil
+}
+
+// FetchOrderBook reads the public order book of symbol without credentials and without storing it
+func (exchange *BitpinExchange) FetchOrderBook(ctx context.Context, symbol string) (*models.OrderBookSnapshot, error) {
+	tradePair, err := exchange.Symbols.Resolve(ctx, exchange.BitpinExchangeModel.ID, symbol)
+	if err != nil {
+		return nil, err
 	}
 
 	request := exchange.Request
-	endpoint := fmt.Sprintf("/api/v1/mth/orderbook/%s/", symbol)
+	endpoint := fmt.Sprintf("/api/v1/mth/orderbook/%s/", tradePair.Symbol)
 
 	respBody, body, err := request.MakeRequest(ctx, "GET", endpoint, nil, nil,
 		exchange.BitpinExchangeModel.BaseURL, false, false, helpers.ApiAccToken)
 	if err != nil {
 		return nil, err
 	}
+	if respBody.StatusCode != http.StatusOK && respBody.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("API error. Exchange %s said: status %d, body: %s", exchange.Name(),
+			respBody.StatusCode, string(body))
+	}
 	orderBookResponse := struct {
 		Bids [][]string `json:"bids"`
 		Asks [][]string `json:"asks"`
@@ -132,33 +159,14 @@ func (exchange *BitpinExchange) GetOrderBook(ctx context.Context, symbol string,
 	if err := json.Unmarshal(body, &orderBookResponse); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-	if respBody.StatusCode != http.StatusOK && respBody.StatusCode != http.StatusAccepted {
-		return nil, fmt.Errorf("API error. Exchange %s said: status %d, body: %s", exchange.Name(),
-			respBody.StatusCode, string(body))
-	}
 
-	bids := make([]orderBook.StandardOrderLevel, 0, len(orderBookResponse.Bids))
-	for _, bid := range orderBookResponse.Bids {
-		if len(bid) >= 2 {
-			price, _ := strconv.ParseFloat(bid[0], 64)
-			quantity, _ := strconv.ParseFloat(bid[1], 64)
-			bids = append(bids, orderBook.StandardOrderLevel{
-				Price:    price,
-				Quantity: quantity,
-			})
-		}
+	bids, err := parseLevels("bid", orderBookResponse.Bids)
+	if err != nil {
+		return nil, err
 	}
-
-	asks := make([]orderBook.StandardOrderLevel, 0, len(orderBookResponse.Asks))
-	for _, ask := range orderBookResponse.Asks {
-		if len(ask) >= 2 {
-			price, _ := strconv.ParseFloat(ask[0], 64)
-			quantity, _ := strconv.ParseFloat(ask[1], 64)
-			asks = append(asks, orderBook.StandardOrderLevel{
-				Price:    price,
-				Quantity: quantity,
-			})
-		}
+	asks, err := parseLevels("ask", orderBookResponse.Asks)
+	if err != nil {
+		return nil, err
 	}
 
 	orderbookInstance := models.OrderBookSnapshot{
@@ -167,7 +175,7 @@ func (exchange *BitpinExchange) GetOrderBook(ctx context.Context, symbol string,
 		},
 		ExchangeID:    exchange.BitpinExchangeModel.ID,
 		TradingPairID: tradePair.ID,
-		Symbol:        symbol,
+		Symbol:        symbols.Canonical(tradePair.BaseAsset, tradePair.QuoteAsset),
 		Bids: models.JSONB{
 			"data": bids,
 		},
@@ -176,13 +184,29 @@ func (exchange *BitpinExchange) GetOrderBook(ctx context.Context, symbol string,
 		},
 		SnapshotTime: time.Now(),
 	}
-	err = exchange.OrderBookRepo.Create(ctx, &orderbookInstance)
-	if err != nil {
-		return nil, err
-	}
 	return &orderbookInstance, nil
 }
 
+// parseLevels reads [price, quantity] levels, a malformed level fails the whole book rather than show as a zero price
+func parseLevels(side string, rawLevels [][]string) ([]orderBook.StandardOrderLevel, error) {
+	levels := make([]orderBook.StandardOrderLevel, 0, len(rawLevels))
+	for _, rawLevel := range rawLevels {
+		if len(rawLevel) < 2 {
+			return nil, fmt.Errorf("invalid %s level %v", side, rawLevel)
+		}
+		price, err := decimal.NewFromString(rawLevel[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s price %q: %w", side, rawLevel[0], err)
+		}
+		quantity, err := decimal.NewFromString(rawLevel[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s quantity %q: %w", side, rawLevel[1], err)
+		}
+		levels = append(levels, orderBook.StandardOrderLevel{Price: price, Quantity: quantity})
+	}
+	return levels, nil
+}
+
 // RenewAccessToken Renews Bitpin access token
 func (exchange *BitpinExchange) RenewAccessToken(ctx context.Context, userId uuid.UUID) (
 	*models.ExchangeCredential, error) {
@@ -193,47 +217,9 @@ func (exchange *BitpinExchange) RenewAccessToken(ctx context.Context, userId uui
 	if err != nil {
 		return nil, errors.New("Internal Server Error")
 	}
-	var body map[string]interface{} = map[string]interface{}{"refresh": creds.RefreshKey}
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-	respBody, pureBody, err := exchange.Request.MakeRequest(ctx, "POST", "/api/v1/usr/refresh_token/",
-		jsonBody, creds, exchange.BitpinExchangeModel.BaseURL, false, false, helpers.ApiRefreshToken)
-	if err != nil {
-		return nil, err
-	}
-	if respBody.StatusCode != http.StatusOK && respBody.StatusCode != http.StatusAccepted &&
-		respBody.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("API error. Exchange %s said: status %d, body: %s", exchange.Name(),
-			respBody.StatusCode, string(pureBody))
-
-	}
-	expectedResponse := struct {
-		Access string `json:"access"`
-	}{}
-	if err := json.Unmarshal(pureBody, &expectedResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-	creds.AccessKey, err = helpers.EncryptAPIKey(expectedResponse.Access, exchange.EnvConf.EncryptionKey)
-	if err != nil {
-		return nil, errors.New("Internal Server Error")
-	}
-
-	creds.RefreshKey, err = helpers.EncryptAPIKey(creds.RefreshKey, exchange.EnvConf.EncryptionKey)
-	if err != nil {
-		return nil, err
-	}
-
-	creds.APIKey, err = helpers.EncryptAPIKey(creds.APIKey, exchange.EnvConf.EncryptionKey)
-	if err != nil {
+	if err := exchange.refreshAccessToken(ctx, creds); err != nil {
 		return nil, err
 	}
-
-	updateErr := exchange.ExchangeCredentialRepo.Update(ctx, creds)
-	if updateErr != nil {
-		return nil, updateErr
-	}
 	return creds, nil
 }
 
@@ -245,130 +231,120 @@ func (exchange *BitpinExchange) PlaceOrder(ctx context.Context, req *order.Stand
 	if err != nil {
 		return nil, errors.New("Internal Server Error")
 	}
+	if req.ClientOrderId == "" {
+		req.ClientOrderId = order.NewClientOrderID()
+	}
+	tradePair, err := exchange.Symbols.Resolve(ctx, exchange.BitpinExchangeModel.ID, req.Symbol)
+	if err != nil {
+		return nil, errors.New("symbol not found for this exchange")
+	}
+	req.Symbol = tradePair.Symbol
+
 	helper := &helpers.OrderCalculationHelper{}
+	if err := helper.ApplyTradingPairFilters(req, tradePair); err != nil {
+		return nil, err
+	}
 	orderData, err := helper.ConvertToBitpinFormat(req)
+	if err != nil {
+		return nil, err
+	}
+	baseAmount, err := helper.GetBaseAmountForExchange(req)
+	if err != nil {
+		return nil, err
+	}
 
-	tradePair, err := exchange.TradingPairRepo.GetByExchangeAndSymbol(ctx, exchange.BitpinExchangeModel.ID, req.Symbol)
+	// claim the client order id first so a retried request never reaches the exchange twice
+	orderHistory, reserved, err := exchange.OrderRepo.Reserve(ctx, &models.OrderHistory{
+		BaseModel: models.BaseModel{
+			ID: uuid.New(),
+		},
+		UserID:               userId,
+		ExchangeCredentialID: creds.ID,
+		ExchangeID:           exchange.BitpinExchangeModel.ID,
+		TradingPairID:        tradePair.ID,
+		ClientOrderID:        req.ClientOrderId,
+		Side:                 string(req.Side),
+		Type:                 string(req.Type),
+		Quantity:             baseAmount,
+		Price:                req.Price,
+	})
 	if err != nil {
-		return nil, errors.New("symbol not found for this exchange")
+		return nil, err
+	}
+	if !reserved {
+		return order.ReplayPlacement(orderHistory)
 	}
+	orderHistory.TradingPair = *tradePair
+
 	body, err := json.Marshal(orderData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
-	request := exchange.Request
-	respBody, body, err := request.MakeRequest(ctx, "POST", "/api/v1/odr/orders/", body, &models.ExchangeCredential{
-		APIKey:    creds.APIKey,
-		SecretKey: creds.SecretKey,
-		AccessKey: creds.AccessKey,
-		IsTestnet: creds.IsTestnet,
-	}, exchange.BitpinExchangeModel.BaseURL, true, false, helpers.ApiAccToken)
+	respBody, body, err := exchange.authorizedRequest(ctx, "POST", "/api/v1/odr/orders/", body, creds)
 	if err != nil {
+		// the exchange may or may not have accepted the order, keep the reservation so retries are not resent
 		return nil, err
 	}
 
-	exchangeOrderResponse := struct {
-		ID                int64      `json:"id"`
-		Symbol            string     `json:"symbol"`
-		Type              string     `json:"type"`
-		Side              string     `json:"side"`
-		Price             string     `json:"price"`
-		StopPrice         *string    `json:"stop_price"`
-		OCOTargetPrice    *string    `json:"oco_target_price"`
-		BaseAmount        string     `json:"base_amount"`
-		QuoteAmount       string     `json:"quote_amount"`
-		Identifier        *string    `json:"identifier"`
-		State             string     `json:"state"`
-		ClosedAt          *time.Time `json:"closed_at"`
-		CreatedAt         time.Time  `json:"created_at"`
-		DealedBaseAmount  string     `json:"dealed_base_amount"`
-		DealedQuoteAmount string     `json:"dealed_quote_amount"`
-		ReqToCancel       bool       `json:"req_to_cancel"`
-		Commission        string     `json:"commission"`
-	}{}
+	exchangeOrderResponse := OrderResponse{}
 	if respBody.StatusCode != http.StatusOK && respBody.StatusCode != http.StatusAccepted &&
 		respBody.StatusCode != http.StatusCreated {
+		if releaseErr := exchange.OrderRepo.ReleaseReservation(ctx, orderHistory.ID); releaseErr != nil {
+			return nil, releaseErr
+		}
 		return nil, fmt.Errorf("API error. Exchange %s said: status %d, body: %s", exchange.Name(),
 			respBody.StatusCode, string(body))
 
 	}
-	if err := json.Unmarshal(body, &exchangeOrderResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	// Convert to standard response
-
-	// Map Bitpin status to standard status
-	var status string
-	switch exchangeOrderResponse.State {
-	case "pending":
-		status = "pending"
-	case "partial":
-		status = "partial"
-	case "filled":
-		status = "failed"
-	case "cancelled":
-		status = "cancelled"
-	default:
-		status = "new"
+	if err := json.Unmarshal(body, &exchangeOrderResponse); err != nil || exchangeOrderResponse.ID == 0 {
+		// accepted but unreadable, the sync worker finds the order by its identifier
+		return nil, fmt.Errorf("API error. Exchange %s said: unreadable order acknowledgement: %s", exchange.Name(),
+			string(body))
 	}
-	quantity, err := strconv.ParseFloat(exchangeOrderResponse.QuoteAmount, 64)
-	if err != nil {
+	orderHistory.ExchangeOrderID = strconv.FormatInt(exchangeOrderResponse.ID, 10)
+	if err := exchange.OrderRepo.SetExchangeOrderID(ctx, orderHistory.ID, orderHistory.ExchangeOrderID); err != nil {
 		return nil, err
 	}
-	priceReturned, err := strconv.ParseFloat(exchangeOrderResponse.Price, 64)
-	if err != nil {
-		return nil, err
+
+	executedQty, _ := decimal.NewFromString(exchangeOrderResponse.DealedBaseAmount)
+	status := StatusMapper{}.MapStatus(exchangeOrderResponse.State, executedQty)
+	if quantity, err := decimal.NewFromString(exchangeOrderResponse.BaseAmount); err == nil {
+		orderHistory.Quantity = quantity
 	}
-	orderHistory := models.OrderHistory{
-		BaseModel: models.BaseModel{
-			ID: uuid.New(),
-		},
-		UserID:               userId,
-		ExchangeCredentialID: creds.ID,
-		ExchangeID:           exchange.BitpinExchangeModel.ID,
-		TradingPairID:        tradePair.ID,
-		ClientOrderID:        strconv.FormatInt(exchangeOrderResponse.ID, 10) + userId.String(),
-		ExchangeOrderID:      strconv.FormatInt(exchangeOrderResponse.ID, 10),
-		Side:                 exchangeOrderResponse.Side,
-		Type:                 exchangeOrderResponse.Type,
-		Quantity:             quantity,
-		Price:                &priceReturned,
-		Status:               status,
-	}
-	err = exchange.OrderRepo.Create(ctx, &orderHistory)
+	// market orders come back without a price, they keep the requested one
+	if priceReturned, err := decimal.NewFromString(exchangeOrderResponse.Price); err == nil &&
+		priceReturned.IsPositive() {
+		orderHistory.Price = &priceReturned
+	}
+	orderHistory.Status = string(status)
+	orderHistory.ExecutedQty = executedQty
+	err = exchange.OrderRepo.ConfirmReservation(ctx, orderHistory, time.Now())
 	if err != nil {
 		return nil, err
 	}
 
-	return &orderHistory, nil
+	return orderHistory, nil
 }
 
-func (exchange *BitpinExchange) CancelOrder(ctx context.Context, orderID *string, userId uuid.UUID, hours *float64) error {
-	creds, err := exchange.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchange.BitpinExchangeModel.ID)
+func (exchange *BitpinExchange) CancelOrder(ctx context.Context, orderID *string, userId uuid.UUID) error {
 	orderId, err := uuid.Parse(*orderID)
 	if err != nil {
 		return errors.New("malformed orderId")
 	}
 	orderData, err := exchange.OrderRepo.GetByID(ctx, orderId)
-	if err != nil {
+	if err != nil || orderData.UserID != userId || orderData.ExchangeID != exchange.BitpinExchangeModel.ID {
 		return errors.New("order record was not found")
 	}
-
-	if creds == nil {
-		return fmt.Errorf("credentials are required")
+	if orderData.ExchangeOrderID == "" {
+		return errors.New("order was never accepted by the exchange")
 	}
+	// the order is cancelled with the credential that placed it, whichever one the request selected
+	creds, err := exchange.ExchangeCredentialRepo.GetByID(ctx, orderData.ExchangeCredentialID)
 	if err != nil {
-		return errors.New("Internal Server Error")
+		return fmt.Errorf("credentials are required")
 	}
-	request := exchange.Request
 	endpoint := fmt.Sprintf("/api/v1/odr/orders/%s/", orderData.ExchangeOrderID)
-	respBody, body, err := request.MakeRequest(ctx, "DELETE", endpoint, nil, &models.ExchangeCredential{
-		APIKey:    creds.APIKey,
-		SecretKey: creds.SecretKey,
-		AccessKey: creds.AccessKey,
-		IsTestnet: creds.IsTestnet,
-	}, exchange.BitpinExchangeModel.BaseURL, true, false, helpers.ApiAccToken)
+	respBody, body, err := exchange.authorizedRequest(ctx, "DELETE", endpoint, nil, creds)
 	if err != nil {
 		return err
 	}
@@ -378,5 +354,180 @@ func (exchange *BitpinExchange) CancelOrder(ctx context.Context, orderID *string
 			respBody.StatusCode, string(body))
 	}
 
-	return nil
+	return exchange.OrderRepo.UpdateStatusWithEvent(ctx, orderData.ID, order.CANCELED, orderData.ExecutedQty, decimal.Zero,
+		orderData.Commission, time.Now())
+}
+
+// FetchOrderStatus looks up the current state of a placed order on Bitpin
+func (exchange *BitpinExchange) FetchOrderStatus(ctx context.Context, orderHistory *models.OrderHistory) (
+	*order.OrderStatusUpdate, error) {
+	creds, err := exchange.ExchangeCredentialRepo.GetByID(ctx, orderHistory.ExchangeCredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("credentials are required")
+	}
+	var exchangeOrderResponse *OrderResponse
+	if orderHistory.ExchangeOrderID == "" {
+		exchangeOrderResponse, err = exchange.orderByIdentifier(ctx, orderHistory.ClientOrderID, creds)
+	} else {
+		exchangeOrderResponse, err = exchange.orderByID(ctx, orderHistory.ExchangeOrderID, creds)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	executedQty, _ := decimal.NewFromString(exchangeOrderResponse.DealedBaseAmount)
+	executedQuote, _ := decimal.NewFromString(exchangeOrderResponse.DealedQuoteAmount)
+	commission, _ := decimal.NewFromString(exchangeOrderResponse.Commission)
+	var executedPrice decimal.Decimal
+	if executedQty.IsPositive() {
+		executedPrice = executedQuote.Div(executedQty)
+	}
+	updatedAt := time.Now()
+	if exchangeOrderResponse.ClosedAt != nil {
+		updatedAt = *exchangeOrderResponse.ClosedAt
+	}
+	return &order.OrderStatusUpdate{
+		ExchangeOrderID: strconv.FormatInt(exchangeOrderResponse.ID, 10),
+		Status:          StatusMapper{}.MapStatus(exchangeOrderResponse.State, executedQty),
+		ExecutedQty:     executedQty,
+		ExecutedPrice:   executedPrice,
+		Commission:      commission,
+		UpdatedAt:       updatedAt,
+	}, nil
+}
+
+func (exchange *BitpinExchange) orderByID(ctx context.Context, exchangeOrderID string,
+	creds *models.ExchangeCredential) (*OrderResponse, error) {
+	endpoint := fmt.Sprintf("/api/v1/odr/orders/%s/", exchangeOrderID)
+	respBody, body, err := exchange.authorizedRequest(ctx, "GET", endpoint, nil, creds)
+	if err != nil {
+		return nil, err
+	}
+	if respBody.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error. Exchange %s said: status %d, body: %s", exchange.Name(),
+			respBody.StatusCode, string(body))
+	}
+	var exchangeOrderResponse OrderResponse
+	if err := json.Unmarshal(body, &exchangeOrderResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &exchangeOrderResponse, nil
+}
+
+// orderByIdentifier finds the order placed with a client order id, for placements whose acknowledgement was lost
+func (exchange *BitpinExchange) orderByIdentifier(ctx context.Context, identifier string,
+	creds *models.ExchangeCredential) (*OrderResponse, error) {
+	query := url.Values{}
+	query.Set("identifier", identifier)
+	respBody, body, err := exchange.authorizedRequest(ctx, "GET", "/api/v1/odr/orders/?"+query.Encode(), nil, creds)
+	if err != nil {
+		return nil, err
+	}
+	if respBody.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error. Exchange %s said: status %d, body: %s", exchange.Name(),
+			respBody.StatusCode, string(body))
+	}
+	var exchangeOrders []OrderResponse
+	if err := json.Unmarshal(body, &exchangeOrders); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	for i := range exchangeOrders {
+		if exchangeOrders[i].Identifier != nil && *exchangeOrders[i].Identifier == identifier {
+			return &exchangeOrders[i], nil
+		}
+	}
+	return nil, order.ErrNotOnExchange
+}
+
+// GetOrder returns a stored order of the user refreshed with its current state on Bitpin
+func (exchange *BitpinExchange) GetOrder(ctx context.Context, orderID string, userId uuid.UUID) (
+	*order.StandardOrderResponse, error) {
+	orderId, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, errors.New("malformed orderId")
+	}
+	orderHistory, err := exchange.OrderRepo.GetOrderHistoryWithTradingPair(ctx, orderId)
+	if err != nil || orderHistory.UserID != userId || orderHistory.ExchangeID != exchange.BitpinExchangeModel.ID {
+		return nil, errors.New("order record was not found")
+	}
+	update, err := exchange.FetchOrderStatus(ctx, orderHistory)
+	if err != nil {
+		return nil, err
+	}
+	orderResponse := order.NewStandardOrderResponse(orderHistory)
+	orderResponse.Status = update.Status
+	orderResponse.ExecutedQty = update.ExecutedQty
+	return &orderResponse, nil
+}
+
+// ListOpenOrders lists the user's active orders on Bitpin, optionally filtered by symbol
+func (exchange *BitpinExchange) ListOpenOrders(ctx context.Context, symbol string, userId uuid.UUID) (
+	[]order.StandardOrderResponse, error) {
+	creds, err := exchange.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchange.BitpinExchangeModel.ID)
+	if creds == nil {
+		return nil, fmt.Errorf("credentials are required")
+	}
+	if err != nil {
+		return nil, errors.New("Internal Server Error")
+	}
+	query := url.Values{}
+	query.Set("state", "active")
+	if symbol != "" {
+		tradePair, err := exchange.Symbols.Resolve(ctx, exchange.BitpinExchangeModel.ID, symbol)
+		if err != nil {
+			return nil, fmt.Errorf("this symbol is not for this exchange ")
+		}
+		query.Set("symbol", tradePair.Symbol)
+	}
+	respBody, body, err := exchange.authorizedRequest(ctx, "GET", "/api/v1/odr/orders/?"+query.Encode(), nil,
+		creds)
+	if err != nil {
+		return nil, err
+	}
+	if respBody.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error. Exchange %s said: status %d, body: %s", exchange.Name(),
+			respBody.StatusCode, string(body))
+	}
+	var exchangeOrders []OrderResponse
+	if err := json.Unmarshal(body, &exchangeOrders); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	orders := make([]order.StandardOrderResponse, 0, len(exchangeOrders))
+	for _, exchangeOrder := range exchangeOrders {
+		quantity, _ := decimal.NewFromString(exchangeOrder.BaseAmount)
+		executedQty, _ := decimal.NewFromString(exchangeOrder.DealedBaseAmount)
+		var price *decimal.Decimal
+		if parsedPrice, err := decimal.NewFromString(exchangeOrder.Price); err == nil {
+			price = &parsedPrice
+		}
+		orderSymbol := exchangeOrder.Symbol
+		if baseAsset, quoteAsset, err := symbols.Parse(exchangeOrder.Symbol); err == nil {
+			orderSymbol = symbols.Canonical(baseAsset, quoteAsset)
+		}
+		orderResponse := order.StandardOrderResponse{
+			Symbol:          orderSymbol,
+			Side:            order.OrderSide(exchangeOrder.Side),
+			Type:            order.OrderType(exchangeOrder.Type),
+			Quantity:        quantity,
+			Price:           price,
+			Status:          StatusMapper{}.MapStatus(exchangeOrder.State, executedQty),
+			CreatedAt:       exchangeOrder.CreatedAt,
+			ExchangeID:      exchange.BitpinExchangeModel.ID.String(),
+			ExecutedQty:     executedQty,
+			ExchangeOrderID: strconv.FormatInt(exchangeOrder.ID, 10),
+		}
+		if exchangeOrder.Identifier != nil {
+			orderResponse.ClientOrderID = *exchangeOrder.Identifier
+		}
+		// orders placed through eye-on are matched back to their local record
+		if orderHistory, err := exchange.OrderRepo.GetByExchangeOrderID(ctx, orderResponse.ExchangeOrderID); err == nil &&
+			orderHistory.ExchangeID == exchange.BitpinExchangeModel.ID {
+			orderResponse.ID = orderHistory.ID.String()
+			orderResponse.ClientOrderID = orderHistory.ClientOrderID
+			orderResponse.UpdatedAt = orderHistory.UpdatedAt
+		}
+		orders = append(orders, orderResponse)
+	}
+	return orders, nil
 }
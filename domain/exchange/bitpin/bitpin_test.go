@@ -0,0 +1,482 @@
+package bitpin
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/balance"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	envCofig "github.com/rzabhd80/eye-on/internal/envConfig"
+	"github.com/rzabhd80/eye-on/internal/exchangefake"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEncryptionKey is a base64 AES-256 key used to store the fake credentials
+const testEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+// newTestExchange wires the adapter to a fake Bitpin and a sqlite database holding a user with credentials the
+// fake accepts and the ETH/USDT pair
+func newTestExchange(t *testing.T) (*BitpinExchange, *exchangefake.BitpinServer, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	fake := exchangefake.NewBitpinServer()
+	t.Cleanup(fake.Close)
+	gormDb := exchangefake.NewDatabase(t)
+	envConf := &envCofig.AppConfig{EncryptionKey: testEncryptionKey}
+
+	exchangeModel := &models.Exchange{
+		BaseModel:   models.BaseModel{ID: uuid.New()},
+		Name:        "bitpin",
+		DisplayName: "bitpin",
+		BaseURL:     fake.URL,
+		IsActive:    true,
+	}
+	testUser := &models.User{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		Username:  "bitpin-test",
+		Email:     "bitpin-test@eye-on.local",
+		Password:  uuid.NewString(),
+		IsActive:  true,
+	}
+	pair := &models.TradingPair{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		ExchangeID: exchangeModel.ID,
+		Symbol:     "ETH_USDT",
+		BaseAsset:  "ETH",
+		QuoteAsset: "USDT",
+		IsActive:   true,
+	}
+	for _, row := range []interface{}{exchangeModel, testUser, pair} {
+		if err := gormDb.Create(row).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	cred := &models.ExchangeCredential{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		UserID:     testUser.ID,
+		ExchangeID: exchangeModel.ID,
+		Label:      "Default",
+		APIKey:     encrypt(t, "bitpin-fake-api-key"),
+		AccessKey:  encrypt(t, fake.AccessToken()),
+		RefreshKey: encrypt(t, fake.RefreshToken()),
+		IsActive:   true,
+		IsDefault:  true,
+	}
+	credRepo := exchangeCredentials.NewExchangeCredentialRepository(gormDb, envConf)
+	if err := credRepo.Create(ctx, cred); err != nil {
+		t.Fatalf("seed credential: %v", err)
+	}
+
+	tradingPairRepo := &traidingPair.TradingPairRepository{DB: gormDb}
+	adapter := &BitpinExchange{
+		BitpinExchangeModel:    exchangeModel,
+		ExchangeRepo:           exchange.NewExchangeRepository(gormDb),
+		ExchangeCredentialRepo: credRepo,
+		UserRepo:               user.NewUserRepository(gormDb),
+		TradingPairRepo:        tradingPairRepo,
+		OrderRepo:              order.NewOrderHistoryRepository(gormDb),
+		OrderBookRepo:          orderBook.NewOrderBookSnapshotRepository(gormDb),
+		BalanceRepo:            balance.NewBalanceSnapshotRepository(gormDb),
+		Symbols:                &symbols.SymbolRegistry{Repo: symbols.NewSymbolRepository(gormDb), TradingPairRepo: tradingPairRepo},
+		Request:                helpers.NewRequest(10 * time.Second),
+		EnvConf:                envConf,
+	}
+	return adapter, fake, testUser.ID
+}
+
+func encrypt(t *testing.T, secret string) string {
+	t.Helper()
+	encrypted, err := helpers.EncryptAPIKey(secret, testEncryptionKey)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	return encrypted
+}
+
+func limitOrder(side order.OrderSide, quantity, price string) *order.StandardOrderRequest {
+	qty := decimal.RequireFromString(quantity)
+	limit := decimal.RequireFromString(price)
+	return &order.StandardOrderRequest{
+		Symbol:        "ETH_USDT",
+		Side:          side,
+		Type:          order.OrderTypeLimit,
+		Quantity:      &qty,
+		Price:         &limit,
+		ClientOrderId: order.NewClientOrderID(),
+	}
+}
+
+func exchangeOrderID(t *testing.T, placed *models.OrderHistory) int64 {
+	t.Helper()
+	id, err := strconv.ParseInt(placed.ExchangeOrderID, 10, 64)
+	if err != nil {
+		t.Fatalf("exchange order id %q: %v", placed.ExchangeOrderID, err)
+	}
+	return id
+}
+
+func TestGetOrderBook(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	fake.SetOrderBook("ETH_USDT",
+		[][]string{{"3000.10", "1.5"}, {"2999.00", "2"}},
+		[][]string{{"3001.20", "0.7"}, {"3002.00", "4"}})
+
+	snapshot, err := adapter.GetOrderBook(context.Background(), "ETH_USDT", userID)
+	if err != nil {
+		t.Fatalf("GetOrderBook: %v", err)
+	}
+	bids, err := orderBook.LevelsFromJSONB(snapshot.Bids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asks, err := orderBook.LevelsFromJSONB(snapshot.Asks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bids) != 2 || len(asks) != 2 {
+		t.Fatalf("got %d bids and %d asks, want 2 and 2", len(bids), len(asks))
+	}
+	if !bids[0].Price.Equal(decimal.RequireFromString("3000.10")) {
+		t.Errorf("best bid %s, want 3000.10", bids[0].Price)
+	}
+	if !asks[0].Price.Equal(decimal.RequireFromString("3001.20")) {
+		t.Errorf("best ask %s, want 3001.20", asks[0].Price)
+	}
+}
+
+func TestFetchOrderBookFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		bids, asks [][]string
+		wantErr    string
+	}{
+		{name: "a book Bitpin does not serve reports its status", wantErr: "status 404"},
+		{name: "a malformed price fails the book", bids: [][]string{{"3000.10", "1.5"}, {"n/a", "2"}},
+			asks: [][]string{{"3001.20", "0.7"}}, wantErr: "invalid bid price"},
+		{name: "a malformed quantity fails the book", bids: [][]string{{"3000.10", "1.5"}},
+			asks: [][]string{{"3001.20", ""}}, wantErr: "invalid ask quantity"},
+		{name: "a level without a quantity fails the book", bids: [][]string{{"3000.10"}},
+			asks: [][]string{{"3001.20", "0.7"}}, wantErr: "invalid bid level"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			adapter, fake, _ := newTestExchange(t)
+			if test.bids != nil || test.asks != nil {
+				fake.SetOrderBook("ETH_USDT", test.bids, test.asks)
+			}
+			snapshot, err := adapter.FetchOrderBook(context.Background(), "ETH_USDT")
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("got %v, %v, want an error containing %q", snapshot, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	fake.SetWallet("usdt", "150.5", "20.25")
+
+	balances, err := adapter.GetBalance(context.Background(), userID, nil)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	for _, snapshot := range balances {
+		if snapshot.Currency != "USDT" {
+			continue
+		}
+		if !snapshot.Total.Equal(decimal.RequireFromString("150.5")) {
+			t.Errorf("total %s, want 150.5", snapshot.Total)
+		}
+		if !snapshot.Available.Equal(decimal.RequireFromString("130.25")) {
+			t.Errorf("available %s, want 130.25", snapshot.Available)
+		}
+		return
+	}
+	t.Fatal("USDT wallet missing from balances")
+}
+
+func TestGetBalanceRejectsMalformedAmounts(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	fake.SetWallet("usdt", "150.5", "")
+
+	if _, err := adapter.GetBalance(context.Background(), userID, nil); err == nil {
+		t.Fatal("a malformed frozen amount was read as a balance")
+	}
+}
+
+func TestFetchExchangeSymbols(t *testing.T) {
+	adapter, fake, _ := newTestExchange(t)
+	fake.SetMarkets(
+		exchangefake.BitpinMarket{Symbol: "ETH_USDT", Base: "ETH", Quote: "USDT", Tradable: true, PricePrecision: 2,
+			BaseAmountPrecision: 4, MinBaseAmount: "0.001", MaxBaseAmount: "50"},
+		exchangefake.BitpinMarket{Symbol: "BTC_IRT", Base: "BTC", Quote: "IRT", PricePrecision: 0,
+			BaseAmountPrecision: 8})
+
+	registry := &BitpinSymbolRegistry{Request: adapter.Request}
+	pairs, err := registry.FetchExchangeSymbols(context.Background(), adapter.BitpinExchangeModel)
+	if err != nil {
+		t.Fatalf("FetchExchangeSymbols: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("got %d pairs, want 2", len(pairs))
+	}
+	eth, btc := pairs[0], pairs[1]
+	if !eth.IsActive || !eth.TickSize.Equal(decimal.RequireFromString("0.01")) ||
+		!eth.StepSize.Equal(decimal.RequireFromString("0.0001")) {
+		t.Errorf("got ETH_USDT active %v tick %s step %s, want active, 0.01 and 0.0001", eth.IsActive, eth.TickSize,
+			eth.StepSize)
+	}
+	if eth.MinQuantity == nil || !eth.MinQuantity.Equal(decimal.RequireFromString("0.001")) ||
+		eth.MaxQuantity == nil || !eth.MaxQuantity.Equal(decimal.NewFromInt(50)) {
+		t.Errorf("got ETH_USDT quantity bounds %v and %v, want 0.001 and 50", eth.MinQuantity, eth.MaxQuantity)
+	}
+	if btc.IsActive || btc.MinQuantity != nil || btc.MaxQuantity != nil {
+		t.Errorf("got BTC_IRT active %v with bounds %v and %v, want inactive without bounds", btc.IsActive,
+			btc.MinQuantity, btc.MaxQuantity)
+	}
+}
+
+func TestPlaceOrderIsIdempotent(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	request := limitOrder(order.OrderSideBuy, "0.5", "2900")
+
+	placed, err := adapter.PlaceOrder(ctx, request, userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.Status != string(order.NEW) || placed.ExchangeOrderID == "" {
+		t.Fatalf("placed order has status %q and exchange id %q", placed.Status, placed.ExchangeOrderID)
+	}
+	retry := limitOrder(order.OrderSideBuy, "0.5", "2900")
+	retry.ClientOrderId = request.ClientOrderId
+	replayed, err := adapter.PlaceOrder(ctx, retry, userID)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if replayed.ID != placed.ID {
+		t.Errorf("retry returned order %s, want %s", replayed.ID, placed.ID)
+	}
+	if sent := fake.Count(http.MethodPost, "/api/v1/odr/orders/"); sent != 1 {
+		t.Errorf("exchange received %d placements, want 1", sent)
+	}
+}
+
+func TestPlaceOrderRefusesADifferentOrderUnderTheSameClientOrderID(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	request := limitOrder(order.OrderSideBuy, "0.5", "2900")
+	if _, err := adapter.PlaceOrder(ctx, request, userID); err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	for name, retry := range map[string]*order.StandardOrderRequest{
+		"side":     limitOrder(order.OrderSideSell, "0.5", "2900"),
+		"quantity": limitOrder(order.OrderSideBuy, "0.6", "2900"),
+		"price":    limitOrder(order.OrderSideBuy, "0.5", "2800"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			retry.ClientOrderId = request.ClientOrderId
+			_, err := adapter.PlaceOrder(ctx, retry, userID)
+			var validationErr *order.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Code != order.CodeClientOrderIDInUse {
+				t.Errorf("got %v, want a %s validation error", err, order.CodeClientOrderIDInUse)
+			}
+		})
+	}
+	if sent := fake.Count(http.MethodPost, "/api/v1/odr/orders/"); sent != 1 {
+		t.Errorf("exchange received %d placements, want 1", sent)
+	}
+}
+
+func TestPlaceOrderMarketWithoutPrice(t *testing.T) {
+	adapter, _, userID := newTestExchange(t)
+	qty := decimal.RequireFromString("0.2")
+	quote := decimal.RequireFromString("600")
+	request := &order.StandardOrderRequest{
+		Symbol:        "ETH_USDT",
+		Side:          order.OrderSideBuy,
+		Type:          order.OrderTypeMarket,
+		Quantity:      &qty,
+		QuoteAmount:   &quote,
+		ClientOrderId: order.NewClientOrderID(),
+	}
+
+	placed, err := adapter.PlaceOrder(context.Background(), request, userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.ExchangeOrderID == "" || placed.Price != nil {
+		t.Errorf("placed market order has exchange id %q and price %v", placed.ExchangeOrderID, placed.Price)
+	}
+}
+
+func TestRejectedPlacementReleasesReservation(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	fake.FailNext(http.MethodPost, "/api/v1/odr/orders/", http.StatusBadRequest,
+		`{"base_amount":["Insufficient balance."]}`)
+	request := limitOrder(order.OrderSideSell, "1", "3100")
+
+	if _, err := adapter.PlaceOrder(ctx, request, userID); err == nil {
+		t.Fatal("rejected placement returned no error")
+	}
+	retry := limitOrder(order.OrderSideSell, "1", "3100")
+	retry.ClientOrderId = request.ClientOrderId
+	placed, err := adapter.PlaceOrder(ctx, retry, userID)
+	if err != nil {
+		t.Fatalf("retry after rejection: %v", err)
+	}
+	if placed.ExchangeOrderID == "" {
+		t.Error("retry after rejection was not sent to the exchange")
+	}
+}
+
+func TestFetchOrderStatusReportsFills(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	placed, err := adapter.PlaceOrder(ctx, limitOrder(order.OrderSideBuy, "0.4", "2950"), userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	if err := fake.Fill(exchangeOrderID(t, placed), "0.1"); err != nil {
+		t.Fatal(err)
+	}
+	update, err := adapter.FetchOrderStatus(ctx, placed)
+	if err != nil {
+		t.Fatalf("FetchOrderStatus: %v", err)
+	}
+	if update.Status != order.PARTIALLY {
+		t.Errorf("status %s after partial fill, want %s", update.Status, order.PARTIALLY)
+	}
+
+	if err := fake.Fill(exchangeOrderID(t, placed), "0.4"); err != nil {
+		t.Fatal(err)
+	}
+	update, err = adapter.FetchOrderStatus(ctx, placed)
+	if err != nil {
+		t.Fatalf("FetchOrderStatus: %v", err)
+	}
+	if update.Status != order.FILLED {
+		t.Errorf("status %s after full fill, want %s", update.Status, order.FILLED)
+	}
+	if !update.ExecutedQty.Equal(decimal.RequireFromString("0.4")) {
+		t.Errorf("executed %s, want 0.4", update.ExecutedQty)
+	}
+}
+
+func TestFetchOrderStatusOfReservation(t *testing.T) {
+	adapter, _, userID := newTestExchange(t)
+	ctx := context.Background()
+	placed, err := adapter.PlaceOrder(ctx, limitOrder(order.OrderSideBuy, "0.2", "2700"), userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	// a reservation whose placement outcome was lost only knows its client order id
+	lost := *placed
+	lost.ExchangeOrderID = ""
+	update, err := adapter.FetchOrderStatus(ctx, &lost)
+	if err != nil {
+		t.Fatalf("FetchOrderStatus: %v", err)
+	}
+	if update.ExchangeOrderID != placed.ExchangeOrderID {
+		t.Errorf("found exchange order %q, want %q", update.ExchangeOrderID, placed.ExchangeOrderID)
+	}
+
+	unsent := lost
+	unsent.ClientOrderID = order.NewClientOrderID()
+	if _, err := adapter.FetchOrderStatus(ctx, &unsent); !errors.Is(err, order.ErrNotOnExchange) {
+		t.Errorf("got %v for an order never sent, want %v", err, order.ErrNotOnExchange)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	placed, err := adapter.PlaceOrder(ctx, limitOrder(order.OrderSideBuy, "0.3", "2800"), userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	orderID := placed.ID.String()
+	if err := adapter.CancelOrder(ctx, &orderID, userID); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if stored, _ := fake.Order(exchangeOrderID(t, placed)); stored.State != "canceled" {
+		t.Errorf("exchange order state %q after cancel, want canceled", stored.State)
+	}
+	canceled, err := adapter.OrderRepo.GetByID(ctx, placed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canceled.Status != string(order.CANCELED) {
+		t.Errorf("stored status %s after cancel, want %s", canceled.Status, order.CANCELED)
+	}
+}
+
+func TestCancelOrderOnlyCancelsBitpinOrders(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	placed, err := adapter.PlaceOrder(ctx, limitOrder(order.OrderSideBuy, "0.3", "2800"), userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	foreign := *placed
+	foreign.ID = uuid.New()
+	foreign.ExchangeID = uuid.New()
+	foreign.ClientOrderID = order.NewClientOrderID()
+	unsent := *placed
+	unsent.ID = uuid.New()
+	unsent.ClientOrderID = order.NewClientOrderID()
+	unsent.ExchangeOrderID = ""
+	for name, stored := range map[string]*models.OrderHistory{"other exchange": &foreign, "reservation": &unsent} {
+		if _, _, err := adapter.OrderRepo.Reserve(ctx, stored); err != nil {
+			t.Fatalf("seed %s: %v", name, err)
+		}
+		orderID := stored.ID.String()
+		if err := adapter.CancelOrder(ctx, &orderID, userID); err == nil {
+			t.Errorf("cancelling the order of the %s returned no error", name)
+		}
+	}
+	if deletes := fake.Count(http.MethodDelete, "/api/v1/odr/orders/"+placed.ExchangeOrderID+"/"); deletes != 0 {
+		t.Errorf("exchange received %d cancels, want 0", deletes)
+	}
+	if deletes := fake.Count(http.MethodDelete, "/api/v1/odr/orders//"); deletes != 0 {
+		t.Errorf("exchange received %d cancels without an order id, want 0", deletes)
+	}
+}
+
+func TestExpiredTokenIsRenewed(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	fake.ExpireAccessToken()
+
+	if _, err := adapter.GetBalance(ctx, userID, nil); err != nil {
+		t.Fatalf("expected the expired token to be renewed, got %v", err)
+	}
+	if refreshes := fake.Count(http.MethodPost, "/api/v1/usr/refresh_token/"); refreshes != 1 {
+		t.Errorf("got %d token refreshes, want 1", refreshes)
+	}
+	if _, err := adapter.RenewAccessToken(ctx, userID); err != nil {
+		t.Fatalf("RenewAccessToken: %v", err)
+	}
+	if _, err := adapter.GetBalance(ctx, userID, nil); err != nil {
+		t.Fatalf("GetBalance after renewal: %v", err)
+	}
+}
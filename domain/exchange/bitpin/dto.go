@@ -1,5 +1,41 @@
 package bitpin
 
+import "time"
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+// OrderResponse is the order object Bitpin returns on placement and lookup
+type OrderResponse struct {
+	ID                int64      `json:"id"`
+	Symbol            string     `json:"symbol"`
+	Type              string     `json:"type"`
+	Side              string     `json:"side"`
+	Price             string     `json:"price"`
+	StopPrice         *string    `json:"stop_price"`
+	OCOTargetPrice    *string    `json:"oco_target_price"`
+	BaseAmount        string     `json:"base_amount"`
+	QuoteAmount       string     `json:"quote_amount"`
+	Identifier        *string    `json:"identifier"`
+	State             string     `json:"state"`
+	ClosedAt          *time.Time `json:"closed_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	DealedBaseAmount  string     `json:"dealed_base_amount"`
+	DealedQuoteAmount string     `json:"dealed_quote_amount"`
+	ReqToCancel       bool       `json:"req_to_cancel"`
+	Commission        string     `json:"commission"`
+}
+
+// Market is a market as Bitpin lists it, precisions are the number of decimals prices and base amounts may have and
+// the base amounts bound the quantity of an order, empty when Bitpin sets no bound
+type Market struct {
+	Symbol              string `json:"symbol"`
+	Base                string `json:"base"`
+	Quote               string `json:"quote"`
+	Tradable            bool   `json:"tradable"`
+	PricePrecision      int32  `json:"price_precision"`
+	BaseAmountPrecision int32  `json:"base_amount_precision"`
+	MinBaseAmount       string `json:"min_base_amount"`
+	MaxBaseAmount       string `json:"max_base_amount"`
+}
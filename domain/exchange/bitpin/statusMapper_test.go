@@ -0,0 +1,35 @@
+package bitpin
+
+import (
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestStatusMapper(t *testing.T) {
+	tests := []struct {
+		status   string
+		executed string
+		want     order.OrderStatus
+	}{
+		{status: "closed", executed: "1", want: order.FILLED},
+		{status: "filled", executed: "1", want: order.FILLED},
+		{status: "done", executed: "1", want: order.FILLED},
+		{status: "canceled", executed: "0", want: order.CANCELED},
+		{status: "Cancelled", executed: "0.5", want: order.CANCELED},
+		{status: "rejected", executed: "0", want: order.REJECTED},
+		{status: "failed", executed: "0", want: order.REJECTED},
+		{status: "partial", executed: "0.5", want: order.PARTIALLY},
+		{status: "active", executed: "0", want: order.NEW},
+		{status: "active", executed: "0.5", want: order.PARTIALLY},
+		{status: "", executed: "0", want: order.NEW},
+	}
+	for _, test := range tests {
+		t.Run(test.status+"/"+test.executed, func(t *testing.T) {
+			got := StatusMapper{}.MapStatus(test.status, decimal.RequireFromString(test.executed))
+			if got != test.want {
+				t.Errorf("MapStatus(%q, %s) = %q, want %q", test.status, test.executed, got, test.want)
+			}
+		})
+	}
+}
@@ -1,77 +1,131 @@
 package bitpin
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/rzabhd80/eye-on/internal/database/models"
 	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"strings"
 )
 
-type BitpinSymbolRegistry struct{}
+// BitpinSymbolRegistry seeds the Bitpin pairs below and, given a Request, reads the listed markets from Bitpin
+type BitpinSymbolRegistry struct {
+	Request *helpers.Request
+}
 
 func (reg *BitpinSymbolRegistry) RegisterExchangeSymbols(bitpinExchange *models.Exchange) *[]models.TradingPair {
 	pairs := []models.TradingPair{
-		{ExchangeID: bitpinExchange.ID, Symbol: "BTC_IRT", BaseAsset: "BTC", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.00000001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BTC_USDT", BaseAsset: "BTC", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(00000001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "ETH_USDT", BaseAsset: "ETH", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.00001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "ETH_IRT", BaseAsset: "ETH", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.00001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "XRP_USDT", BaseAsset: "XRP", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.00001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "USDT_IRT", BaseAsset: "USDT", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "SOL_IRT", BaseAsset: "SOL", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "SOL_USDT", BaseAsset: "SOL", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BNB_IRT", BaseAsset: "BNB", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BNB_USDT", BaseAsset: "BNB", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "USDC_IRT", BaseAsset: "USDC", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "ADA_IRT", BaseAsset: "ADA", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "ADA_USDT", BaseAsset: "ADA", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.0001),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "DOGE_IRT", BaseAsset: "DOGE", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "DOGE_USDT", BaseAsset: "DOGE", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.00001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "TRX_IRT", BaseAsset: "TRX", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "TRX_USDT", BaseAsset: "TRX", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.0001),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "LINK_USDT", BaseAsset: "LINK", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.0001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "DOT_IRT", BaseAsset: "DOT", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "DOT_USDT", BaseAsset: "DOT", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.0001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "LTC_IRT", BaseAsset: "LTC", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "LTC_USDT", BaseAsset: "LTC", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.001),
-			StepSize: helpers.FloatPointer(0.001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "AVAX_IRT", BaseAsset: "AVAX", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "AVAX_USDT", BaseAsset: "AVAX", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "UNI_IRT", BaseAsset: "UNI", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "UNI_USDT", BaseAsset: "UNI", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.0001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "TON_USDT", BaseAsset: "TON", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.0001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "ATOM_USDT", BaseAsset: "ATOM", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.0001),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "XLM_USDT", BaseAsset: "XLM", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.0001),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BCH_IRT", BaseAsset: "BCH", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BCH_USDT", BaseAsset: "BCH", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BTC_IRT", BaseAsset: "BTC", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BTC_USDT", BaseAsset: "BTC", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "ETH_USDT", BaseAsset: "ETH", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.00001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "ETH_IRT", BaseAsset: "ETH", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.00001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "XRP_USDT", BaseAsset: "XRP", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.00001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "USDT_IRT", BaseAsset: "USDT", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "SOL_IRT", BaseAsset: "SOL", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "SOL_USDT", BaseAsset: "SOL", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BNB_IRT", BaseAsset: "BNB", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BNB_USDT", BaseAsset: "BNB", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "USDC_IRT", BaseAsset: "USDC", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "ADA_IRT", BaseAsset: "ADA", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "ADA_USDT", BaseAsset: "ADA", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.0001),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "DOGE_IRT", BaseAsset: "DOGE", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "DOGE_USDT", BaseAsset: "DOGE", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.00001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "TRX_IRT", BaseAsset: "TRX", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "TRX_USDT", BaseAsset: "TRX", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.0001),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "LINK_USDT", BaseAsset: "LINK", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.0001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "DOT_IRT", BaseAsset: "DOT", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "DOT_USDT", BaseAsset: "DOT", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.0001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "LTC_IRT", BaseAsset: "LTC", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "LTC_USDT", BaseAsset: "LTC", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.001),
+			StepSize: helpers.DecimalPointer(0.001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "AVAX_IRT", BaseAsset: "AVAX", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "AVAX_USDT", BaseAsset: "AVAX", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "UNI_IRT", BaseAsset: "UNI", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "UNI_USDT", BaseAsset: "UNI", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.0001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "TON_USDT", BaseAsset: "TON", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.0001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "ATOM_USDT", BaseAsset: "ATOM", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.0001),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "XLM_USDT", BaseAsset: "XLM", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.0001),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BCH_IRT", BaseAsset: "BCH", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BCH_USDT", BaseAsset: "BCH", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
 	}
 
 	return &pairs
 }
+
+// FetchExchangeSymbols reads every market Bitpin lists with its filters and quantity bounds, markets that are not
+// tradable come back inactive
+func (reg *BitpinSymbolRegistry) FetchExchangeSymbols(ctx context.Context, bitpinExchange *models.Exchange) (
+	[]models.TradingPair, error) {
+	respBody, body, err := reg.Request.MakeRequest(ctx, "GET", "/api/v1/mkt/markets/", nil, nil,
+		bitpinExchange.BaseURL, false, false, helpers.ApiAccToken)
+	if err != nil {
+		return nil, err
+	}
+	if respBody.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error. Exchange %s said: status %d, body: %s", bitpinExchange.Name,
+			respBody.StatusCode, string(body))
+	}
+	var markets []Market
+	if err := json.Unmarshal(body, &markets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	pairs := make([]models.TradingPair, 0, len(markets))
+	for _, market := range markets {
+		tickSize := decimal.New(1, -market.PricePrecision)
+		stepSize := decimal.New(1, -market.BaseAmountPrecision)
+		pairs = append(pairs, models.TradingPair{
+			ExchangeID:  bitpinExchange.ID,
+			Symbol:      strings.ToUpper(market.Symbol),
+			BaseAsset:   strings.ToUpper(market.Base),
+			QuoteAsset:  strings.ToUpper(market.Quote),
+			MinQuantity: optionalAmount(market.MinBaseAmount),
+			MaxQuantity: optionalAmount(market.MaxBaseAmount),
+			TickSize:    &tickSize,
+			StepSize:    &stepSize,
+			IsActive:    market.Tradable,
+		})
+	}
+	return pairs, nil
+}
+
+// optionalAmount parses a bound Bitpin may leave empty, an empty, malformed or zero bound is no bound
+func optionalAmount(amount string) *decimal.Decimal {
+	value, err := decimal.NewFromString(amount)
+	if err != nil || !value.IsPositive() {
+		return nil
+	}
+	return &value
+}
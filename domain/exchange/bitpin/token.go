@@ -0,0 +1,186 @@
+package bitpin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// tokenLockTTL bounds how long one renewal may hold the lock and how long the others wait for it
+	tokenLockTTL  = 10 * time.Second
+	tokenLockPoll = 200 * time.Millisecond
+)
+
+var ErrTokenRenewal = errors.New("bitpin access token could not be renewed")
+
+// releaseTokenLock deletes the lock in KEYS[1] only while it still holds the owner token in ARGV[1], so a renewal
+// that outlived the TTL does not free a lock another instance took since
+var releaseTokenLock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// tokenLocks holds one mutex per credential id so calls in this process renew a token one at a time
+var tokenLocks sync.Map
+
+// authorizedRequest sends a call signed with the access token of creds. A token about to expire is renewed first,
+// and a call answered with 401 is sent once more with a renewed token; Bitpin rejected it before acting on it, so
+// replaying is safe for placements and cancels too
+func (exchange *BitpinExchange) authorizedRequest(ctx context.Context, method, endpoint string, body []byte,
+	creds *models.ExchangeCredential) (*http.Response, []byte, error) {
+	renewed := false
+	if creds.RefreshKey != "" && tokenExpiresWithin(creds.AccessKey, exchange.TokenRefreshBefore) {
+		// a failed early renewal is not fatal, the token may still be accepted
+		renewed = exchange.refreshAccessToken(ctx, creds) == nil
+	}
+	resp, respBody, err := exchange.send(ctx, method, endpoint, body, creds)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || creds.RefreshKey == "" || renewed {
+		return resp, respBody, err
+	}
+	if err := exchange.refreshAccessToken(ctx, creds); err != nil {
+		return nil, nil, err
+	}
+	return exchange.send(ctx, method, endpoint, body, creds)
+}
+
+func (exchange *BitpinExchange) send(ctx context.Context, method, endpoint string, body []byte,
+	creds *models.ExchangeCredential) (*http.Response, []byte, error) {
+	return exchange.Request.MakeRequest(ctx, method, endpoint, body, &models.ExchangeCredential{
+		BaseModel: models.BaseModel{ID: creds.ID},
+		APIKey:    creds.APIKey,
+		SecretKey: creds.SecretKey,
+		AccessKey: creds.AccessKey,
+		IsTestnet: creds.IsTestnet,
+	}, exchange.BitpinExchangeModel.BaseURL, true, false, helpers.ApiAccToken)
+}
+
+// refreshAccessToken renews the access token of creds, stores it encrypted and sets it on creds. One caller per
+// credential renews at a time, across instances through a redis lock, the others pick up the token it stored.
+// While redis is unreachable each instance renews on its own
+func (exchange *BitpinExchange) refreshAccessToken(ctx context.Context, creds *models.ExchangeCredential) error {
+	credentialLock, _ := tokenLocks.LoadOrStore(creds.ID, &sync.Mutex{})
+	credentialLock.(*sync.Mutex).Lock()
+	defer credentialLock.(*sync.Mutex).Unlock()
+
+	staleToken := creds.AccessKey
+	// another call may have renewed the token while this one waited
+	if stored, err := exchange.storedAccessToken(ctx, creds.ID); err == nil && stored != "" && stored != staleToken {
+		creds.AccessKey = stored
+		return nil
+	}
+	if exchange.Redis != nil {
+		owner := uuid.NewString()
+		locked, err := exchange.Redis.SetNX(ctx, tokenLockKey(creds.ID), owner, tokenLockTTL).Result()
+		if err == nil && !locked {
+			return exchange.awaitAccessToken(ctx, creds, staleToken)
+		}
+		if err == nil {
+			defer releaseTokenLock.Run(context.Background(), exchange.Redis, []string{tokenLockKey(creds.ID)}, owner)
+		}
+	}
+
+	accessToken, err := exchange.requestAccessToken(ctx, creds)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTokenRenewal, err)
+	}
+	encrypted, err := helpers.EncryptAPIKey(accessToken, exchange.EnvConf.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	if err := exchange.ExchangeCredentialRepo.UpdateAccessKey(ctx, creds.ID, encrypted); err != nil {
+		return err
+	}
+	creds.AccessKey = accessToken
+	return nil
+}
+
+// awaitAccessToken waits for the instance holding the lock to store a token other than staleToken
+func (exchange *BitpinExchange) awaitAccessToken(ctx context.Context, creds *models.ExchangeCredential,
+	staleToken string) error {
+	ticker := time.NewTicker(tokenLockPoll)
+	defer ticker.Stop()
+	deadline := time.After(tokenLockTTL)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("%w: timed out waiting for another renewal", ErrTokenRenewal)
+		case <-ticker.C:
+			stored, err := exchange.storedAccessToken(ctx, creds.ID)
+			if err == nil && stored != "" && stored != staleToken {
+				creds.AccessKey = stored
+				return nil
+			}
+		}
+	}
+}
+
+func (exchange *BitpinExchange) storedAccessToken(ctx context.Context, credentialID uuid.UUID) (string, error) {
+	stored, err := exchange.ExchangeCredentialRepo.GetByID(ctx, credentialID)
+	if err != nil {
+		return "", err
+	}
+	return stored.AccessKey, nil
+}
+
+// requestAccessToken trades the refresh key of creds for a new access token
+func (exchange *BitpinExchange) requestAccessToken(ctx context.Context, creds *models.ExchangeCredential) (string, error) {
+	jsonBody, err := json.Marshal(map[string]interface{}{"refresh": creds.RefreshKey})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	respBody, pureBody, err := exchange.Request.MakeRequest(ctx, "POST", "/api/v1/usr/refresh_token/",
+		jsonBody, creds, exchange.BitpinExchangeModel.BaseURL, false, false, helpers.ApiRefreshToken)
+	if err != nil {
+		return "", err
+	}
+	if respBody.StatusCode != http.StatusOK && respBody.StatusCode != http.StatusAccepted &&
+		respBody.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("API error. Exchange %s said: status %d, body: %s", exchange.Name(),
+			respBody.StatusCode, string(pureBody))
+	}
+	expectedResponse := struct {
+		Access string `json:"access"`
+	}{}
+	if err := json.Unmarshal(pureBody, &expectedResponse); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if expectedResponse.Access == "" {
+		return "", fmt.Errorf("exchange %s returned no access token", exchange.Name())
+	}
+	return expectedResponse.Access, nil
+}
+
+// tokenExpiresWithin reports whether a JWT access token expires within window. Tokens that are not JWTs or carry
+// no exp are taken as valid, a 401 still renews them
+func tokenExpiresWithin(accessToken string, window time.Duration) bool {
+	if accessToken == "" || window <= 0 {
+		return false
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, claims); err != nil {
+		return false
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return false
+	}
+	return time.Until(expiresAt.Time) < window
+}
+
+func tokenLockKey(credentialID uuid.UUID) string {
+	return "bitpin:token-refresh:" + credentialID.String()
+}
@@ -0,0 +1,68 @@
+package bitpin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"go.uber.org/zap"
+	"time"
+)
+
+// TokenRefresher renews the Bitpin access tokens that would expire before its next run, so calls rarely meet an
+// expired token. Tokens that are not JWTs are left to the renewal on 401
+type TokenRefresher struct {
+	Exchange *BitpinExchange
+	Interval time.Duration
+	Logger   *zap.Logger
+}
+
+// Run renews expiring tokens every Interval until ctx is cancelled
+func (refresher *TokenRefresher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(refresher.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := refresher.RefreshExpiring(ctx); err != nil {
+				refresher.Logger.Warn("failed to renew some bitpin access tokens", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RefreshExpiring renews every active Bitpin token expiring within TokenRefreshBefore plus Interval
+func (refresher *TokenRefresher) RefreshExpiring(ctx context.Context) error {
+	exchange := refresher.Exchange
+	creds, err := exchange.ExchangeCredentialRepo.ListActiveByExchange(ctx, exchange.BitpinExchangeModel.ID)
+	if err != nil {
+		return err
+	}
+	window := exchange.TokenRefreshBefore + refresher.Interval
+	var errs []error
+	for _, cred := range creds {
+		if ctx.Err() != nil {
+			break
+		}
+		if cred.AccessKey == "" || cred.RefreshKey == "" {
+			continue
+		}
+		accessToken, err := helpers.DecryptAPIKey(cred.AccessKey, exchange.EnvConf.EncryptionKey)
+		if err != nil || !tokenExpiresWithin(accessToken, window) {
+			continue
+		}
+		decrypted, err := exchange.ExchangeCredentialRepo.GetByID(ctx, cred.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("credential %s: %w", cred.ID, err))
+			continue
+		}
+		if err := exchange.refreshAccessToken(ctx, decrypted); err != nil {
+			errs = append(errs, fmt.Errorf("credential %s: %w", cred.ID, err))
+			continue
+		}
+		refresher.Logger.Info("renewed bitpin access token", zap.String("credential", cred.ID.String()))
+	}
+	return errors.Join(errs...)
+}
@@ -1,5 +1,57 @@
 package nobitex
 
+import "time"
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+// Order is a single order as Nobitex reports it
+type Order struct {
+	Type            string    `json:"type"`
+	Execution       string    `json:"execution"`
+	TradeType       string    `json:"tradeType"`
+	SrcCurrency     string    `json:"srcCurrency"`
+	DstCurrency     string    `json:"dstCurrency"`
+	Price           string    `json:"price"`
+	Amount          string    `json:"amount"`
+	TotalPrice      string    `json:"totalPrice"`
+	TotalOrderPrice string    `json:"totalOrderPrice"`
+	MatchedAmount   string    `json:"matchedAmount"`
+	UnmatchedAmount string    `json:"unmatchedAmount"`
+	ClientOrderID   string    `json:"clientOrderId"`
+	IsMyOrder       bool      `json:"isMyOrder"`
+	ID              int64     `json:"id"`
+	Status          string    `json:"status"`
+	Partial         bool      `json:"partial"`
+	Fee             string    `json:"fee"`
+	User            string    `json:"user"`
+	CreatedAt       time.Time `json:"created_at"`
+	Market          string    `json:"market"`
+	AveragePrice    string    `json:"averagePrice"`
+}
+
+// OrderResponse is the envelope Nobitex returns on order placement and status lookup
+type OrderResponse struct {
+	Status string `json:"status"`
+	Code   string `json:"code"`
+	Order  Order  `json:"order"`
+}
+
+// OrderListResponse is the envelope Nobitex returns when listing orders
+type OrderListResponse struct {
+	Status string  `json:"status"`
+	Orders []Order `json:"orders"`
+}
+
+// OptionsResponse is the part of the Nobitex options Eye-On reads, precisions and order amount bounds are keyed by
+// market symbol
+type OptionsResponse struct {
+	Status  string `json:"status"`
+	Nobitex struct {
+		AmountPrecisions map[string]string `json:"amountPrecisions"`
+		PricePrecisions  map[string]string `json:"pricePrecisions"`
+		MinOrderAmounts  map[string]string `json:"minOrderAmounts"`
+		MaxOrderAmounts  map[string]string `json:"maxOrderAmounts"`
+	} `json:"nobitex"`
+}
@@ -11,10 +11,12 @@ import (
 	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
 	"github.com/rzabhd80/eye-on/domain/order"
 	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
 	"github.com/rzabhd80/eye-on/domain/traidingPair"
 	"github.com/rzabhd80/eye-on/domain/user"
 	"github.com/rzabhd80/eye-on/internal/database/models"
 	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/shopspring/decimal"
 	"net/http"
 	"strconv"
 	"strings"
@@ -30,16 +32,30 @@ type NobitexExchange struct {
 	OrderRepo              *order.OrderRepository
 	OrderBookRepo          *orderBook.OrderBookSnapshotRepository
 	BalanceRepo            *balance.BalanceSnapshotRepository
+	Symbols                *symbols.SymbolRegistry
 	Request                *helpers.Request
 }
 
-func (exchange *NobitexExchange) Name() string                   { return exchange.NobitexExchangeModel.Name }
-func (exchange *NobitexExchange) Ping(ctx context.Context) error { return nil }
+func (exchange *NobitexExchange) Name() string { return exchange.NobitexExchangeModel.Name }
+
+// Ping reads the public USDT/IRT order book, any answer below 500 means the exchange is up
+func (exchange *NobitexExchange) Ping(ctx context.Context) error {
+	respBody, _, err := exchange.Request.MakeRequest(ctx, "GET", "/v3/orderbook/USDTIRT", nil, nil,
+		exchange.NobitexExchangeModel.BaseURL, false, false, helpers.ApiKeyAuth)
+	if err != nil {
+		return err
+	}
+	if respBody.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("response from %s: status %d", exchange.Name(), respBody.StatusCode)
+	}
+	return nil
+}
+
 func (exchange *NobitexExchange) GetBalance(ctx context.Context, userId uuid.UUID, symbol *string) ([]models.BalanceSnapshot, error) {
 	if symbol == nil {
-		return nil, errors.New("Symbol cannot be null")
+		return exchange.listWallets(ctx, userId)
 	}
-	nobiSymbol := strings.ToLower(*symbol)
+	asset := strings.ToUpper(*symbol)
 	creds, err := exchange.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchange.NobitexExchangeModel.ID)
 	if creds == nil {
 		return nil, fmt.Errorf("credentials are required")
@@ -47,15 +63,20 @@ func (exchange *NobitexExchange) GetBalance(ctx context.Context, userId uuid.UUI
 	if err != nil {
 		return nil, errors.New("Internal Server Error")
 	}
+	assets, err := exchange.Symbols.Assets(ctx, exchange.NobitexExchangeModel.ID)
+	if err != nil {
+		return nil, err
+	}
 	request := exchange.Request
 	symbolBody := map[string]string{
-		"currency": nobiSymbol,
+		"currency": strings.ToLower(assets.Code(asset)),
 	}
 	marshaledBody, err := json.Marshal(symbolBody)
 	if err != nil {
 		return nil, err
 	}
-	respBody, body, err := request.MakeRequest(ctx, "POST", "/users/wallets/balance", marshaledBody, &models.ExchangeCredential{
+	respBody, body, err := request.MakeRequest(helpers.Idempotent(ctx), "POST", "/users/wallets/balance", marshaledBody, &models.ExchangeCredential{
+		BaseModel: models.BaseModel{ID: creds.ID},
 		APIKey:    creds.APIKey,
 		SecretKey: creds.SecretKey,
 		IsTestnet: creds.IsTestnet,
@@ -77,17 +98,18 @@ func (exchange *NobitexExchange) GetBalance(ctx context.Context, userId uuid.UUI
 		return nil, fmt.Errorf("response from %s: balance request failed: %s", exchange.Name(), string(body))
 	}
 
-	total, err := strconv.ParseFloat(balanceResp.Balance, 64)
+	total, err := decimal.NewFromString(balanceResp.Balance)
 	if err != nil {
 		return nil, err
 	}
+	total = assets.FromExchange(asset, total)
 	balanceSnapshot := []models.BalanceSnapshot{models.BalanceSnapshot{
 		BaseModel:    models.BaseModel{ID: uuid.New()},
 		UserID:       userId,
 		ExchangeID:   exchange.NobitexExchangeModel.ID,
 		Total:        total,
 		Available:    total,
-		Currency:     nobiSymbol,
+		Currency:     asset,
 		SnapshotTime: time.Now(),
 	}}
 	return balanceSnapshot, nil
@@ -100,17 +122,30 @@ func (exchange *NobitexExchange) GetOrderBook(ctx context.Context, symbol string
 	if err != nil {
 		return nil, errors.New("Internal Server Error")
 	}
-	nobiSymbol := exchange.standardize(symbol)
-	tradePair, err := exchange.TradingPairRepo.GetByExchangeAndSymbol(ctx, exchange.NobitexExchangeModel.ID, nobiSymbol)
-	if tradePair == nil {
-		return nil, fmt.Errorf("this symbol is not for this exchange ")
+	orderbookInstance, err := exchange.FetchOrderBook(ctx, symbol)
+	if err != nil {
+		return nil, err
 	}
+	err = exchange.OrderBookRepo.Create(ctx, orderbookInstance)
 	if err != nil {
-		return nil, errors.New("Internal Server Error")
+		return nil, err
+	}
+	return orderbookInstance, nil
+}
+
+// FetchOrderBook reads the public order book of symbol without credentials and without storing it
+func (exchange *NobitexExchange) FetchOrderBook(ctx context.Context, symbol string) (*models.OrderBookSnapshot, error) {
+	tradePair, err := exchange.Symbols.Resolve(ctx, exchange.NobitexExchangeModel.ID, symbol)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := exchange.Symbols.Assets(ctx, exchange.NobitexExchangeModel.ID)
+	if err != nil {
+		return nil, err
 	}
 
 	request := exchange.Request
-	endpoint := fmt.Sprintf("/v3/orderbook/%s", nobiSymbol)
+	endpoint := fmt.Sprintf("/v3/orderbook/%s", tradePair.Symbol)
 
 	respBody, body, err := request.MakeRequest(ctx, "GET", endpoint, nil, nil,
 		exchange.NobitexExchangeModel.BaseURL, false, false, helpers.ApiKeyAuth)
@@ -137,10 +172,10 @@ func (exchange *NobitexExchange) GetOrderBook(ctx context.Context, symbol string
 	bids := make([]orderBook.StandardOrderLevel, 0, len(orderBookResponse.Bids))
 	for _, bid := range orderBookResponse.Bids {
 		if len(bid) >= 2 {
-			price, _ := strconv.ParseFloat(bid[0], 64)
-			quantity, _ := strconv.ParseFloat(bid[1], 64)
+			price, _ := decimal.NewFromString(bid[0])
+			quantity, _ := decimal.NewFromString(bid[1])
 			bids = append(bids, orderBook.StandardOrderLevel{
-				Price:    price,
+				Price:    assets.FromExchange(tradePair.QuoteAsset, price),
 				Quantity: quantity,
 			})
 		}
@@ -149,10 +184,10 @@ func (exchange *NobitexExchange) GetOrderBook(ctx context.Context, symbol string
 	asks := make([]orderBook.StandardOrderLevel, 0, len(orderBookResponse.Asks))
 	for _, ask := range orderBookResponse.Asks {
 		if len(ask) >= 2 {
-			price, _ := strconv.ParseFloat(ask[0], 64)
-			quantity, _ := strconv.ParseFloat(ask[1], 64)
+			price, _ := decimal.NewFromString(ask[0])
+			quantity, _ := decimal.NewFromString(ask[1])
 			asks = append(asks, orderBook.StandardOrderLevel{
-				Price:    price,
+				Price:    assets.FromExchange(tradePair.QuoteAsset, price),
 				Quantity: quantity,
 			})
 		}
@@ -164,7 +199,7 @@ func (exchange *NobitexExchange) GetOrderBook(ctx context.Context, symbol string
 		},
 		ExchangeID:    exchange.NobitexExchangeModel.ID,
 		TradingPairID: tradePair.ID,
-		Symbol:        symbol,
+		Symbol:        symbols.Canonical(tradePair.BaseAsset, tradePair.QuoteAsset),
 		Bids: models.JSONB{
 			"data": bids,
 		},
@@ -173,10 +208,6 @@ func (exchange *NobitexExchange) GetOrderBook(ctx context.Context, symbol string
 		},
 		SnapshotTime: time.Now(),
 	}
-	err = exchange.OrderBookRepo.Create(ctx, &orderbookInstance)
-	if err != nil {
-		return nil, err
-	}
 	return &orderbookInstance, nil
 }
 func (exchange *NobitexExchange) PlaceOrder(ctx context.Context, req *order.StandardOrderRequest, userId uuid.UUID) (*models.OrderHistory, error) {
@@ -187,167 +218,192 @@ func (exchange *NobitexExchange) PlaceOrder(ctx context.Context, req *order.Stan
 	if err != nil {
 		return nil, errors.New("Internal Server Error")
 	}
-	req.Symbol = exchange.standardize(req.Symbol)
-	tradePair, err := exchange.TradingPairRepo.GetByExchangeAndSymbol(ctx, exchange.NobitexExchangeModel.ID, req.Symbol)
+	tradePair, err := exchange.Symbols.Resolve(ctx, exchange.NobitexExchangeModel.ID, req.Symbol)
 	if err != nil {
 		return nil, errors.New("symbol not found for this exchange")
 	}
+	assets, err := exchange.Symbols.Assets(ctx, exchange.NobitexExchangeModel.ID)
+	if err != nil {
+		return nil, err
+	}
+	req.Symbol = tradePair.Symbol
+	req.BaseCurrency = strings.ToLower(assets.Code(tradePair.BaseAsset))
+	req.QuoteCurrency = strings.ToLower(assets.Code(tradePair.QuoteAsset))
+	if req.ClientOrderId == "" {
+		req.ClientOrderId = order.NewClientOrderID()
+	}
 
 	helper := &helpers.OrderCalculationHelper{}
+	if err := helper.ApplyTradingPairFilters(req, tradePair); err != nil {
+		return nil, err
+	}
 	orderData, err := helper.ConvertToNobitexFormat(req)
 
+	if err != nil {
+		return nil, err
+	}
+	if _, found := orderData["price"]; found {
+		orderData["price"] = assets.ToExchange(tradePair.QuoteAsset, *req.Price).StringFixed(8)
+	}
+	requestedQty, err := helper.GetQuantityForExchange(req)
 	if err != nil {
 		return nil, err
 	}
 
+	// claim the client order id first so a retried request never reaches the exchange twice
+	orderHistory, reserved, err := exchange.OrderRepo.Reserve(ctx, &models.OrderHistory{
+		BaseModel: models.BaseModel{
+			ID: uuid.New(),
+		},
+		UserID:               userId,
+		ExchangeCredentialID: creds.ID,
+		ExchangeID:           exchange.NobitexExchangeModel.ID,
+		TradingPairID:        tradePair.ID,
+		ClientOrderID:        req.ClientOrderId,
+		Side:                 string(req.Side),
+		Type:                 string(req.Type),
+		Quantity:             requestedQty,
+		Price:                req.Price,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return order.ReplayPlacement(orderHistory)
+	}
+	orderHistory.TradingPair = *tradePair
+
 	body, err := json.Marshal(orderData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 	request := exchange.Request
 	respBody, body, err := request.MakeRequest(ctx, "POST", "/market/orders/add", body, &models.ExchangeCredential{
+		BaseModel: models.BaseModel{ID: creds.ID},
 		APIKey:    creds.APIKey,
 		SecretKey: creds.SecretKey,
 		IsTestnet: creds.IsTestnet,
 	}, exchange.NobitexExchangeModel.BaseURL, false, true, helpers.ApiKeyAuth)
 	if err != nil {
+		// the exchange may or may not have accepted the order, keep the reservation so retries are not resent
 		return nil, err
 	}
-	fmt.Printf("response body: %s\n", string(body))
-	type ExchangeOrderResponse struct {
-		Status string `json:"status"`
-		Order  struct {
-			Type            string    `json:"type"`
-			Execution       string    `json:"execution"`
-			TradeType       string    `json:"tradeType"`
-			SrcCurrency     string    `json:"srcCurrency"`
-			DstCurrency     string    `json:"dstCurrency"`
-			Price           string    `json:"price"`
-			Amount          string    `json:"amount"`
-			TotalPrice      string    `json:"totalPrice"`
-			TotalOrderPrice string    `json:"totalOrderPrice"`
-			MatchedAmount   string    `json:"matchedAmount"`
-			UnmatchedAmount string    `json:"unmatchedAmount"`
-			ClientOrderID   string    `json:"clientOrderId"`
-			IsMyOrder       bool      `json:"isMyOrder"`
-			ID              int64     `json:"id"`
-			Status          string    `json:"status"`
-			Partial         bool      `json:"partial"`
-			Fee             string    `json:"fee"`
-			User            string    `json:"user"`
-			CreatedAt       time.Time `json:"created_at"`
-			Market          string    `json:"market"`
-			AveragePrice    string    `json:"averagePrice"`
-		} `json:"order"`
-	}
-	var exchangeOrderResponse ExchangeOrderResponse
-	if err := json.Unmarshal(body, &exchangeOrderResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	if respBody.StatusCode != http.StatusOK && respBody.StatusCode != http.StatusAccepted {
+	var exchangeOrderResponse OrderResponse
+	if respBody.StatusCode != http.StatusOK && respBody.StatusCode != http.StatusAccepted ||
+		json.Unmarshal(body, &exchangeOrderResponse) == nil && exchangeOrderResponse.Status == "failed" {
+		if releaseErr := exchange.OrderRepo.ReleaseReservation(ctx, orderHistory.ID); releaseErr != nil {
+			return nil, releaseErr
+		}
 		return nil, fmt.Errorf("response from %s: order creation failed: %s", exchange.Name(), string(body))
 	}
-	if exchangeOrderResponse.Status == "failed" {
-		return nil, fmt.Errorf("response from %s: order creation failed: %s", exchange.Name(), string(body))
+	if exchangeOrderResponse.Order.ID == 0 {
+		// accepted but unreadable, the sync worker finds the order by its client order id
+		return nil, fmt.Errorf("response from %s: unreadable order acknowledgement: %s", exchange.Name(),
+			string(body))
+	}
+	orderHistory.ExchangeOrderID = strconv.FormatInt(exchangeOrderResponse.Order.ID, 10)
+	if err := exchange.OrderRepo.SetExchangeOrderID(ctx, orderHistory.ID, orderHistory.ExchangeOrderID); err != nil {
+		return nil, err
 	}
 
-	// Convert to standard response
-
-	// Map Bitpin status to standard status
-	var status string
-	switch exchangeOrderResponse.Order.Status {
-	case "pending":
-		status = "pending"
-	case "partial":
-		status = "partial"
-	case "filled":
-		status = "failed"
-	case "cancelled":
-		status = "cancelled"
-	default:
-		status = "new"
-	}
-	var quantity float64
-	if exchangeOrderResponse.Order.Amount != "" {
-		quantity, err = strconv.ParseFloat(exchangeOrderResponse.Order.Amount, 64)
-		if err != nil {
-			return nil, err
-		}
+	executedQty, _ := decimal.NewFromString(exchangeOrderResponse.Order.MatchedAmount)
+	status := StatusMapper{}.MapStatus(exchangeOrderResponse.Order.Status, executedQty)
+	if quantity, err := decimal.NewFromString(exchangeOrderResponse.Order.Amount); err == nil {
+		orderHistory.Quantity = quantity
+	}
+	// market orders come back without a price, they keep the requested one
+	priceReturned, _ := decimal.NewFromString(exchangeOrderResponse.Order.Price)
+	totalPriceReturned, _ := decimal.NewFromString(exchangeOrderResponse.Order.TotalOrderPrice)
+	if !priceReturned.IsZero() {
+		price := assets.FromExchange(tradePair.QuoteAsset, priceReturned)
+		orderHistory.Price = &price
+	} else if !totalPriceReturned.IsZero() {
+		price := assets.FromExchange(tradePair.QuoteAsset, totalPriceReturned)
+		orderHistory.Price = &price
+	}
+	orderHistory.Status = string(status)
+	orderHistory.ExecutedQty = executedQty
+	err = exchange.OrderRepo.ConfirmReservation(ctx, orderHistory, time.Now())
+	if err != nil {
+		return nil, err
 	}
-	var price float64
-	priceReturned, err := strconv.ParseFloat(exchangeOrderResponse.Order.Price, 64)
-	totalPriceReturned, err := strconv.ParseFloat(exchangeOrderResponse.Order.TotalOrderPrice, 64)
-	if priceReturned != 0 {
-		price = priceReturned
-	} else if totalPriceReturned != 0 {
-		price = totalPriceReturned
+
+	return orderHistory, nil
+}
+
+// CancelOrder cancels one order by its exchange order id and records the cancellation
+func (exchange *NobitexExchange) CancelOrder(ctx context.Context, orderID *string, userId uuid.UUID) error {
+	orderId, err := uuid.Parse(*orderID)
+	if err != nil {
+		return errors.New("malformed order id")
 	}
-	var orderType string
-	if req.Type == order.OrderTypeMarket {
-		orderType = "market"
-	} else {
-		orderType = "limit"
+	orderHistory, err := exchange.OrderRepo.GetByID(ctx, orderId)
+	if err != nil || orderHistory.UserID != userId || orderHistory.ExchangeID != exchange.NobitexExchangeModel.ID {
+		return errors.New("order record was not found")
 	}
+	exchangeOrderId, err := strconv.ParseInt(orderHistory.ExchangeOrderID, 10, 64)
 	if err != nil {
-		return nil, err
+		return errors.New("order was never accepted by the exchange")
 	}
-	orderHistory := models.OrderHistory{
-		BaseModel: models.BaseModel{
-			ID: uuid.New(),
-		},
-		UserID:               userId,
-		ExchangeCredentialID: creds.ID,
-		ExchangeID:           exchange.NobitexExchangeModel.ID,
-		TradingPairID:        tradePair.ID,
-		ClientOrderID:        strconv.FormatInt(exchangeOrderResponse.Order.ID, 10) + userId.String(),
-		ExchangeOrderID:      strconv.FormatInt(exchangeOrderResponse.Order.ID, 10),
-		Side:                 exchangeOrderResponse.Order.Type,
-		Type:                 orderType,
-		Quantity:             quantity,
-		Price:                &price,
-		Status:               status,
+	// the order is cancelled with the credential that placed it, whichever one the request selected
+	creds, err := exchange.ExchangeCredentialRepo.GetByID(ctx, orderHistory.ExchangeCredentialID)
+	if err != nil {
+		return fmt.Errorf("credentials are required")
 	}
-	err = exchange.OrderRepo.Create(ctx, &orderHistory)
+	requestBodyJson, err := json.Marshal(map[string]interface{}{
+		"order":  exchangeOrderId,
+		"status": "canceled",
+	})
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
-
-	return &orderHistory, nil
+	if err := exchange.cancelRequest(ctx, "/market/orders/update-status", requestBodyJson, creds); err != nil {
+		return err
+	}
+	return exchange.OrderRepo.UpdateStatusWithEvent(ctx, orderHistory.ID, order.CANCELED, orderHistory.ExecutedQty,
+		decimal.Zero, orderHistory.Commission, time.Now())
 }
 
-func (exchange *NobitexExchange) CancelOrder(ctx context.Context, orderID *string, userId uuid.UUID, hours *float64) error {
-	if hours == nil {
-		return errors.New("nobitex expects hours (to cancel orders made since x hours ago)")
+// CancelOldOrders cancels every open order of the market and execution type in req placed more than req.Hours ago.
+// The sync worker records which orders actually moved
+func (exchange *NobitexExchange) CancelOldOrders(ctx context.Context, req *order.CancelOldOrdersRequest,
+	userId uuid.UUID) error {
+	if req.Hours <= 0 {
+		return errors.New("hours must be positive")
 	}
 	creds, err := exchange.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchange.NobitexExchangeModel.ID)
-	if creds == nil {
-		return fmt.Errorf("credentials are required")
-	}
 	if err != nil {
-		return errors.New("Internal Server Error")
+		return fmt.Errorf("credentials are required")
 	}
-	orderId, err := uuid.Parse(*orderID)
+	tradePair, err := exchange.Symbols.Resolve(ctx, exchange.NobitexExchangeModel.ID, req.Symbol)
 	if err != nil {
-		return errors.New("malformed order id")
+		return errors.New("symbol not found for this exchange")
 	}
-	orderHistory, err := exchange.OrderRepo.GetOrderHistoryWithTradingPair(ctx, orderId)
+	assets, err := exchange.Symbols.Assets(ctx, exchange.NobitexExchangeModel.ID)
 	if err != nil {
 		return err
 	}
-	var srcCurrency string = strings.ToLower(orderHistory.TradingPair.BaseAsset)
-	var destCurrecny string = strings.ToLower(orderHistory.TradingPair.QuoteAsset)
-
-	request := exchange.Request
 	requestBody := map[string]interface{}{
-		"execution":    orderHistory.Type,
-		"srcCurrency":  srcCurrency,
-		"destCurrency": destCurrecny,
-		"hours":        *hours,
+		"srcCurrency": strings.ToLower(assets.Code(tradePair.BaseAsset)),
+		"dstCurrency": strings.ToLower(assets.Code(tradePair.QuoteAsset)),
+		"hours":       req.Hours,
+	}
+	if req.Type != "" {
+		requestBody["execution"] = strings.ToLower(req.Type)
 	}
 	requestBodyJson, err := json.Marshal(requestBody)
-	respBody, body, err := request.MakeRequest(ctx, "POST", "/market/orders/cancel-old", requestBodyJson,
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return exchange.cancelRequest(ctx, "/market/orders/cancel-old", requestBodyJson, creds)
+}
+
+// cancelRequest sends a cancellation to endpoint and fails unless Nobitex confirms it
+func (exchange *NobitexExchange) cancelRequest(ctx context.Context, endpoint string, body []byte,
+	creds *models.ExchangeCredential) error {
+	respBody, pureBody, err := exchange.Request.MakeRequest(ctx, "POST", endpoint, body,
 		&models.ExchangeCredential{
+			BaseModel: models.BaseModel{ID: creds.ID},
 			APIKey:    creds.APIKey,
 			SecretKey: creds.SecretKey,
 			IsTestnet: creds.IsTestnet,
@@ -356,26 +412,271 @@ func (exchange *NobitexExchange) CancelOrder(ctx context.Context, orderID *strin
 		return err
 	}
 	if respBody.StatusCode != http.StatusOK && respBody.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("response from %s: order cancellation failed: %s", exchange.Name(), string(body))
+		return fmt.Errorf("response from %s: order cancellation failed: %s", exchange.Name(), string(pureBody))
 	}
-
 	var cancelResp struct {
 		Status        string `json:"status"`
 		UpdatedStatus string `json:"updatedStatus"`
 	}
-	if err := json.Unmarshal(body, &cancelResp); err != nil {
+	if err := json.Unmarshal(pureBody, &cancelResp); err != nil {
 		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-
+	if cancelResp.Status != "ok" {
+		return fmt.Errorf("response from %s: order cancellation failed: %s", exchange.Name(), string(pureBody))
+	}
 	return nil
 }
 
-func (exchange *NobitexExchange) standardize(symbol string) string {
-	if strings.ContainsAny(symbol, "_") {
-		res := strings.Split(symbol, "_")
-		standardSymbol := res[0] + res[1]
-		return standardSymbol
+// FetchOrderStatus looks up the current state of a placed order on Nobitex
+func (exchange *NobitexExchange) FetchOrderStatus(ctx context.Context, orderHistory *models.OrderHistory) (
+	*order.OrderStatusUpdate, error) {
+	creds, err := exchange.ExchangeCredentialRepo.GetByID(ctx, orderHistory.ExchangeCredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("credentials are required")
+	}
+	// a placement whose acknowledgement was lost is looked up by its client order id
+	lookup := map[string]interface{}{"clientOrderId": orderHistory.ClientOrderID}
+	if orderHistory.ExchangeOrderID != "" {
+		exchangeOrderId, err := strconv.ParseInt(orderHistory.ExchangeOrderID, 10, 64)
+		if err != nil {
+			return nil, errors.New("malformed exchange order id")
+		}
+		lookup = map[string]interface{}{"id": exchangeOrderId}
 	}
-	return symbol
+	requestBodyJson, err := json.Marshal(lookup)
+	if err != nil {
+		return nil, err
+	}
+	respBody, body, err := exchange.Request.MakeRequest(helpers.Idempotent(ctx), "POST", "/market/orders/status", requestBodyJson,
+		&models.ExchangeCredential{
+			BaseModel: models.BaseModel{ID: creds.ID},
+			APIKey:    creds.APIKey,
+			SecretKey: creds.SecretKey,
+			IsTestnet: creds.IsTestnet,
+		}, exchange.NobitexExchangeModel.BaseURL, false, true, helpers.ApiKeyAuth)
+	if err != nil {
+		return nil, err
+	}
+	var exchangeOrderResponse OrderResponse
+	unmarshalErr := json.Unmarshal(body, &exchangeOrderResponse)
+	if orderHistory.ExchangeOrderID == "" && (respBody.StatusCode == http.StatusNotFound ||
+		exchangeOrderResponse.Status == "failed" && exchangeOrderResponse.Code == "NotFound") {
+		return nil, fmt.Errorf("%w: %s", order.ErrNotOnExchange, string(body))
+	}
+	if respBody.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response from %s: order status request failed: %s", exchange.Name(), string(body))
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", unmarshalErr)
+	}
+	if exchangeOrderResponse.Status == "failed" {
+		return nil, fmt.Errorf("response from %s: order status request failed: %s", exchange.Name(), string(body))
+	}
+
+	quoteAsset, err := exchange.quoteAsset(ctx, orderHistory)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := exchange.Symbols.Assets(ctx, exchange.NobitexExchangeModel.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	executedQty, _ := decimal.NewFromString(exchangeOrderResponse.Order.MatchedAmount)
+	executedPrice, _ := decimal.NewFromString(exchangeOrderResponse.Order.AveragePrice)
+	executedPrice = assets.FromExchange(quoteAsset, executedPrice)
+	commission, _ := decimal.NewFromString(exchangeOrderResponse.Order.Fee)
+	// the fee of a sell is charged in the quote currency, of a buy in the base one
+	if orderHistory.Side == string(order.OrderSideSell) {
+		commission = assets.FromExchange(quoteAsset, commission)
+	}
+	return &order.OrderStatusUpdate{
+		ExchangeOrderID: strconv.FormatInt(exchangeOrderResponse.Order.ID, 10),
+		Status:          StatusMapper{}.MapStatus(exchangeOrderResponse.Order.Status, executedQty),
+		ExecutedQty:     executedQty,
+		ExecutedPrice:   executedPrice,
+		Commission:      commission,
+		UpdatedAt:       time.Now(),
+	}, nil
+}
+
+// GetOrder returns a stored order of the user refreshed with its current state on Nobitex
+func (exchange *NobitexExchange) GetOrder(ctx context.Context, orderID string, userId uuid.UUID) (
+	*order.StandardOrderResponse, error) {
+	orderId, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, errors.New("malformed order id")
+	}
+	orderHistory, err := exchange.OrderRepo.GetOrderHistoryWithTradingPair(ctx, orderId)
+	if err != nil || orderHistory.UserID != userId || orderHistory.ExchangeID != exchange.NobitexExchangeModel.ID {
+		return nil, errors.New("order record was not found")
+	}
+	update, err := exchange.FetchOrderStatus(ctx, orderHistory)
+	if err != nil {
+		return nil, err
+	}
+	orderResponse := order.NewStandardOrderResponse(orderHistory)
+	orderResponse.Status = update.Status
+	orderResponse.ExecutedQty = update.ExecutedQty
+	return &orderResponse, nil
+}
 
+// ListOpenOrders lists the user's open orders on Nobitex, optionally filtered by symbol
+func (exchange *NobitexExchange) ListOpenOrders(ctx context.Context, symbol string, userId uuid.UUID) (
+	[]order.StandardOrderResponse, error) {
+	creds, err := exchange.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchange.NobitexExchangeModel.ID)
+	if creds == nil {
+		return nil, fmt.Errorf("credentials are required")
+	}
+	if err != nil {
+		return nil, errors.New("Internal Server Error")
+	}
+	assets, err := exchange.Symbols.Assets(ctx, exchange.NobitexExchangeModel.ID)
+	if err != nil {
+		return nil, err
+	}
+	requestBody := map[string]interface{}{
+		"status":  "open",
+		"details": 2,
+	}
+	if symbol != "" {
+		tradePair, err := exchange.Symbols.Resolve(ctx, exchange.NobitexExchangeModel.ID, symbol)
+		if err != nil {
+			return nil, fmt.Errorf("this symbol is not for this exchange ")
+		}
+		requestBody["srcCurrency"] = strings.ToLower(assets.Code(tradePair.BaseAsset))
+		requestBody["dstCurrency"] = strings.ToLower(assets.Code(tradePair.QuoteAsset))
+	}
+	requestBodyJson, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	respBody, body, err := exchange.Request.MakeRequest(helpers.Idempotent(ctx), "POST", "/market/orders/list", requestBodyJson,
+		&models.ExchangeCredential{
+			BaseModel: models.BaseModel{ID: creds.ID},
+			APIKey:    creds.APIKey,
+			SecretKey: creds.SecretKey,
+			IsTestnet: creds.IsTestnet,
+		}, exchange.NobitexExchangeModel.BaseURL, false, true, helpers.ApiKeyAuth)
+	if err != nil {
+		return nil, err
+	}
+	if respBody.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response from %s: order list request failed: %s", exchange.Name(), string(body))
+	}
+	var listResponse OrderListResponse
+	if err := json.Unmarshal(body, &listResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if listResponse.Status == "failed" {
+		return nil, fmt.Errorf("response from %s: order list request failed: %s", exchange.Name(), string(body))
+	}
+
+	orders := make([]order.StandardOrderResponse, 0, len(listResponse.Orders))
+	for _, exchangeOrder := range listResponse.Orders {
+		quantity, _ := decimal.NewFromString(exchangeOrder.Amount)
+		executedQty, _ := decimal.NewFromString(exchangeOrder.MatchedAmount)
+		quoteAsset := assets.Asset(exchangeOrder.DstCurrency)
+		var price *decimal.Decimal
+		if parsedPrice, err := decimal.NewFromString(exchangeOrder.Price); err == nil {
+			parsedPrice = assets.FromExchange(quoteAsset, parsedPrice)
+			price = &parsedPrice
+		}
+		orderResponse := order.StandardOrderResponse{
+			Symbol:          symbols.Canonical(assets.Asset(exchangeOrder.SrcCurrency), quoteAsset),
+			Side:            order.OrderSide(exchangeOrder.Type),
+			Type:            order.OrderType(strings.ToLower(exchangeOrder.Execution)),
+			Quantity:        quantity,
+			Price:           price,
+			Status:          StatusMapper{}.MapStatus(exchangeOrder.Status, executedQty),
+			CreatedAt:       exchangeOrder.CreatedAt,
+			ExchangeID:      exchange.NobitexExchangeModel.ID.String(),
+			ExecutedQty:     executedQty,
+			ExchangeOrderID: strconv.FormatInt(exchangeOrder.ID, 10),
+			ClientOrderID:   exchangeOrder.ClientOrderID,
+		}
+		// orders placed through eye-on are matched back to their local record
+		if orderHistory, err := exchange.OrderRepo.GetByExchangeOrderID(ctx, orderResponse.ExchangeOrderID); err == nil &&
+			orderHistory.ExchangeID == exchange.NobitexExchangeModel.ID {
+			orderResponse.ID = orderHistory.ID.String()
+			orderResponse.UpdatedAt = orderHistory.UpdatedAt
+		}
+		orders = append(orders, orderResponse)
+	}
+	return orders, nil
+}
+
+// listWallets returns every wallet of the user, Nobitex only answers single currencies on /users/wallets/balance
+func (exchange *NobitexExchange) listWallets(ctx context.Context, userId uuid.UUID) ([]models.BalanceSnapshot, error) {
+	creds, err := exchange.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchange.NobitexExchangeModel.ID)
+	if creds == nil {
+		return nil, fmt.Errorf("credentials are required")
+	}
+	if err != nil {
+		return nil, errors.New("Internal Server Error")
+	}
+	assets, err := exchange.Symbols.Assets(ctx, exchange.NobitexExchangeModel.ID)
+	if err != nil {
+		return nil, err
+	}
+	respBody, body, err := exchange.Request.MakeRequest(helpers.Idempotent(ctx), "POST", "/users/wallets/list", []byte("{}"),
+		&models.ExchangeCredential{
+			BaseModel: models.BaseModel{ID: creds.ID},
+			APIKey:    creds.APIKey,
+			SecretKey: creds.SecretKey,
+			IsTestnet: creds.IsTestnet,
+		}, exchange.NobitexExchangeModel.BaseURL, false, true, helpers.ApiKeyAuth)
+	if err != nil {
+		return nil, err
+	}
+	if respBody.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response from %s: wallet list request failed: %s", exchange.Name(), string(body))
+	}
+	walletsResp := struct {
+		Status  string `json:"status"`
+		Wallets []struct {
+			Currency       string `json:"currency"`
+			Balance        string `json:"balance"`
+			BlockedBalance string `json:"blockedBalance"`
+		} `json:"wallets"`
+	}{}
+	if err := json.Unmarshal(body, &walletsResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if walletsResp.Status == "failed" {
+		return nil, fmt.Errorf("response from %s: wallet list request failed: %s", exchange.Name(), string(body))
+	}
+	balanceSnapshot := make([]models.BalanceSnapshot, 0, len(walletsResp.Wallets))
+	for _, wallet := range walletsResp.Wallets {
+		total, err := decimal.NewFromString(wallet.Balance)
+		if err != nil {
+			return nil, err
+		}
+		blocked, _ := decimal.NewFromString(wallet.BlockedBalance)
+		asset := assets.Asset(wallet.Currency)
+		total = assets.FromExchange(asset, total)
+		blocked = assets.FromExchange(asset, blocked)
+		balanceSnapshot = append(balanceSnapshot, models.BalanceSnapshot{
+			BaseModel:    models.BaseModel{ID: uuid.New()},
+			UserID:       userId,
+			ExchangeID:   exchange.NobitexExchangeModel.ID,
+			Total:        total,
+			Available:    total.Sub(blocked),
+			Currency:     asset,
+			SnapshotTime: time.Now(),
+		})
+	}
+	return balanceSnapshot, nil
+}
+
+// quoteAsset returns the quote asset of an order, loading its trading pair when it was not preloaded
+func (exchange *NobitexExchange) quoteAsset(ctx context.Context, orderHistory *models.OrderHistory) (string, error) {
+	if orderHistory.TradingPair.QuoteAsset != "" {
+		return orderHistory.TradingPair.QuoteAsset, nil
+	}
+	tradePair, err := exchange.TradingPairRepo.GetByID(ctx, orderHistory.TradingPairID)
+	if err != nil {
+		return "", err
+	}
+	return tradePair.QuoteAsset, nil
 }
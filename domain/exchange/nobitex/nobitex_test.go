@@ -0,0 +1,366 @@
+package nobitex
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/balance"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/domain/user"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	envCofig "github.com/rzabhd80/eye-on/internal/envConfig"
+	"github.com/rzabhd80/eye-on/internal/exchangefake"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	// testEncryptionKey is a base64 AES-256 key used to store the fake credentials
+	testEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	testAPIKey        = "nobitex-fake-api-key"
+)
+
+// newTestExchange wires the adapter to a fake Nobitex and a sqlite database holding a user with an api key the
+// fake accepts and the BTC/USDT pair
+func newTestExchange(t *testing.T) (*NobitexExchange, *exchangefake.NobitexServer, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	fake := exchangefake.NewNobitexServer(testAPIKey)
+	t.Cleanup(fake.Close)
+	gormDb := exchangefake.NewDatabase(t)
+	envConf := &envCofig.AppConfig{EncryptionKey: testEncryptionKey}
+
+	exchangeModel := &models.Exchange{
+		BaseModel:   models.BaseModel{ID: uuid.New()},
+		Name:        "nobitex",
+		DisplayName: "nobitex",
+		BaseURL:     fake.URL,
+		IsActive:    true,
+	}
+	testUser := &models.User{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		Username:  "nobitex-test",
+		Email:     "nobitex-test@eye-on.local",
+		Password:  uuid.NewString(),
+		IsActive:  true,
+	}
+	pair := &models.TradingPair{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		ExchangeID: exchangeModel.ID,
+		Symbol:     "BTCUSDT",
+		BaseAsset:  "BTC",
+		QuoteAsset: "USDT",
+		IsActive:   true,
+	}
+	for _, row := range []interface{}{exchangeModel, testUser, pair} {
+		if err := gormDb.Create(row).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	apiKey, err := helpers.EncryptAPIKey(testAPIKey, testEncryptionKey)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	credRepo := exchangeCredentials.NewExchangeCredentialRepository(gormDb, envConf)
+	err = credRepo.Create(ctx, &models.ExchangeCredential{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		UserID:     testUser.ID,
+		ExchangeID: exchangeModel.ID,
+		Label:      "Default",
+		APIKey:     apiKey,
+		IsActive:   true,
+		IsDefault:  true,
+	})
+	if err != nil {
+		t.Fatalf("seed credential: %v", err)
+	}
+
+	tradingPairRepo := &traidingPair.TradingPairRepository{DB: gormDb}
+	adapter := &NobitexExchange{
+		NobitexExchangeModel:   exchangeModel,
+		ExchangeRepo:           exchange.NewExchangeRepository(gormDb),
+		ExchangeCredentialRepo: credRepo,
+		UserRepo:               user.NewUserRepository(gormDb),
+		TradingPairRepo:        tradingPairRepo,
+		OrderRepo:              order.NewOrderHistoryRepository(gormDb),
+		OrderBookRepo:          orderBook.NewOrderBookSnapshotRepository(gormDb),
+		BalanceRepo:            balance.NewBalanceSnapshotRepository(gormDb),
+		Symbols:                &symbols.SymbolRegistry{Repo: symbols.NewSymbolRepository(gormDb), TradingPairRepo: tradingPairRepo},
+		Request:                helpers.NewRequest(10 * time.Second),
+	}
+	return adapter, fake, testUser.ID
+}
+
+func limitOrder(side order.OrderSide, quantity, price string) *order.StandardOrderRequest {
+	qty := decimal.RequireFromString(quantity)
+	limit := decimal.RequireFromString(price)
+	return &order.StandardOrderRequest{
+		Symbol:        "BTCUSDT",
+		Side:          side,
+		Type:          order.OrderTypeLimit,
+		Quantity:      &qty,
+		Price:         &limit,
+		BaseCurrency:  "BTC",
+		QuoteCurrency: "USDT",
+		ClientOrderId: order.NewClientOrderID(),
+	}
+}
+
+func exchangeOrderID(t *testing.T, placed *models.OrderHistory) int64 {
+	t.Helper()
+	id, err := strconv.ParseInt(placed.ExchangeOrderID, 10, 64)
+	if err != nil {
+		t.Fatalf("exchange order id %q: %v", placed.ExchangeOrderID, err)
+	}
+	return id
+}
+
+func TestGetOrderBook(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	fake.SetOrderBook("BTCUSDT",
+		[][]string{{"64000.5", "0.3"}, {"63990", "1.1"}},
+		[][]string{{"64010", "0.25"}, {"64020.75", "2"}})
+
+	snapshot, err := adapter.GetOrderBook(context.Background(), "BTC_USDT", userID)
+	if err != nil {
+		t.Fatalf("GetOrderBook: %v", err)
+	}
+	bids, err := orderBook.LevelsFromJSONB(snapshot.Bids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asks, err := orderBook.LevelsFromJSONB(snapshot.Asks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bids) != 2 || len(asks) != 2 {
+		t.Fatalf("got %d bids and %d asks, want 2 and 2", len(bids), len(asks))
+	}
+	if !bids[0].Price.Equal(decimal.RequireFromString("64000.5")) {
+		t.Errorf("best bid %s, want 64000.5", bids[0].Price)
+	}
+	if !asks[0].Price.Equal(decimal.RequireFromString("64010")) {
+		t.Errorf("best ask %s, want 64010", asks[0].Price)
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	fake.SetBalance("usdt", "812.1234")
+
+	currency := "USDT"
+	balances, err := adapter.GetBalance(ctx, userID, &currency)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if len(balances) != 1 {
+		t.Fatalf("got %d balances, want 1", len(balances))
+	}
+	if !balances[0].Total.Equal(decimal.RequireFromString("812.1234")) {
+		t.Errorf("balance %s, want 812.1234", balances[0].Total)
+	}
+
+	wallets, err := adapter.GetBalance(ctx, userID, nil)
+	if err != nil {
+		t.Fatalf("GetBalance of every wallet: %v", err)
+	}
+	for _, wallet := range wallets {
+		if wallet.Currency != "USDT" {
+			continue
+		}
+		if !wallet.Total.Equal(balances[0].Total) {
+			t.Errorf("wallet list balance %s, want %s", wallet.Total, balances[0].Total)
+		}
+		return
+	}
+	t.Fatal("USDT wallet missing from wallet list")
+}
+
+func TestFetchExchangeSymbols(t *testing.T) {
+	adapter, fake, _ := newTestExchange(t)
+	fake.SetMarket("BTCUSDT", exchangefake.NobitexMarket{AmountPrecision: "0.000001", PricePrecision: "0.01",
+		MinOrderAmount: "0.0001", MaxOrderAmount: "10"})
+	fake.SetMarket("ETHIRT", exchangefake.NobitexMarket{AmountPrecision: "0.0001", PricePrecision: "10"})
+
+	registry := &NobitexSymbolRegistry{Request: adapter.Request}
+	pairs, err := registry.FetchExchangeSymbols(context.Background(), adapter.NobitexExchangeModel)
+	if err != nil {
+		t.Fatalf("FetchExchangeSymbols: %v", err)
+	}
+	listed := make(map[string]models.TradingPair, len(pairs))
+	for _, pair := range pairs {
+		listed[pair.Symbol] = pair
+	}
+	btc, found := listed["BTCUSDT"]
+	if !found || btc.BaseAsset != "BTC" || btc.QuoteAsset != "USDT" {
+		t.Fatalf("got pairs %v, want BTCUSDT as BTC/USDT", pairs)
+	}
+	if !btc.StepSize.Equal(decimal.RequireFromString("0.000001")) ||
+		!btc.TickSize.Equal(decimal.RequireFromString("0.01")) {
+		t.Errorf("got BTCUSDT step %s tick %s, want 0.000001 and 0.01", btc.StepSize, btc.TickSize)
+	}
+	if btc.MinQuantity == nil || !btc.MinQuantity.Equal(decimal.RequireFromString("0.0001")) ||
+		btc.MaxQuantity == nil || !btc.MaxQuantity.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("got BTCUSDT quantity bounds %v and %v, want 0.0001 and 10", btc.MinQuantity, btc.MaxQuantity)
+	}
+	eth, found := listed["ETHIRT"]
+	if !found || eth.MinQuantity != nil || eth.MaxQuantity != nil {
+		t.Errorf("got ETHIRT %v, want it listed without quantity bounds", eth)
+	}
+}
+
+func TestPlaceOrderIsIdempotent(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	request := limitOrder(order.OrderSideBuy, "0.01", "60000")
+
+	placed, err := adapter.PlaceOrder(ctx, request, userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.ExchangeOrderID == "" {
+		t.Fatal("placed order has no exchange id")
+	}
+	retry := limitOrder(order.OrderSideBuy, "0.01", "60000")
+	retry.ClientOrderId = request.ClientOrderId
+	replayed, err := adapter.PlaceOrder(ctx, retry, userID)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if replayed.ID != placed.ID {
+		t.Errorf("retry returned order %s, want %s", replayed.ID, placed.ID)
+	}
+	if sent := fake.Count(http.MethodPost, "/market/orders/add"); sent != 1 {
+		t.Errorf("exchange received %d placements, want 1", sent)
+	}
+}
+
+func TestFailedPlacementReleasesReservation(t *testing.T) {
+	for name, fault := range map[string]struct {
+		status int
+		body   string
+	}{
+		"failed status":   {http.StatusOK, `{"status":"failed","code":"OverValueOrder","message":"Order value is too high"}`},
+		"non json reject": {http.StatusBadRequest, "Bad Request"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			adapter, fake, userID := newTestExchange(t)
+			ctx := context.Background()
+			fake.FailNext(http.MethodPost, "/market/orders/add", fault.status, fault.body)
+			request := limitOrder(order.OrderSideSell, "0.02", "70000")
+
+			if _, err := adapter.PlaceOrder(ctx, request, userID); err == nil {
+				t.Fatal("failed placement returned no error")
+			}
+			retry := limitOrder(order.OrderSideSell, "0.02", "70000")
+			retry.ClientOrderId = request.ClientOrderId
+			placed, err := adapter.PlaceOrder(ctx, retry, userID)
+			if err != nil {
+				t.Fatalf("retry after rejection: %v", err)
+			}
+			if placed.ExchangeOrderID == "" {
+				t.Error("retry after rejection was not sent to the exchange")
+			}
+		})
+	}
+}
+
+func TestFetchOrderStatusReportsFills(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	placed, err := adapter.PlaceOrder(ctx, limitOrder(order.OrderSideBuy, "0.05", "61000"), userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	if err := fake.Fill(exchangeOrderID(t, placed), "0.05"); err != nil {
+		t.Fatal(err)
+	}
+	update, err := adapter.FetchOrderStatus(ctx, placed)
+	if err != nil {
+		t.Fatalf("FetchOrderStatus: %v", err)
+	}
+	if update.Status != order.FILLED {
+		t.Errorf("status %s after full fill, want %s", update.Status, order.FILLED)
+	}
+	if !update.ExecutedQty.Equal(decimal.RequireFromString("0.05")) {
+		t.Errorf("executed %s, want 0.05", update.ExecutedQty)
+	}
+}
+
+func TestFetchOrderStatusOfReservation(t *testing.T) {
+	adapter, _, userID := newTestExchange(t)
+	ctx := context.Background()
+	placed, err := adapter.PlaceOrder(ctx, limitOrder(order.OrderSideBuy, "0.02", "58000"), userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	// a reservation whose placement outcome was lost only knows its client order id
+	lost := *placed
+	lost.ExchangeOrderID = ""
+	update, err := adapter.FetchOrderStatus(ctx, &lost)
+	if err != nil {
+		t.Fatalf("FetchOrderStatus: %v", err)
+	}
+	if update.ExchangeOrderID != placed.ExchangeOrderID {
+		t.Errorf("found exchange order %q, want %q", update.ExchangeOrderID, placed.ExchangeOrderID)
+	}
+
+	unsent := lost
+	unsent.ClientOrderID = order.NewClientOrderID()
+	if _, err := adapter.FetchOrderStatus(ctx, &unsent); !errors.Is(err, order.ErrNotOnExchange) {
+		t.Errorf("got %v for an order never sent, want %v", err, order.ErrNotOnExchange)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	ctx := context.Background()
+	placed, err := adapter.PlaceOrder(ctx, limitOrder(order.OrderSideBuy, "0.03", "59000"), userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	other, err := adapter.PlaceOrder(ctx, limitOrder(order.OrderSideBuy, "0.04", "59000"), userID)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	orderID := placed.ID.String()
+	if err := adapter.CancelOrder(ctx, &orderID, userID); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if stored, _ := fake.Order(exchangeOrderID(t, placed)); stored.Status != "Canceled" {
+		t.Errorf("exchange order status %q after cancel, want Canceled", stored.Status)
+	}
+	if stored, _ := fake.Order(exchangeOrderID(t, other)); stored.Status != "Active" {
+		t.Errorf("other order of the market has status %q after cancel, want Active", stored.Status)
+	}
+	canceled, err := adapter.OrderRepo.GetByID(ctx, placed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canceled.Status != string(order.CANCELED) {
+		t.Errorf("stored status %s after cancel, want %s", canceled.Status, order.CANCELED)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	adapter, fake, userID := newTestExchange(t)
+	fake.FailNext(http.MethodPost, "/users/wallets/balance", http.StatusUnauthorized, `{"detail":"Invalid token."}`)
+
+	currency := "USDT"
+	if _, err := adapter.GetBalance(context.Background(), userID, &currency); err == nil {
+		t.Error("unauthorized balance request returned no error")
+	}
+}
@@ -0,0 +1,28 @@
+package nobitex
+
+import (
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+// StatusMapper translates Nobitex order statuses into the canonical order status
+type StatusMapper struct{}
+
+var _ order.IStatusMapper = StatusMapper{}
+
+func (mapper StatusMapper) MapStatus(exchangeStatus string, executedQty decimal.Decimal) order.OrderStatus {
+	switch strings.ToLower(exchangeStatus) {
+	case "done":
+		return order.FILLED
+	case "canceled", "cancelled":
+		return order.CANCELED
+	case "rejected", "failed":
+		return order.REJECTED
+	default:
+		if executedQty.IsPositive() {
+			return order.PARTIALLY
+		}
+		return order.NEW
+	}
+}
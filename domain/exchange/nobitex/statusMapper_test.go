@@ -0,0 +1,33 @@
+package nobitex
+
+import (
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestStatusMapper(t *testing.T) {
+	tests := []struct {
+		status   string
+		executed string
+		want     order.OrderStatus
+	}{
+		{status: "Done", executed: "1", want: order.FILLED},
+		{status: "Canceled", executed: "0", want: order.CANCELED},
+		{status: "cancelled", executed: "0.5", want: order.CANCELED},
+		{status: "Rejected", executed: "0", want: order.REJECTED},
+		{status: "failed", executed: "0", want: order.REJECTED},
+		{status: "Active", executed: "0", want: order.NEW},
+		{status: "Active", executed: "0.5", want: order.PARTIALLY},
+		{status: "Inactive", executed: "0", want: order.NEW},
+		{status: "New", executed: "0", want: order.NEW},
+	}
+	for _, test := range tests {
+		t.Run(test.status+"/"+test.executed, func(t *testing.T) {
+			got := StatusMapper{}.MapStatus(test.status, decimal.RequireFromString(test.executed))
+			if got != test.want {
+				t.Errorf("MapStatus(%q, %s) = %q, want %q", test.status, test.executed, got, test.want)
+			}
+		})
+	}
+}
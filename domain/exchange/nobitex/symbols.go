@@ -1,54 +1,135 @@
 package nobitex
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/rzabhd80/eye-on/internal/database/models"
 	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"strings"
 )
 
-type NobitexSymbolRegistry struct{}
+// quoteAssets are the quote currencies of Nobitex markets, its symbols carry no separator
+var quoteAssets = []string{"USDT", "IRT"}
+
+// NobitexSymbolRegistry seeds the Nobitex pairs below and, given a Request, reads the listed markets from Nobitex
+type NobitexSymbolRegistry struct {
+	Request *helpers.Request
+}
 
 func (reg *NobitexSymbolRegistry) RegisterExchangeSymbols(bitpinExchange *models.Exchange) *[]models.TradingPair {
 	pairs := []models.TradingPair{
-		{ExchangeID: bitpinExchange.ID, Symbol: "BTCIRT", BaseAsset: "BTC", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.00000001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "ETHIRT", BaseAsset: "ETH", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.00000001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "USDTIRT", BaseAsset: "USDT", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BNBIRT", BaseAsset: "BNB", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "USDCIRT", BaseAsset: "USDC", QuoteAsset: "IRT", TickSize: helpers.FloatPointer(1),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.00000001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.00000001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "LTCUSDT", BaseAsset: "LTC", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "XRPUSDT", BaseAsset: "XRP", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BCHUSDT", BaseAsset: "BCH", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "BNBUSDT", BaseAsset: "BNB", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "EOSUSDT", BaseAsset: "EOS", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "XLMUSDT", BaseAsset: "XLM", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "ETCUSDT", BaseAsset: "ETC", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "TRXUSDT", BaseAsset: "TRX", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.01), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "DOGEUSDT", BaseAsset: "DOGE", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "UNIUSDT", BaseAsset: "UNI", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "DAIUSDT", BaseAsset: "DAI", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "LINKUSDT", BaseAsset: "LINK", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
-		{ExchangeID: bitpinExchange.ID, Symbol: "DOTUSDT", BaseAsset: "DOT", QuoteAsset: "USDT", TickSize: helpers.FloatPointer(0.01),
-			StepSize: helpers.FloatPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BTCIRT", BaseAsset: "BTC", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "ETHIRT", BaseAsset: "ETH", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "USDTIRT", BaseAsset: "USDT", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BNBIRT", BaseAsset: "BNB", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "USDCIRT", BaseAsset: "USDC", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "LTCUSDT", BaseAsset: "LTC", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "XRPUSDT", BaseAsset: "XRP", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BCHUSDT", BaseAsset: "BCH", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "BNBUSDT", BaseAsset: "BNB", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "EOSUSDT", BaseAsset: "EOS", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "XLMUSDT", BaseAsset: "XLM", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "ETCUSDT", BaseAsset: "ETC", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "TRXUSDT", BaseAsset: "TRX", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.01), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "DOGEUSDT", BaseAsset: "DOGE", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "UNIUSDT", BaseAsset: "UNI", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "DAIUSDT", BaseAsset: "DAI", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "LINKUSDT", BaseAsset: "LINK", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: bitpinExchange.ID, Symbol: "DOTUSDT", BaseAsset: "DOT", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
 	}
 	return &pairs
 }
+
+// ExchangeAssets declares the currencies Nobitex names differently, it trades IRT markets in rials
+func (reg *NobitexSymbolRegistry) ExchangeAssets(nobitexExchange *models.Exchange) []models.ExchangeAsset {
+	return []models.ExchangeAsset{
+		{ExchangeID: nobitexExchange.ID, Asset: "IRT", Code: "rls", Scale: decimal.NewFromInt(10)},
+	}
+}
+
+// FetchExchangeSymbols reads the markets Nobitex lists from its options with their filters and order amount bounds,
+// prices of IRT markets are in rials
+func (reg *NobitexSymbolRegistry) FetchExchangeSymbols(ctx context.Context, nobitexExchange *models.Exchange) (
+	[]models.TradingPair, error) {
+	respBody, body, err := reg.Request.MakeRequest(ctx, "GET", "/v2/options", nil, nil,
+		nobitexExchange.BaseURL, false, false, helpers.ApiKeyAuth)
+	if err != nil {
+		return nil, err
+	}
+	if respBody.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response from %s: options request failed: %s", nobitexExchange.Name, string(body))
+	}
+	var options OptionsResponse
+	if err := json.Unmarshal(body, &options); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if options.Status == "failed" {
+		return nil, fmt.Errorf("response from %s: options request failed: %s", nobitexExchange.Name, string(body))
+	}
+	pairs := make([]models.TradingPair, 0, len(options.Nobitex.AmountPrecisions))
+	for symbol, amountPrecision := range options.Nobitex.AmountPrecisions {
+		symbol = strings.ToUpper(symbol)
+		baseAsset, quoteAsset, found := splitSymbol(symbol)
+		if !found {
+			continue
+		}
+		pair := models.TradingPair{
+			ExchangeID: nobitexExchange.ID,
+			Symbol:     symbol,
+			BaseAsset:  baseAsset,
+			QuoteAsset: quoteAsset,
+			IsActive:   true,
+		}
+		if stepSize, err := decimal.NewFromString(amountPrecision); err == nil {
+			pair.StepSize = &stepSize
+		}
+		if tickSize, err := decimal.NewFromString(options.Nobitex.PricePrecisions[symbol]); err == nil {
+			pair.TickSize = &tickSize
+		}
+		if minQuantity, err := decimal.NewFromString(options.Nobitex.MinOrderAmounts[symbol]); err == nil &&
+			minQuantity.IsPositive() {
+			pair.MinQuantity = &minQuantity
+		}
+		if maxQuantity, err := decimal.NewFromString(options.Nobitex.MaxOrderAmounts[symbol]); err == nil &&
+			maxQuantity.IsPositive() {
+			pair.MaxQuantity = &maxQuantity
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// splitSymbol splits a Nobitex market symbol such as BTCIRT by its quote currency
+func splitSymbol(symbol string) (string, string, bool) {
+	for _, quoteAsset := range quoteAssets {
+		if baseAsset, found := strings.CutSuffix(symbol, quoteAsset); found && baseAsset != "" {
+			return baseAsset, quoteAsset, true
+		}
+	}
+	return "", "", false
+}
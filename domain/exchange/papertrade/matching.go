@@ -0,0 +1,96 @@
+package papertrade
+
+import (
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// MatchingEngine fills paper orders against the liquidity of an order book snapshot
+type MatchingEngine struct {
+	FeeRate decimal.Decimal
+}
+
+// Match takes up to quantity from the opposite side of book without crossing limit, a nil limit is a market order.
+// Every level is only as deep as the snapshot shows, the same snapshot is never matched twice for one order.
+func (engine MatchingEngine) Match(book *models.OrderBookSnapshot, side order.OrderSide, quantity decimal.Decimal,
+	limit *decimal.Decimal) (Execution, error) {
+	levels, err := engine.opposite(book, side)
+	if err != nil {
+		return Execution{}, err
+	}
+	filledQty, filledQuote := decimal.Zero, decimal.Zero
+	for _, level := range levels {
+		remaining := quantity.Sub(filledQty)
+		if !remaining.IsPositive() {
+			break
+		}
+		if limit != nil && crosses(side, level.Price, *limit) {
+			break
+		}
+		take := decimal.Min(remaining, level.Quantity)
+		if !take.IsPositive() {
+			continue
+		}
+		filledQty = filledQty.Add(take)
+		filledQuote = filledQuote.Add(take.Mul(level.Price))
+	}
+	return Execution{
+		Qty:        filledQty,
+		Quote:      filledQuote,
+		Commission: engine.commission(side, filledQty, filledQuote),
+	}, nil
+}
+
+// commission is charged on what the user receives, base for buys and quote for sells
+func (engine MatchingEngine) commission(side order.OrderSide, qty, quote decimal.Decimal) decimal.Decimal {
+	if side == order.OrderSideBuy {
+		return qty.Mul(engine.FeeRate)
+	}
+	return quote.Mul(engine.FeeRate)
+}
+
+// opposite returns the levels an order of side trades against, best price first
+func (engine MatchingEngine) opposite(book *models.OrderBookSnapshot, side order.OrderSide) (
+	[]orderBook.StandardOrderLevel, error) {
+	if side == order.OrderSideBuy {
+		asks, err := orderBook.LevelsFromJSONB(book.Asks)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(asks, func(i, j int) bool { return asks[i].Price.LessThan(asks[j].Price) })
+		return asks, nil
+	}
+	bids, err := orderBook.LevelsFromJSONB(book.Bids)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(bids, func(i, j int) bool { return bids[i].Price.GreaterThan(bids[j].Price) })
+	return bids, nil
+}
+
+// crosses reports whether price is worse than the order's limit
+func crosses(side order.OrderSide, price, limit decimal.Decimal) bool {
+	if side == order.OrderSideBuy {
+		return price.GreaterThan(limit)
+	}
+	return price.LessThan(limit)
+}
+
+// statusAfter is the status of an order once executedQty of quantity is filled, market orders never rest on the book
+func statusAfter(orderType order.OrderType, quantity, executedQty decimal.Decimal) order.OrderStatus {
+	switch {
+	case executedQty.GreaterThanOrEqual(quantity):
+		return order.FILLED
+	case orderType == order.OrderTypeMarket && executedQty.IsPositive():
+		return order.CANCELED
+	case orderType == order.OrderTypeMarket:
+		return order.REJECTED
+	case executedQty.IsPositive():
+		return order.PARTIALLY
+	default:
+		return order.NEW
+	}
+}
@@ -0,0 +1,113 @@
+package papertrade
+
+import (
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func dec(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func decPtr(value string) *decimal.Decimal {
+	d := dec(value)
+	return &d
+}
+
+// bookOf builds a snapshot from price and quantity pairs, given out of order so the engine has to sort them
+func bookOf(bids, asks []string) *models.OrderBookSnapshot {
+	levels := func(priceQuantity []string) models.JSONB {
+		var result []orderBook.StandardOrderLevel
+		for i := 0; i+1 < len(priceQuantity); i += 2 {
+			result = append(result, orderBook.StandardOrderLevel{Price: dec(priceQuantity[i]),
+				Quantity: dec(priceQuantity[i+1])})
+		}
+		return models.JSONB{"data": result}
+	}
+	return &models.OrderBookSnapshot{Bids: levels(bids), Asks: levels(asks)}
+}
+
+func TestMatch(t *testing.T) {
+	book := bookOf([]string{"98", "2", "99", "1", "97", "5"}, []string{"102", "5", "100", "1", "101", "2"})
+	engine := MatchingEngine{FeeRate: dec("0.001")}
+	tests := []struct {
+		name       string
+		side       order.OrderSide
+		quantity   string
+		limit      *decimal.Decimal
+		orderType  order.OrderType
+		qty        string
+		quote      string
+		commission string
+		status     order.OrderStatus
+	}{
+		{name: "limit buy fills up to its limit and rests", side: order.OrderSideBuy, quantity: "5",
+			limit: decPtr("101"), orderType: order.OrderTypeLimit, qty: "3", quote: "302", commission: "0.003",
+			status: order.PARTIALLY},
+		{name: "limit sell fills down to its limit and rests", side: order.OrderSideSell, quantity: "4",
+			limit: decPtr("98"), orderType: order.OrderTypeLimit, qty: "3", quote: "295", commission: "0.295",
+			status: order.PARTIALLY},
+		{name: "limit buy below the book does not fill", side: order.OrderSideBuy, quantity: "1",
+			limit: decPtr("99"), orderType: order.OrderTypeLimit, qty: "0", quote: "0", commission: "0",
+			status: order.NEW},
+		{name: "limit buy within the best level fills", side: order.OrderSideBuy, quantity: "0.5",
+			limit: decPtr("100"), orderType: order.OrderTypeLimit, qty: "0.5", quote: "50", commission: "0.0005",
+			status: order.FILLED},
+		{name: "market buy exhausting the book cancels the rest", side: order.OrderSideBuy, quantity: "10",
+			orderType: order.OrderTypeMarket, qty: "8", quote: "812", commission: "0.008", status: order.CANCELED},
+		{name: "market sell exhausting the book cancels the rest", side: order.OrderSideSell, quantity: "10",
+			orderType: order.OrderTypeMarket, qty: "8", quote: "780", commission: "0.78", status: order.CANCELED},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			execution, err := engine.Match(book, test.side, dec(test.quantity), test.limit)
+			if err != nil {
+				t.Fatalf("Match: %v", err)
+			}
+			if !execution.Qty.Equal(dec(test.qty)) || !execution.Quote.Equal(dec(test.quote)) ||
+				!execution.Commission.Equal(dec(test.commission)) {
+				t.Errorf("got qty %s quote %s commission %s, want %s %s %s", execution.Qty, execution.Quote,
+					execution.Commission, test.qty, test.quote, test.commission)
+			}
+			if status := statusAfter(test.orderType, dec(test.quantity), execution.Qty); status != test.status {
+				t.Errorf("got status %s, want %s", status, test.status)
+			}
+		})
+	}
+}
+
+func TestMatchEmptyBook(t *testing.T) {
+	execution, err := MatchingEngine{}.Match(bookOf(nil, nil), order.OrderSideBuy, dec("1"), nil)
+	if err != nil {
+		t.Fatalf("Match: %v", err)
+	}
+	if !execution.Qty.IsZero() {
+		t.Errorf("got qty %s from an empty book, want 0", execution.Qty)
+	}
+	if status := statusAfter(order.OrderTypeMarket, dec("1"), execution.Qty); status != order.REJECTED {
+		t.Errorf("got status %s for an unfilled market order, want %s", status, order.REJECTED)
+	}
+}
+
+func TestStatusAfter(t *testing.T) {
+	tests := []struct {
+		orderType order.OrderType
+		executed  string
+		want      order.OrderStatus
+	}{
+		{orderType: order.OrderTypeLimit, executed: "0", want: order.NEW},
+		{orderType: order.OrderTypeLimit, executed: "1", want: order.PARTIALLY},
+		{orderType: order.OrderTypeLimit, executed: "2", want: order.FILLED},
+		{orderType: order.OrderTypeMarket, executed: "0", want: order.REJECTED},
+		{orderType: order.OrderTypeMarket, executed: "1", want: order.CANCELED},
+		{orderType: order.OrderTypeMarket, executed: "2", want: order.FILLED},
+	}
+	for _, test := range tests {
+		if got := statusAfter(test.orderType, dec("2"), dec(test.executed)); got != test.want {
+			t.Errorf("statusAfter(%s, 2, %s) = %s, want %s", test.orderType, test.executed, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package papertrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// PaperTradeExchange simulates an exchange locally, orders are matched against the order books of a source exchange
+// and paid from virtual balances so strategies can be tried without credentials or funds
+type PaperTradeExchange struct {
+	PaperTradeExchangeModel *models.Exchange
+	SourceExchangeModel     *models.Exchange           // exchange whose order books drive the matching engine
+	MarketData              registry.IOrderBookFetcher // optional live source, recorded snapshots are used when it fails
+	MaxBookAge              time.Duration              // recorded snapshots older than this are not matched against
+	ExchangeCredentialRepo  *exchangeCredentials.ExchangeCredentialRepository
+	TradingPairRepo         *traidingPair.TradingPairRepository
+	OrderRepo               *order.OrderRepository
+	OrderBookRepo           *orderBook.OrderBookSnapshotRepository
+	PaperRepo               *PaperTradeRepository
+	Symbols                 *symbols.SymbolRegistry
+	Engine                  MatchingEngine
+	InitialBalances         map[string]decimal.Decimal
+}
+
+var _ registry.IExchange = &PaperTradeExchange{}
+
+var ErrStaleOrderBook = errors.New("the latest recorded order book is too old to simulate against")
+
+func (exchange *PaperTradeExchange) Name() string                   { return exchange.PaperTradeExchangeModel.Name }
+func (exchange *PaperTradeExchange) Ping(ctx context.Context) error { return nil }
+
+func (exchange *PaperTradeExchange) GetBalance(ctx context.Context, userId uuid.UUID, symbol *string) (
+	[]models.BalanceSnapshot, error) {
+	if err := exchange.PaperRepo.EnsureBalances(ctx, userId, exchange.InitialBalances); err != nil {
+		return nil, err
+	}
+	currency := ""
+	if symbol != nil {
+		currency = strings.ToUpper(*symbol)
+	}
+	wallets, err := exchange.PaperRepo.GetBalances(ctx, userId, currency)
+	if err != nil {
+		return nil, err
+	}
+	balanceSnapshot := make([]models.BalanceSnapshot, 0, len(wallets))
+	for _, wallet := range wallets {
+		balanceSnapshot = append(balanceSnapshot, models.BalanceSnapshot{
+			BaseModel:    models.BaseModel{ID: uuid.New()},
+			UserID:       userId,
+			ExchangeID:   exchange.PaperTradeExchangeModel.ID,
+			Currency:     wallet.Currency,
+			Total:        wallet.Available.Add(wallet.Locked),
+			Available:    wallet.Available,
+			SnapshotTime: time.Now(),
+		})
+	}
+	return balanceSnapshot, nil
+}
+
+func (exchange *PaperTradeExchange) GetOrderBook(ctx context.Context, symbol string, userId uuid.UUID) (
+	*models.OrderBookSnapshot, error) {
+	tradePair, err := exchange.Symbols.Resolve(ctx, exchange.PaperTradeExchangeModel.ID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("this symbol is not for this exchange ")
+	}
+	return exchange.sourceBook(ctx, tradePair)
+}
+
+// sourceBook returns the freshest order book of the source exchange for the same market as tradePair. The live book
+// is public so it needs no credentials on the source exchange, a recorded one is used only while it is recent
+func (exchange *PaperTradeExchange) sourceBook(ctx context.Context, tradePair *models.TradingPair) (
+	*models.OrderBookSnapshot, error) {
+	sourcePair, err := exchange.TradingPairRepo.GetByExchangeAndAssets(ctx, exchange.SourceExchangeModel.ID,
+		tradePair.BaseAsset, tradePair.QuoteAsset)
+	if err != nil {
+		return nil, fmt.Errorf("%s has no %s/%s market to simulate against", exchange.SourceExchangeModel.Name,
+			tradePair.BaseAsset, tradePair.QuoteAsset)
+	}
+	if exchange.MarketData != nil {
+		if snapshot, err := exchange.MarketData.FetchOrderBook(ctx, sourcePair.Symbol); err == nil {
+			return snapshot, nil
+		}
+	}
+	snapshot, err := exchange.OrderBookRepo.GetLatestByTradingPair(ctx, sourcePair.ID)
+	if err != nil {
+		return nil, fmt.Errorf("no recorded order book for %s on %s", sourcePair.Symbol, exchange.SourceExchangeModel.Name)
+	}
+	if age := time.Since(snapshot.SnapshotTime); age > exchange.MaxBookAge {
+		return nil, fmt.Errorf("%w: %s on %s is %s old", ErrStaleOrderBook, sourcePair.Symbol,
+			exchange.SourceExchangeModel.Name, age.Round(time.Second))
+	}
+	return snapshot, nil
+}
+
+// credential returns the user's paper credential, order histories reference one like on a real exchange
+func (exchange *PaperTradeExchange) credential(ctx context.Context, userId uuid.UUID) (*models.ExchangeCredential, error) {
+	creds, err := exchange.ExchangeCredentialRepo.GetByUserAndExchange(ctx, userId, exchange.PaperTradeExchangeModel.ID)
+	if err == nil {
+		return creds, nil
+	}
+	// only a missing default credential is created, a selected one the user does not hold is an error
+	if !errors.Is(err, gorm.ErrRecordNotFound) || !exchangeCredentials.SelectorFrom(ctx).IsZero() {
+		return nil, err
+	}
+	apiKey, err := helpers.EncryptAPIKey("paper", exchange.ExchangeCredentialRepo.EnvConf.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	creds = &models.ExchangeCredential{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		UserID:     userId,
+		ExchangeID: exchange.PaperTradeExchangeModel.ID,
+		Label:      "Paper",
+		APIKey:     apiKey,
+		IsActive:   true,
+		IsDefault:  true,
+		IsTestnet:  true,
+	}
+	if err := exchange.ExchangeCredentialRepo.Create(ctx, creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+func (exchange *PaperTradeExchange) PlaceOrder(ctx context.Context, req *order.StandardOrderRequest, userId uuid.UUID) (
+	*models.OrderHistory, error) {
+	creds, err := exchange.credential(ctx, userId)
+	if err != nil {
+		return nil, errors.New("Internal Server Error")
+	}
+	if err := exchange.PaperRepo.EnsureBalances(ctx, userId, exchange.InitialBalances); err != nil {
+		return nil, err
+	}
+	tradePair, err := exchange.Symbols.Resolve(ctx, exchange.PaperTradeExchangeModel.ID, req.Symbol)
+	if err != nil {
+		return nil, errors.New("symbol not found for this exchange")
+	}
+	req.Symbol = tradePair.Symbol
+	if req.ClientOrderId == "" {
+		req.ClientOrderId = order.NewClientOrderID()
+	}
+
+	helper := &helpers.OrderCalculationHelper{}
+	if err := helper.ApplyTradingPairFilters(req, tradePair); err != nil {
+		return nil, err
+	}
+	quantity, err := helper.GetBaseAmountForExchange(req)
+	if err != nil {
+		return nil, order.NewValidationError(order.CodeQuantityRequired, "quantity",
+			"paper orders need a base quantity or a price to derive it")
+	}
+	var limit *decimal.Decimal
+	if req.Type == order.OrderTypeLimit {
+		limit = req.Price
+	}
+
+	orderHistory, reserved, err := exchange.OrderRepo.Reserve(ctx, &models.OrderHistory{
+		BaseModel: models.BaseModel{
+			ID: uuid.New(),
+		},
+		UserID:               userId,
+		ExchangeCredentialID: creds.ID,
+		ExchangeID:           exchange.PaperTradeExchangeModel.ID,
+		TradingPairID:        tradePair.ID,
+		ClientOrderID:        req.ClientOrderId,
+		Side:                 string(req.Side),
+		Type:                 string(req.Type),
+		Quantity:             quantity,
+		Price:                req.Price,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return order.ReplayPlacement(orderHistory)
+	}
+	orderHistory.TradingPair = *tradePair
+
+	book, err := exchange.sourceBook(ctx, tradePair)
+	if err != nil {
+		if releaseErr := exchange.OrderRepo.ReleaseReservation(ctx, orderHistory.ID); releaseErr != nil {
+			return nil, releaseErr
+		}
+		return nil, err
+	}
+	execution, err := exchange.Engine.Match(book, req.Side, quantity, limit)
+	if err != nil {
+		if releaseErr := exchange.OrderRepo.ReleaseReservation(ctx, orderHistory.ID); releaseErr != nil {
+			return nil, releaseErr
+		}
+		return nil, err
+	}
+	execution.Status = statusAfter(req.Type, quantity, execution.Qty)
+
+	// buys hold the quote they may spend, limit buys at their limit price, sells hold the base they sell
+	lockAmount := quantity
+	if req.Side == order.OrderSideBuy {
+		lockAmount = execution.Quote
+		if limit != nil {
+			lockAmount = quantity.Mul(*limit)
+		}
+	}
+	paperOrder := &models.PaperOrder{
+		BaseModel:     models.BaseModel{ID: uuid.New()},
+		UserID:        userId,
+		TradingPairID: tradePair.ID,
+		ClientOrderID: req.ClientOrderId,
+		Side:          string(req.Side),
+		Type:          string(req.Type),
+		Quantity:      quantity,
+		Price:         limit,
+		TradingPair:   *tradePair,
+	}
+	if err := exchange.PaperRepo.Submit(ctx, paperOrder, lockAmount, execution); err != nil {
+		if releaseErr := exchange.OrderRepo.ReleaseReservation(ctx, orderHistory.ID); releaseErr != nil {
+			return nil, releaseErr
+		}
+		if errors.Is(err, ErrInsufficientBalance) {
+			return nil, order.NewValidationError(order.CodeInsufficientFund, "quantity",
+				"not enough paper %s to place this order", lockCurrency(paperOrder))
+		}
+		return nil, err
+	}
+
+	orderHistory.ExchangeOrderID = paperOrder.ID.String()
+	orderHistory.Status = paperOrder.Status
+	orderHistory.ExecutedQty = paperOrder.ExecutedQty
+	orderHistory.ExecutedPrice = averagePrice(paperOrder)
+	orderHistory.Commission = paperOrder.Commission
+	if orderHistory.Price == nil {
+		orderHistory.Price = orderHistory.ExecutedPrice
+	}
+	if err := exchange.OrderRepo.ConfirmReservation(ctx, orderHistory, time.Now()); err != nil {
+		return nil, err
+	}
+	return orderHistory, nil
+}
+
+func (exchange *PaperTradeExchange) CancelOrder(ctx context.Context, orderID *string, userId uuid.UUID) error {
+	orderId, err := uuid.Parse(*orderID)
+	if err != nil {
+		return errors.New("malformed orderId")
+	}
+	orderData, err := exchange.OrderRepo.GetByID(ctx, orderId)
+	if err != nil || orderData.UserID != userId || orderData.ExchangeID != exchange.PaperTradeExchangeModel.ID {
+		return errors.New("order record was not found")
+	}
+	paperOrderID, err := uuid.Parse(orderData.ExchangeOrderID)
+	if err != nil {
+		return errors.New("order was never accepted by the paper exchange")
+	}
+	paperOrder, err := exchange.PaperRepo.Execute(ctx, paperOrderID, func(paperOrder *models.PaperOrder) (
+		Execution, error) {
+		if order.OrderStatus(paperOrder.Status).IsTerminal() {
+			return Execution{}, fmt.Errorf("order is already %s", paperOrder.Status)
+		}
+		return Execution{Status: order.CANCELED}, nil
+	})
+	if err != nil {
+		return err
+	}
+	return exchange.OrderRepo.UpdateStatusWithEvent(ctx, orderData.ID, order.CANCELED, paperOrder.ExecutedQty,
+		priceOrZero(averagePrice(paperOrder)), paperOrder.Commission, time.Now())
+}
+
+// FetchOrderStatus matches the open part of an order against the newest source book and reports the result
+func (exchange *PaperTradeExchange) FetchOrderStatus(ctx context.Context, orderHistory *models.OrderHistory) (
+	*order.OrderStatusUpdate, error) {
+	paperOrder, err := exchange.paperOrder(ctx, orderHistory)
+	if err != nil {
+		return nil, err
+	}
+	if !order.OrderStatus(paperOrder.Status).IsTerminal() {
+		paperOrder, err = exchange.matchResting(ctx, paperOrder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &order.OrderStatusUpdate{
+		ExchangeOrderID: paperOrder.ID.String(),
+		Status:          order.OrderStatus(paperOrder.Status),
+		ExecutedQty:     paperOrder.ExecutedQty,
+		ExecutedPrice:   priceOrZero(averagePrice(paperOrder)),
+		Commission:      paperOrder.Commission,
+		UpdatedAt:       paperOrder.UpdatedAt,
+	}, nil
+}
+
+// paperOrder loads the paper order behind orderHistory, by client order id when the placement was never confirmed
+func (exchange *PaperTradeExchange) paperOrder(ctx context.Context, orderHistory *models.OrderHistory) (
+	*models.PaperOrder, error) {
+	if orderHistory.ExchangeOrderID == "" {
+		paperOrder, err := exchange.PaperRepo.GetOrderByClientOrderID(ctx, orderHistory.UserID,
+			orderHistory.ClientOrderID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, order.ErrNotOnExchange
+		}
+		return paperOrder, err
+	}
+	paperOrderID, err := uuid.Parse(orderHistory.ExchangeOrderID)
+	if err != nil {
+		return nil, errors.New("order was never accepted by the paper exchange")
+	}
+	return exchange.PaperRepo.GetOrder(ctx, paperOrderID)
+}
+
+// matchResting fills a resting limit order from a snapshot taken after it was last matched
+func (exchange *PaperTradeExchange) matchResting(ctx context.Context, paperOrder *models.PaperOrder) (
+	*models.PaperOrder, error) {
+	book, err := exchange.sourceBook(ctx, &paperOrder.TradingPair)
+	if err != nil {
+		return nil, err
+	}
+	return exchange.PaperRepo.Execute(ctx, paperOrder.ID, func(locked *models.PaperOrder) (Execution, error) {
+		current := order.OrderStatus(locked.Status)
+		if current.IsTerminal() || !book.SnapshotTime.After(locked.UpdatedAt) {
+			return Execution{Status: current}, nil
+		}
+		execution, err := exchange.Engine.Match(book, order.OrderSide(locked.Side),
+			locked.Quantity.Sub(locked.ExecutedQty), locked.Price)
+		if err != nil {
+			return Execution{}, err
+		}
+		execution.Status = statusAfter(order.OrderType(locked.Type), locked.Quantity,
+			locked.ExecutedQty.Add(execution.Qty))
+		return execution, nil
+	})
+}
+
+func (exchange *PaperTradeExchange) GetOrder(ctx context.Context, orderID string, userId uuid.UUID) (
+	*order.StandardOrderResponse, error) {
+	orderId, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, errors.New("malformed orderId")
+	}
+	orderHistory, err := exchange.OrderRepo.GetOrderHistoryWithTradingPair(ctx, orderId)
+	if err != nil || orderHistory.UserID != userId || orderHistory.ExchangeID != exchange.PaperTradeExchangeModel.ID {
+		return nil, errors.New("order record was not found")
+	}
+	update, err := exchange.FetchOrderStatus(ctx, orderHistory)
+	if err != nil {
+		return nil, err
+	}
+	orderResponse := order.NewStandardOrderResponse(orderHistory)
+	orderResponse.Status = update.Status
+	orderResponse.ExecutedQty = update.ExecutedQty
+	return &orderResponse, nil
+}
+
+// ListOpenOrders lists the user's resting paper orders, optionally filtered by symbol
+func (exchange *PaperTradeExchange) ListOpenOrders(ctx context.Context, symbol string, userId uuid.UUID) (
+	[]order.StandardOrderResponse, error) {
+	var tradingPairID *uuid.UUID
+	if symbol != "" {
+		tradePair, err := exchange.Symbols.Resolve(ctx, exchange.PaperTradeExchangeModel.ID, symbol)
+		if err != nil {
+			return nil, errors.New("symbol not found for this exchange")
+		}
+		tradingPairID = &tradePair.ID
+	}
+	paperOrders, err := exchange.PaperRepo.GetOpenOrders(ctx, userId, tradingPairID)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]order.StandardOrderResponse, 0, len(paperOrders))
+	for _, paperOrder := range paperOrders {
+		orderResponse := order.StandardOrderResponse{
+			Symbol:          symbols.Canonical(paperOrder.TradingPair.BaseAsset, paperOrder.TradingPair.QuoteAsset),
+			Side:            order.OrderSide(paperOrder.Side),
+			Type:            order.OrderType(paperOrder.Type),
+			Quantity:        paperOrder.Quantity,
+			Price:           paperOrder.Price,
+			Status:          order.OrderStatus(paperOrder.Status),
+			CreatedAt:       paperOrder.CreatedAt,
+			UpdatedAt:       paperOrder.UpdatedAt,
+			ExchangeID:      exchange.PaperTradeExchangeModel.ID.String(),
+			ExecutedQty:     paperOrder.ExecutedQty,
+			ExchangeOrderID: paperOrder.ID.String(),
+			ClientOrderID:   paperOrder.ClientOrderID,
+		}
+		if orderHistory, err := exchange.OrderRepo.GetByExchangeOrderID(ctx, orderResponse.ExchangeOrderID); err == nil &&
+			orderHistory.ExchangeID == exchange.PaperTradeExchangeModel.ID {
+			orderResponse.ID = orderHistory.ID.String()
+		}
+		orders = append(orders, orderResponse)
+	}
+	return orders, nil
+}
+
+// averagePrice is the volume weighted fill price, nil while nothing is filled
+func averagePrice(paperOrder *models.PaperOrder) *decimal.Decimal {
+	if !paperOrder.ExecutedQty.IsPositive() {
+		return nil
+	}
+	price := paperOrder.ExecutedQuote.Div(paperOrder.ExecutedQty)
+	return &price
+}
+
+func priceOrZero(price *decimal.Decimal) decimal.Decimal {
+	if price == nil {
+		return decimal.Zero
+	}
+	return *price
+}
@@ -0,0 +1,107 @@
+package papertrade
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/exchangefake"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+// staticFetcher answers every public order book read with book, or err when it is set
+type staticFetcher struct {
+	book *models.OrderBookSnapshot
+	err  error
+}
+
+func (fetcher staticFetcher) FetchOrderBook(ctx context.Context, symbol string) (*models.OrderBookSnapshot, error) {
+	return fetcher.book, fetcher.err
+}
+
+// newTestExchange returns a paper exchange simulating against a source exchange listing BTC/USDT, with the pair the
+// paper orders are placed on
+func newTestExchange(t *testing.T) (*PaperTradeExchange, *models.TradingPair) {
+	t.Helper()
+	gormDb := exchangefake.NewDatabase(t)
+	source := &models.Exchange{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "nobitex", DisplayName: "nobitex",
+		BaseURL: "https://apiv2.nobitex.ir", IsActive: true}
+	sourcePair := &models.TradingPair{BaseModel: models.BaseModel{ID: uuid.New()}, ExchangeID: source.ID,
+		Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT", IsActive: true}
+	for _, row := range []interface{}{source, sourcePair} {
+		if err := gormDb.Create(row).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	paper := &PaperTradeExchange{
+		SourceExchangeModel: source,
+		TradingPairRepo:     &traidingPair.TradingPairRepository{DB: gormDb},
+		OrderBookRepo:       orderBook.NewOrderBookSnapshotRepository(gormDb),
+		MaxBookAge:          time.Minute,
+	}
+	return paper, &models.TradingPair{Symbol: "BTC/USDT", BaseAsset: "BTC", QuoteAsset: "USDT"}
+}
+
+func recordBook(t *testing.T, paper *PaperTradeExchange, bestBid string, at time.Time) {
+	t.Helper()
+	sourcePair, err := paper.TradingPairRepo.GetByExchangeAndAssets(context.Background(), paper.SourceExchangeModel.ID,
+		"BTC", "USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bid := orderBook.StandardOrderLevel{Price: decimal.RequireFromString(bestBid), Quantity: decimal.NewFromInt(1)}
+	err = paper.OrderBookRepo.Create(context.Background(), &models.OrderBookSnapshot{
+		ExchangeID:    paper.SourceExchangeModel.ID,
+		TradingPairID: sourcePair.ID,
+		Symbol:        sourcePair.Symbol,
+		Bids:          models.JSONB{"data": []orderBook.StandardOrderLevel{bid}},
+		Asks:          models.JSONB{"data": []orderBook.StandardOrderLevel{}},
+		SnapshotTime:  at,
+	})
+	if err != nil {
+		t.Fatalf("record book: %v", err)
+	}
+}
+
+func TestSourceBookPrefersTheLiveBook(t *testing.T) {
+	paper, pair := newTestExchange(t)
+	recordBook(t, paper, "100", time.Now())
+	live := &models.OrderBookSnapshot{Symbol: "BTCUSDT", SnapshotTime: time.Now()}
+	paper.MarketData = staticFetcher{book: live}
+
+	book, err := paper.sourceBook(context.Background(), pair)
+	if err != nil {
+		t.Fatalf("sourceBook: %v", err)
+	}
+	if book != live {
+		t.Errorf("got a recorded book while the live one was readable")
+	}
+}
+
+func TestSourceBookFallsBackToRecentRecordedBook(t *testing.T) {
+	for name, test := range map[string]struct {
+		age     time.Duration
+		wantErr error
+	}{
+		"recent": {age: 10 * time.Second},
+		"stale":  {age: time.Hour, wantErr: ErrStaleOrderBook},
+	} {
+		t.Run(name, func(t *testing.T) {
+			paper, pair := newTestExchange(t)
+			paper.MarketData = staticFetcher{err: errors.New("source unreachable")}
+			recordBook(t, paper, "100", time.Now().Add(-test.age))
+
+			book, err := paper.sourceBook(context.Background(), pair)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr == nil && (book.BestBid == nil || !book.BestBid.Equal(decimal.NewFromInt(100))) {
+				t.Errorf("got best bid %v from the recorded book, want 100", book.BestBid)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package papertrade
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrInsufficientBalance = errors.New("insufficient paper balance")
+
+// Execution is the result of running an order through the matching engine once
+type Execution struct {
+	Qty        decimal.Decimal // base asset filled
+	Quote      decimal.Decimal // quote asset paid or received for Qty
+	Commission decimal.Decimal // charged in the asset the user receives
+	Status     order.OrderStatus
+}
+
+type IPaperTradeRepository interface {
+	EnsureBalances(ctx context.Context, userID uuid.UUID, initial map[string]decimal.Decimal) error
+	GetBalances(ctx context.Context, userID uuid.UUID, currency string) ([]models.PaperBalance, error)
+	GetOrder(ctx context.Context, id uuid.UUID) (*models.PaperOrder, error)
+	GetOpenOrders(ctx context.Context, userID uuid.UUID, tradingPairID *uuid.UUID) ([]models.PaperOrder, error)
+	Submit(ctx context.Context, paperOrder *models.PaperOrder, lockAmount decimal.Decimal, execution Execution) error
+	Execute(ctx context.Context, id uuid.UUID,
+		match func(paperOrder *models.PaperOrder) (Execution, error)) (*models.PaperOrder, error)
+}
+
+type PaperTradeRepository struct {
+	db *gorm.DB
+}
+
+func NewPaperTradeRepository(db *gorm.DB) *PaperTradeRepository {
+	return &PaperTradeRepository{db: db}
+}
+
+// EnsureBalances funds the virtual wallets of a user the first time they use the paper exchange
+func (r *PaperTradeRepository) EnsureBalances(ctx context.Context, userID uuid.UUID,
+	initial map[string]decimal.Decimal) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.PaperBalance{}).Where("user_id = ?", userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 || len(initial) == 0 {
+		return nil
+	}
+	balances := make([]models.PaperBalance, 0, len(initial))
+	for currency, amount := range initial {
+		balances = append(balances, models.PaperBalance{
+			BaseModel: models.BaseModel{ID: uuid.New()},
+			UserID:    userID,
+			Currency:  currency,
+			Available: amount,
+			Locked:    decimal.Zero,
+		})
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&balances).Error
+}
+
+func (r *PaperTradeRepository) GetBalances(ctx context.Context, userID uuid.UUID, currency string) (
+	[]models.PaperBalance, error) {
+	var balances []models.PaperBalance
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if currency != "" {
+		query = query.Where("currency = ?", currency)
+	}
+	err := query.Order("currency ASC").Find(&balances).Error
+	return balances, err
+}
+
+func (r *PaperTradeRepository) GetOrder(ctx context.Context, id uuid.UUID) (*models.PaperOrder, error) {
+	var paperOrder models.PaperOrder
+	err := r.db.WithContext(ctx).Preload("TradingPair").First(&paperOrder, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paperOrder, nil
+}
+
+// GetOrderByClientOrderID returns the paper order the user placed with clientOrderID
+func (r *PaperTradeRepository) GetOrderByClientOrderID(ctx context.Context, userID uuid.UUID, clientOrderID string) (
+	*models.PaperOrder, error) {
+	var paperOrder models.PaperOrder
+	err := r.db.WithContext(ctx).Preload("TradingPair").
+		First(&paperOrder, "user_id = ? AND client_order_id = ?", userID, clientOrderID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paperOrder, nil
+}
+
+func (r *PaperTradeRepository) GetOpenOrders(ctx context.Context, userID uuid.UUID, tradingPairID *uuid.UUID) (
+	[]models.PaperOrder, error) {
+	var paperOrders []models.PaperOrder
+	query := r.db.WithContext(ctx).Preload("TradingPair").
+		Where("user_id = ? AND status IN ?", userID, order.OpenStatuses)
+	if tradingPairID != nil {
+		query = query.Where("trading_pair_id = ?", *tradingPairID)
+	}
+	err := query.Order("created_at DESC").Find(&paperOrders).Error
+	return paperOrders, err
+}
+
+// Submit holds lockAmount of the funding asset for a new order and applies its immediate execution atomically
+func (r *PaperTradeRepository) Submit(ctx context.Context, paperOrder *models.PaperOrder, lockAmount decimal.Decimal,
+	execution Execution) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := adjustBalance(tx, paperOrder.UserID, lockCurrency(paperOrder), lockAmount.Neg(), lockAmount); err != nil {
+			return err
+		}
+		paperOrder.LockedAmount = lockAmount
+		paperOrder.Status = string(order.NEW)
+		if err := tx.Omit(clause.Associations).Create(paperOrder).Error; err != nil {
+			return err
+		}
+		return settle(tx, paperOrder, execution)
+	})
+}
+
+// Execute locks an order, lets match decide what fills next and settles the result atomically
+func (r *PaperTradeRepository) Execute(ctx context.Context, id uuid.UUID,
+	match func(paperOrder *models.PaperOrder) (Execution, error)) (*models.PaperOrder, error) {
+	var paperOrder models.PaperOrder
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&paperOrder, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&paperOrder.TradingPair, "id = ?", paperOrder.TradingPairID).Error; err != nil {
+			return err
+		}
+		execution, err := match(&paperOrder)
+		if err != nil {
+			return err
+		}
+		return settle(tx, &paperOrder, execution)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &paperOrder, nil
+}
+
+// settle moves the filled amounts between wallets and releases what is still held once the order is done
+func settle(tx *gorm.DB, paperOrder *models.PaperOrder, execution Execution) error {
+	if current := order.OrderStatus(paperOrder.Status); current != execution.Status {
+		if err := order.ValidateTransition(current, execution.Status); err != nil {
+			return err
+		}
+	}
+	base, quote := paperOrder.TradingPair.BaseAsset, paperOrder.TradingPair.QuoteAsset
+	if execution.Qty.IsPositive() {
+		if paperOrder.Side == string(order.OrderSideBuy) {
+			if err := adjustBalance(tx, paperOrder.UserID, quote, decimal.Zero, execution.Quote.Neg()); err != nil {
+				return err
+			}
+			paperOrder.LockedAmount = paperOrder.LockedAmount.Sub(execution.Quote)
+			if err := adjustBalance(tx, paperOrder.UserID, base, execution.Qty.Sub(execution.Commission),
+				decimal.Zero); err != nil {
+				return err
+			}
+		} else {
+			if err := adjustBalance(tx, paperOrder.UserID, base, decimal.Zero, execution.Qty.Neg()); err != nil {
+				return err
+			}
+			paperOrder.LockedAmount = paperOrder.LockedAmount.Sub(execution.Qty)
+			if err := adjustBalance(tx, paperOrder.UserID, quote, execution.Quote.Sub(execution.Commission),
+				decimal.Zero); err != nil {
+				return err
+			}
+		}
+		paperOrder.ExecutedQty = paperOrder.ExecutedQty.Add(execution.Qty)
+		paperOrder.ExecutedQuote = paperOrder.ExecutedQuote.Add(execution.Quote)
+		paperOrder.Commission = paperOrder.Commission.Add(execution.Commission)
+	}
+	paperOrder.Status = string(execution.Status)
+	if execution.Status.IsTerminal() && paperOrder.LockedAmount.IsPositive() {
+		if err := adjustBalance(tx, paperOrder.UserID, lockCurrency(paperOrder), paperOrder.LockedAmount,
+			paperOrder.LockedAmount.Neg()); err != nil {
+			return err
+		}
+		paperOrder.LockedAmount = decimal.Zero
+	}
+	// updated_at marks the last match, later matches only use snapshots taken after it
+	paperOrder.UpdatedAt = time.Now()
+	return tx.Model(paperOrder).
+		Select("executed_qty", "executed_quote", "commission", "locked_amount", "status", "updated_at").
+		Updates(paperOrder).Error
+}
+
+// adjustBalance changes a wallet by the given deltas, refusing to take it below zero
+func adjustBalance(tx *gorm.DB, userID uuid.UUID, currency string, availableDelta, lockedDelta decimal.Decimal) error {
+	var wallet models.PaperBalance
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND currency = ?", userID, currency).
+		First(&wallet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		wallet = models.PaperBalance{BaseModel: models.BaseModel{ID: uuid.New()}, UserID: userID, Currency: currency}
+		if err := tx.Create(&wallet).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	wallet.Available = wallet.Available.Add(availableDelta)
+	wallet.Locked = wallet.Locked.Add(lockedDelta)
+	if wallet.Available.IsNegative() || wallet.Locked.IsNegative() {
+		return ErrInsufficientBalance
+	}
+	return tx.Model(&wallet).Select("available", "locked").Updates(&wallet).Error
+}
+
+// lockCurrency is the asset an order spends, quote for buys and base for sells
+func lockCurrency(paperOrder *models.PaperOrder) string {
+	if paperOrder.Side == string(order.OrderSideBuy) {
+		return paperOrder.TradingPair.QuoteAsset
+	}
+	return paperOrder.TradingPair.BaseAsset
+}
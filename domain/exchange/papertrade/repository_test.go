@@ -0,0 +1,158 @@
+package papertrade
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/exchangefake"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+// newTestRepository returns a paper repository whose user holds 1000 USDT, with the BTC/USDT pair orders are placed on
+func newTestRepository(t *testing.T) (*PaperTradeRepository, uuid.UUID, models.TradingPair) {
+	t.Helper()
+	gormDb := exchangefake.NewDatabase(t)
+	pair := models.TradingPair{BaseModel: models.BaseModel{ID: uuid.New()}, ExchangeID: uuid.New(), Symbol: "BTC/USDT",
+		BaseAsset: "BTC", QuoteAsset: "USDT", IsActive: true}
+	if err := gormDb.Create(&pair).Error; err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	repo := NewPaperTradeRepository(gormDb)
+	userID := uuid.New()
+	if err := repo.EnsureBalances(context.Background(), userID,
+		map[string]decimal.Decimal{"USDT": dec("1000")}); err != nil {
+		t.Fatalf("EnsureBalances: %v", err)
+	}
+	return repo, userID, pair
+}
+
+func newPaperOrder(userID uuid.UUID, pair models.TradingPair, side order.OrderSide, quantity string,
+	limit *decimal.Decimal) *models.PaperOrder {
+	return &models.PaperOrder{
+		BaseModel:     models.BaseModel{ID: uuid.New()},
+		UserID:        userID,
+		TradingPairID: pair.ID,
+		ClientOrderID: uuid.NewString(),
+		Side:          string(side),
+		Type:          string(order.OrderTypeLimit),
+		Quantity:      dec(quantity),
+		Price:         limit,
+		TradingPair:   pair,
+	}
+}
+
+// assertWallet checks the available and locked amounts of a wallet of the user
+func assertWallet(t *testing.T, repo *PaperTradeRepository, userID uuid.UUID, currency, available, locked string) {
+	t.Helper()
+	wallets, err := repo.GetBalances(context.Background(), userID, currency)
+	if err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	gotAvailable, gotLocked := decimal.Zero, decimal.Zero
+	if len(wallets) == 1 {
+		gotAvailable, gotLocked = wallets[0].Available, wallets[0].Locked
+	}
+	if !gotAvailable.Equal(dec(available)) || !gotLocked.Equal(dec(locked)) {
+		t.Errorf("%s: got available %s locked %s, want %s %s", currency, gotAvailable, gotLocked, available,
+			locked)
+	}
+}
+
+func TestSubmitAndExecuteSettleBalances(t *testing.T) {
+	repo, userID, pair := newTestRepository(t)
+	ctx := context.Background()
+
+	// a limit buy of 5 at 101 holds 505 USDT and fills 3 right away for 302
+	paperOrder := newPaperOrder(userID, pair, order.OrderSideBuy, "5", decPtr("101"))
+	err := repo.Submit(ctx, paperOrder, dec("505"), Execution{Qty: dec("3"), Quote: dec("302"),
+		Commission: dec("0.003"), Status: order.PARTIALLY})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	// available + locked only shrink by what was spent
+	assertWallet(t, repo, userID, "USDT", "495", "203")
+	assertWallet(t, repo, userID, "BTC", "2.997", "0")
+	if !paperOrder.LockedAmount.Equal(dec("203")) {
+		t.Errorf("got locked amount %s on the order, want 203", paperOrder.LockedAmount)
+	}
+
+	// the rest fills for less than it held, the lock left over goes back once the order is filled
+	filled, err := repo.Execute(ctx, paperOrder.ID, func(paperOrder *models.PaperOrder) (Execution, error) {
+		return Execution{Qty: dec("2"), Quote: dec("200"), Commission: dec("0.002"), Status: order.FILLED}, nil
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	assertWallet(t, repo, userID, "USDT", "498", "0")
+	assertWallet(t, repo, userID, "BTC", "4.995", "0")
+	if filled.Status != string(order.FILLED) || !filled.ExecutedQty.Equal(dec("5")) ||
+		!filled.ExecutedQuote.Equal(dec("502")) || !filled.Commission.Equal(dec("0.005")) ||
+		!filled.LockedAmount.IsZero() {
+		t.Errorf("got order %s executed %s for %s commission %s locked %s, want filled 5 for 502, 0.005 and 0",
+			filled.Status, filled.ExecutedQty, filled.ExecutedQuote, filled.Commission, filled.LockedAmount)
+	}
+}
+
+func TestCancelReleasesTheLock(t *testing.T) {
+	repo, userID, pair := newTestRepository(t)
+	ctx := context.Background()
+
+	buy := newPaperOrder(userID, pair, order.OrderSideBuy, "2", decPtr("100"))
+	if err := repo.Submit(ctx, buy, dec("200"), Execution{Status: order.NEW}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	assertWallet(t, repo, userID, "USDT", "800", "200")
+
+	_, err := repo.Execute(ctx, buy.ID, func(paperOrder *models.PaperOrder) (Execution, error) {
+		return Execution{Status: order.CANCELED}, nil
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	assertWallet(t, repo, userID, "USDT", "1000", "0")
+
+	// a closed order can not be reopened
+	_, err = repo.Execute(ctx, buy.ID, func(paperOrder *models.PaperOrder) (Execution, error) {
+		return Execution{Status: order.NEW}, nil
+	})
+	if !errors.Is(err, order.ErrInvalidTransition) {
+		t.Errorf("got %v reopening a canceled order, want ErrInvalidTransition", err)
+	}
+	assertWallet(t, repo, userID, "USDT", "1000", "0")
+}
+
+func TestSellSettlesQuoteMinusCommission(t *testing.T) {
+	repo, userID, pair := newTestRepository(t)
+	ctx := context.Background()
+	buy := newPaperOrder(userID, pair, order.OrderSideBuy, "1", decPtr("100"))
+	if err := repo.Submit(ctx, buy, dec("100"), Execution{Qty: dec("1"), Quote: dec("100"),
+		Status: order.FILLED}); err != nil {
+		t.Fatalf("Submit buy: %v", err)
+	}
+
+	sell := newPaperOrder(userID, pair, order.OrderSideSell, "1", decPtr("110"))
+	if err := repo.Submit(ctx, sell, dec("1"), Execution{Qty: dec("0.4"), Quote: dec("44"),
+		Commission: dec("0.044"), Status: order.PARTIALLY}); err != nil {
+		t.Fatalf("Submit sell: %v", err)
+	}
+	assertWallet(t, repo, userID, "BTC", "0", "0.6")
+	assertWallet(t, repo, userID, "USDT", "943.956", "0")
+}
+
+func TestSubmitRefusesMoreThanAvailable(t *testing.T) {
+	repo, userID, pair := newTestRepository(t)
+	ctx := context.Background()
+
+	paperOrder := newPaperOrder(userID, pair, order.OrderSideBuy, "20", decPtr("100"))
+	err := repo.Submit(ctx, paperOrder, dec("2000"), Execution{Status: order.NEW})
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("got %v, want ErrInsufficientBalance", err)
+	}
+	assertWallet(t, repo, userID, "USDT", "1000", "0")
+	if _, err := repo.GetOrder(ctx, paperOrder.ID); err == nil {
+		t.Errorf("the refused order was stored")
+	}
+}
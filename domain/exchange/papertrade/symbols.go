@@ -0,0 +1,27 @@
+package papertrade
+
+import (
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/helpers"
+)
+
+// PaperTradeSymbolRegistry lists the pairs the paper exchange trades, named like the source exchange symbols
+type PaperTradeSymbolRegistry struct{}
+
+func (reg *PaperTradeSymbolRegistry) RegisterExchangeSymbols(paperExchange *models.Exchange) *[]models.TradingPair {
+	pairs := []models.TradingPair{
+		{ExchangeID: paperExchange.ID, Symbol: "BTCIRT", BaseAsset: "BTC", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: paperExchange.ID, Symbol: "ETHIRT", BaseAsset: "ETH", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: paperExchange.ID, Symbol: "USDTIRT", BaseAsset: "USDT", QuoteAsset: "IRT", TickSize: helpers.DecimalPointer(1),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+		{ExchangeID: paperExchange.ID, Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: paperExchange.ID, Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.00000001), IsActive: true},
+		{ExchangeID: paperExchange.ID, Symbol: "DOGEUSDT", BaseAsset: "DOGE", QuoteAsset: "USDT", TickSize: helpers.DecimalPointer(0.01),
+			StepSize: helpers.DecimalPointer(0.0001), IsActive: true},
+	}
+	return &pairs
+}
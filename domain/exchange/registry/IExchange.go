@@ -24,11 +24,36 @@ type ExchangeConfig struct {
 type ISymbolFactory interface {
 	RegisterExchangeSymbols(bitpinExchange *models.Exchange) *[]models.TradingPair
 }
+
+// IRemoteSymbolFactory is implemented by symbol factories that can read the listed markets from the exchange, the
+// pairs of RegisterExchangeSymbols then only seed the exchange until its first sync
+type IRemoteSymbolFactory interface {
+	ISymbolFactory
+	FetchExchangeSymbols(ctx context.Context, exchange *models.Exchange) ([]models.TradingPair, error)
+}
 type IExchange interface {
 	Name() string
 	Ping(ctx context.Context) error
 	GetBalance(ctx context.Context, userId uuid.UUID, sign *string) ([]models.BalanceSnapshot, error)
 	GetOrderBook(ctx context.Context, symbol string, userId uuid.UUID) (*models.OrderBookSnapshot, error)
 	PlaceOrder(ctx context.Context, req *order.StandardOrderRequest, userId uuid.UUID) (*models.OrderHistory, error)
-	CancelOrder(ctx context.Context, orderID *string, userId uuid.UUID, hours *float64) error
+	CancelOrder(ctx context.Context, orderID *string, userId uuid.UUID) error
+	GetOrder(ctx context.Context, orderID string, userId uuid.UUID) (*order.StandardOrderResponse, error)
+	ListOpenOrders(ctx context.Context, symbol string, userId uuid.UUID) ([]order.StandardOrderResponse, error)
+	FetchOrderStatus(ctx context.Context, orderHistory *models.OrderHistory) (*order.OrderStatusUpdate, error)
+}
+
+// ITokenRenewer is implemented by exchanges whose access tokens expire and can be renewed on demand
+type ITokenRenewer interface {
+	RenewAccessToken(ctx context.Context, userId uuid.UUID) (*models.ExchangeCredential, error)
+}
+
+// IBulkCanceller is implemented by exchanges that can cancel every old order of a market at once
+type IBulkCanceller interface {
+	CancelOldOrders(ctx context.Context, req *order.CancelOldOrdersRequest, userId uuid.UUID) error
+}
+
+// IOrderBookFetcher is implemented by exchanges whose order books are public and can be read without a user
+type IOrderBookFetcher interface {
+	FetchOrderBook(ctx context.Context, symbol string) (*models.OrderBookSnapshot, error)
 }
@@ -10,11 +10,11 @@ import (
 	"github.com/rzabhd80/eye-on/domain/traidingPair"
 	"github.com/rzabhd80/eye-on/internal/database/models"
 	"gorm.io/gorm"
+	"strings"
+	"sync"
 )
 
-var (
-	exchanges = make(map[string]ExchangeConfig)
-)
+var ErrExchangeNotSupported = errors.New("exchange is not supported")
 
 // ExchangeRegistry manages exchange registration and creation
 type ExchangeRegistry struct {
@@ -23,6 +23,8 @@ type ExchangeRegistry struct {
 	tradingPairRepo         *traidingPair.TradingPairRepository
 	exchangeCredentialsRepo *exchangeCredentials.ExchangeCredentialRepository
 	constructors            map[string]IExchange
+	configs                 map[string]ExchangeConfig // by lower case name
+	mu                      sync.RWMutex
 }
 
 func NewRegistry(repo *exchange.ExchangeRepository, tradingRepo *traidingPair.TradingPairRepository,
@@ -33,6 +35,7 @@ func NewRegistry(repo *exchange.ExchangeRepository, tradingRepo *traidingPair.Tr
 		tradingPairRepo:         tradingRepo,
 		exchangeCredentialsRepo: exchangeCredentialsRepo,
 		constructors:            make(map[string]IExchange),
+		configs:                 make(map[string]ExchangeConfig),
 	}
 }
 
@@ -46,10 +49,9 @@ type ExchangeResult struct {
 // GetOrCreateExchangeConfig creates or retrieves an exchange
 func (r *ExchangeRegistry) GetOrCreateExchangeConfig(ctx context.Context, cfg ExchangeConfig) (
 	*ExchangeResult, error) {
-	exchangeConf, found := exchanges[cfg.Name]
-	if !found {
-		exchanges[cfg.Name] = exchangeConf
-	}
+	r.mu.Lock()
+	r.configs[strings.ToLower(cfg.Name)] = cfg
+	r.mu.Unlock()
 	// Start a transaction
 	tx := r.db.WithContext(ctx).Begin()
 	defer func() {
@@ -90,6 +92,12 @@ func (r *ExchangeRegistry) GetOrCreateExchangeConfig(ctx context.Context, cfg Ex
 			tx.Rollback()
 			return nil, fmt.Errorf("failed to query exchangeInstance: %w", err)
 		}
+	} else if exchangeInstance.RateLimit != cfg.RateLimit {
+		// the configured budget wins over the stored one
+		exchangeInstance.RateLimit = cfg.RateLimit
+		if err := r.exchangeRepo.Update(ctx, exchangeInstance); err != nil {
+			return nil, fmt.Errorf("failed to update rate limit: %w", err)
+		}
 	}
 
 	symbols := cfg.SymbolFactory.RegisterExchangeSymbols(exchangeInstance)
@@ -142,8 +150,39 @@ func (r *ExchangeRegistry) GetOrCreateExchangeConfig(ctx context.Context, cfg Ex
 	}, nil
 }
 
+// Register makes an exchange adapter resolvable by its Name
+func (r *ExchangeRegistry) Register(exchange IExchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.constructors[strings.ToLower(exchange.Name())] = exchange
+}
+
+// Get resolves a registered exchange adapter by name
+func (r *ExchangeRegistry) Get(name string) (IExchange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	exchange, found := r.constructors[strings.ToLower(name)]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrExchangeNotSupported, name)
+	}
+	return exchange, nil
+}
+
+// SymbolFactory returns the symbol factory an exchange was created with
+func (r *ExchangeRegistry) SymbolFactory(name string) (ISymbolFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cfg, found := r.configs[strings.ToLower(name)]
+	if !found || cfg.SymbolFactory == nil {
+		return nil, false
+	}
+	return cfg.SymbolFactory, true
+}
+
 // ListSupportedExchanges returns a list of registered exchange names
 func (r *ExchangeRegistry) ListSupportedExchanges() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	exchanges := make([]string, 0, len(r.constructors))
 	for name := range r.constructors {
 		exchanges = append(exchanges, name)
@@ -158,6 +197,13 @@ func SetDefaultRegistry(registry *ExchangeRegistry) {
 }
 
 // Register registers an exchange constructor in the default registry
+func Register(exchange IExchange) error {
+	if defaultRegistry == nil {
+		return fmt.Errorf("default registry not initialized")
+	}
+	defaultRegistry.Register(exchange)
+	return nil
+}
 
 // GetOrCreateExchange uses the default registry
 func GetOrCreateExchange(ctx context.Context, cfg ExchangeConfig) (*ExchangeResult, error) {
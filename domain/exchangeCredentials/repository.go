@@ -2,6 +2,7 @@ package exchangeCredentials
 
 import (
 	"context"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/rzabhd80/eye-on/internal/database/models"
 	envCofig "github.com/rzabhd80/eye-on/internal/envConfig"
@@ -14,7 +15,14 @@ type IExchangeCredentialRepository interface {
 	Create(ctx context.Context, cred *models.ExchangeCredential) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.ExchangeCredential, error)
 	GetByUserAndExchange(ctx context.Context, userID, exchangeID uuid.UUID) (*models.ExchangeCredential, error)
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.ExchangeCredential, error)
+	GetByUserAndID(ctx context.Context, userID, id uuid.UUID) (*models.ExchangeCredential, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.ExchangeCredential, error)
+	ListActiveUserIDs(ctx context.Context) ([]uuid.UUID, error)
+	ListActiveByExchange(ctx context.Context, exchangeID uuid.UUID) ([]models.ExchangeCredential, error)
 	Update(ctx context.Context, cred *models.ExchangeCredential) error
+	SetDefault(ctx context.Context, cred *models.ExchangeCredential) error
+	UpdateAccessKey(ctx context.Context, id uuid.UUID, accessKey string) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	UpdateLastUsed(ctx context.Context, id uuid.UUID) error
 }
@@ -38,62 +46,177 @@ func (r *ExchangeCredentialRepository) GetByID(ctx context.Context, id uuid.UUID
 	if err != nil {
 		return nil, err
 	}
+	if err := r.decrypt(&cred); err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// GetByUserAndExchange returns the active credential of the user on the exchange that the selector on ctx names,
+// the default one when there is none
+func (r *ExchangeCredentialRepository) GetByUserAndExchange(ctx context.Context, userID, exchangeID uuid.UUID) (
+	*models.ExchangeCredential, error) {
+	var creds *models.ExchangeCredential
+	query := r.Db.WithContext(ctx).
+		Preload("Exchange").
+		Where("user_id = ? AND exchange_id = ? AND is_active = ?", userID, exchangeID, true)
+	switch selector := SelectorFrom(ctx); {
+	case selector.ID != uuid.Nil:
+		query = query.Where("id = ?", selector.ID)
+	case selector.Label != "":
+		query = query.Where("label = ?", selector.Label)
+	default:
+		query = query.Order("is_default DESC")
+	}
+	err := query.Order("updated_at DESC").First(&creds).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.decrypt(creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// decrypt replaces the api, access and refresh keys of cred by their plain values
+func (r *ExchangeCredentialRepository) decrypt(cred *models.ExchangeCredential) error {
 	key := r.EnvConf.EncryptionKey
 	apiKeyDecr, err := helpers.DecryptAPIKey(cred.APIKey, key)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	var accKeyDec string
 	if cred.AccessKey != "" {
 		accKeyDec, err = helpers.DecryptAPIKey(cred.AccessKey, key)
 		if err != nil {
-			return nil, err
+			return err
+		}
+	}
+	var refKeyDec string
+	if cred.RefreshKey != "" {
+		refKeyDec, err = helpers.DecryptAPIKey(cred.RefreshKey, key)
+		if err != nil {
+			return err
 		}
 	}
 	cred.AccessKey = accKeyDec
 	cred.APIKey = apiKeyDecr
-	return &cred, nil
+	cred.RefreshKey = refKeyDec
+	return nil
 }
 
-func (r *ExchangeCredentialRepository) GetByUserAndExchange(ctx context.Context, userID, exchangeID uuid.UUID) (
+// GetByUserAndID returns a credential of the user with its exchange, keys stay encrypted
+func (r *ExchangeCredentialRepository) GetByUserAndID(ctx context.Context, userID, id uuid.UUID) (
 	*models.ExchangeCredential, error) {
-	var creds *models.ExchangeCredential
+	var cred models.ExchangeCredential
 	err := r.Db.WithContext(ctx).
 		Preload("Exchange").
-		Where("user_id = ? AND exchange_id = ? AND is_active = ?", userID, exchangeID, true).
-		Order("updated_at DESC").
-		First(&creds).Error
-	key := r.EnvConf.EncryptionKey
-	apiKeyDecr, err := helpers.DecryptAPIKey(creds.APIKey, key)
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&cred).Error
 	if err != nil {
 		return nil, err
 	}
+	return &cred, nil
+}
 
-	var accKeyDec string
-	if creds.AccessKey != "" {
-		accKeyDec, err = helpers.DecryptAPIKey(creds.AccessKey, key)
-		if err != nil {
-			return nil, err
+// ListByUser returns every credential of the user with its exchange, inactive ones too, keys stay encrypted
+func (r *ExchangeCredentialRepository) ListByUser(ctx context.Context, userID uuid.UUID) (
+	[]models.ExchangeCredential, error) {
+	var creds []models.ExchangeCredential
+	err := r.Db.WithContext(ctx).
+		Preload("Exchange").
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&creds).Error
+	return creds, err
+}
+
+// SetDefault makes cred the default credential of its user on its exchange, the previous default loses the flag
+func (r *ExchangeCredentialRepository) SetDefault(ctx context.Context, cred *models.ExchangeCredential) error {
+	return r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ExchangeCredential{}).
+			Where("user_id = ? AND exchange_id = ? AND id <> ? AND is_default", cred.UserID, cred.ExchangeID, cred.ID).
+			Update("is_default", false).Error; err != nil {
+			return err
 		}
-	}
-	var refKeyDec string
-	if creds.RefreshKey != "" {
-		refKeyDec, err = helpers.DecryptAPIKey(creds.RefreshKey, key)
-	}
-	creds.AccessKey = accKeyDec
-	creds.APIKey = apiKeyDecr
-	creds.RefreshKey = refKeyDec
+		cred.IsDefault = true
+		return tx.Model(&models.ExchangeCredential{}).
+			Where("id = ?", cred.ID).
+			Update("is_default", true).Error
+	})
+}
+
+// ListActiveByUser returns the user's active credentials with their exchange, keys stay encrypted
+func (r *ExchangeCredentialRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) (
+	[]models.ExchangeCredential, error) {
+	var creds []models.ExchangeCredential
+	err := r.Db.WithContext(ctx).
+		Preload("Exchange").
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Order("created_at").
+		Find(&creds).Error
 	return creds, err
+}
 
+// ListActiveUserIDs returns every user holding at least one active credential
+func (r *ExchangeCredentialRepository) ListActiveUserIDs(ctx context.Context) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.Db.WithContext(ctx).
+		Model(&models.ExchangeCredential{}).
+		Where("is_active = ?", true).
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// ListActiveByExchange returns the active credentials on an exchange, keys stay encrypted
+func (r *ExchangeCredentialRepository) ListActiveByExchange(ctx context.Context, exchangeID uuid.UUID) (
+	[]models.ExchangeCredential, error) {
+	var creds []models.ExchangeCredential
+	err := r.Db.WithContext(ctx).
+		Where("exchange_id = ? AND is_active = ?", exchangeID, true).
+		Order("created_at").
+		Find(&creds).Error
+	return creds, err
 }
 
 func (r *ExchangeCredentialRepository) Update(ctx context.Context, cred *models.ExchangeCredential) error {
 	return r.Db.WithContext(ctx).Save(cred).Error
 }
 
+// UpdateAccessKey stores a renewed access key, already encrypted, without touching the other keys
+func (r *ExchangeCredentialRepository) UpdateAccessKey(ctx context.Context, id uuid.UUID, accessKey string) error {
+	return r.Db.WithContext(ctx).Model(&models.ExchangeCredential{}).
+		Where("id = ?", id).
+		Update("access_key", accessKey).Error
+}
+
+// Delete removes a credential, when it was the default the most recently updated active one left takes its place
 func (r *ExchangeCredentialRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.Db.WithContext(ctx).Delete(&models.ExchangeCredential{}, id).Error
+	return r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cred models.ExchangeCredential
+		if err := tx.First(&cred, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&cred).Error; err != nil {
+			return err
+		}
+		if !cred.IsDefault {
+			return nil
+		}
+		var next models.ExchangeCredential
+		err := tx.Where("user_id = ? AND exchange_id = ? AND is_active = ?", cred.UserID, cred.ExchangeID, true).
+			Order("updated_at DESC").
+			First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
 }
 
 func (r *ExchangeCredentialRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID) error {
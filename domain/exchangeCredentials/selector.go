@@ -0,0 +1,56 @@
+package exchangeCredentials
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"strings"
+)
+
+type selectorKey struct{}
+
+// Selector names one of the credentials a user holds on an exchange, by id or by label. The zero Selector names
+// the default credential
+type Selector struct {
+	ID    uuid.UUID
+	Label string
+}
+
+// ParseSelector builds a selector from the optional credential id and label of a request, the id wins when both
+// are given
+func ParseSelector(id, label string) (Selector, error) {
+	selector := Selector{Label: strings.TrimSpace(label)}
+	if id != "" {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return Selector{}, fmt.Errorf("malformed credential_id")
+		}
+		selector.ID = parsed
+	}
+	return selector, nil
+}
+
+// IsZero reports whether the selector leaves the choice to the default credential
+func (selector Selector) IsZero() bool {
+	return selector.ID == uuid.Nil && selector.Label == ""
+}
+
+// Matches reports whether cred is the credential the selector names, any credential matches the zero Selector
+func (selector Selector) Matches(cred *models.ExchangeCredential) bool {
+	if selector.ID != uuid.Nil {
+		return cred.ID == selector.ID
+	}
+	return selector.Label == "" || cred.Label == selector.Label
+}
+
+// WithSelector makes the credential lookups made with ctx pick the credential selector names instead of the default
+func WithSelector(ctx context.Context, selector Selector) context.Context {
+	return context.WithValue(ctx, selectorKey{}, selector)
+}
+
+// SelectorFrom returns the selector set on ctx, the zero Selector when there is none
+func SelectorFrom(ctx context.Context) Selector {
+	selector, _ := ctx.Value(selectorKey{}).(Selector)
+	return selector
+}
@@ -0,0 +1,105 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ISnapshotStore is implemented by the snapshot repositories the pruner keeps in check
+type ISnapshotStore interface {
+	DownsampleSnapshots(ctx context.Context, olderThan time.Time, bucket time.Duration) (int64, error)
+	DeleteOldSnapshots(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+// RetentionPolicy thins snapshots older than DownsampleAfter to one per DownsampleBucket and deletes the ones older
+// than Retention. A zero DownsampleAfter or Retention disables that step
+type RetentionPolicy struct {
+	DownsampleAfter  time.Duration
+	DownsampleBucket time.Duration
+	Retention        time.Duration
+}
+
+// Table is a snapshot table and the policy applied to it
+type Table struct {
+	Name   string
+	Store  ISnapshotStore
+	Policy RetentionPolicy
+}
+
+// PruneResult counts the snapshots one pass removed from a table
+type PruneResult struct {
+	Table       string
+	Downsampled int64
+	Expired     int64
+}
+
+// Pruner applies the retention policy of every table on start and then every Interval
+type Pruner struct {
+	Tables   []Table
+	Interval time.Duration
+	Logger   *zap.Logger
+}
+
+// Run prunes right away and then every Interval until ctx is cancelled
+func (pruner *Pruner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pruner.Interval)
+	defer ticker.Stop()
+	for {
+		results, err := pruner.PruneAll(ctx)
+		if err != nil {
+			pruner.Logger.Error("failed to prune snapshots", zap.Error(err))
+		}
+		for _, result := range results {
+			pruner.Logger.Info("pruned snapshots",
+				zap.String("table", result.Table),
+				zap.Int64("downsampled", result.Downsampled),
+				zap.Int64("expired", result.Expired))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneAll prunes every table, a failing table does not stop the others
+func (pruner *Pruner) PruneAll(ctx context.Context) ([]PruneResult, error) {
+	var results []PruneResult
+	var failures []error
+	for _, table := range pruner.Tables {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		result, err := Prune(ctx, table, time.Now())
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", table.Name, err))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(failures...)
+}
+
+// Prune downsamples and then expires the snapshots of one table relative to now
+func Prune(ctx context.Context, table Table, now time.Time) (PruneResult, error) {
+	result := PruneResult{Table: table.Name}
+	policy := table.Policy
+	if policy.DownsampleAfter > 0 && policy.DownsampleBucket > 0 {
+		downsampled, err := table.Store.DownsampleSnapshots(ctx, now.Add(-policy.DownsampleAfter), policy.DownsampleBucket)
+		if err != nil {
+			return result, fmt.Errorf("failed to downsample: %w", err)
+		}
+		result.Downsampled = downsampled
+	}
+	if policy.Retention > 0 {
+		expired, err := table.Store.DeleteOldSnapshots(ctx, now.Add(-policy.Retention))
+		if err != nil {
+			return result, fmt.Errorf("failed to delete expired snapshots: %w", err)
+		}
+		result.Expired = expired
+	}
+	return result, nil
+}
@@ -0,0 +1,182 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrUnlistedMarket = errors.New("market is not listed on any exchange")
+
+// Consolidator merges the order books a market has on every exchange into one book
+type Consolidator struct {
+	Registry        *registry.ExchangeRegistry
+	TradingPairRepo *traidingPair.TradingPairRepository
+}
+
+// venueBook is the order book read from one exchange
+type venueBook struct {
+	quote VenueQuote
+	bids  []orderBook.StandardOrderLevel
+	asks  []orderBook.StandardOrderLevel
+}
+
+// Book reads the market from every exchange listing it concurrently and merges the levels, keeping depth levels
+// per side when depth is positive. Only exchanges with public order books take part, simulated exchanges would
+// count the liquidity of their source twice
+func (consolidator *Consolidator) Book(ctx context.Context, symbol string, depth int) (
+	*ConsolidatedBookResponse, error) {
+	base, quote, err := symbols.Parse(symbol)
+	if err != nil {
+		return nil, err
+	}
+	pairs, err := consolidator.TradingPairRepo.GetByAssets(ctx, base, quote)
+	if err != nil {
+		return nil, err
+	}
+
+	type venue struct {
+		pair    models.TradingPair
+		fetcher registry.IOrderBookFetcher
+	}
+	var venues []venue
+	for _, pair := range pairs {
+		adapter, err := consolidator.Registry.Get(pair.Exchange.Name)
+		if err != nil {
+			continue
+		}
+		if fetcher, ok := adapter.(registry.IOrderBookFetcher); ok {
+			venues = append(venues, venue{pair: pair, fetcher: fetcher})
+		}
+	}
+	if len(venues) == 0 {
+		return nil, fmt.Errorf("%w: %s/%s", ErrUnlistedMarket, base, quote)
+	}
+
+	books := make([]venueBook, len(venues))
+	var wg sync.WaitGroup
+	for i, v := range venues {
+		wg.Add(1)
+		go func(i int, v venue) {
+			defer wg.Done()
+			books[i] = fetchVenue(ctx, v.fetcher, v.pair)
+		}(i, v)
+	}
+	wg.Wait()
+	sort.Slice(books, func(i, j int) bool { return books[i].quote.Exchange < books[j].quote.Exchange })
+
+	response := &ConsolidatedBookResponse{
+		Symbol:     symbols.Canonical(base, quote),
+		BaseAsset:  base,
+		QuoteAsset: quote,
+		Venues:     make([]VenueQuote, 0, len(books)),
+		Timestamp:  time.Now(),
+	}
+	bids := make(map[string]*ConsolidatedLevel)
+	asks := make(map[string]*ConsolidatedLevel)
+	for _, book := range books {
+		response.Venues = append(response.Venues, book.quote)
+		if book.quote.Error != "" {
+			continue
+		}
+		mergeLevels(bids, book.quote.Exchange, book.bids)
+		mergeLevels(asks, book.quote.Exchange, book.asks)
+		if bid := book.quote.BestBid; bid != nil &&
+			(response.BestBid == nil || bid.Price.GreaterThan(response.BestBid.Price)) {
+			response.BestBid = &BestPrice{Exchange: book.quote.Exchange, StandardOrderLevel: *bid}
+		}
+		if ask := book.quote.BestAsk; ask != nil &&
+			(response.BestAsk == nil || ask.Price.LessThan(response.BestAsk.Price)) {
+			response.BestAsk = &BestPrice{Exchange: book.quote.Exchange, StandardOrderLevel: *ask}
+		}
+	}
+	response.Bids = sortedLevels(bids, true, depth)
+	response.Asks = sortedLevels(asks, false, depth)
+	return response, nil
+}
+
+func fetchVenue(ctx context.Context, fetcher registry.IOrderBookFetcher, pair models.TradingPair) venueBook {
+	book := venueBook{quote: VenueQuote{ExchangeID: pair.ExchangeID, Exchange: pair.Exchange.Name, Symbol: pair.Symbol}}
+	snapshot, err := fetcher.FetchOrderBook(ctx, pair.Symbol)
+	if err == nil {
+		book.bids, err = orderBook.LevelsFromJSONB(snapshot.Bids)
+	}
+	if err == nil {
+		book.asks, err = orderBook.LevelsFromJSONB(snapshot.Asks)
+	}
+	if err != nil {
+		book.quote.Error = err.Error()
+		return book
+	}
+	book.quote.Timestamp = snapshot.SnapshotTime
+	book.quote.BestBid = bestLevel(book.bids, true)
+	book.quote.BestAsk = bestLevel(book.asks, false)
+	if book.quote.BestBid != nil && book.quote.BestAsk != nil {
+		spread := book.quote.BestAsk.Price.Sub(book.quote.BestBid.Price)
+		book.quote.Spread = &spread
+	}
+	return book
+}
+
+// bestLevel returns the highest bid or the lowest ask, levels without a positive price and quantity are ignored
+func bestLevel(levels []orderBook.StandardOrderLevel, bids bool) *orderBook.StandardOrderLevel {
+	var best *orderBook.StandardOrderLevel
+	for i := range levels {
+		if !levels[i].Price.IsPositive() || !levels[i].Quantity.IsPositive() {
+			continue
+		}
+		if best == nil || (bids && levels[i].Price.GreaterThan(best.Price)) ||
+			(!bids && levels[i].Price.LessThan(best.Price)) {
+			best = &levels[i]
+		}
+	}
+	return best
+}
+
+// mergeLevels adds the levels of exchangeName to merged, keyed by the canonical price string
+func mergeLevels(merged map[string]*ConsolidatedLevel, exchangeName string, levels []orderBook.StandardOrderLevel) {
+	for _, level := range levels {
+		if !level.Price.IsPositive() || !level.Quantity.IsPositive() {
+			continue
+		}
+		key := level.Price.String()
+		consolidated, found := merged[key]
+		if !found {
+			consolidated = &ConsolidatedLevel{Price: level.Price, Quantity: decimal.Zero}
+			merged[key] = consolidated
+		}
+		consolidated.Quantity = consolidated.Quantity.Add(level.Quantity)
+		last := len(consolidated.Sources) - 1
+		if last >= 0 && consolidated.Sources[last].Exchange == exchangeName {
+			consolidated.Sources[last].Quantity = consolidated.Sources[last].Quantity.Add(level.Quantity)
+			continue
+		}
+		consolidated.Sources = append(consolidated.Sources, LevelSource{Exchange: exchangeName, Quantity: level.Quantity})
+	}
+}
+
+func sortedLevels(merged map[string]*ConsolidatedLevel, descending bool, depth int) []ConsolidatedLevel {
+	levels := make([]ConsolidatedLevel, 0, len(merged))
+	for _, level := range merged {
+		levels = append(levels, *level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if descending {
+			return levels[i].Price.GreaterThan(levels[j].Price)
+		}
+		return levels[i].Price.LessThan(levels[j].Price)
+	})
+	if depth > 0 && len(levels) > depth {
+		levels = levels[:depth]
+	}
+	return levels
+}
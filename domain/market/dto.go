@@ -0,0 +1,57 @@
+package market
+
+import (
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// ConsolidatedBookRequest limits the number of merged levels per side
+type ConsolidatedBookRequest struct {
+	Depth int `query:"depth"`
+}
+
+// LevelSource is the part of a consolidated level resting on one exchange
+type LevelSource struct {
+	Exchange string          `json:"exchange"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
+// ConsolidatedLevel is the liquidity of every exchange at one price
+type ConsolidatedLevel struct {
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Sources  []LevelSource   `json:"sources"`
+}
+
+// BestPrice is the top of one side of the book and the exchange quoting it
+type BestPrice struct {
+	Exchange string `json:"exchange"`
+	orderBook.StandardOrderLevel
+}
+
+// VenueQuote is the top of the book of one exchange, Error is set when its book could not be read
+type VenueQuote struct {
+	ExchangeID uuid.UUID                     `json:"exchange_id"`
+	Exchange   string                        `json:"exchange"`
+	Symbol     string                        `json:"symbol"`
+	BestBid    *orderBook.StandardOrderLevel `json:"best_bid"`
+	BestAsk    *orderBook.StandardOrderLevel `json:"best_ask"`
+	Spread     *decimal.Decimal              `json:"spread"`
+	Timestamp  time.Time                     `json:"timestamp"`
+	Error      string                        `json:"error,omitempty"`
+}
+
+// ConsolidatedBookResponse is the merged order book of a market across exchanges
+type ConsolidatedBookResponse struct {
+	Symbol     string              `json:"symbol"`
+	BaseAsset  string              `json:"base_asset"`
+	QuoteAsset string              `json:"quote_asset"`
+	Bids       []ConsolidatedLevel `json:"bids"` // price descending
+	Asks       []ConsolidatedLevel `json:"asks"` // price ascending
+	BestBid    *BestPrice          `json:"best_bid"`
+	BestAsk    *BestPrice          `json:"best_ask"`
+	Venues     []VenueQuote        `json:"venues"`
+	Timestamp  time.Time           `json:"timestamp"`
+}
@@ -0,0 +1,226 @@
+package marketCache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	hotSymbolsKey   = "orderbook:hot"
+	refreshTimeout  = 10 * time.Second
+	hotMemberSuffix = "|"
+)
+
+// releaseRefreshLock deletes the lock in KEYS[1] only while it still holds the owner token in ARGV[1], so a refresh
+// that outlived refreshTimeout does not free a lock another instance took since
+var releaseRefreshLock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Loader reads an order book from the exchange when the cache can not serve it
+type Loader func(ctx context.Context) (*models.OrderBookSnapshot, error)
+
+// OrderBookCache keeps order books and tickers per exchange and symbol in Redis. Entries younger than TTL are served
+// as they are, entries up to Stale older than that are still served while one request refreshes them in the
+// background. A zero TTL disables the cache
+type OrderBookCache struct {
+	Client    *redis.Client
+	TTL       time.Duration
+	Stale     time.Duration
+	Snapshots *orderBook.OrderBookSnapshotRepository // optional, public books read through Fetch are stored with it
+	Logger    *zap.Logger
+}
+
+// OrderBook returns the cached book of an exchange and symbol, calling load on a miss and caching its result
+func (cache *OrderBookCache) OrderBook(ctx context.Context, exchangeName, symbol string, load Loader) (
+	*CachedOrderBook, error) {
+	if cache == nil || cache.TTL <= 0 {
+		return cache.load(ctx, exchangeName, symbol, load)
+	}
+	exchangeName, symbol = strings.ToLower(exchangeName), cacheSymbol(symbol)
+	cached, err := cache.Get(ctx, exchangeName, symbol)
+	if err != nil {
+		cached, err = cache.load(ctx, exchangeName, symbol, load)
+		if err != nil {
+			return nil, err
+		}
+		cache.markHot(ctx, exchangeName, symbol)
+		return cached, nil
+	}
+	cache.markHot(ctx, exchangeName, symbol)
+	if cached.Age() > cache.TTL {
+		cache.revalidate(exchangeName, symbol, load)
+	}
+	return cached, nil
+}
+
+// Ticker returns the cached ticker of an exchange and symbol, going through the order book on a miss
+func (cache *OrderBookCache) Ticker(ctx context.Context, exchangeName, symbol string, load Loader) (
+	*TickerResponse, error) {
+	if cache != nil && cache.TTL > 0 {
+		var ticker Ticker
+		raw, err := cache.Client.Get(ctx, tickerKey(strings.ToLower(exchangeName), cacheSymbol(symbol))).Bytes()
+		if err == nil && json.Unmarshal(raw, &ticker) == nil && time.Since(ticker.FetchedAt) <= cache.TTL {
+			cache.markHot(ctx, strings.ToLower(exchangeName), cacheSymbol(symbol))
+			return &TickerResponse{Ticker: ticker, Cached: true, CacheAgeMs: time.Since(ticker.FetchedAt).Milliseconds()}, nil
+		}
+	}
+	book, err := cache.OrderBook(ctx, exchangeName, symbol, load)
+	if err != nil {
+		return nil, err
+	}
+	return &TickerResponse{
+		Ticker:     NewTicker(strings.ToLower(exchangeName), book),
+		Cached:     book.Cached,
+		CacheAgeMs: book.Age().Milliseconds(),
+	}, nil
+}
+
+// Get returns the cached book of an exchange and symbol whatever its age, redis.Nil when there is none
+func (cache *OrderBookCache) Get(ctx context.Context, exchangeName, symbol string) (*CachedOrderBook, error) {
+	raw, err := cache.Client.Get(ctx, orderBookKey(strings.ToLower(exchangeName), cacheSymbol(symbol))).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var cached CachedOrderBook
+	if err := json.Unmarshal(raw, &cached); err != nil {
+		return nil, err
+	}
+	cached.Cached = true
+	return &cached, nil
+}
+
+// Set caches a book read from the exchange now along with its ticker, both kept for TTL plus Stale
+func (cache *OrderBookCache) Set(ctx context.Context, exchangeName, symbol string,
+	snapshot *models.OrderBookSnapshot) (*CachedOrderBook, error) {
+	exchangeName, symbol = strings.ToLower(exchangeName), cacheSymbol(symbol)
+	cached := &CachedOrderBook{Snapshot: snapshot, FetchedAt: time.Now()}
+	rawBook, err := json.Marshal(cached)
+	if err != nil {
+		return cached, err
+	}
+	rawTicker, err := json.Marshal(NewTicker(exchangeName, cached))
+	if err != nil {
+		return cached, err
+	}
+	expiration := cache.TTL + cache.Stale
+	_, err = cache.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, orderBookKey(exchangeName, symbol), rawBook, expiration)
+		pipe.Set(ctx, tickerKey(exchangeName, symbol), rawTicker, expiration)
+		return nil
+	})
+	return cached, err
+}
+
+// Fetch reads the public book of symbol from the exchange and stores it as a snapshot, valuation, candles and paper
+// trading read the books the cache served from there. A failing write does not fail the read
+func (cache *OrderBookCache) Fetch(ctx context.Context, fetcher registry.IOrderBookFetcher, symbol string) (
+	*models.OrderBookSnapshot, error) {
+	snapshot, err := fetcher.FetchOrderBook(ctx, symbol)
+	if err != nil || cache == nil || cache.Snapshots == nil {
+		return snapshot, err
+	}
+	if err := cache.Snapshots.Create(ctx, snapshot); err != nil {
+		cache.Logger.Warn("failed to store order book snapshot", zap.String("symbol", symbol), zap.Error(err))
+	}
+	return snapshot, nil
+}
+
+// load reads the book from the exchange and caches it, a failing cache write does not fail the request
+func (cache *OrderBookCache) load(ctx context.Context, exchangeName, symbol string, load Loader) (
+	*CachedOrderBook, error) {
+	snapshot, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cache == nil || cache.TTL <= 0 {
+		return &CachedOrderBook{Snapshot: snapshot, FetchedAt: time.Now()}, nil
+	}
+	cached, err := cache.Set(ctx, exchangeName, symbol, snapshot)
+	if err != nil {
+		cache.Logger.Warn("failed to cache order book", zap.String("exchange", exchangeName),
+			zap.String("symbol", symbol), zap.Error(err))
+	}
+	return cached, nil
+}
+
+// revalidate refreshes a stale entry in the background, only the request that takes the refresh lock does it
+func (cache *OrderBookCache) revalidate(exchangeName, symbol string, load Loader) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		defer cancel()
+		owner := uuid.NewString()
+		locked, err := cache.Client.SetNX(ctx, lockKey(exchangeName, symbol), owner, refreshTimeout).Result()
+		if err != nil || !locked {
+			return
+		}
+		defer releaseRefreshLock.Run(context.Background(), cache.Client, []string{lockKey(exchangeName, symbol)}, owner)
+		if _, err := cache.load(ctx, exchangeName, symbol, load); err != nil {
+			cache.Logger.Warn("failed to refresh order book", zap.String("exchange", exchangeName),
+				zap.String("symbol", symbol), zap.Error(err))
+		}
+	}()
+}
+
+// markHot records that a book was served so the refresher keeps it warm. Only books the cache held or the exchange
+// returned are recorded, a symbol nobody can read does not join the hot set
+func (cache *OrderBookCache) markHot(ctx context.Context, exchangeName, symbol string) {
+	cache.Client.ZAdd(ctx, hotSymbolsKey, &redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: exchangeName + hotMemberSuffix + symbol,
+	})
+}
+
+// HotSymbols drops the books nobody asked for within window and returns the others as exchange and symbol pairs
+func (cache *OrderBookCache) HotSymbols(ctx context.Context, window time.Duration) ([][2]string, error) {
+	cutoff := strconv.FormatInt(time.Now().Add(-window).Unix(), 10)
+	if err := cache.Client.ZRemRangeByScore(ctx, hotSymbolsKey, "-inf", "("+cutoff).Err(); err != nil {
+		return nil, err
+	}
+	members, err := cache.Client.ZRange(ctx, hotSymbolsKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	hot := make([][2]string, 0, len(members))
+	for _, member := range members {
+		exchangeName, symbol, found := strings.Cut(member, hotMemberSuffix)
+		if found {
+			hot = append(hot, [2]string{exchangeName, symbol})
+		}
+	}
+	return hot, nil
+}
+
+// cacheSymbol keys books by canonical symbol so BTC-IRT and BTC_IRT share an entry, other symbols by upper case
+func cacheSymbol(symbol string) string {
+	if baseAsset, quoteAsset, err := symbols.Parse(symbol); err == nil {
+		return symbols.Canonical(baseAsset, quoteAsset)
+	}
+	return strings.ToUpper(symbol)
+}
+
+func orderBookKey(exchangeName, symbol string) string {
+	return fmt.Sprintf("orderbook:%s:%s", exchangeName, symbol)
+}
+
+func tickerKey(exchangeName, symbol string) string {
+	return fmt.Sprintf("ticker:%s:%s", exchangeName, symbol)
+}
+
+func lockKey(exchangeName, symbol string) string {
+	return fmt.Sprintf("orderbook:lock:%s:%s", exchangeName, symbol)
+}
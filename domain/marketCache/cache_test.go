@@ -0,0 +1,68 @@
+package marketCache
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/exchangefake"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// bookFetcher answers every symbol with the same public book of pairID
+type bookFetcher struct {
+	pairID uuid.UUID
+	calls  int
+}
+
+func (fetcher *bookFetcher) FetchOrderBook(ctx context.Context, symbol string) (*models.OrderBookSnapshot, error) {
+	fetcher.calls++
+	level := func(price, quantity string) orderBook.StandardOrderLevel {
+		return orderBook.StandardOrderLevel{Price: decimal.RequireFromString(price),
+			Quantity: decimal.RequireFromString(quantity)}
+	}
+	return &models.OrderBookSnapshot{
+		BaseModel:     models.BaseModel{ID: uuid.New()},
+		ExchangeID:    uuid.New(),
+		TradingPairID: fetcher.pairID,
+		Symbol:        symbol,
+		Bids:          models.JSONB{"data": []orderBook.StandardOrderLevel{level("99", "1")}},
+		Asks:          models.JSONB{"data": []orderBook.StandardOrderLevel{level("101", "2")}},
+		SnapshotTime:  time.Now(),
+	}, nil
+}
+
+func TestCacheMissStoresTheSnapshot(t *testing.T) {
+	snapshots := orderBook.NewOrderBookSnapshotRepository(exchangefake.NewDatabase(t))
+	// nothing listens there, every read is a miss and every write fails
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: time.Second})
+	t.Cleanup(func() { _ = client.Close() })
+	cache := &OrderBookCache{Client: client, TTL: time.Second, Stale: time.Second, Snapshots: snapshots,
+		Logger: zap.NewNop()}
+	fetcher := &bookFetcher{pairID: uuid.New()}
+
+	book, err := cache.OrderBook(context.Background(), "bitpin", "BTC-USDT",
+		func(ctx context.Context) (*models.OrderBookSnapshot, error) {
+			return cache.Fetch(ctx, fetcher, "BTC-USDT")
+		})
+	if err != nil {
+		t.Fatalf("OrderBook: %v", err)
+	}
+	if book.Cached || fetcher.calls != 1 {
+		t.Fatalf("got cached %v after %d reads, want one read of the exchange", book.Cached, fetcher.calls)
+	}
+
+	stored, err := snapshots.GetLatestByTradingPair(context.Background(), fetcher.pairID)
+	if err != nil {
+		t.Fatalf("no snapshot was stored: %v", err)
+	}
+	if stored.ID != book.Snapshot.ID || stored.BestBid == nil || !stored.BestBid.Equal(decimal.NewFromInt(99)) ||
+		stored.BestAsk == nil || !stored.BestAsk.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("got stored snapshot %s with bid %v ask %v, want %s with 99 and 101", stored.ID, stored.BestBid,
+			stored.BestAsk, book.Snapshot.ID)
+	}
+}
@@ -0,0 +1,59 @@
+package marketCache
+
+import (
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// CachedOrderBook is an order book as stored in the cache with the time it was read from the exchange
+type CachedOrderBook struct {
+	Snapshot  *models.OrderBookSnapshot `json:"snapshot"`
+	FetchedAt time.Time                 `json:"fetched_at"`
+	Cached    bool                      `json:"-"` // false when the book was just read from the exchange
+}
+
+// Age is how long ago the book was read from the exchange
+func (book *CachedOrderBook) Age() time.Duration {
+	return time.Since(book.FetchedAt)
+}
+
+// Ticker is the top of book of one exchange and symbol
+type Ticker struct {
+	Exchange  string           `json:"exchange"`
+	Symbol    string           `json:"symbol"`
+	BestBid   *decimal.Decimal `json:"best_bid"`
+	BestAsk   *decimal.Decimal `json:"best_ask"`
+	MidPrice  *decimal.Decimal `json:"mid_price"`
+	Spread    *decimal.Decimal `json:"spread"`
+	FetchedAt time.Time        `json:"fetched_at"`
+}
+
+// TickerResponse is a ticker with the age of the cached book it comes from
+type TickerResponse struct {
+	Ticker
+	Cached     bool  `json:"cached"`
+	CacheAgeMs int64 `json:"cache_age_ms"`
+}
+
+// NewTicker reads the top of book of a snapshot, sides without levels are left empty
+func NewTicker(exchangeName string, book *CachedOrderBook) Ticker {
+	ticker := Ticker{Exchange: exchangeName, Symbol: book.Snapshot.Symbol, FetchedAt: book.FetchedAt}
+	if bids, err := orderBook.LevelsFromJSONB(book.Snapshot.Bids); err == nil {
+		if bids = orderBook.SortLevels(bids, true, 1); len(bids) > 0 {
+			ticker.BestBid = &bids[0].Price
+		}
+	}
+	if asks, err := orderBook.LevelsFromJSONB(book.Snapshot.Asks); err == nil {
+		if asks = orderBook.SortLevels(asks, false, 1); len(asks) > 0 {
+			ticker.BestAsk = &asks[0].Price
+		}
+	}
+	if ticker.BestBid != nil && ticker.BestAsk != nil {
+		midPrice := ticker.BestBid.Add(*ticker.BestAsk).Div(decimal.NewFromInt(2))
+		spread := ticker.BestAsk.Sub(*ticker.BestBid)
+		ticker.MidPrice, ticker.Spread = &midPrice, &spread
+	}
+	return ticker
+}
@@ -0,0 +1,78 @@
+package marketCache
+
+import (
+	"context"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"go.uber.org/zap"
+	"time"
+)
+
+const refresherLockKey = "orderbook:refresher"
+
+// Refresher keeps the books asked for within HotWindow warm by reading them again before they go stale. Only
+// exchanges with public order books are refreshed, and only one api instance refreshes on each tick
+type Refresher struct {
+	Cache     *OrderBookCache
+	Registry  *registry.ExchangeRegistry
+	Interval  time.Duration
+	HotWindow time.Duration
+	Logger    *zap.Logger
+}
+
+// Run refreshes the hot books every Interval until ctx is cancelled
+func (refresher *Refresher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(refresher.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			refresher.RefreshHot(ctx)
+		}
+	}
+}
+
+// RefreshHot reads again every hot book older than half the cache TTL
+func (refresher *Refresher) RefreshHot(ctx context.Context) {
+	locked, err := refresher.Cache.Client.SetNX(ctx, refresherLockKey, 1, refresher.Interval).Result()
+	if err != nil {
+		refresher.Logger.Warn("failed to take the order book refresh lock", zap.Error(err))
+		return
+	}
+	if !locked {
+		return
+	}
+	hot, err := refresher.Cache.HotSymbols(ctx, refresher.HotWindow)
+	if err != nil {
+		refresher.Logger.Warn("failed to list hot order books", zap.Error(err))
+		return
+	}
+	for _, entry := range hot {
+		if ctx.Err() != nil {
+			return
+		}
+		exchangeName, symbol := entry[0], entry[1]
+		if cached, err := refresher.Cache.Get(ctx, exchangeName, symbol); err == nil && cached.Age() < refresher.Cache.TTL/2 {
+			continue
+		}
+		adapter, err := refresher.Registry.Get(exchangeName)
+		if err != nil {
+			continue
+		}
+		fetcher, public := adapter.(registry.IOrderBookFetcher)
+		if !public {
+			continue
+		}
+		snapshot, err := refresher.Cache.Fetch(ctx, fetcher, symbol)
+		if err != nil {
+			refresher.Logger.Warn("failed to refresh order book", zap.String("exchange", exchangeName),
+				zap.String("symbol", symbol), zap.Error(err))
+			continue
+		}
+		if _, err := refresher.Cache.Set(ctx, exchangeName, symbol, snapshot); err != nil {
+			refresher.Logger.Warn("failed to cache order book", zap.String("exchange", exchangeName),
+				zap.String("symbol", symbol), zap.Error(err))
+		}
+	}
+}
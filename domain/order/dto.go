@@ -1,6 +1,7 @@
 package order
 
 import (
+	"github.com/shopspring/decimal"
 	"time"
 )
 
@@ -22,6 +23,7 @@ const (
 type OrderStatus string
 
 const (
+	RESERVED  OrderStatus = "reserved" // client order id claimed, not yet acknowledged by the exchange
 	NEW       OrderStatus = "new"
 	FILLED    OrderStatus = "filled"
 	PARTIALLY OrderStatus = "partially_filled"
@@ -29,33 +31,90 @@ const (
 	REJECTED  OrderStatus = "rejected"
 )
 
+// OpenStatuses are the statuses of orders that may still change on the exchange
+var OpenStatuses = []string{string(NEW), string(PARTIALLY)}
+
+type OrderEventType string
+
+const (
+	EventNew         OrderEventType = "new"
+	EventPartialFill OrderEventType = "partial_fill"
+	EventFilled      OrderEventType = "filled"
+	EventCanceled    OrderEventType = "canceled"
+	EventRejected    OrderEventType = "rejected"
+)
+
+// EventTypeFor returns the order event recorded when an order reaches status
+func EventTypeFor(status OrderStatus) OrderEventType {
+	switch status {
+	case PARTIALLY:
+		return EventPartialFill
+	case FILLED:
+		return EventFilled
+	case CANCELED:
+		return EventCanceled
+	case REJECTED:
+		return EventRejected
+	default:
+		return EventNew
+	}
+}
+
 type StandardOrderRequest struct {
-	Symbol        string    `json:"symbol" validate:"required"`
-	Side          OrderSide `json:"side" validate:"required,oneof=buy sell"`
-	Type          OrderType `json:"type" validate:"required,oneof=market limit"`
-	Quantity      *float64  `json:"quantity,omitempty"` // Amount of base asset
-	BaseCurrency  string    `json:"base_currency,omitempty"`
-	QuoteCurrency string    `json:"Quote_currency,omitempty"`
-	BaseAmount    *float64  `json:"base_amount,omitempty"`     // Amount of base asset (e.g., BTC amount)
-	QuoteAmount   *float64  `json:"quote_amount,omitempty"`    // Amount of quote asset (e.g., USDT amount)
-	Price         *float64  `json:"price,omitempty"`           // Price per unit
-	StopPrice     *float64  `json:"stop_price,omitempty"`      // For stop orders
-	TimeInForce   string    `json:"time_in_force,omitempty"`   // GTC, IOC, FOK, etc.
-	ClientOrderId string    `json:"client_order_id,omitempty"` // Client-specified order ID
+	Symbol        string           `json:"symbol" validate:"required"`
+	Side          OrderSide        `json:"side" validate:"required,oneof=buy sell"`
+	Type          OrderType        `json:"type" validate:"required,oneof=market limit"`
+	Quantity      *decimal.Decimal `json:"quantity,omitempty"` // Amount of base asset
+	BaseCurrency  string           `json:"base_currency,omitempty"`
+	QuoteCurrency string           `json:"Quote_currency,omitempty"`
+	BaseAmount    *decimal.Decimal `json:"base_amount,omitempty"`     // Amount of base asset (e.g., BTC amount)
+	QuoteAmount   *decimal.Decimal `json:"quote_amount,omitempty"`    // Amount of quote asset (e.g., USDT amount)
+	Price         *decimal.Decimal `json:"price,omitempty"`           // Price per unit
+	StopPrice     *decimal.Decimal `json:"stop_price,omitempty"`      // For stop orders
+	TimeInForce   string           `json:"time_in_force,omitempty"`   // GTC, IOC, FOK, etc.
+	ClientOrderId string           `json:"client_order_id,omitempty"` // Client-specified order ID
 }
 
 // StandardOrderResponse represents unified order response
 type StandardOrderResponse struct {
-	ID         string      `json:"id"`
-	Symbol     string      `json:"symbol"`
-	Side       OrderSide   `json:"side"`
-	Type       OrderType   `json:"type"`
-	Quantity   float64     `json:"quantity"`
-	Price      *float64    `json:"price,omitempty"`
-	Status     OrderStatus `json:"status"`
-	CreatedAt  time.Time   `json:"created_at"`
-	UpdatedAt  time.Time   `json:"updated_at"`
-	ExchangeID string      `json:"exchange_id"`
+	ID         string           `json:"id"`
+	Symbol     string           `json:"symbol"`
+	Side       OrderSide        `json:"side"`
+	Type       OrderType        `json:"type"`
+	Quantity   decimal.Decimal  `json:"quantity"`
+	Price      *decimal.Decimal `json:"price,omitempty"`
+	Status     OrderStatus      `json:"status"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	ExchangeID string           `json:"exchange_id"`
+
+	ExecutedQty     decimal.Decimal `json:"executed_qty"`
+	ExchangeOrderID string          `json:"exchange_order_id,omitempty"`
+	ClientOrderID   string          `json:"client_order_id,omitempty"`
+}
+
+// ListedOrderResponse is an order the exchange lists as open, Known tells whether it is stored as open as well
+type ListedOrderResponse struct {
+	StandardOrderResponse
+	Known bool `json:"known"`
+}
+
+// OpenOrdersResponse cross-checks the open orders of an exchange against the ones stored as open for the same
+// credential. Orders the exchange lists but nobody stored as open are not Known, MissingOnExchange holds the stored
+// open orders the exchange no longer lists
+type OpenOrdersResponse struct {
+	Orders            []ListedOrderResponse   `json:"orders"`
+	MissingOnExchange []StandardOrderResponse `json:"missing_on_exchange"`
+}
+
+// OrderStatusUpdate is the state of a placed order as reported by its exchange
+type OrderStatusUpdate struct {
+	ExchangeOrderID string // Id the exchange gave the order, learnt here when the placement acknowledgement was lost
+	Status          OrderStatus
+	ExecutedQty     decimal.Decimal
+	ExecutedPrice   decimal.Decimal // Average fill price, zero while nothing is filled
+	Commission      decimal.Decimal
+	UpdatedAt       time.Time
 }
 
 type CreateOrderRequest struct {
@@ -66,9 +125,24 @@ type CreateOrderRequest struct {
 	Price    string    `json:"price,omitempty"` // Required for limit orders
 }
 
+type GetOrderRequest struct {
+	OrderId string `json:"orderId"`
+}
+
+type ListOrdersRequest struct {
+	Status string `query:"status"`
+	Symbol string `query:"symbol"`
+}
+
 type CancelOrderRequest struct {
-	OrderId string   `json:"orderId"`
-	Hours   *float64 `json:"hours,omitempty"`
+	OrderId string `json:"orderId"`
+}
+
+// CancelOldOrdersRequest names the market, and optionally the order type, whose orders older than Hours are cancelled
+type CancelOldOrdersRequest struct {
+	Symbol string  `json:"symbol"`
+	Type   string  `json:"type,omitempty"`
+	Hours  float64 `json:"hours"`
 }
 type CreateOrderResponse struct {
 	OrderID   string `json:"order_id"`
@@ -1,8 +1,99 @@
 package order
 
-import "github.com/rzabhd80/eye-on/internal/database/models"
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"strings"
+)
+
+var ErrPlacementInProgress = errors.New(
+	"an order with this client_order_id was already submitted and its outcome is not known yet")
+
+// ErrNotOnExchange is returned when looking up an order by its client order id finds nothing, the exchange never
+// accepted it
+var ErrNotOnExchange = errors.New("the exchange has no order with this client_order_id")
 
 type Order struct {
 	orderHistory *models.OrderHistory
 	orderEvents  []*models.OrderEvent
 }
+
+// NewStandardOrderResponse builds the unified order response from a stored order, its symbol in the canonical
+// BASE/QUOTE form when the trading pair is loaded
+func NewStandardOrderResponse(orderHistory *models.OrderHistory) StandardOrderResponse {
+	return StandardOrderResponse{
+		ID:              orderHistory.ID.String(),
+		Symbol:          canonicalSymbol(orderHistory.TradingPair),
+		Side:            OrderSide(orderHistory.Side),
+		Type:            OrderType(orderHistory.Type),
+		Quantity:        orderHistory.Quantity,
+		Price:           orderHistory.Price,
+		Status:          OrderStatus(orderHistory.Status),
+		CreatedAt:       orderHistory.CreatedAt,
+		UpdatedAt:       orderHistory.UpdatedAt,
+		ExchangeID:      orderHistory.ExchangeID.String(),
+		ExecutedQty:     orderHistory.ExecutedQty,
+		ExchangeOrderID: orderHistory.ExchangeOrderID,
+		ClientOrderID:   orderHistory.ClientOrderID,
+	}
+}
+
+// ReconcileOpenOrders pairs the open orders an exchange lists with the stored open orders of the same credential.
+// They match on the exchange order id, or on the client order id while the stored order never learnt its exchange id
+func ReconcileOpenOrders(listed []StandardOrderResponse, stored []models.OrderHistory) OpenOrdersResponse {
+	byExchangeID := make(map[string]int, len(stored))
+	byClientID := make(map[string]int, len(stored))
+	for i, storedOrder := range stored {
+		if storedOrder.ExchangeOrderID != "" {
+			byExchangeID[storedOrder.ExchangeOrderID] = i
+		}
+		if storedOrder.ClientOrderID != "" {
+			byClientID[storedOrder.ClientOrderID] = i
+		}
+	}
+	matched := make([]bool, len(stored))
+	response := OpenOrdersResponse{
+		Orders:            make([]ListedOrderResponse, 0, len(listed)),
+		MissingOnExchange: []StandardOrderResponse{},
+	}
+	for _, listedOrder := range listed {
+		i, found := byExchangeID[listedOrder.ExchangeOrderID]
+		if !found || listedOrder.ExchangeOrderID == "" {
+			i, found = byClientID[listedOrder.ClientOrderID]
+			found = found && listedOrder.ClientOrderID != ""
+		}
+		if found {
+			matched[i] = true
+		}
+		response.Orders = append(response.Orders, ListedOrderResponse{StandardOrderResponse: listedOrder, Known: found})
+	}
+	for i := range stored {
+		if !matched[i] {
+			response.MissingOnExchange = append(response.MissingOnExchange, NewStandardOrderResponse(&stored[i]))
+		}
+	}
+	return response
+}
+
+// canonicalSymbol writes pair the way symbols.Canonical does, which this package can not import since symbols
+// depends on it
+func canonicalSymbol(pair models.TradingPair) string {
+	if pair.BaseAsset == "" || pair.QuoteAsset == "" {
+		return pair.Symbol
+	}
+	return strings.ToUpper(pair.BaseAsset) + "/" + strings.ToUpper(pair.QuoteAsset)
+}
+
+// NewClientOrderID generates a client order id for callers that did not supply one, 32 characters fit every exchange
+func NewClientOrderID() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// ReplayPlacement returns the stored result of an earlier placement that used the same client order id
+func ReplayPlacement(existing *models.OrderHistory) (*models.OrderHistory, error) {
+	if existing.Status == string(RESERVED) {
+		return nil, ErrPlacementInProgress
+	}
+	return existing, nil
+}
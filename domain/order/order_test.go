@@ -0,0 +1,65 @@
+package order_test
+
+import (
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"testing"
+)
+
+func TestNewStandardOrderResponseSymbol(t *testing.T) {
+	tests := []struct {
+		name string
+		pair models.TradingPair
+		want string
+	}{
+		{name: "nobitex pair", pair: models.TradingPair{Symbol: "BTCIRT", BaseAsset: "BTC", QuoteAsset: "IRT"},
+			want: symbols.Canonical("BTC", "IRT")},
+		{name: "bitpin pair", pair: models.TradingPair{Symbol: "ETH_USDT", BaseAsset: "eth", QuoteAsset: "usdt"},
+			want: symbols.Canonical("ETH", "USDT")},
+		{name: "pair without assets keeps its symbol", pair: models.TradingPair{Symbol: "BTCIRT"}, want: "BTCIRT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := order.NewStandardOrderResponse(&models.OrderHistory{TradingPair: test.pair})
+			if response.Symbol != test.want {
+				t.Errorf("got symbol %q, want %q", response.Symbol, test.want)
+			}
+		})
+	}
+}
+
+func TestReconcileOpenOrders(t *testing.T) {
+	listed := []order.StandardOrderResponse{
+		{ExchangeOrderID: "1", ClientOrderID: "a"},
+		{ExchangeOrderID: "2", ClientOrderID: "b"},
+		{ExchangeOrderID: "3"},
+	}
+	stored := []models.OrderHistory{
+		{ExchangeOrderID: "1", ClientOrderID: "a"},
+		{ClientOrderID: "b"}, // placement acknowledgement lost, matched on its client order id
+		{ExchangeOrderID: "4", ClientOrderID: "d"},
+	}
+	response := order.ReconcileOpenOrders(listed, stored)
+
+	wantKnown := map[string]bool{"1": true, "2": true, "3": false}
+	if len(response.Orders) != len(listed) {
+		t.Fatalf("got %d listed orders, want %d", len(response.Orders), len(listed))
+	}
+	for _, listedOrder := range response.Orders {
+		if listedOrder.Known != wantKnown[listedOrder.ExchangeOrderID] {
+			t.Errorf("order %s: got known %v, want %v", listedOrder.ExchangeOrderID, listedOrder.Known,
+				wantKnown[listedOrder.ExchangeOrderID])
+		}
+	}
+	if len(response.MissingOnExchange) != 1 || response.MissingOnExchange[0].ClientOrderID != "d" {
+		t.Errorf("got missing on exchange %+v, want only the order with client order id d", response.MissingOnExchange)
+	}
+}
+
+func TestReconcileOpenOrdersEmpty(t *testing.T) {
+	response := order.ReconcileOpenOrders(nil, nil)
+	if response.Orders == nil || response.MissingOnExchange == nil {
+		t.Errorf("got nil lists %+v, want empty ones so they encode as []", response)
+	}
+}
@@ -4,13 +4,22 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type IOrderRepository interface {
 	Create(ctx context.Context, order *models.OrderHistory) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.OrderHistory, error)
 	GetByClientOrderID(ctx context.Context, clientOrderID string) (*models.OrderHistory, error)
+	GetByUserAndClientOrderID(ctx context.Context, userID uuid.UUID, clientOrderID string) (*models.OrderHistory, error)
+	Reserve(ctx context.Context, order *models.OrderHistory) (*models.OrderHistory, bool, error)
+	ConfirmReservation(ctx context.Context, order *models.OrderHistory, eventTime time.Time) error
+	ReleaseReservation(ctx context.Context, orderID uuid.UUID) error
+	SetExchangeOrderID(ctx context.Context, orderID uuid.UUID, exchangeOrderID string) error
+	GetStaleReservations(ctx context.Context, reservedBefore time.Time) ([]models.OrderHistory, error)
 	GetByExchangeOrderID(ctx context.Context, exchangeOrderID string) (*models.OrderHistory, error)
 	GetByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.OrderHistory, error)
 	GetOpenOrders(ctx context.Context, userID, exchangeCredentialID uuid.UUID) ([]models.OrderHistory, error)
@@ -18,9 +27,10 @@ type IOrderRepository interface {
 	CreateEvent(ctx context.Context, event *models.OrderEvent) error
 	GetByOrderEventID(ctx context.Context, orderHistID uuid.UUID) ([]models.OrderEvent, error)
 	EventList(ctx context.Context, limit, offset int) ([]models.OrderEvent, error)
-	//UpdateStatusWithEvent(ctx context.Context, orderID uuid.UUID,
-	//	status string, executedQty, executedPrice, commission float64,
-	//	eventType string, eventTime time.Time) error
+	GetAllOpenOrders(ctx context.Context) ([]models.OrderHistory, error)
+	CreateWithEvent(ctx context.Context, order *models.OrderHistory, eventTime time.Time) error
+	UpdateStatusWithEvent(ctx context.Context, orderID uuid.UUID,
+		status OrderStatus, executedQty, executedPrice, commission decimal.Decimal, eventTime time.Time) error
 	//
 	//AddEvent(ctx context.Context, orderID uuid.UUID, event *models.OrderEvent) error
 	//GetOrderWithEvents(ctx context.Context, orderID uuid.UUID) (*models.OrderEvent, error)
@@ -68,6 +78,125 @@ func (r *OrderRepository) GetByClientOrderID(ctx context.Context, clientOrderID
 	return &order, nil
 }
 
+func (r *OrderRepository) GetByUserAndClientOrderID(ctx context.Context, userID uuid.UUID, clientOrderID string) (
+	*models.OrderHistory, error) {
+	var order models.OrderHistory
+	err := r.db.WithContext(ctx).
+		Preload("Exchange").
+		Preload("TradingPair").
+		First(&order, "user_id = ? AND client_order_id = ?", userID, clientOrderID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Reserve claims the client order id of an order before it is sent to the exchange. When the user already used the
+// client order id for the same order the earlier one is returned instead and the boolean is false. A client order id
+// used on another exchange or for a different order is refused.
+func (r *OrderRepository) Reserve(ctx context.Context, order *models.OrderHistory) (*models.OrderHistory, bool, error) {
+	order.Status = string(RESERVED)
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(order)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return order, true, nil
+	}
+	existing, err := r.GetByUserAndClientOrderID(ctx, order.UserID, order.ClientOrderID)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing.ExchangeID != order.ExchangeID {
+		return nil, false, NewValidationError(CodeClientOrderIDInUse, "client_order_id",
+			"client_order_id %s was already used on %s", order.ClientOrderID, existing.Exchange.Name)
+	}
+	if field := replayMismatch(existing, order); field != "" {
+		return nil, false, NewValidationError(CodeClientOrderIDInUse, "client_order_id",
+			"client_order_id %s was already used for an order with a different %s", order.ClientOrderID, field)
+	}
+	return existing, false, nil
+}
+
+// replayMismatch names the first field in which a replayed order differs from the stored one, empty when it is the
+// same order. The exchange acknowledgement replaces the price of market orders by what they executed at, so prices
+// are only compared for limit orders
+func replayMismatch(existing, replayed *models.OrderHistory) string {
+	switch {
+	case existing.TradingPairID != replayed.TradingPairID:
+		return "symbol"
+	case existing.Side != replayed.Side:
+		return "side"
+	case existing.Type != replayed.Type:
+		return "type"
+	case !existing.Quantity.Equal(replayed.Quantity):
+		return "quantity"
+	case replayed.Type == string(OrderTypeLimit) && !samePrice(existing.Price, replayed.Price):
+		return "price"
+	}
+	return ""
+}
+
+func samePrice(price, other *decimal.Decimal) bool {
+	if price == nil || other == nil {
+		return price == nil && other == nil
+	}
+	return price.Equal(*other)
+}
+
+// ConfirmReservation stores the exchange acknowledgement of a reserved order and records its first event
+func (r *OrderRepository) ConfirmReservation(ctx context.Context, order *models.OrderHistory, eventTime time.Time) error {
+	status, err := ParseStatus(order.Status)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reserved models.OrderHistory
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reserved, "id = ?", order.ID).Error; err != nil {
+			return err
+		}
+		if err := ValidateTransition(OrderStatus(reserved.Status), status); err != nil {
+			return err
+		}
+		order.Status = string(status)
+		err := tx.Model(order).
+			Select("exchange_order_id", "quantity", "price", "status", "executed_qty", "executed_price", "commission").
+			Updates(order).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(newOrderEvent(order, status, eventTime)).Error
+	})
+}
+
+// ReleaseReservation frees the client order id of an order the exchange refused, so the caller may retry with it
+func (r *OrderRepository) ReleaseReservation(ctx context.Context, orderID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND status = ?", orderID, string(RESERVED)).
+		Delete(&models.OrderHistory{}).Error
+}
+
+// SetExchangeOrderID stores the id the exchange gave a reserved order as soon as it is known, so the order can be
+// reconciled even when the rest of the acknowledgement cannot be read
+func (r *OrderRepository) SetExchangeOrderID(ctx context.Context, orderID uuid.UUID, exchangeOrderID string) error {
+	return r.db.WithContext(ctx).Model(&models.OrderHistory{}).
+		Where("id = ? AND status = ?", orderID, string(RESERVED)).
+		Update("exchange_order_id", exchangeOrderID).Error
+}
+
+// GetStaleReservations returns the orders still reserved since before reservedBefore, oldest first
+func (r *OrderRepository) GetStaleReservations(ctx context.Context, reservedBefore time.Time) (
+	[]models.OrderHistory, error) {
+	var orders []models.OrderHistory
+	err := r.db.WithContext(ctx).
+		Preload("Exchange").
+		Preload("TradingPair").
+		Where("status = ? AND created_at < ?", string(RESERVED), reservedBefore).
+		Order("created_at ASC").
+		Find(&orders).Error
+	return orders, err
+}
+
 func (r *OrderRepository) GetByExchangeOrderID(ctx context.Context, exchangeOrderID string) (*models.OrderHistory,
 	error) {
 	var order models.OrderHistory
@@ -117,26 +246,86 @@ func (r *OrderRepository) GetOpenOrders(ctx context.Context, userID, exchangeCre
 		Preload("Exchange").
 		Preload("TradingPair").
 		Where("user_id = ? AND exchange_credential_id = ? AND status IN ?",
-			userID, exchangeCredentialID, []string{"NEW", "PARTIALLY_FILLED"}).
+			userID, exchangeCredentialID, OpenStatuses).
 		Order("created_at DESC").
 		Find(&orders).Error
 	return orders, err
 }
 
+// GetAllOpenOrders returns the open orders of every user, oldest first
+func (r *OrderRepository) GetAllOpenOrders(ctx context.Context) ([]models.OrderHistory, error) {
+	var orders []models.OrderHistory
+	err := r.db.WithContext(ctx).
+		Preload("Exchange").
+		Preload("TradingPair").
+		Where("status IN ?", OpenStatuses).
+		Order("created_at ASC").
+		Find(&orders).Error
+	return orders, err
+}
+
 func (r *OrderRepository) Update(ctx context.Context, order *models.OrderHistory) error {
 	return r.db.WithContext(ctx).Save(order).Error
 }
 
-func (r *OrderRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string, executedQty, executedPrice,
-	commission float64) error {
-	return r.db.WithContext(ctx).Model(&models.OrderHistory{}).
-		Where("id = ?", id).
-		Updates(map[string]interface{}{
-			"status":         status,
-			"executed_qty":   executedQty,
-			"executed_price": executedPrice,
-			"commission":     commission,
-		}).Error
+// CreateWithEvent stores a newly placed order together with the event for its initial status
+func (r *OrderRepository) CreateWithEvent(ctx context.Context, order *models.OrderHistory, eventTime time.Time) error {
+	status, err := ParseStatus(order.Status)
+	if err != nil {
+		return err
+	}
+	order.Status = string(status)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		return tx.Create(newOrderEvent(order, status, eventTime)).Error
+	})
+}
+
+// UpdateStatusWithEvent moves an order to status through the state machine and appends the matching event atomically
+func (r *OrderRepository) UpdateStatusWithEvent(ctx context.Context, orderID uuid.UUID,
+	status OrderStatus, executedQty, executedPrice, commission decimal.Decimal, eventTime time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.OrderHistory
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", orderID).Error; err != nil {
+			return err
+		}
+		current, err := ParseStatus(order.Status)
+		if err != nil {
+			return err
+		}
+		if err := ValidateTransition(current, status); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"status":       string(status),
+			"executed_qty": executedQty,
+			"commission":   commission,
+		}
+		if executedPrice.IsPositive() {
+			updates["executed_price"] = executedPrice
+		}
+		if err := tx.Model(&order).Updates(updates).Error; err != nil {
+			return err
+		}
+		order.Status, order.ExecutedQty = string(status), executedQty
+		return tx.Create(newOrderEvent(&order, status, eventTime)).Error
+	})
+}
+
+func newOrderEvent(order *models.OrderHistory, status OrderStatus, eventTime time.Time) *models.OrderEvent {
+	remainingQty := order.Quantity.Sub(order.ExecutedQty)
+	if remainingQty.IsNegative() || status.IsTerminal() {
+		remainingQty = decimal.Zero
+	}
+	return &models.OrderEvent{
+		OrderHistID:  order.ID,
+		EventType:    string(EventTypeFor(status)),
+		FilledQty:    order.ExecutedQty,
+		RemainingQty: remainingQty,
+		EventTime:    eventTime,
+	}
 }
 
 func (r *OrderRepository) CreateEvent(ctx context.Context, event *models.OrderEvent) error {
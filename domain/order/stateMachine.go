@@ -0,0 +1,69 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// IStatusMapper translates an exchange specific order status into the canonical OrderStatus
+type IStatusMapper interface {
+	MapStatus(exchangeStatus string, executedQty decimal.Decimal) OrderStatus
+}
+
+// transitions lists the statuses each non terminal status may move to, a partially filled order may keep filling
+var transitions = map[OrderStatus][]OrderStatus{
+	RESERVED:  {NEW, PARTIALLY, FILLED, CANCELED, REJECTED},
+	NEW:       {PARTIALLY, FILLED, CANCELED, REJECTED},
+	PARTIALLY: {PARTIALLY, FILLED, CANCELED},
+}
+
+// statusAliases maps legacy and exchange spellings that were stored before statuses were canonical
+var statusAliases = map[string]OrderStatus{
+	"reserved":         RESERVED,
+	"new":              NEW,
+	"pending":          NEW,
+	"partially_filled": PARTIALLY,
+	"partial":          PARTIALLY,
+	"partially":        PARTIALLY,
+	"filled":           FILLED,
+	"canceled":         CANCELED,
+	"cancelled":        CANCELED,
+	"rejected":         REJECTED,
+}
+
+// ParseStatus returns the canonical status for a stored status string
+func ParseStatus(status string) (OrderStatus, error) {
+	canonical, found := statusAliases[strings.ToLower(status)]
+	if !found {
+		return "", fmt.Errorf("unknown order status: %s", status)
+	}
+	return canonical, nil
+}
+
+// IsTerminal reports whether the order can no longer change
+func (status OrderStatus) IsTerminal() bool {
+	_, open := transitions[status]
+	return !open
+}
+
+// CanTransitionTo reports whether an order in status may move to next
+func (status OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range transitions[status] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns ErrInvalidTransition when current may not move to next
+func ValidateTransition(current, next OrderStatus) error {
+	if !current.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current, next)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package order_test
+
+import (
+	"errors"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"testing"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		current, next order.OrderStatus
+		allowed       bool
+	}{
+		{current: order.RESERVED, next: order.NEW, allowed: true},
+		{current: order.RESERVED, next: order.FILLED, allowed: true},
+		{current: order.RESERVED, next: order.REJECTED, allowed: true},
+		{current: order.NEW, next: order.PARTIALLY, allowed: true},
+		{current: order.NEW, next: order.FILLED, allowed: true},
+		{current: order.NEW, next: order.CANCELED, allowed: true},
+		{current: order.NEW, next: order.REJECTED, allowed: true},
+		{current: order.PARTIALLY, next: order.PARTIALLY, allowed: true},
+		{current: order.PARTIALLY, next: order.FILLED, allowed: true},
+		{current: order.PARTIALLY, next: order.CANCELED, allowed: true},
+		{current: order.NEW, next: order.NEW, allowed: false},
+		{current: order.NEW, next: order.RESERVED, allowed: false},
+		{current: order.PARTIALLY, next: order.NEW, allowed: false},
+		{current: order.PARTIALLY, next: order.REJECTED, allowed: false},
+		{current: order.FILLED, next: order.NEW, allowed: false},
+		{current: order.FILLED, next: order.CANCELED, allowed: false},
+		{current: order.CANCELED, next: order.FILLED, allowed: false},
+		{current: order.CANCELED, next: order.NEW, allowed: false},
+		{current: order.REJECTED, next: order.NEW, allowed: false},
+	}
+	for _, test := range tests {
+		t.Run(string(test.current)+"->"+string(test.next), func(t *testing.T) {
+			err := order.ValidateTransition(test.current, test.next)
+			if test.allowed && err != nil {
+				t.Errorf("got %v, want the transition allowed", err)
+			}
+			if !test.allowed && !errors.Is(err, order.ErrInvalidTransition) {
+				t.Errorf("got %v, want ErrInvalidTransition", err)
+			}
+			if test.current.CanTransitionTo(test.next) != test.allowed {
+				t.Errorf("CanTransitionTo disagrees with ValidateTransition")
+			}
+		})
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   order.OrderStatus
+	}{
+		{status: "reserved", want: order.RESERVED},
+		{status: "new", want: order.NEW},
+		{status: "pending", want: order.NEW},
+		{status: "partially_filled", want: order.PARTIALLY},
+		{status: "partial", want: order.PARTIALLY},
+		{status: "Partially", want: order.PARTIALLY},
+		{status: "FILLED", want: order.FILLED},
+		{status: "canceled", want: order.CANCELED},
+		{status: "cancelled", want: order.CANCELED},
+		{status: "rejected", want: order.REJECTED},
+	}
+	for _, test := range tests {
+		t.Run(test.status, func(t *testing.T) {
+			got, err := order.ParseStatus(test.status)
+			if err != nil || got != test.want {
+				t.Errorf("got %q, %v, want %q", got, err, test.want)
+			}
+		})
+	}
+	for _, status := range []string{"", "failed", "open", "done"} {
+		if got, err := order.ParseStatus(status); err == nil {
+			t.Errorf("ParseStatus(%q) = %q, want an error", status, got)
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	tests := map[order.OrderStatus]bool{
+		order.RESERVED:  false,
+		order.NEW:       false,
+		order.PARTIALLY: false,
+		order.FILLED:    true,
+		order.CANCELED:  true,
+		order.REJECTED:  true,
+	}
+	for status, want := range tests {
+		if got := status.IsTerminal(); got != want {
+			t.Errorf("%s.IsTerminal() = %v, want %v", status, got, want)
+		}
+	}
+}
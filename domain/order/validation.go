@@ -0,0 +1,38 @@
+package order
+
+import "fmt"
+
+type ValidationCode string
+
+const (
+	CodeInvalidSide      ValidationCode = "invalid_side"
+	CodeInvalidType      ValidationCode = "invalid_type"
+	CodePriceRequired    ValidationCode = "price_required"
+	CodeQuantityRequired ValidationCode = "quantity_required"
+	CodeInvalidPrice     ValidationCode = "invalid_price"
+	CodeQuantityTooSmall ValidationCode = "quantity_below_minimum"
+	CodeQuantityTooLarge ValidationCode = "quantity_above_maximum"
+	CodeBelowStepSize    ValidationCode = "quantity_below_step_size"
+	CodeSymbolInactive   ValidationCode = "symbol_inactive"
+	CodeInsufficientFund ValidationCode = "insufficient_balance"
+	CodeInvalidSymbol    ValidationCode = "invalid_symbol"
+	CodeClientOrderID    ValidationCode = "invalid_client_order_id"
+	// CodeClientOrderIDInUse is a client order id the user already placed a different order with, or one on another
+	// exchange
+	CodeClientOrderIDInUse ValidationCode = "client_order_id_in_use"
+)
+
+// ValidationError is returned when an order is rejected before it is sent to the exchange
+type ValidationError struct {
+	Code    ValidationCode `json:"code"`
+	Field   string         `json:"field,omitempty"`
+	Message string         `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func NewValidationError(code ValidationCode, field string, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Code: code, Field: field, Message: fmt.Sprintf(format, args...)}
+}
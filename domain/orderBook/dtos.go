@@ -2,6 +2,7 @@ package orderBook
 
 import (
 	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
 )
 
 type GetOrderBookRequest struct {
@@ -14,14 +15,16 @@ type StandardOrderBookRequest struct {
 }
 
 type StandardOrderBookResponse struct {
-	Symbol    string       `json:"symbol"`
-	Bids      models.JSONB `json:"bids"` // Buying orders (price descending)
-	Asks      models.JSONB `json:"asks"` // Selling orders (price ascending)
-	Timestamp string       `json:"timestamp"`
+	Symbol     string       `json:"symbol"`
+	Bids       models.JSONB `json:"bids"` // Buying orders (price descending)
+	Asks       models.JSONB `json:"asks"` // Selling orders (price ascending)
+	Timestamp  string       `json:"timestamp"`
+	Cached     bool         `json:"cached"`       // served from the order book cache
+	CacheAgeMs int64        `json:"cache_age_ms"` // since the book was read from the exchange
 }
 
 // StandardOrderLevel represents price level in order book
 type StandardOrderLevel struct {
-	Price    float64 `json:"price"`
-	Quantity float64 `json:"quantity"`
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
 }
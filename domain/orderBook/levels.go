@@ -0,0 +1,80 @@
+package orderBook
+
+import (
+	"encoding/json"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// LevelsFromJSONB decodes the price levels stored under "data" in an order book snapshot side
+func LevelsFromJSONB(side models.JSONB) ([]StandardOrderLevel, error) {
+	raw, err := json.Marshal(side["data"])
+	if err != nil {
+		return nil, err
+	}
+	var levels []StandardOrderLevel
+	if err := json.Unmarshal(raw, &levels); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// SortLevels orders bids by price descending and asks ascending, and keeps the best depth levels when depth is
+// positive
+func SortLevels(levels []StandardOrderLevel, descending bool, depth int) []StandardOrderLevel {
+	sorted := append([]StandardOrderLevel(nil), levels...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if descending {
+			return sorted[i].Price.GreaterThan(sorted[j].Price)
+		}
+		return sorted[i].Price.LessThan(sorted[j].Price)
+	})
+	if depth > 0 && len(sorted) > depth {
+		sorted = sorted[:depth]
+	}
+	return sorted
+}
+
+// diffLevels returns the levels of current that differ from base, and the levels of base missing from current with
+// a zero quantity
+func diffLevels(base, current []StandardOrderLevel) []StandardOrderLevel {
+	baseQuantities := make(map[string]decimal.Decimal, len(base))
+	for _, level := range base {
+		baseQuantities[level.Price.String()] = level.Quantity
+	}
+	changes := make([]StandardOrderLevel, 0)
+	for _, level := range current {
+		quantity, found := baseQuantities[level.Price.String()]
+		if !found || !quantity.Equal(level.Quantity) {
+			changes = append(changes, level)
+		}
+		delete(baseQuantities, level.Price.String())
+	}
+	for _, level := range base {
+		if _, removed := baseQuantities[level.Price.String()]; removed {
+			changes = append(changes, StandardOrderLevel{Price: level.Price, Quantity: decimal.Zero})
+		}
+	}
+	return changes
+}
+
+// applyLevels rebuilds a side from its keyframe levels and the changes of a delta
+func applyLevels(base, changes []StandardOrderLevel, descending bool) []StandardOrderLevel {
+	levels := make(map[string]StandardOrderLevel, len(base)+len(changes))
+	for _, level := range base {
+		levels[level.Price.String()] = level
+	}
+	for _, change := range changes {
+		if change.Quantity.IsZero() {
+			delete(levels, change.Price.String())
+			continue
+		}
+		levels[change.Price.String()] = change
+	}
+	merged := make([]StandardOrderLevel, 0, len(levels))
+	for _, level := range levels {
+		merged = append(merged, level)
+	}
+	return SortLevels(merged, descending, 0)
+}
@@ -0,0 +1,72 @@
+package orderBook
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+// levelsOf builds levels from price and quantity pairs
+func levelsOf(priceQuantity ...string) []StandardOrderLevel {
+	levels := make([]StandardOrderLevel, 0, len(priceQuantity)/2)
+	for i := 0; i+1 < len(priceQuantity); i += 2 {
+		levels = append(levels, StandardOrderLevel{Price: decimal.RequireFromString(priceQuantity[i]),
+			Quantity: decimal.RequireFromString(priceQuantity[i+1])})
+	}
+	return levels
+}
+
+func equalLevels(got, want []StandardOrderLevel) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if !got[i].Price.Equal(want[i].Price) || !got[i].Quantity.Equal(want[i].Quantity) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiffAndApplyLevelsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		base, book []StandardOrderLevel
+		descending bool
+		depth      int
+		changes    int
+	}{
+		{name: "unchanged book has no changes", base: levelsOf("100", "1", "99", "2"),
+			book: levelsOf("100", "1", "99", "2"), descending: true, changes: 0},
+		{name: "changed quantity", base: levelsOf("100", "1", "99", "2"), book: levelsOf("100", "3", "99", "2"),
+			descending: true, changes: 1},
+		{name: "removed level", base: levelsOf("100", "1", "99", "2", "98", "4"), book: levelsOf("100", "1", "98", "4"),
+			descending: true, changes: 1},
+		{name: "added level", base: levelsOf("101", "1", "102", "2"), book: levelsOf("100", "5", "101", "1", "102", "2"),
+			changes: 1},
+		{name: "everything replaced", base: levelsOf("101", "1", "102", "2"), book: levelsOf("103", "1", "104", "2"),
+			changes: 4},
+		{name: "empty book removes every level", base: levelsOf("101", "1", "102", "2"), book: levelsOf(),
+			changes: 2},
+		{name: "empty keyframe adds every level", base: levelsOf(), book: levelsOf("101", "1", "102", "2"),
+			changes: 2},
+		{name: "prices written differently are the same level", base: levelsOf("100.50", "1"),
+			book: levelsOf("100.5", "2"), descending: true, changes: 1},
+		{name: "levels pushed out by the depth are removed", base: levelsOf("100", "1", "99", "2", "98", "3"),
+			book: levelsOf("101", "1", "100", "1", "99", "2", "98", "3"), descending: true, depth: 3, changes: 2},
+		{name: "levels coming back within the depth are added", base: levelsOf("101", "1", "102", "2", "103", "3"),
+			book: levelsOf("102", "2", "103", "3", "104", "4", "105", "5"), depth: 3, changes: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			base := SortLevels(test.base, test.descending, test.depth)
+			book := SortLevels(test.book, test.descending, test.depth)
+			changes := diffLevels(base, book)
+			if len(changes) != test.changes {
+				t.Errorf("got %d changes %v, want %d", len(changes), changes, test.changes)
+			}
+			if got := applyLevels(base, changes, test.descending); !equalLevels(got, book) {
+				t.Errorf("applying the changes to the keyframe gave %v, want %v", got, book)
+			}
+		})
+	}
+}
@@ -2,29 +2,164 @@ package orderBook
 
 import (
 	"context"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/rzabhd80/eye-on/internal/database/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
 	"time"
 )
 
+// keyframeMaxAge bounds how long deltas keep pointing at one keyframe, so that no keyframe a process still refers to
+// is old enough to be pruned
+const keyframeMaxAge = time.Hour
+
 type IOrderBookSnapshotRepository interface {
 	Create(ctx context.Context, snapshot *models.OrderBookSnapshot) error
 	GetLatestByTradingPair(ctx context.Context, tradingPairID uuid.UUID) (*models.OrderBookSnapshot, error)
+	GetLatestByTradingPairBefore(ctx context.Context, tradingPairID uuid.UUID, before time.Time) (*models.OrderBookSnapshot, error)
 	GetHistory(ctx context.Context, tradingPairID uuid.UUID, limit int) ([]models.OrderBookSnapshot, error)
-	DeleteOldSnapshots(ctx context.Context, olderThan time.Time) error
+	DownsampleSnapshots(ctx context.Context, olderThan time.Time, bucket time.Duration) (int64, error)
+	DeleteOldSnapshots(ctx context.Context, olderThan time.Time) (int64, error)
 }
 
+// OrderBookSnapshotRepository stores order books truncated to Depth levels a side (0 keeps every level). Every
+// KeyframeInterval snapshots of a pair one is stored in full and the others as deltas against it (1 or less stores
+// every snapshot in full). Snapshots are always read back in full
 type OrderBookSnapshotRepository struct {
-	db *gorm.DB
+	db               *gorm.DB
+	Depth            int
+	KeyframeInterval int
+
+	mu        sync.Mutex
+	keyframes map[uuid.UUID]*keyframe
+}
+
+// keyframe is the last full snapshot this process stored for a pair
+type keyframe struct {
+	id     uuid.UUID
+	bids   []StandardOrderLevel
+	asks   []StandardOrderLevel
+	taken  time.Time
+	deltas int
 }
 
 func NewOrderBookSnapshotRepository(db *gorm.DB) *OrderBookSnapshotRepository {
-	return &OrderBookSnapshotRepository{db: db}
+	return &OrderBookSnapshotRepository{db: db, keyframes: make(map[uuid.UUID]*keyframe)}
 }
 
+// Create stores the snapshot truncated and, when it is smaller, as a delta. The snapshot passed in keeps every level
+// and gets the id and best prices of the stored one
 func (r *OrderBookSnapshotRepository) Create(ctx context.Context, snapshot *models.OrderBookSnapshot) error {
-	return r.db.WithContext(ctx).Create(&snapshot).Error
+	bids, err := LevelsFromJSONB(snapshot.Bids)
+	if err != nil {
+		return fmt.Errorf("invalid bids: %w", err)
+	}
+	asks, err := LevelsFromJSONB(snapshot.Asks)
+	if err != nil {
+		return fmt.Errorf("invalid asks: %w", err)
+	}
+	bids = SortLevels(bids, true, r.Depth)
+	asks = SortLevels(asks, false, r.Depth)
+	snapshot.BestBid, snapshot.BestAsk = nil, nil
+	if len(bids) > 0 {
+		snapshot.BestBid = &bids[0].Price
+	}
+	if len(asks) > 0 {
+		snapshot.BestAsk = &asks[0].Price
+	}
+	if snapshot.ID == uuid.Nil {
+		snapshot.ID = uuid.New()
+	}
+
+	stored := *snapshot
+	stored.BaseSnapshotID = nil
+	stored.Bids = models.JSONB{"data": bids}
+	stored.Asks = models.JSONB{"data": asks}
+	base := r.keyframe(snapshot.TradingPairID, snapshot.SnapshotTime)
+	if base != nil {
+		bidChanges, askChanges := diffLevels(base.bids, bids), diffLevels(base.asks, asks)
+		if len(bidChanges)+len(askChanges) < len(bids)+len(asks) {
+			stored.BaseSnapshotID = &base.id
+			stored.Bids = models.JSONB{"data": bidChanges}
+			stored.Asks = models.JSONB{"data": askChanges}
+		}
+	}
+	if err := r.db.WithContext(ctx).Omit(clause.Associations).Create(&stored).Error; err != nil {
+		// the keyframe may have been pruned, the next snapshot starts a new one
+		r.forgetKeyframe(snapshot.TradingPairID)
+		return err
+	}
+	snapshot.CreatedAt, snapshot.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored.BaseSnapshotID != nil {
+		base.deltas++
+		return nil
+	}
+	if r.keyframes == nil {
+		r.keyframes = make(map[uuid.UUID]*keyframe)
+	}
+	r.keyframes[snapshot.TradingPairID] = &keyframe{id: stored.ID, bids: bids, asks: asks, taken: snapshot.SnapshotTime}
+	return nil
+}
+
+// keyframe returns the keyframe the next snapshot of a pair can be a delta of, nil when it has to be a keyframe
+func (r *OrderBookSnapshotRepository) keyframe(tradingPairID uuid.UUID, at time.Time) *keyframe {
+	if r.KeyframeInterval <= 1 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	base, found := r.keyframes[tradingPairID]
+	if !found || base.deltas+1 >= r.KeyframeInterval || at.Sub(base.taken) > keyframeMaxAge || at.Before(base.taken) {
+		return nil
+	}
+	return base
+}
+
+func (r *OrderBookSnapshotRepository) forgetKeyframe(tradingPairID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keyframes, tradingPairID)
+}
+
+// expand rebuilds the full book of a delta from its keyframe, keyframes are returned as they are
+func (r *OrderBookSnapshotRepository) expand(ctx context.Context, snapshot *models.OrderBookSnapshot,
+	bases map[uuid.UUID]*models.OrderBookSnapshot) error {
+	if snapshot.BaseSnapshotID == nil {
+		return nil
+	}
+	base, found := bases[*snapshot.BaseSnapshotID]
+	if !found {
+		base = &models.OrderBookSnapshot{}
+		if err := r.db.WithContext(ctx).Unscoped().First(base, "id = ?", *snapshot.BaseSnapshotID).Error; err != nil {
+			return fmt.Errorf("failed to load keyframe of snapshot %s: %w", snapshot.ID, err)
+		}
+		bases[base.ID] = base
+	}
+	for _, side := range []struct {
+		delta      *models.JSONB
+		keyframe   models.JSONB
+		descending bool
+	}{
+		{&snapshot.Bids, base.Bids, true},
+		{&snapshot.Asks, base.Asks, false},
+	} {
+		baseLevels, err := LevelsFromJSONB(side.keyframe)
+		if err != nil {
+			return err
+		}
+		changes, err := LevelsFromJSONB(*side.delta)
+		if err != nil {
+			return err
+		}
+		*side.delta = models.JSONB{"data": applyLevels(baseLevels, changes, side.descending)}
+	}
+	snapshot.BaseSnapshotID = nil
+	return nil
 }
 
 func (r *OrderBookSnapshotRepository) GetLatestByTradingPair(ctx context.Context, tradingPairID uuid.UUID) (
@@ -38,6 +173,26 @@ func (r *OrderBookSnapshotRepository) GetLatestByTradingPair(ctx context.Context
 	if err != nil {
 		return nil, err
 	}
+	if err := r.expand(ctx, &snapshot, make(map[uuid.UUID]*models.OrderBookSnapshot)); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// GetLatestByTradingPairBefore returns the last snapshot of the pair taken at or before the given time
+func (r *OrderBookSnapshotRepository) GetLatestByTradingPairBefore(ctx context.Context, tradingPairID uuid.UUID,
+	before time.Time) (*models.OrderBookSnapshot, error) {
+	var snapshot models.OrderBookSnapshot
+	err := r.db.WithContext(ctx).
+		Where("trading_pair_id = ? AND snapshot_time <= ?", tradingPairID, before).
+		Order("snapshot_time DESC").
+		First(&snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.expand(ctx, &snapshot, make(map[uuid.UUID]*models.OrderBookSnapshot)); err != nil {
+		return nil, err
+	}
 	return &snapshot, nil
 }
 
@@ -49,9 +204,49 @@ func (r *OrderBookSnapshotRepository) GetHistory(ctx context.Context, tradingPai
 		Order("snapshot_time DESC").
 		Limit(limit).
 		Find(&snapshots).Error
-	return snapshots, err
+	if err != nil {
+		return nil, err
+	}
+	bases := make(map[uuid.UUID]*models.OrderBookSnapshot)
+	for i := range snapshots {
+		if err := r.expand(ctx, &snapshots[i], bases); err != nil {
+			return nil, err
+		}
+	}
+	return snapshots, nil
+}
+
+// DownsampleSnapshots keeps the last snapshot of every bucket per trading pair among the snapshots older than
+// olderThan and deletes the others, except keyframes a kept snapshot still depends on
+func (r *OrderBookSnapshotRepository) DownsampleSnapshots(ctx context.Context, olderThan time.Time,
+	bucket time.Duration) (int64, error) {
+	bucketSeconds := int64(bucket / time.Second)
+	if bucketSeconds < 1 {
+		bucketSeconds = 1
+	}
+	result := r.db.WithContext(ctx).Exec(`WITH doomed AS (
+			SELECT id FROM (
+				SELECT id, row_number() OVER (
+					PARTITION BY trading_pair_id, floor(extract(epoch FROM snapshot_time) / @bucket)
+					ORDER BY snapshot_time DESC) AS position
+				FROM order_book_snapshots
+				WHERE snapshot_time < @before
+			) ranked WHERE position > 1
+		)
+		DELETE FROM order_book_snapshots
+		WHERE id IN (SELECT id FROM doomed)
+		AND id NOT IN (SELECT base_snapshot_id FROM order_book_snapshots
+			WHERE base_snapshot_id IS NOT NULL AND id NOT IN (SELECT id FROM doomed))`,
+		map[string]interface{}{"before": olderThan, "bucket": bucketSeconds})
+	return result.RowsAffected, result.Error
 }
 
-func (r *OrderBookSnapshotRepository) DeleteOldSnapshots(ctx context.Context, olderThan time.Time) error {
-	return r.db.WithContext(ctx).Where("snapshot_time < ?", olderThan).Delete(&models.OrderBookSnapshot{}).Error
+// DeleteOldSnapshots hard deletes the snapshots taken before olderThan, except keyframes of newer deltas
+func (r *OrderBookSnapshotRepository) DeleteOldSnapshots(ctx context.Context, olderThan time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`DELETE FROM order_book_snapshots
+		WHERE snapshot_time < @before
+		AND id NOT IN (SELECT base_snapshot_id FROM order_book_snapshots
+			WHERE base_snapshot_id IS NOT NULL AND snapshot_time >= @before)`,
+		map[string]interface{}{"before": olderThan})
+	return result.RowsAffected, result.Error
 }
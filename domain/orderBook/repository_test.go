@@ -0,0 +1,139 @@
+package orderBook
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/rzabhd80/eye-on/internal/exchangefake"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestRepository(t *testing.T, depth, keyframeInterval int) *OrderBookSnapshotRepository {
+	t.Helper()
+	repo := NewOrderBookSnapshotRepository(exchangefake.NewDatabase(t))
+	repo.Depth, repo.KeyframeInterval = depth, keyframeInterval
+	return repo
+}
+
+func storeBook(t *testing.T, repo *OrderBookSnapshotRepository, pairID uuid.UUID, minutes int,
+	bids, asks []StandardOrderLevel) *models.OrderBookSnapshot {
+	t.Helper()
+	snapshot := &models.OrderBookSnapshot{
+		ExchangeID:    uuid.Nil,
+		TradingPairID: pairID,
+		Symbol:        "BTCUSDT",
+		Bids:          models.JSONB{"data": bids},
+		Asks:          models.JSONB{"data": asks},
+		SnapshotTime:  testStart.Add(time.Duration(minutes) * time.Minute),
+	}
+	if err := repo.Create(context.Background(), snapshot); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return snapshot
+}
+
+func assertBook(t *testing.T, snapshot models.OrderBookSnapshot, bids, asks []StandardOrderLevel) {
+	t.Helper()
+	if snapshot.BaseSnapshotID != nil {
+		t.Errorf("snapshot %s was read back as a delta", snapshot.ID)
+	}
+	gotBids, err := LevelsFromJSONB(snapshot.Bids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotAsks, err := LevelsFromJSONB(snapshot.Asks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalLevels(gotBids, bids) || !equalLevels(gotAsks, asks) {
+		t.Errorf("snapshot at %s: got bids %v asks %v, want %v %v", snapshot.SnapshotTime, gotBids, gotAsks, bids,
+			asks)
+	}
+}
+
+func TestSnapshotsReadBackInFull(t *testing.T) {
+	repo := newTestRepository(t, 3, 3)
+	pairID := uuid.New()
+	books := []struct{ bids, asks []StandardOrderLevel }{
+		{levelsOf("99", "1", "98", "2", "97", "3", "96", "4"), levelsOf("100", "1", "101", "2", "102", "3")},
+		// a bid level removed, an ask quantity changed
+		{levelsOf("99", "1", "97", "3", "96", "4"), levelsOf("100", "5", "101", "2", "102", "3")},
+		// a better bid pushes the worst one out of the depth
+		{levelsOf("99.5", "1", "99", "1", "98", "2", "97", "3"), levelsOf("100", "1", "101", "2", "102", "3")},
+		// the keyframe interval is reached, a new keyframe starts
+		{levelsOf("99", "1"), levelsOf("100", "1")},
+		{levelsOf("99", "2"), levelsOf()},
+	}
+	var stored []*models.OrderBookSnapshot
+	for i, book := range books {
+		stored = append(stored, storeBook(t, repo, pairID, i, book.bids, book.asks))
+	}
+
+	var deltas int64
+	if err := repo.db.Model(&models.OrderBookSnapshot{}).Where("base_snapshot_id IS NOT NULL").
+		Count(&deltas).Error; err != nil {
+		t.Fatal(err)
+	}
+	if deltas == 0 {
+		t.Fatalf("no snapshot was stored as a delta")
+	}
+
+	history, err := repo.GetHistory(context.Background(), pairID, len(books))
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(history) != len(books) {
+		t.Fatalf("got %d snapshots, want %d", len(history), len(books))
+	}
+	for i, snapshot := range history {
+		book := books[len(books)-1-i]
+		if snapshot.ID != stored[len(books)-1-i].ID {
+			t.Fatalf("history is not newest first")
+		}
+		assertBook(t, snapshot, SortLevels(book.bids, true, 3), SortLevels(book.asks, false, 3))
+	}
+}
+
+func TestDeleteOldSnapshotsKeepsReferencedKeyframes(t *testing.T) {
+	repo := newTestRepository(t, 0, 10)
+	pairID := uuid.New()
+	keyframe := storeBook(t, repo, pairID, 0, levelsOf("99", "1", "98", "2"), levelsOf("100", "1", "101", "2"))
+	oldDelta := storeBook(t, repo, pairID, 1, levelsOf("99", "2", "98", "2"), levelsOf("100", "1", "101", "2"))
+	recent := storeBook(t, repo, pairID, 10, levelsOf("99", "3", "98", "2"), levelsOf("100", "1"))
+	if oldDelta.BaseSnapshotID != nil {
+		t.Fatal("Create left the delta on the snapshot passed in")
+	}
+
+	deleted, err := repo.DeleteOldSnapshots(context.Background(), testStart.Add(5*time.Minute))
+	if err != nil {
+		t.Fatalf("DeleteOldSnapshots: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d snapshots, want only the old delta", deleted)
+	}
+	var remaining []models.OrderBookSnapshot
+	if err := repo.db.Unscoped().Order("snapshot_time").Find(&remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 2 || remaining[0].ID != keyframe.ID || remaining[1].ID != recent.ID {
+		t.Fatalf("got %d snapshots left, want the keyframe and the recent delta", len(remaining))
+	}
+
+	latest, err := repo.GetLatestByTradingPair(context.Background(), pairID)
+	if err != nil {
+		t.Fatalf("GetLatestByTradingPair: %v", err)
+	}
+	assertBook(t, *latest, levelsOf("99", "3", "98", "2"), levelsOf("100", "1"))
+
+	// once no delta refers to it the keyframe goes as well
+	deleted, err = repo.DeleteOldSnapshots(context.Background(), testStart.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteOldSnapshots: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d snapshots, want the keyframe and the recent delta", deleted)
+	}
+}
@@ -0,0 +1,36 @@
+package orderBookStream
+
+import (
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"time"
+)
+
+// OrderBookUpdate is one pushed state of a live order book, Error is set instead of the levels when the
+// exchange could not be read
+type OrderBookUpdate struct {
+	Exchange  string                         `json:"exchange"`
+	Symbol    string                         `json:"symbol"`
+	Sequence  uint64                         `json:"sequence"`
+	Bids      []orderBook.StandardOrderLevel `json:"bids"`
+	Asks      []orderBook.StandardOrderLevel `json:"asks"`
+	Timestamp time.Time                      `json:"timestamp"`
+	Error     string                         `json:"error,omitempty"`
+}
+
+// sameBook reports whether update carries the same levels and error as previous
+func (update *OrderBookUpdate) sameBook(previous *OrderBookUpdate) bool {
+	return update.Error == previous.Error && sameLevels(update.Bids, previous.Bids) &&
+		sameLevels(update.Asks, previous.Asks)
+}
+
+func sameLevels(levels, others []orderBook.StandardOrderLevel) bool {
+	if len(levels) != len(others) {
+		return false
+	}
+	for i := range levels {
+		if !levels[i].Price.Equal(others[i].Price) || !levels[i].Quantity.Equal(others[i].Quantity) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package orderBookStream
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hub keeps the live order books that have subscribers in memory. Every exchange and symbol has one poller that
+// is started by its first subscriber and stopped when the last one leaves
+type Hub struct {
+	Registry *registry.ExchangeRegistry
+	Interval time.Duration
+	Logger   *zap.Logger
+
+	mu      sync.Mutex
+	pollers map[streamKey]*poller
+}
+
+type streamKey struct {
+	exchange string
+	symbol   string
+}
+
+// poller refreshes one order book, userID is the first subscriber and is only used by exchanges that cannot
+// read order books without a user
+type poller struct {
+	key         streamKey
+	userID      uuid.UUID
+	adapter     registry.IExchange
+	cancel      context.CancelFunc
+	subscribers map[*Subscription]struct{}
+	latest      *OrderBookUpdate
+	sequence    uint64
+}
+
+// Subscription receives the updates of one order book. Updates only keeps the newest update, a slow reader skips
+// the ones it missed
+type Subscription struct {
+	Updates <-chan OrderBookUpdate
+	updates chan OrderBookUpdate
+	hub     *Hub
+	poller  *poller
+	once    sync.Once
+}
+
+// Subscribe starts streaming the order book of symbol on exchangeName, the latest known state is delivered
+// immediately when the book is already being polled
+func (hub *Hub) Subscribe(exchangeName, symbol string, userID uuid.UUID) (*Subscription, error) {
+	adapter, err := hub.Registry.Get(strings.ToLower(exchangeName))
+	if err != nil {
+		return nil, err
+	}
+	// spellings of the same canonical symbol share one poller
+	if baseAsset, quoteAsset, err := symbols.Parse(symbol); err == nil {
+		symbol = symbols.Canonical(baseAsset, quoteAsset)
+	}
+	key := streamKey{exchange: adapter.Name(), symbol: strings.ToUpper(symbol)}
+	updates := make(chan OrderBookUpdate, 1)
+	subscription := &Subscription{Updates: updates, updates: updates, hub: hub}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.pollers == nil {
+		hub.pollers = make(map[streamKey]*poller)
+	}
+	streamPoller, found := hub.pollers[key]
+	if !found {
+		ctx, cancel := context.WithCancel(context.Background())
+		streamPoller = &poller{
+			key:         key,
+			userID:      userID,
+			adapter:     adapter,
+			cancel:      cancel,
+			subscribers: make(map[*Subscription]struct{}),
+		}
+		hub.pollers[key] = streamPoller
+		go hub.poll(ctx, streamPoller)
+	}
+	streamPoller.subscribers[subscription] = struct{}{}
+	subscription.poller = streamPoller
+	if streamPoller.latest != nil {
+		subscription.push(*streamPoller.latest)
+	}
+	return subscription, nil
+}
+
+// Close stops the subscription and closes Updates, the poller stops with its last subscriber
+func (subscription *Subscription) Close() {
+	subscription.once.Do(func() {
+		hub := subscription.hub
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		streamPoller := subscription.poller
+		delete(streamPoller.subscribers, subscription)
+		close(subscription.updates)
+		if len(streamPoller.subscribers) == 0 {
+			streamPoller.cancel()
+			delete(hub.pollers, streamPoller.key)
+		}
+	})
+}
+
+// push replaces an unread update with update so the reader always gets the newest book
+func (subscription *Subscription) push(update OrderBookUpdate) {
+	select {
+	case subscription.updates <- update:
+		return
+	default:
+	}
+	select {
+	case <-subscription.updates:
+	default:
+	}
+	select {
+	case subscription.updates <- update:
+	default:
+	}
+}
+
+// Close stops every poller and closes every subscription
+func (hub *Hub) Close() {
+	hub.mu.Lock()
+	var subscriptions []*Subscription
+	for _, streamPoller := range hub.pollers {
+		for subscription := range streamPoller.subscribers {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	hub.mu.Unlock()
+	for _, subscription := range subscriptions {
+		subscription.Close()
+	}
+}
+
+func (hub *Hub) poll(ctx context.Context, streamPoller *poller) {
+	ticker := time.NewTicker(hub.Interval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		hub.refresh(ctx, streamPoller)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh reads the order book once and pushes it to the subscribers when it changed
+func (hub *Hub) refresh(ctx context.Context, streamPoller *poller) {
+	snapshot, err := hub.fetch(ctx, streamPoller)
+	if ctx.Err() != nil {
+		return
+	}
+	update := OrderBookUpdate{
+		Exchange:  streamPoller.key.exchange,
+		Symbol:    streamPoller.key.symbol,
+		Bids:      []orderBook.StandardOrderLevel{},
+		Asks:      []orderBook.StandardOrderLevel{},
+		Timestamp: time.Now(),
+	}
+	if err == nil {
+		update.Bids, err = orderBook.LevelsFromJSONB(snapshot.Bids)
+	}
+	if err == nil {
+		update.Asks, err = orderBook.LevelsFromJSONB(snapshot.Asks)
+	}
+	if err != nil {
+		hub.Logger.Warn("failed to refresh live order book",
+			zap.String("exchange", streamPoller.key.exchange),
+			zap.String("symbol", streamPoller.key.symbol),
+			zap.Error(err))
+		update.Bids, update.Asks = []orderBook.StandardOrderLevel{}, []orderBook.StandardOrderLevel{}
+		update.Error = err.Error()
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if streamPoller.latest != nil && update.sameBook(streamPoller.latest) {
+		return
+	}
+	streamPoller.sequence++
+	update.Sequence = streamPoller.sequence
+	streamPoller.latest = &update
+	for subscription := range streamPoller.subscribers {
+		subscription.push(update)
+	}
+}
+
+func (hub *Hub) fetch(ctx context.Context, streamPoller *poller) (*models.OrderBookSnapshot, error) {
+	if fetcher, ok := streamPoller.adapter.(registry.IOrderBookFetcher); ok {
+		return fetcher.FetchOrderBook(ctx, streamPoller.key.symbol)
+	}
+	return streamPoller.adapter.GetOrderBook(ctx, streamPoller.key.symbol, streamPoller.userID)
+}
@@ -0,0 +1,123 @@
+package orderSync
+
+import (
+	"context"
+	"errors"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"go.uber.org/zap"
+	"time"
+)
+
+// OrderSyncWorker periodically refreshes open orders from their exchanges and records lifecycle events. Orders
+// still reserved after ReservationTimeout lost their placement acknowledgement and are reconciled with the exchange
+type OrderSyncWorker struct {
+	Registry           *registry.ExchangeRegistry
+	OrderRepo          *order.OrderRepository
+	Interval           time.Duration
+	ReservationTimeout time.Duration
+	Logger             *zap.Logger
+}
+
+// Run syncs open orders every Interval until ctx is cancelled
+func (worker *OrderSyncWorker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(worker.Interval)
+	defer ticker.Stop()
+	for {
+		worker.SyncOpenOrders(ctx)
+		if worker.ReservationTimeout > 0 {
+			worker.ReconcileReservations(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOpenOrders refreshes every open order once
+func (worker *OrderSyncWorker) SyncOpenOrders(ctx context.Context) {
+	openOrders, err := worker.OrderRepo.GetAllOpenOrders(ctx)
+	if err != nil {
+		worker.Logger.Error("failed to load open orders", zap.Error(err))
+		return
+	}
+	for i := range openOrders {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := worker.syncOrder(ctx, &openOrders[i]); err != nil {
+			worker.Logger.Warn("failed to sync order",
+				zap.String("order_id", openOrders[i].ID.String()),
+				zap.String("exchange", openOrders[i].Exchange.Name),
+				zap.Error(err))
+		}
+	}
+}
+
+// ReconcileReservations looks up every stale reservation on its exchange. Orders the exchange has are confirmed
+// with their current state, the others were never placed and their client order ids are released
+func (worker *OrderSyncWorker) ReconcileReservations(ctx context.Context) {
+	reservations, err := worker.OrderRepo.GetStaleReservations(ctx, time.Now().Add(-worker.ReservationTimeout))
+	if err != nil {
+		worker.Logger.Error("failed to load stale reservations", zap.Error(err))
+		return
+	}
+	for i := range reservations {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := worker.reconcile(ctx, &reservations[i]); err != nil {
+			worker.Logger.Warn("failed to reconcile reserved order",
+				zap.String("order_id", reservations[i].ID.String()),
+				zap.String("client_order_id", reservations[i].ClientOrderID),
+				zap.String("exchange", reservations[i].Exchange.Name),
+				zap.Error(err))
+		}
+	}
+}
+
+func (worker *OrderSyncWorker) reconcile(ctx context.Context, orderHistory *models.OrderHistory) error {
+	exchangeAdapter, err := worker.Registry.Get(orderHistory.Exchange.Name)
+	if err != nil {
+		return err
+	}
+	update, err := exchangeAdapter.FetchOrderStatus(ctx, orderHistory)
+	if errors.Is(err, order.ErrNotOnExchange) {
+		worker.Logger.Info("releasing reservation the exchange never received",
+			zap.String("order_id", orderHistory.ID.String()),
+			zap.String("client_order_id", orderHistory.ClientOrderID))
+		return worker.OrderRepo.ReleaseReservation(ctx, orderHistory.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if update.ExchangeOrderID != "" {
+		orderHistory.ExchangeOrderID = update.ExchangeOrderID
+	}
+	orderHistory.Status = string(update.Status)
+	orderHistory.ExecutedQty = update.ExecutedQty
+	orderHistory.Commission = update.Commission
+	if update.ExecutedPrice.IsPositive() {
+		orderHistory.ExecutedPrice = &update.ExecutedPrice
+	}
+	return worker.OrderRepo.ConfirmReservation(ctx, orderHistory, update.UpdatedAt)
+}
+
+func (worker *OrderSyncWorker) syncOrder(ctx context.Context, orderHistory *models.OrderHistory) error {
+	exchangeAdapter, err := worker.Registry.Get(orderHistory.Exchange.Name)
+	if err != nil {
+		return err
+	}
+	update, err := exchangeAdapter.FetchOrderStatus(ctx, orderHistory)
+	if err != nil {
+		return err
+	}
+	if string(update.Status) == orderHistory.Status && update.ExecutedQty.Equal(orderHistory.ExecutedQty) {
+		return nil
+	}
+	return worker.OrderRepo.UpdateStatusWithEvent(ctx, orderHistory.ID, update.Status, update.ExecutedQty,
+		update.ExecutedPrice, update.Commission, update.UpdatedAt)
+}
@@ -0,0 +1,49 @@
+package portfolio
+
+import (
+	"github.com/rzabhd80/eye-on/domain/balance"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Holding is a balance with its value in every reference currency it could be priced in
+type Holding struct {
+	balance.StandardBalanceResponse
+	Value map[string]decimal.Decimal `json:"value,omitempty"`
+}
+
+// NetWorth is the value of a set of holdings per reference currency, Unpriced lists the assets left out
+type NetWorth struct {
+	Value    map[string]decimal.Decimal `json:"value"`
+	Unpriced []string                   `json:"unpriced,omitempty"`
+}
+
+// ExchangeHoldings is what the user holds on one exchange, Error is set instead when the exchange could not be read
+type ExchangeHoldings struct {
+	Exchange string    `json:"exchange"`
+	Balances []Holding `json:"balances"`
+	NetWorth *NetWorth `json:"net_worth,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// PortfolioResponse is the user's holdings per exchange and merged per asset across every exchange
+type PortfolioResponse struct {
+	Exchanges    []ExchangeHoldings `json:"exchanges"`
+	Totals       []Holding          `json:"totals"`
+	NetWorth     NetWorth           `json:"net_worth"`
+	SnapshotTime time.Time          `json:"snapshot_time"`
+}
+
+// HistoryRequest selects the range and spacing of the net worth series
+type HistoryRequest struct {
+	From     string `query:"from"`
+	To       string `query:"to"`
+	Interval string `query:"interval"`
+}
+
+// NetWorthPoint is the user's net worth at one point of the series, in total and per exchange
+type NetWorthPoint struct {
+	Time      time.Time           `json:"time"`
+	NetWorth  NetWorth            `json:"net_worth"`
+	Exchanges map[string]NetWorth `json:"exchanges"`
+}
@@ -0,0 +1,357 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/balance"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxHistoryPoints caps the length of a net worth series
+const MaxHistoryPoints = 1000
+
+// Aggregator collects balances from every exchange a user has credentials for and values them
+type Aggregator struct {
+	Registry               *registry.ExchangeRegistry
+	ExchangeRepo           *exchange.ExchangeRepository
+	ExchangeCredentialRepo *exchangeCredentials.ExchangeCredentialRepository
+	BalanceRepo            *balance.BalanceSnapshotRepository
+	Valuator               *Valuator
+}
+
+// exchangeResult is the outcome of reading one exchange
+type exchangeResult struct {
+	exchange  models.Exchange
+	snapshots []models.BalanceSnapshot
+	err       error
+}
+
+// fetch reads the balances of every exchange the user has active credentials for concurrently. Sub accounts on
+// the same exchange are read one after the other and summed per currency
+func (aggregator *Aggregator) fetch(ctx context.Context, userID uuid.UUID) ([]exchangeResult, error) {
+	creds, err := aggregator.ExchangeCredentialRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exchanges := make([]models.Exchange, 0, len(creds))
+	credentialIDs := make(map[uuid.UUID][]uuid.UUID, len(creds))
+	for _, cred := range creds {
+		if _, seen := credentialIDs[cred.ExchangeID]; !seen {
+			exchanges = append(exchanges, cred.Exchange)
+		}
+		credentialIDs[cred.ExchangeID] = append(credentialIDs[cred.ExchangeID], cred.ID)
+	}
+
+	results := make([]exchangeResult, len(exchanges))
+	var wg sync.WaitGroup
+	for i, exchangeModel := range exchanges {
+		wg.Add(1)
+		go func(i int, exchangeModel models.Exchange) {
+			defer wg.Done()
+			results[i] = exchangeResult{exchange: exchangeModel}
+			exchangeAdapter, err := aggregator.Registry.Get(exchangeModel.Name)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			for _, credentialID := range credentialIDs[exchangeModel.ID] {
+				credentialCtx := exchangeCredentials.WithSelector(ctx, exchangeCredentials.Selector{ID: credentialID})
+				snapshots, err := exchangeAdapter.GetBalance(credentialCtx, userID, nil)
+				if err != nil {
+					results[i].snapshots, results[i].err = nil, err
+					return
+				}
+				results[i].snapshots = mergeSnapshots(results[i].snapshots, snapshots)
+			}
+		}(i, exchangeModel)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// mergeSnapshots adds the balances of another sub account to those read so far, one snapshot per currency
+func mergeSnapshots(merged, snapshots []models.BalanceSnapshot) []models.BalanceSnapshot {
+	if len(merged) == 0 {
+		return snapshots
+	}
+	index := make(map[string]int, len(merged))
+	for i, snapshot := range merged {
+		index[strings.ToUpper(snapshot.Currency)] = i
+	}
+	for _, snapshot := range snapshots {
+		if i, found := index[strings.ToUpper(snapshot.Currency)]; found {
+			merged[i].Total = merged[i].Total.Add(snapshot.Total)
+			merged[i].Available = merged[i].Available.Add(snapshot.Available)
+			continue
+		}
+		index[strings.ToUpper(snapshot.Currency)] = len(merged)
+		merged = append(merged, snapshot)
+	}
+	return merged
+}
+
+// normalizeSnapshot stamps a fetched balance with the batch time and the canonical upper case currency
+func normalizeSnapshot(snapshot *models.BalanceSnapshot, userID uuid.UUID, snapshotTime time.Time) {
+	snapshot.Currency = strings.ToUpper(snapshot.Currency)
+	snapshot.UserID = userID
+	snapshot.SnapshotTime = snapshotTime
+}
+
+// Snapshot reads and stores the balances of every exchange of the user, returning the errors of the exchanges
+// that could not be read by exchange name
+func (aggregator *Aggregator) Snapshot(ctx context.Context, userID uuid.UUID) (map[string]error, error) {
+	results, err := aggregator.fetch(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	snapshotTime := time.Now()
+	failures := make(map[string]error)
+	var snapshots []models.BalanceSnapshot
+	for _, result := range results {
+		if result.err != nil {
+			failures[result.exchange.Name] = result.err
+			continue
+		}
+		for _, snapshot := range result.snapshots {
+			normalizeSnapshot(&snapshot, userID, snapshotTime)
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	if len(snapshots) > 0 {
+		if err := aggregator.BalanceRepo.BulkCreate(ctx, &snapshots); err != nil {
+			return failures, err
+		}
+	}
+	return failures, nil
+}
+
+// Collect reads every exchange concurrently, stores the balances as one snapshot batch, merges them per asset and
+// values them. An exchange that fails is reported in its own entry and left out of the totals
+func (aggregator *Aggregator) Collect(ctx context.Context, userID uuid.UUID) (*PortfolioResponse, error) {
+	results, err := aggregator.fetch(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotTime := time.Now()
+	pricer := aggregator.Valuator.NewPricer(nil)
+	response := &PortfolioResponse{
+		Exchanges:    make([]ExchangeHoldings, 0, len(results)),
+		NetWorth:     newNetWorth(),
+		SnapshotTime: snapshotTime,
+	}
+	var snapshots []models.BalanceSnapshot
+	totals := make(map[string]*Holding)
+	for _, result := range results {
+		holdings := ExchangeHoldings{Exchange: result.exchange.Name, Balances: []Holding{}}
+		if result.err != nil {
+			holdings.Error = result.err.Error()
+			response.Exchanges = append(response.Exchanges, holdings)
+			continue
+		}
+		worth := newNetWorth()
+		for _, snapshot := range result.snapshots {
+			normalizeSnapshot(&snapshot, userID, snapshotTime)
+			snapshots = append(snapshots, snapshot)
+
+			holding := Holding{StandardBalanceResponse: balance.StandardBalanceResponse{
+				Asset:  snapshot.Currency,
+				Free:   snapshot.Available,
+				Locked: snapshot.Total.Sub(snapshot.Available),
+				Total:  snapshot.Total,
+			}}
+			holding.Value = pricer.valueInto(ctx, &worth, snapshot.Currency, snapshot.Total, result.exchange.ID)
+			holdings.Balances = append(holdings.Balances, holding)
+			addHolding(totals, holding)
+		}
+		response.NetWorth.add(worth)
+		holdings.NetWorth = &worth
+		response.Exchanges = append(response.Exchanges, holdings)
+	}
+
+	response.Totals = make([]Holding, 0, len(totals))
+	for _, total := range totals {
+		response.Totals = append(response.Totals, *total)
+	}
+	sort.Slice(response.Totals, func(i, j int) bool { return response.Totals[i].Asset < response.Totals[j].Asset })
+
+	if len(snapshots) > 0 {
+		if err := aggregator.BalanceRepo.BulkCreate(ctx, &snapshots); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// History values the stored balance snapshots of the user every interval between from and to, carrying each
+// exchange's latest balances forward until a newer snapshot of that exchange replaces all of them
+func (aggregator *Aggregator) History(ctx context.Context, userID uuid.UUID, from, to time.Time,
+	interval time.Duration) ([]NetWorthPoint, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("to must be after from")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	if points := int(to.Sub(from)/interval) + 1; points > MaxHistoryPoints {
+		return nil, fmt.Errorf("%d points requested, at most %d are allowed, use a larger interval",
+			points, MaxHistoryPoints)
+	}
+	current, err := aggregator.BalanceRepo.GetUserLatestBefore(ctx, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := aggregator.BalanceRepo.GetUserSnapshotsBetween(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings := newHeldBalances()
+	for _, snapshot := range current {
+		holdings.add(snapshot)
+	}
+	exchangeNames := make(map[uuid.UUID]string)
+
+	series := make([]NetWorthPoint, 0, int(to.Sub(from)/interval)+1)
+	next := 0
+	for at := from; !at.After(to); at = at.Add(interval) {
+		for ; next < len(snapshots) && !snapshots[next].SnapshotTime.After(at); next++ {
+			holdings.add(snapshots[next])
+		}
+		pointTime := at
+		pricer := aggregator.Valuator.NewPricer(&pointTime)
+		point := NetWorthPoint{Time: at, NetWorth: newNetWorth(), Exchanges: make(map[string]NetWorth)}
+		for exchangeID, balances := range holdings.balances {
+			name, err := aggregator.exchangeName(ctx, exchangeNames, exchangeID)
+			if err != nil {
+				return nil, err
+			}
+			worth, found := point.Exchanges[name]
+			if !found {
+				worth = newNetWorth()
+			}
+			for currency, snapshot := range balances {
+				pricer.valueInto(ctx, &worth, currency, snapshot.Total, exchangeID)
+			}
+			point.Exchanges[name] = worth
+		}
+		for _, worth := range point.Exchanges {
+			point.NetWorth.add(worth)
+		}
+		series = append(series, point)
+	}
+	return series, nil
+}
+
+// heldBalances keeps the balances of the latest snapshot batch of every exchange by currency. The snapshots of one
+// batch share their SnapshotTime, an asset missing from a newer batch is no longer held
+type heldBalances struct {
+	batchTimes map[uuid.UUID]time.Time
+	balances   map[uuid.UUID]map[string]models.BalanceSnapshot
+}
+
+func newHeldBalances() *heldBalances {
+	return &heldBalances{
+		batchTimes: make(map[uuid.UUID]time.Time),
+		balances:   make(map[uuid.UUID]map[string]models.BalanceSnapshot),
+	}
+}
+
+// add records snapshot, a snapshot newer than the exchange's batch replaces the whole batch and an older one is ignored
+func (held *heldBalances) add(snapshot models.BalanceSnapshot) {
+	batchTime, found := held.batchTimes[snapshot.ExchangeID]
+	if found && snapshot.SnapshotTime.Before(batchTime) {
+		return
+	}
+	if !found || snapshot.SnapshotTime.After(batchTime) {
+		held.batchTimes[snapshot.ExchangeID] = snapshot.SnapshotTime
+		held.balances[snapshot.ExchangeID] = make(map[string]models.BalanceSnapshot)
+	}
+	held.balances[snapshot.ExchangeID][strings.ToUpper(snapshot.Currency)] = snapshot
+}
+
+func (aggregator *Aggregator) exchangeName(ctx context.Context, names map[uuid.UUID]string, id uuid.UUID) (
+	string, error) {
+	if name, found := names[id]; found {
+		return name, nil
+	}
+	exchangeModel, err := aggregator.ExchangeRepo.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	names[id] = exchangeModel.Name
+	return exchangeModel.Name, nil
+}
+
+// valueInto values amount of asset in every reference currency and adds it to worth, assets that cannot be priced
+// are listed as unpriced
+func (pricer *Pricer) valueInto(ctx context.Context, worth *NetWorth, asset string, amount decimal.Decimal,
+	exchangeID uuid.UUID) map[string]decimal.Decimal {
+	values := make(map[string]decimal.Decimal, len(ReferenceCurrencies))
+	for _, currency := range ReferenceCurrencies {
+		value, err := pricer.Value(ctx, amount, asset, currency, exchangeID)
+		if err != nil {
+			continue
+		}
+		values[currency] = value
+		worth.Value[currency] = worth.Value[currency].Add(value)
+	}
+	if len(values) < len(ReferenceCurrencies) && amount.IsPositive() && !contains(worth.Unpriced, asset) {
+		worth.Unpriced = append(worth.Unpriced, asset)
+	}
+	return values
+}
+
+func newNetWorth() NetWorth {
+	worth := NetWorth{Value: make(map[string]decimal.Decimal, len(ReferenceCurrencies))}
+	for _, currency := range ReferenceCurrencies {
+		worth.Value[currency] = decimal.Zero
+	}
+	return worth
+}
+
+func (worth *NetWorth) add(other NetWorth) {
+	for currency, value := range other.Value {
+		worth.Value[currency] = worth.Value[currency].Add(value)
+	}
+	for _, asset := range other.Unpriced {
+		if !contains(worth.Unpriced, asset) {
+			worth.Unpriced = append(worth.Unpriced, asset)
+		}
+	}
+}
+
+func addHolding(totals map[string]*Holding, holding Holding) {
+	total, found := totals[holding.Asset]
+	if !found {
+		total = &Holding{
+			StandardBalanceResponse: balance.StandardBalanceResponse{Asset: holding.Asset},
+			Value:                   make(map[string]decimal.Decimal),
+		}
+		totals[holding.Asset] = total
+	}
+	total.Free = total.Free.Add(holding.Free)
+	total.Locked = total.Locked.Add(holding.Locked)
+	total.Total = total.Total.Add(holding.Total)
+	for currency, value := range holding.Value {
+		total.Value[currency] = total.Value[currency].Add(value)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
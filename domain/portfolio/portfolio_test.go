@@ -0,0 +1,88 @@
+package portfolio
+
+import (
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestHeldBalances(t *testing.T) {
+	bitpin, nobitex := uuid.New(), uuid.New()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := func(exchangeID uuid.UUID, minutes int, currency, total string) models.BalanceSnapshot {
+		return models.BalanceSnapshot{
+			ExchangeID:   exchangeID,
+			Currency:     currency,
+			Total:        decimal.RequireFromString(total),
+			SnapshotTime: start.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		snapshots []models.BalanceSnapshot
+		want      map[uuid.UUID]map[string]string
+	}{
+		{
+			name: "asset missing from a newer batch is dropped",
+			snapshots: []models.BalanceSnapshot{
+				snapshot(bitpin, 0, "BTC", "1"),
+				snapshot(bitpin, 0, "USDT", "100"),
+				snapshot(bitpin, 10, "USDT", "60000"),
+			},
+			want: map[uuid.UUID]map[string]string{bitpin: {"USDT": "60000"}},
+		},
+		{
+			name: "newer batch of one exchange keeps the other exchanges",
+			snapshots: []models.BalanceSnapshot{
+				snapshot(bitpin, 0, "BTC", "1"),
+				snapshot(nobitex, 0, "ETH", "2"),
+				snapshot(bitpin, 10, "USDT", "5"),
+			},
+			want: map[uuid.UUID]map[string]string{bitpin: {"USDT": "5"}, nobitex: {"ETH": "2"}},
+		},
+		{
+			name: "older snapshots read out of order are ignored",
+			snapshots: []models.BalanceSnapshot{
+				snapshot(bitpin, 10, "USDT", "5"),
+				snapshot(bitpin, 0, "BTC", "1"),
+				snapshot(bitpin, 10, "eth", "3"),
+			},
+			want: map[uuid.UUID]map[string]string{bitpin: {"USDT": "5", "ETH": "3"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			held := newHeldBalances()
+			for _, current := range test.snapshots {
+				held.add(current)
+			}
+			got := make(map[uuid.UUID]map[string]string, len(held.balances))
+			for exchangeID, balances := range held.balances {
+				got[exchangeID] = make(map[string]string, len(balances))
+				for currency, balance := range balances {
+					got[exchangeID][currency] = balance.Total.String()
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("held %v, want %v", describe(got), describe(test.want))
+			}
+		})
+	}
+}
+
+// describe lists held balances as currency=total pairs in a stable order
+func describe(held map[uuid.UUID]map[string]string) []string {
+	var pairs []string
+	for _, balances := range held {
+		for currency, total := range balances {
+			pairs = append(pairs, currency+"="+total)
+		}
+	}
+	sort.Strings(pairs)
+	return pairs
+}
@@ -0,0 +1,174 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+const (
+	IRT  = "IRT"
+	USDT = "USDT"
+	// RLS is the rial, Nobitex reports toman balances in it
+	RLS = "RLS"
+)
+
+// ReferenceCurrencies are the currencies every holding is valued in
+var ReferenceCurrencies = []string{IRT, USDT}
+
+var errNoPrice = errors.New("no order book to price from")
+
+// Valuator prices assets from the mid-price of the latest stored order book snapshots
+type Valuator struct {
+	TradingPairRepo *traidingPair.TradingPairRepository
+	OrderBookRepo   *orderBook.OrderBookSnapshotRepository
+}
+
+// Pricer values assets at one point in time, looked up prices are cached for its lifetime
+type Pricer struct {
+	valuator *Valuator
+	at       *time.Time
+	prices   map[string]*decimal.Decimal
+}
+
+// NewPricer prices from the latest snapshots, or from the last snapshots taken at or before at when it is set
+func (valuator *Valuator) NewPricer(at *time.Time) *Pricer {
+	return &Pricer{valuator: valuator, at: at, prices: make(map[string]*decimal.Decimal)}
+}
+
+// Value returns amount of asset expressed in quote, preferring the order books of preferredExchange
+func (pricer *Pricer) Value(ctx context.Context, amount decimal.Decimal, asset, quote string,
+	preferredExchange uuid.UUID) (decimal.Decimal, error) {
+	price, err := pricer.Price(ctx, asset, quote, preferredExchange)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return amount.Mul(price), nil
+}
+
+// Price returns the price of one unit of asset in quote. Without a direct or inverse market the price is
+// routed through USDT
+func (pricer *Pricer) Price(ctx context.Context, asset, quote string, preferredExchange uuid.UUID) (
+	decimal.Decimal, error) {
+	asset, quote = strings.ToUpper(asset), strings.ToUpper(quote)
+	if asset == RLS {
+		price, err := pricer.Price(ctx, IRT, quote, preferredExchange)
+		return price.Div(decimal.NewFromInt(10)), err
+	}
+	if asset == quote {
+		return decimal.NewFromInt(1), nil
+	}
+	price, err := pricer.market(ctx, asset, quote, preferredExchange)
+	if err == nil {
+		return price, nil
+	}
+	if !errors.Is(err, errNoPrice) || asset == USDT || quote == USDT {
+		return decimal.Zero, fmt.Errorf("cannot price %s in %s: %w", asset, quote, err)
+	}
+	inUSDT, err := pricer.market(ctx, asset, USDT, preferredExchange)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("cannot price %s in %s: %w", asset, quote, err)
+	}
+	usdtPrice, err := pricer.market(ctx, USDT, quote, preferredExchange)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("cannot price %s in %s: %w", asset, quote, err)
+	}
+	return inUSDT.Mul(usdtPrice), nil
+}
+
+// market prices asset in quote from a direct market, or from the inverse one
+func (pricer *Pricer) market(ctx context.Context, asset, quote string, preferredExchange uuid.UUID) (
+	decimal.Decimal, error) {
+	key := asset + "/" + quote
+	if cached, found := pricer.prices[key]; found {
+		if cached == nil {
+			return decimal.Zero, errNoPrice
+		}
+		return *cached, nil
+	}
+	price, err := pricer.mid(ctx, asset, quote, preferredExchange)
+	if errors.Is(err, errNoPrice) {
+		var inverse decimal.Decimal
+		inverse, err = pricer.mid(ctx, quote, asset, preferredExchange)
+		if err == nil {
+			price = decimal.NewFromInt(1).DivRound(inverse, 16)
+		}
+	}
+	if errors.Is(err, errNoPrice) {
+		pricer.prices[key] = nil
+	}
+	if err != nil {
+		return decimal.Zero, err
+	}
+	pricer.prices[key] = &price
+	return price, nil
+}
+
+// mid returns the mid-price of the base/quote market, trying preferredExchange before the others
+func (pricer *Pricer) mid(ctx context.Context, base, quote string, preferredExchange uuid.UUID) (
+	decimal.Decimal, error) {
+	pairs, err := pricer.valuator.TradingPairRepo.GetByAssets(ctx, base, quote)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for i := range pairs {
+		if pairs[i].ExchangeID == preferredExchange {
+			pairs[0], pairs[i] = pairs[i], pairs[0]
+			break
+		}
+	}
+	for _, pair := range pairs {
+		var snapshot *models.OrderBookSnapshot
+		if pricer.at != nil {
+			snapshot, err = pricer.valuator.OrderBookRepo.GetLatestByTradingPairBefore(ctx, pair.ID, *pricer.at)
+		} else {
+			snapshot, err = pricer.valuator.OrderBookRepo.GetLatestByTradingPair(ctx, pair.ID)
+		}
+		if err != nil {
+			continue
+		}
+		if price, ok := MidPrice(snapshot); ok {
+			return price, nil
+		}
+	}
+	return decimal.Zero, errNoPrice
+}
+
+// MidPrice is the average of the best bid and best ask, or the only side present
+func MidPrice(snapshot *models.OrderBookSnapshot) (decimal.Decimal, bool) {
+	bids, err := orderBook.LevelsFromJSONB(snapshot.Bids)
+	if err != nil {
+		return decimal.Zero, false
+	}
+	asks, err := orderBook.LevelsFromJSONB(snapshot.Asks)
+	if err != nil {
+		return decimal.Zero, false
+	}
+	var bestBid, bestAsk *decimal.Decimal
+	for i := range bids {
+		if bids[i].Price.IsPositive() && (bestBid == nil || bids[i].Price.GreaterThan(*bestBid)) {
+			bestBid = &bids[i].Price
+		}
+	}
+	for i := range asks {
+		if asks[i].Price.IsPositive() && (bestAsk == nil || asks[i].Price.LessThan(*bestAsk)) {
+			bestAsk = &asks[i].Price
+		}
+	}
+	switch {
+	case bestBid != nil && bestAsk != nil:
+		return bestBid.Add(*bestAsk).Div(decimal.NewFromInt(2)), true
+	case bestBid != nil:
+		return *bestBid, true
+	case bestAsk != nil:
+		return *bestAsk, true
+	}
+	return decimal.Zero, false
+}
@@ -0,0 +1,57 @@
+package smartOrder
+
+import (
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Allocation is the part of a routed order planned for one exchange
+type Allocation struct {
+	Exchange      string          `json:"exchange"`
+	Symbol        string          `json:"symbol"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	ExpectedPrice decimal.Decimal `json:"expected_price"` // average over the levels it was planned against, zero when resting
+}
+
+// RoutingFailure is an allocation the exchange did not accept
+type RoutingFailure struct {
+	Allocation
+	Error string `json:"error"`
+}
+
+// SkippedVenue is an exchange left out of the plan because its order book could not be read
+type SkippedVenue struct {
+	Exchange string `json:"exchange"`
+	Error    string `json:"error"`
+}
+
+// ChildOrderResponse is one child order of a routed order
+type ChildOrderResponse struct {
+	Exchange string `json:"exchange"`
+	order.StandardOrderResponse
+	ExecutedPrice *decimal.Decimal `json:"executed_price,omitempty"`
+}
+
+// SmartOrderResponse is a routed order with its children, Plan, Unplaced, Skipped and Failures are only set by the
+// request that routed or cancelled it. Unplaced is the quantity the step sizes and limits of the venues left out
+type SmartOrderResponse struct {
+	ID            uuid.UUID            `json:"id"`
+	ClientOrderID string               `json:"client_order_id"`
+	Symbol        string               `json:"symbol"`
+	Side          order.OrderSide      `json:"side"`
+	Type          order.OrderType      `json:"type"`
+	Quantity      decimal.Decimal      `json:"quantity"`
+	Price         *decimal.Decimal     `json:"price,omitempty"`
+	Status        order.OrderStatus    `json:"status"`
+	ExecutedQty   decimal.Decimal      `json:"executed_qty"`
+	AveragePrice  *decimal.Decimal     `json:"average_price,omitempty"`
+	Children      []ChildOrderResponse `json:"children"`
+	Plan          []Allocation         `json:"plan,omitempty"`
+	Unplaced      *decimal.Decimal     `json:"unplaced,omitempty"`
+	Failures      []RoutingFailure     `json:"failures,omitempty"`
+	Skipped       []SkippedVenue       `json:"skipped,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
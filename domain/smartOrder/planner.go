@@ -0,0 +1,140 @@
+package smartOrder
+
+import (
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// venue is an exchange the user can trade the market on, levels is the side of its book the order takes from
+type venue struct {
+	adapter registry.IExchange
+	pair    models.TradingPair
+	levels  []orderBook.StandardOrderLevel
+}
+
+// better reports whether price is better than other for an order on side
+func better(side order.OrderSide, price, other decimal.Decimal) bool {
+	if side == order.OrderSideBuy {
+		return price.LessThan(other)
+	}
+	return price.GreaterThan(other)
+}
+
+// plan splits quantity across venues by taking the best priced levels of all of them first, so the average price
+// is the best the combined books offer. Allocations are rounded down to the step size of their pair and dropped
+// below its minimum quantity, whatever the books cannot fill at or within limit rests on the venues quoting the best
+// price as far as their pairs allow. The quantity no venue can take is returned as unplaced
+func plan(venues []venue, side order.OrderSide, quantity decimal.Decimal, limit *decimal.Decimal) (
+	[]Allocation, decimal.Decimal) {
+	type quote struct {
+		venue int
+		level orderBook.StandardOrderLevel
+	}
+	var quotes []quote
+	for i, v := range venues {
+		for _, level := range v.levels {
+			if !level.Price.IsPositive() || !level.Quantity.IsPositive() {
+				continue
+			}
+			if limit != nil && better(side, *limit, level.Price) {
+				continue
+			}
+			quotes = append(quotes, quote{venue: i, level: level})
+		}
+	}
+	sort.SliceStable(quotes, func(i, j int) bool { return better(side, quotes[i].level.Price, quotes[j].level.Price) })
+
+	planned := make([]decimal.Decimal, len(venues))
+	cost := make([]decimal.Decimal, len(venues))
+	remaining := quantity
+	for _, q := range quotes {
+		if !remaining.IsPositive() {
+			break
+		}
+		take := decimal.Min(remaining, q.level.Quantity)
+		planned[q.venue] = planned[q.venue].Add(take)
+		cost[q.venue] = cost[q.venue].Add(take.Mul(q.level.Price))
+		remaining = remaining.Sub(take)
+	}
+
+	expected := make([]decimal.Decimal, len(venues))
+	for i, v := range venues {
+		if !planned[i].IsPositive() {
+			continue
+		}
+		expected[i] = cost[i].DivRound(planned[i], 10)
+		rounded := v.round(planned[i])
+		remaining = remaining.Add(planned[i].Sub(rounded))
+		planned[i] = rounded
+	}
+	for _, i := range rankVenues(venues, side, planned) {
+		if !remaining.IsPositive() {
+			break
+		}
+		rounded := venues[i].round(planned[i].Add(remaining))
+		if rounded.GreaterThan(planned[i]) {
+			remaining = remaining.Sub(rounded.Sub(planned[i]))
+			planned[i] = rounded
+		}
+	}
+
+	var allocations []Allocation
+	for i, v := range venues {
+		if planned[i].IsPositive() {
+			allocations = append(allocations, Allocation{
+				Exchange:      v.adapter.Name(),
+				Symbol:        v.pair.Symbol,
+				Quantity:      planned[i],
+				ExpectedPrice: expected[i],
+			})
+		}
+	}
+	return allocations, remaining
+}
+
+// round caps quantity at the maximum quantity of the pair, rounds it down to its step size and drops it to zero
+// below its minimum quantity, so the child order passes the pre-trade validation of the pair
+func (v *venue) round(quantity decimal.Decimal) decimal.Decimal {
+	if v.pair.MaxQuantity != nil && v.pair.MaxQuantity.IsPositive() {
+		quantity = decimal.Min(quantity, *v.pair.MaxQuantity)
+	}
+	if v.pair.StepSize != nil && v.pair.StepSize.IsPositive() {
+		quantity = quantity.Div(*v.pair.StepSize).Floor().Mul(*v.pair.StepSize)
+	}
+	if v.pair.MinQuantity != nil && quantity.LessThan(*v.pair.MinQuantity) {
+		return decimal.Zero
+	}
+	return quantity
+}
+
+// rankVenues orders the venues by their top of book, best first, venues without any level follow by their
+// allocation, largest first
+func rankVenues(venues []venue, side order.OrderSide, planned []decimal.Decimal) []int {
+	tops := make([]*decimal.Decimal, len(venues))
+	ranked := make([]int, len(venues))
+	for i, v := range venues {
+		ranked[i] = i
+		for _, level := range v.levels {
+			if level.Price.IsPositive() && level.Quantity.IsPositive() {
+				price := level.Price
+				tops[i] = &price
+				break
+			}
+		}
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		i, j := ranked[a], ranked[b]
+		switch {
+		case tops[i] != nil && tops[j] != nil:
+			return better(side, *tops[i], *tops[j])
+		case tops[i] != nil || tops[j] != nil:
+			return tops[i] != nil
+		}
+		return planned[i].GreaterThan(planned[j])
+	})
+	return ranked
+}
@@ -0,0 +1,244 @@
+package smartOrder
+
+import (
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+// namedExchange only answers Name, the planner never calls the other methods
+type namedExchange struct {
+	registry.IExchange
+	name string
+}
+
+func (exchange namedExchange) Name() string { return exchange.name }
+
+func dec(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func decPtr(value string) *decimal.Decimal {
+	d := dec(value)
+	return &d
+}
+
+func levels(priceQuantity ...string) []orderBook.StandardOrderLevel {
+	var result []orderBook.StandardOrderLevel
+	for i := 0; i+1 < len(priceQuantity); i += 2 {
+		result = append(result, orderBook.StandardOrderLevel{Price: dec(priceQuantity[i]),
+			Quantity: dec(priceQuantity[i+1])})
+	}
+	return result
+}
+
+func testVenue(name string, step, min string, book []orderBook.StandardOrderLevel) venue {
+	pair := models.TradingPair{Symbol: name + "-pair"}
+	if step != "" {
+		pair.StepSize = decPtr(step)
+	}
+	if min != "" {
+		pair.MinQuantity = decPtr(min)
+	}
+	return venue{adapter: namedExchange{name: name}, pair: pair, levels: book}
+}
+
+func TestBetter(t *testing.T) {
+	tests := []struct {
+		name         string
+		side         order.OrderSide
+		price, other string
+		want         bool
+	}{
+		{name: "lower price is better to buy", side: order.OrderSideBuy, price: "99", other: "100", want: true},
+		{name: "higher price is worse to buy", side: order.OrderSideBuy, price: "101", other: "100", want: false},
+		{name: "higher price is better to sell", side: order.OrderSideSell, price: "101", other: "100", want: true},
+		{name: "lower price is worse to sell", side: order.OrderSideSell, price: "99", other: "100", want: false},
+		{name: "equal price is not better", side: order.OrderSideBuy, price: "100", other: "100", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := better(test.side, dec(test.price), dec(test.other)); got != test.want {
+				t.Errorf("better(%s, %s, %s) = %v, want %v", test.side, test.price, test.other, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		venues   []venue
+		side     order.OrderSide
+		quantity string
+		limit    *decimal.Decimal
+		want     map[string]string
+		unplaced string
+	}{
+		{
+			name: "best levels of all books are taken first",
+			venues: []venue{
+				testVenue("a", "", "", levels("100", "1", "102", "5")),
+				testVenue("b", "", "", levels("101", "1", "103", "5")),
+			},
+			side:     order.OrderSideBuy,
+			quantity: "3",
+			want:     map[string]string{"a": "2", "b": "1"},
+			unplaced: "0",
+		},
+		{
+			name: "sells take the highest bids first",
+			venues: []venue{
+				testVenue("a", "", "", levels("99", "1")),
+				testVenue("b", "", "", levels("100", "1", "98", "5")),
+			},
+			side:     order.OrderSideSell,
+			quantity: "2",
+			want:     map[string]string{"a": "1", "b": "1"},
+			unplaced: "0",
+		},
+		{
+			name: "levels beyond the limit are cut off and the rest rests on the best venue",
+			venues: []venue{
+				testVenue("a", "", "", levels("100", "1", "105", "5")),
+				testVenue("b", "", "", levels("101", "1", "106", "5")),
+			},
+			side:     order.OrderSideBuy,
+			quantity: "4",
+			limit:    decPtr("101"),
+			want:     map[string]string{"a": "3", "b": "1"},
+			unplaced: "0",
+		},
+		{
+			name: "allocations are rounded down to the step size and the leftover goes to the best venue",
+			venues: []venue{
+				testVenue("a", "0.1", "", levels("100", "0.15")),
+				testVenue("b", "0.1", "", levels("101", "5")),
+			},
+			side:     order.OrderSideBuy,
+			quantity: "1",
+			want:     map[string]string{"a": "0.2", "b": "0.8"},
+			unplaced: "0",
+		},
+		{
+			name: "the leftover is rounded on the receiving venue and the rest is unplaced",
+			venues: []venue{
+				testVenue("a", "0.1", "", levels("100", "0.15")),
+			},
+			side:     order.OrderSideBuy,
+			quantity: "0.15",
+			want:     map[string]string{"a": "0.1"},
+			unplaced: "0.05",
+		},
+		{
+			name: "allocations below the minimum quantity move to another venue",
+			venues: []venue{
+				testVenue("a", "0.01", "1", levels("100", "0.5")),
+				testVenue("b", "0.01", "1", levels("101", "5")),
+			},
+			side:     order.OrderSideBuy,
+			quantity: "2",
+			want:     map[string]string{"b": "2"},
+			unplaced: "0",
+		},
+		{
+			name: "a leftover below every minimum quantity is unplaced",
+			venues: []venue{
+				testVenue("a", "", "1", levels("100", "0.5")),
+			},
+			side:     order.OrderSideBuy,
+			quantity: "0.5",
+			want:     map[string]string{},
+			unplaced: "0.5",
+		},
+		{
+			name: "without any level the order rests on one venue",
+			venues: []venue{
+				testVenue("a", "", "", nil),
+				testVenue("b", "", "", nil),
+			},
+			side:     order.OrderSideBuy,
+			quantity: "2",
+			want:     map[string]string{"a": "2"},
+			unplaced: "0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allocations, unplaced := plan(test.venues, test.side, dec(test.quantity), test.limit)
+			got := make(map[string]string, len(allocations))
+			for _, allocation := range allocations {
+				got[allocation.Exchange] = allocation.Quantity.String()
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("got allocations %v, want %v", got, test.want)
+			}
+			for exchange, quantity := range test.want {
+				if !dec(got[exchange]).Equal(dec(quantity)) {
+					t.Errorf("got %s on %s, want %s (allocations %v)", got[exchange], exchange, quantity, got)
+				}
+			}
+			if !unplaced.Equal(dec(test.unplaced)) {
+				t.Errorf("got unplaced %s, want %s", unplaced, test.unplaced)
+			}
+			total := unplaced
+			for _, allocation := range allocations {
+				total = total.Add(allocation.Quantity)
+			}
+			if !total.Equal(dec(test.quantity)) {
+				t.Errorf("allocations and unplaced add up to %s, want %s", total, test.quantity)
+			}
+		})
+	}
+}
+
+func TestPlanExpectedPrice(t *testing.T) {
+	venues := []venue{testVenue("a", "", "", levels("100", "1", "110", "1"))}
+	allocations, _ := plan(venues, order.OrderSideBuy, dec("2"), nil)
+	if len(allocations) != 1 {
+		t.Fatalf("got %d allocations, want 1", len(allocations))
+	}
+	if !allocations[0].ExpectedPrice.Equal(dec("105")) {
+		t.Errorf("got expected price %s, want 105", allocations[0].ExpectedPrice)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	child := func(status order.OrderStatus, executed string) models.OrderHistory {
+		return models.OrderHistory{Status: string(status), ExecutedQty: dec(executed)}
+	}
+	tests := []struct {
+		name     string
+		children []models.OrderHistory
+		want     order.OrderStatus
+		executed string
+	}{
+		{name: "no children is rejected", want: order.REJECTED, executed: "0"},
+		{name: "every child rejected", children: []models.OrderHistory{child(order.REJECTED, "0"),
+			child(order.REJECTED, "0")}, want: order.REJECTED, executed: "0"},
+		{name: "every child filled", children: []models.OrderHistory{child(order.FILLED, "1"),
+			child(order.FILLED, "2")}, want: order.FILLED, executed: "3"},
+		{name: "open child with a fill is partially filled", children: []models.OrderHistory{child(order.FILLED, "1"),
+			child(order.NEW, "0")}, want: order.PARTIALLY, executed: "1"},
+		{name: "reserved child without fills is new", children: []models.OrderHistory{child(order.RESERVED, "0"),
+			child(order.NEW, "0")}, want: order.NEW, executed: "0"},
+		{name: "filled and canceled children are canceled", children: []models.OrderHistory{child(order.FILLED, "1"),
+			child(order.CANCELED, "0")}, want: order.CANCELED, executed: "1"},
+		{name: "rejected and canceled children are canceled", children: []models.OrderHistory{
+			child(order.REJECTED, "0"), child(order.CANCELED, "0")}, want: order.CANCELED, executed: "0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, executed := progress(test.children)
+			if status != test.want {
+				t.Errorf("got status %s, want %s", status, test.want)
+			}
+			if !executed.Equal(dec(test.executed)) {
+				t.Errorf("got executed %s, want %s", executed, test.executed)
+			}
+		})
+	}
+}
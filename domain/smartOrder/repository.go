@@ -0,0 +1,86 @@
+package smartOrder
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IRoutedOrderRepository interface {
+	Reserve(ctx context.Context, routedOrder *models.RoutedOrder) (*models.RoutedOrder, bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.RoutedOrder, error)
+	GetByUserAndClientOrderID(ctx context.Context, userID uuid.UUID, clientOrderID string) (*models.RoutedOrder, error)
+	LinkChild(ctx context.Context, parentID, userID uuid.UUID, clientOrderID string) error
+	UpdateProgress(ctx context.Context, routedOrder *models.RoutedOrder) error
+}
+
+type RoutedOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewRoutedOrderRepository(db *gorm.DB) *RoutedOrderRepository {
+	return &RoutedOrderRepository{db: db}
+}
+
+// Reserve claims the client order id of a routed order before any child is placed. When the user already used the
+// client order id the earlier order is returned instead and the boolean is false
+func (r *RoutedOrderRepository) Reserve(ctx context.Context, routedOrder *models.RoutedOrder) (
+	*models.RoutedOrder, bool, error) {
+	routedOrder.Status = string(order.RESERVED)
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(routedOrder)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return routedOrder, true, nil
+	}
+	existing, err := r.GetByUserAndClientOrderID(ctx, routedOrder.UserID, routedOrder.ClientOrderID)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// GetByID returns the routed order with its child orders, their exchanges and trading pairs
+func (r *RoutedOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.RoutedOrder, error) {
+	var routedOrder models.RoutedOrder
+	err := r.withChildren(ctx).First(&routedOrder, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &routedOrder, nil
+}
+
+func (r *RoutedOrderRepository) GetByUserAndClientOrderID(ctx context.Context, userID uuid.UUID,
+	clientOrderID string) (*models.RoutedOrder, error) {
+	var routedOrder models.RoutedOrder
+	err := r.withChildren(ctx).
+		First(&routedOrder, "user_id = ? AND client_order_id = ?", userID, clientOrderID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &routedOrder, nil
+}
+
+// LinkChild marks the user's order with clientOrderID as a child of the routed order parentID
+func (r *RoutedOrderRepository) LinkChild(ctx context.Context, parentID, userID uuid.UUID, clientOrderID string) error {
+	return r.db.WithContext(ctx).Model(&models.OrderHistory{}).
+		Where("user_id = ? AND client_order_id = ?", userID, clientOrderID).
+		Update("parent_order_id", parentID).Error
+}
+
+// UpdateProgress stores the status and executed quantity derived from the children
+func (r *RoutedOrderRepository) UpdateProgress(ctx context.Context, routedOrder *models.RoutedOrder) error {
+	return r.db.WithContext(ctx).Model(routedOrder).Select("status", "executed_qty").Updates(routedOrder).Error
+}
+
+func (r *RoutedOrderRepository) withChildren(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Preload("ChildOrders", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("ChildOrders.Exchange").
+		Preload("ChildOrders.TradingPair")
+}
@@ -0,0 +1,376 @@
+package smartOrder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/domain/orderBook"
+	"github.com/rzabhd80/eye-on/domain/symbols"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+)
+
+// maxClientOrderIDLength leaves room for the suffix of the child client order ids
+const maxClientOrderIDLength = 80
+
+var (
+	ErrRoutedOrderNotFound = errors.New("routed order not found")
+	ErrNoVenues            = errors.New("no exchange you have credentials for lists this market")
+	ErrNoOrderBooks        = errors.New("no order book of this market could be read")
+)
+
+// SmartRouter splits orders for a canonical market across the exchanges the user has credentials for
+type SmartRouter struct {
+	Registry               *registry.ExchangeRegistry
+	ExchangeCredentialRepo *exchangeCredentials.ExchangeCredentialRepository
+	TradingPairRepo        *traidingPair.TradingPairRepository
+	RoutedOrderRepo        *RoutedOrderRepository
+}
+
+// Route plans req against the live order books of the user's exchanges and places one child order per exchange
+// through its adapter. Replaying a client order id returns the routed order placed with it
+func (router *SmartRouter) Route(ctx context.Context, req *order.StandardOrderRequest, userID uuid.UUID) (
+	*SmartOrderResponse, error) {
+	base, quote, err := symbols.Parse(req.Symbol)
+	if err != nil {
+		return nil, order.NewValidationError(order.CodeInvalidSymbol, "symbol", "%s", err.Error())
+	}
+	quantity := req.Quantity
+	if quantity == nil || !quantity.IsPositive() {
+		quantity = req.BaseAmount
+	}
+	if quantity == nil || !quantity.IsPositive() {
+		return nil, order.NewValidationError(order.CodeQuantityRequired, "quantity",
+			"smart orders are sized in the base asset, specify quantity or base_amount")
+	}
+	if req.ClientOrderId == "" {
+		req.ClientOrderId = order.NewClientOrderID()
+	}
+	if len(req.ClientOrderId) > maxClientOrderIDLength {
+		return nil, order.NewValidationError(order.CodeClientOrderID, "client_order_id",
+			"client_order_id must be at most %d characters", maxClientOrderIDLength)
+	}
+
+	venues, skipped, err := router.venues(ctx, userID, base, quote, req.Side)
+	if err != nil {
+		return nil, err
+	}
+	routedOrder, reserved, err := router.RoutedOrderRepo.Reserve(ctx, &models.RoutedOrder{
+		BaseModel:     models.BaseModel{ID: uuid.New()},
+		UserID:        userID,
+		ClientOrderID: req.ClientOrderId,
+		Symbol:        symbols.Canonical(base, quote),
+		BaseAsset:     base,
+		QuoteAsset:    quote,
+		Side:          string(req.Side),
+		Type:          string(req.Type),
+		Quantity:      *quantity,
+		Price:         req.Price,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		if routedOrder.Status == string(order.RESERVED) {
+			return nil, order.ErrPlacementInProgress
+		}
+		return router.refresh(ctx, routedOrder)
+	}
+
+	allocations, unplaced := plan(venues, req.Side, *quantity, req.Price)
+	failures := router.place(ctx, routedOrder, req, venues, allocations, userID)
+
+	routedOrder, err = router.RoutedOrderRepo.GetByID(ctx, routedOrder.ID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := router.refresh(ctx, routedOrder)
+	if err != nil {
+		return nil, err
+	}
+	response.Plan = allocations
+	if unplaced.IsPositive() {
+		response.Unplaced = &unplaced
+	}
+	response.Failures = failures
+	response.Skipped = skipped
+	return response, nil
+}
+
+// venues reads the books of the market on every exchange the user has active credentials for. Only exchanges with
+// public order books are routed to, simulated exchanges would mix paper and real fills. Exchanges whose book cannot
+// be read are left out and returned as skipped
+func (router *SmartRouter) venues(ctx context.Context, userID uuid.UUID, base, quote string, side order.OrderSide) (
+	[]venue, []SkippedVenue, error) {
+	creds, err := router.ExchangeCredentialRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// a selected credential limits the routing to the exchanges it names a credential on
+	selector := exchangeCredentials.SelectorFrom(ctx)
+	usable := make(map[uuid.UUID]bool, len(creds))
+	for _, cred := range creds {
+		if selector.Matches(&cred) {
+			usable[cred.ExchangeID] = true
+		}
+	}
+	pairs, err := router.TradingPairRepo.GetByAssets(ctx, base, quote)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var venues []venue
+	for _, pair := range pairs {
+		if !usable[pair.ExchangeID] {
+			continue
+		}
+		adapter, err := router.Registry.Get(pair.Exchange.Name)
+		if err != nil {
+			continue
+		}
+		if _, ok := adapter.(registry.IOrderBookFetcher); ok {
+			venues = append(venues, venue{adapter: adapter, pair: pair})
+		}
+	}
+	if len(venues) == 0 {
+		return nil, nil, fmt.Errorf("%w: %s/%s", ErrNoVenues, base, quote)
+	}
+
+	errs := make([]error, len(venues))
+	var wg sync.WaitGroup
+	for i := range venues {
+		wg.Add(1)
+		go func(i int, v *venue) {
+			defer wg.Done()
+			snapshot, err := v.adapter.(registry.IOrderBookFetcher).FetchOrderBook(ctx, v.pair.Symbol)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			source := snapshot.Asks
+			if side == order.OrderSideSell {
+				source = snapshot.Bids
+			}
+			levels, err := orderBook.LevelsFromJSONB(source)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			sort.SliceStable(levels, func(i, j int) bool { return better(side, levels[i].Price, levels[j].Price) })
+			v.levels = levels
+		}(i, &venues[i])
+	}
+	wg.Wait()
+
+	readable := venues[:0]
+	var skipped []SkippedVenue
+	for i, v := range venues {
+		if errs[i] != nil {
+			skipped = append(skipped, SkippedVenue{Exchange: v.adapter.Name(), Error: errs[i].Error()})
+			continue
+		}
+		readable = append(readable, v)
+	}
+	if len(readable) == 0 {
+		return nil, skipped, fmt.Errorf("%w: %s/%s: %w", ErrNoOrderBooks, base, quote, errors.Join(errs...))
+	}
+	return readable, skipped, nil
+}
+
+// place sends the child orders concurrently and links every stored child to routedOrder
+func (router *SmartRouter) place(ctx context.Context, routedOrder *models.RoutedOrder, req *order.StandardOrderRequest,
+	venues []venue, allocations []Allocation, userID uuid.UUID) []RoutingFailure {
+	adapters := make(map[string]venue, len(venues))
+	for _, v := range venues {
+		adapters[v.adapter.Name()] = v
+	}
+	errs := make([]error, len(allocations))
+	var wg sync.WaitGroup
+	for i, allocation := range allocations {
+		wg.Add(1)
+		go func(i int, allocation Allocation) {
+			defer wg.Done()
+			v := adapters[allocation.Exchange]
+			quantity := allocation.Quantity
+			child := order.StandardOrderRequest{
+				Symbol:        v.pair.Symbol,
+				Side:          req.Side,
+				Type:          req.Type,
+				Quantity:      &quantity,
+				BaseCurrency:  v.pair.BaseAsset,
+				QuoteCurrency: v.pair.QuoteAsset,
+				Price:         req.Price,
+				TimeInForce:   req.TimeInForce,
+				ClientOrderId: routedOrder.ClientOrderID + "-" + allocation.Exchange,
+			}
+			_, errs[i] = v.adapter.PlaceOrder(ctx, &child, userID)
+			// a placement with an unknown outcome keeps its reservation, link it too so it is tracked
+			if err := router.RoutedOrderRepo.LinkChild(ctx, routedOrder.ID, userID, child.ClientOrderId); err != nil &&
+				errs[i] == nil {
+				errs[i] = err
+			}
+		}(i, allocation)
+	}
+	wg.Wait()
+
+	var failures []RoutingFailure
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, RoutingFailure{Allocation: allocations[i], Error: err.Error()})
+		}
+	}
+	return failures
+}
+
+// Get returns the routed order of the user with the current state of its children
+func (router *SmartRouter) Get(ctx context.Context, id, userID uuid.UUID) (*SmartOrderResponse, error) {
+	routedOrder, err := router.load(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return router.refresh(ctx, routedOrder)
+}
+
+// Cancel cancels every open child of the routed order on its exchange, children that could not be cancelled are
+// reported as failures
+func (router *SmartRouter) Cancel(ctx context.Context, id, userID uuid.UUID) (*SmartOrderResponse, error) {
+	routedOrder, err := router.load(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	var failures []RoutingFailure
+	for _, child := range routedOrder.ChildOrders {
+		if !isOpen(child.Status) {
+			continue
+		}
+		err := router.cancelChild(ctx, &child, userID)
+		if err != nil {
+			failures = append(failures, RoutingFailure{
+				Allocation: Allocation{Exchange: child.Exchange.Name, Symbol: child.TradingPair.Symbol,
+					Quantity: child.Quantity},
+				Error: err.Error(),
+			})
+		}
+	}
+	routedOrder, err = router.RoutedOrderRepo.GetByID(ctx, routedOrder.ID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := router.refresh(ctx, routedOrder)
+	if err != nil {
+		return nil, err
+	}
+	response.Failures = failures
+	return response, nil
+}
+
+// cancelChild cancels one child by its id, never through a bulk cancel that would reach other orders of the market
+func (router *SmartRouter) cancelChild(ctx context.Context, child *models.OrderHistory, userID uuid.UUID) error {
+	adapter, err := router.Registry.Get(child.Exchange.Name)
+	if err != nil {
+		return err
+	}
+	orderID := child.ID.String()
+	return adapter.CancelOrder(ctx, &orderID, userID)
+}
+
+func (router *SmartRouter) load(ctx context.Context, id, userID uuid.UUID) (*models.RoutedOrder, error) {
+	routedOrder, err := router.RoutedOrderRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && routedOrder.UserID != userID) {
+		return nil, ErrRoutedOrderNotFound
+	}
+	return routedOrder, err
+}
+
+// refresh derives the status and executed quantity of the routed order from its children, stores them when they
+// changed and builds the response
+func (router *SmartRouter) refresh(ctx context.Context, routedOrder *models.RoutedOrder) (*SmartOrderResponse, error) {
+	status, executed := progress(routedOrder.ChildOrders)
+	if routedOrder.Status != string(status) || !routedOrder.ExecutedQty.Equal(executed) {
+		routedOrder.Status = string(status)
+		routedOrder.ExecutedQty = executed
+		if err := router.RoutedOrderRepo.UpdateProgress(ctx, routedOrder); err != nil {
+			return nil, err
+		}
+	}
+	return newSmartOrderResponse(routedOrder), nil
+}
+
+// progress is new until a child fills, filled once every child is and canceled or rejected once none is open
+func progress(children []models.OrderHistory) (order.OrderStatus, decimal.Decimal) {
+	executed := decimal.Zero
+	open, filled, rejected := 0, 0, 0
+	for _, child := range children {
+		executed = executed.Add(child.ExecutedQty)
+		switch {
+		case isOpen(child.Status) || child.Status == string(order.RESERVED):
+			open++
+		case child.Status == string(order.FILLED):
+			filled++
+		case child.Status == string(order.REJECTED):
+			rejected++
+		}
+	}
+	switch {
+	case len(children) == 0 || rejected == len(children):
+		return order.REJECTED, executed
+	case filled == len(children):
+		return order.FILLED, executed
+	case open > 0 && executed.IsPositive():
+		return order.PARTIALLY, executed
+	case open > 0:
+		return order.NEW, executed
+	}
+	return order.CANCELED, executed
+}
+
+func isOpen(status string) bool {
+	for _, openStatus := range order.OpenStatuses {
+		if status == openStatus {
+			return true
+		}
+	}
+	return false
+}
+
+func newSmartOrderResponse(routedOrder *models.RoutedOrder) *SmartOrderResponse {
+	response := &SmartOrderResponse{
+		ID:            routedOrder.ID,
+		ClientOrderID: routedOrder.ClientOrderID,
+		Symbol:        routedOrder.Symbol,
+		Side:          order.OrderSide(routedOrder.Side),
+		Type:          order.OrderType(routedOrder.Type),
+		Quantity:      routedOrder.Quantity,
+		Price:         routedOrder.Price,
+		Status:        order.OrderStatus(routedOrder.Status),
+		ExecutedQty:   routedOrder.ExecutedQty,
+		Children:      make([]ChildOrderResponse, 0, len(routedOrder.ChildOrders)),
+		CreatedAt:     routedOrder.CreatedAt,
+		UpdatedAt:     routedOrder.UpdatedAt,
+	}
+	executedQuote := decimal.Zero
+	for i := range routedOrder.ChildOrders {
+		child := &routedOrder.ChildOrders[i]
+		response.Children = append(response.Children, ChildOrderResponse{
+			Exchange:              child.Exchange.Name,
+			StandardOrderResponse: order.NewStandardOrderResponse(child),
+			ExecutedPrice:         child.ExecutedPrice,
+		})
+		if child.ExecutedPrice != nil {
+			executedQuote = executedQuote.Add(child.ExecutedPrice.Mul(child.ExecutedQty))
+		}
+	}
+	if routedOrder.ExecutedQty.IsPositive() && executedQuote.IsPositive() {
+		averagePrice := executedQuote.DivRound(routedOrder.ExecutedQty, 10)
+		response.AveragePrice = &averagePrice
+	}
+	return response
+}
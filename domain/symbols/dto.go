@@ -0,0 +1,24 @@
+package symbols
+
+import "github.com/rzabhd80/eye-on/internal/database/models"
+
+// SymbolResponse is a canonical symbol with the native symbol every exchange listing it uses
+type SymbolResponse struct {
+	Symbol     string            `json:"symbol"`
+	BaseAsset  string            `json:"base_asset"`
+	QuoteAsset string            `json:"quote_asset"`
+	Exchanges  map[string]string `json:"exchanges"`
+}
+
+func NewSymbolResponse(canonicalSymbol models.CanonicalSymbol) SymbolResponse {
+	exchanges := make(map[string]string, len(canonicalSymbol.TradingPairs))
+	for _, pair := range canonicalSymbol.TradingPairs {
+		exchanges[pair.Exchange.Name] = pair.Symbol
+	}
+	return SymbolResponse{
+		Symbol:     canonicalSymbol.Symbol,
+		BaseAsset:  canonicalSymbol.BaseAsset,
+		QuoteAsset: canonicalSymbol.QuoteAsset,
+		Exchanges:  exchanges,
+	}
+}
@@ -0,0 +1,159 @@
+package symbols
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"strings"
+	"sync"
+)
+
+// IAssetMapper is implemented by exchange symbol factories whose asset codes or units differ from the canonical ones
+type IAssetMapper interface {
+	ExchangeAssets(exchange *models.Exchange) []models.ExchangeAsset
+}
+
+// SymbolRegistry resolves canonical symbols to the trading pairs of an exchange and translates assets between
+// their canonical form and the exchange codes
+type SymbolRegistry struct {
+	Repo            *SymbolRepository
+	TradingPairRepo *traidingPair.TradingPairRepository
+
+	mu     sync.RWMutex
+	assets map[uuid.UUID]*Assets
+}
+
+// Register stores the asset mappings of an exchange and links its trading pairs to their canonical symbols
+func (registry *SymbolRegistry) Register(ctx context.Context, exchangeID uuid.UUID,
+	assets []models.ExchangeAsset) error {
+	for i := range assets {
+		assets[i].ExchangeID = exchangeID
+		assets[i].Asset = strings.ToUpper(assets[i].Asset)
+		if assets[i].ID == uuid.Nil {
+			assets[i].ID = uuid.New()
+		}
+		if assets[i].Scale.IsZero() {
+			assets[i].Scale = decimal.NewFromInt(1)
+		}
+	}
+	if err := registry.Repo.UpsertExchangeAssets(ctx, assets); err != nil {
+		return fmt.Errorf("failed to store exchange assets: %w", err)
+	}
+	if err := registry.Repo.LinkTradingPairs(ctx); err != nil {
+		return fmt.Errorf("failed to link canonical symbols: %w", err)
+	}
+	registry.mu.Lock()
+	delete(registry.assets, exchangeID)
+	registry.mu.Unlock()
+	return nil
+}
+
+// Resolve finds the trading pair of an exchange for a canonical symbol such as BTC/IRT, falling back to the
+// exchange's own symbol so that BTCIRT keeps working on Nobitex
+func (registry *SymbolRegistry) Resolve(ctx context.Context, exchangeID uuid.UUID, symbol string) (
+	*models.TradingPair, error) {
+	if baseAsset, quoteAsset, err := Parse(symbol); err == nil {
+		pair, err := registry.TradingPairRepo.GetByExchangeAndAssets(ctx, exchangeID, baseAsset, quoteAsset)
+		if err == nil {
+			return pair, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	pair, err := registry.TradingPairRepo.GetByExchangeAndSymbol(ctx, exchangeID, strings.ToUpper(symbol))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrUnlistedSymbol, symbol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Assets returns the asset mappings of an exchange, loaded once and kept until the exchange registers again
+func (registry *SymbolRegistry) Assets(ctx context.Context, exchangeID uuid.UUID) (*Assets, error) {
+	registry.mu.RLock()
+	cached, found := registry.assets[exchangeID]
+	registry.mu.RUnlock()
+	if found {
+		return cached, nil
+	}
+	mappings, err := registry.Repo.GetExchangeAssets(ctx, exchangeID)
+	if err != nil {
+		return nil, err
+	}
+	assets := NewAssets(mappings)
+	registry.mu.Lock()
+	if registry.assets == nil {
+		registry.assets = make(map[uuid.UUID]*Assets)
+	}
+	registry.assets[exchangeID] = assets
+	registry.mu.Unlock()
+	return assets, nil
+}
+
+// Assets translates the assets of one exchange, assets without a mapping keep their name and unit
+type Assets struct {
+	byAsset map[string]models.ExchangeAsset
+	byCode  map[string]models.ExchangeAsset
+}
+
+func NewAssets(mappings []models.ExchangeAsset) *Assets {
+	assets := &Assets{
+		byAsset: make(map[string]models.ExchangeAsset, len(mappings)),
+		byCode:  make(map[string]models.ExchangeAsset, len(mappings)),
+	}
+	for _, mapping := range mappings {
+		assets.byAsset[strings.ToUpper(mapping.Asset)] = mapping
+		assets.byCode[strings.ToUpper(mapping.Code)] = mapping
+	}
+	return assets
+}
+
+// Code returns the code the exchange uses for a canonical asset
+func (assets *Assets) Code(asset string) string {
+	if mapping, found := assets.lookupAsset(asset); found {
+		return mapping.Code
+	}
+	return asset
+}
+
+// Asset returns the canonical asset of an exchange code
+func (assets *Assets) Asset(code string) string {
+	if assets != nil {
+		if mapping, found := assets.byCode[strings.ToUpper(code)]; found {
+			return mapping.Asset
+		}
+	}
+	return strings.ToUpper(code)
+}
+
+// ToExchange converts an amount of a canonical asset to the unit the exchange counts it in
+func (assets *Assets) ToExchange(asset string, amount decimal.Decimal) decimal.Decimal {
+	if mapping, found := assets.lookupAsset(asset); found && !mapping.Scale.IsZero() {
+		return amount.Mul(mapping.Scale)
+	}
+	return amount
+}
+
+// FromExchange converts an amount the exchange reports for a canonical asset back to the canonical unit
+func (assets *Assets) FromExchange(asset string, amount decimal.Decimal) decimal.Decimal {
+	if mapping, found := assets.lookupAsset(asset); found && !mapping.Scale.IsZero() {
+		return amount.Div(mapping.Scale)
+	}
+	return amount
+}
+
+func (assets *Assets) lookupAsset(asset string) (models.ExchangeAsset, bool) {
+	if assets == nil {
+		return models.ExchangeAsset{}, false
+	}
+	mapping, found := assets.byAsset[strings.ToUpper(asset)]
+	return mapping, found
+}
@@ -0,0 +1,74 @@
+package symbols
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ISymbolRepository interface {
+	List(ctx context.Context) ([]models.CanonicalSymbol, error)
+	GetExchangeAssets(ctx context.Context, exchangeID uuid.UUID) ([]models.ExchangeAsset, error)
+	UpsertExchangeAssets(ctx context.Context, assets []models.ExchangeAsset) error
+	LinkTradingPairs(ctx context.Context) error
+}
+
+type SymbolRepository struct {
+	db *gorm.DB
+}
+
+func NewSymbolRepository(db *gorm.DB) *SymbolRepository {
+	return &SymbolRepository{db: db}
+}
+
+// List returns every canonical symbol with the active trading pairs listing it and their exchange
+func (r *SymbolRepository) List(ctx context.Context) ([]models.CanonicalSymbol, error) {
+	var canonicalSymbols []models.CanonicalSymbol
+	err := r.db.WithContext(ctx).
+		Preload("TradingPairs", "is_active = ?", true).
+		Preload("TradingPairs.Exchange").
+		Order("symbol").
+		Find(&canonicalSymbols).Error
+	return canonicalSymbols, err
+}
+
+func (r *SymbolRepository) GetExchangeAssets(ctx context.Context, exchangeID uuid.UUID) (
+	[]models.ExchangeAsset, error) {
+	var assets []models.ExchangeAsset
+	err := r.db.WithContext(ctx).Where("exchange_id = ?", exchangeID).Find(&assets).Error
+	return assets, err
+}
+
+// UpsertExchangeAssets stores the asset mappings, replacing the code and scale of mappings that already exist
+func (r *SymbolRepository) UpsertExchangeAssets(ctx context.Context, assets []models.ExchangeAsset) error {
+	if len(assets) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit("Exchange").
+		Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "exchange_id"}, {Name: "asset"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+			DoUpdates:   clause.AssignmentColumns([]string{"code", "scale", "updated_at"}),
+		}).
+		Create(&assets).Error
+}
+
+// LinkTradingPairs creates the canonical symbols of trading pairs that are not linked yet and links them
+func (r *SymbolRepository) LinkTradingPairs(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO canonical_symbols (symbol, base_asset, quote_asset)
+			SELECT DISTINCT upper(base_asset) || '/' || upper(quote_asset), upper(base_asset), upper(quote_asset)
+			FROM trading_pairs WHERE canonical_symbol_id IS NULL AND deleted_at IS NULL
+			ON CONFLICT DO NOTHING`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE trading_pairs SET canonical_symbol_id = canonical_symbols.id
+			FROM canonical_symbols
+			WHERE trading_pairs.canonical_symbol_id IS NULL AND trading_pairs.deleted_at IS NULL
+			AND canonical_symbols.deleted_at IS NULL
+			AND canonical_symbols.symbol = upper(trading_pairs.base_asset) || '/' || upper(trading_pairs.quote_asset)`).Error
+	})
+}
@@ -0,0 +1,29 @@
+package symbols
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidSymbol  = errors.New("invalid symbol")
+	ErrUnlistedSymbol = errors.New("symbol is not listed on this exchange")
+)
+
+// Canonical writes a market in its exchange independent form, BASE/QUOTE such as BTC/IRT
+func Canonical(baseAsset, quoteAsset string) string {
+	return strings.ToUpper(baseAsset) + "/" + strings.ToUpper(quoteAsset)
+}
+
+// Parse splits a canonical symbol into its upper case assets. BTC_IRT and BTC-IRT are accepted as well since a
+// slash can not be used in url paths
+func Parse(symbol string) (string, string, error) {
+	assets := strings.FieldsFunc(strings.ToUpper(symbol), func(r rune) bool {
+		return r == '_' || r == '-' || r == '/'
+	})
+	if len(assets) != 2 {
+		return "", "", fmt.Errorf("%w %q, expected BASE/QUOTE such as BTC/USDT", ErrInvalidSymbol, symbol)
+	}
+	return assets[0], assets[1], nil
+}
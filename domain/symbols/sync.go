@@ -0,0 +1,209 @@
+package symbols
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rzabhd80/eye-on/domain/exchange"
+	"github.com/rzabhd80/eye-on/domain/exchange/registry"
+	"github.com/rzabhd80/eye-on/domain/traidingPair"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNoRemoteSymbols = errors.New("exchange can not list its markets")
+
+// SyncResult counts the trading pairs one sync of an exchange changed
+type SyncResult struct {
+	Exchange    string
+	Created     int
+	Updated     int
+	Deactivated int
+}
+
+// SymbolSyncer keeps the trading pairs of exchanges with a remote symbol factory in line with the markets they list
+type SymbolSyncer struct {
+	Registry        *registry.ExchangeRegistry
+	ExchangeRepo    *exchange.ExchangeRepository
+	TradingPairRepo *traidingPair.TradingPairRepository
+	Symbols         *SymbolRegistry
+	Interval        time.Duration
+	Logger          *zap.Logger
+}
+
+// Run syncs every exchange right away and then every Interval until ctx is cancelled
+func (syncer *SymbolSyncer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(syncer.Interval)
+	defer ticker.Stop()
+	for {
+		syncer.SyncLogged(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncLogged syncs every exchange once and logs the outcome instead of returning it
+func (syncer *SymbolSyncer) SyncLogged(ctx context.Context) {
+	results, err := syncer.SyncAll(ctx)
+	if err != nil {
+		syncer.Logger.Error("failed to sync trading pairs", zap.Error(err))
+	}
+	for _, result := range results {
+		syncer.Logger.Info("synced trading pairs",
+			zap.String("exchange", result.Exchange),
+			zap.Int("created", result.Created),
+			zap.Int("updated", result.Updated),
+			zap.Int("deactivated", result.Deactivated))
+	}
+}
+
+// SyncAll syncs every registered exchange that can list its markets, a failing exchange does not stop the others
+func (syncer *SymbolSyncer) SyncAll(ctx context.Context) ([]SyncResult, error) {
+	names := syncer.Registry.ListSupportedExchanges()
+	sort.Strings(names)
+	var results []SyncResult
+	var failures []error
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		factory, found := syncer.Registry.SymbolFactory(name)
+		if !found {
+			continue
+		}
+		if _, remote := factory.(registry.IRemoteSymbolFactory); !remote {
+			continue
+		}
+		result, err := syncer.SyncExchange(ctx, name)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		results = append(results, *result)
+	}
+	return results, errors.Join(failures...)
+}
+
+// SyncExchange upserts the pairs an exchange lists and deactivates the stored pairs it no longer lists. Filters are
+// converted to canonical units through the exchange asset mappings
+func (syncer *SymbolSyncer) SyncExchange(ctx context.Context, name string) (*SyncResult, error) {
+	factory, found := syncer.Registry.SymbolFactory(name)
+	remoteFactory, remote := factory.(registry.IRemoteSymbolFactory)
+	if !found || !remote {
+		return nil, fmt.Errorf("%w: %s", ErrNoRemoteSymbols, name)
+	}
+	exchangeModel, err := syncer.ExchangeRepo.GetByName(ctx, strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	listedPairs, err := remoteFactory.FetchExchangeSymbols(ctx, exchangeModel)
+	if err != nil {
+		return nil, err
+	}
+	// an empty listing is far more likely a broken response than a delisting of every market
+	if len(listedPairs) == 0 {
+		return nil, fmt.Errorf("%s listed no markets, keeping the stored pairs", exchangeModel.Name)
+	}
+	assets, err := syncer.Symbols.Assets(ctx, exchangeModel.ID)
+	if err != nil {
+		return nil, err
+	}
+	storedPairs, err := syncer.TradingPairRepo.GetByExchange(ctx, exchangeModel.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]*models.TradingPair, len(*storedPairs))
+	for i := range *storedPairs {
+		stored[(*storedPairs)[i].Symbol] = &(*storedPairs)[i]
+	}
+
+	result := &SyncResult{Exchange: exchangeModel.Name}
+	listed := make(map[string]bool, len(listedPairs))
+	for _, listedPair := range listedPairs {
+		listedPair.Symbol = strings.ToUpper(listedPair.Symbol)
+		listedPair.BaseAsset = assets.Asset(listedPair.BaseAsset)
+		listedPair.QuoteAsset = assets.Asset(listedPair.QuoteAsset)
+		listedPair.TickSize = fromExchange(assets, listedPair.QuoteAsset, listedPair.TickSize)
+		listedPair.StepSize = fromExchange(assets, listedPair.BaseAsset, listedPair.StepSize)
+		listedPair.MinQuantity = fromExchange(assets, listedPair.BaseAsset, listedPair.MinQuantity)
+		listedPair.MaxQuantity = fromExchange(assets, listedPair.BaseAsset, listedPair.MaxQuantity)
+		listed[listedPair.Symbol] = true
+
+		storedPair, found := stored[listedPair.Symbol]
+		if !found {
+			listedPair.ID = uuid.New()
+			listedPair.ExchangeID = exchangeModel.ID
+			if err := syncer.TradingPairRepo.Create(ctx, &listedPair); err != nil {
+				return result, fmt.Errorf("failed to create trading pair %s: %w", listedPair.Symbol, err)
+			}
+			result.Created++
+			continue
+		}
+		if !mergeMarketInfo(storedPair, &listedPair) {
+			continue
+		}
+		if err := syncer.TradingPairRepo.UpdateMarketInfo(ctx, storedPair); err != nil {
+			return result, fmt.Errorf("failed to update trading pair %s: %w", storedPair.Symbol, err)
+		}
+		result.Updated++
+	}
+	for _, storedPair := range stored {
+		if listed[storedPair.Symbol] || !storedPair.IsActive {
+			continue
+		}
+		storedPair.IsActive = false
+		if err := syncer.TradingPairRepo.UpdateMarketInfo(ctx, storedPair); err != nil {
+			return result, fmt.Errorf("failed to deactivate trading pair %s: %w", storedPair.Symbol, err)
+		}
+		result.Deactivated++
+	}
+	if result.Created > 0 {
+		if err := syncer.Symbols.Repo.LinkTradingPairs(ctx); err != nil {
+			return result, fmt.Errorf("failed to link canonical symbols: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// mergeMarketInfo copies the listed assets, filters and state onto the stored pair and reports whether it changed.
+// Quantity limits the exchange does not report are left as they are
+func mergeMarketInfo(storedPair, listedPair *models.TradingPair) bool {
+	changed := false
+	if storedPair.BaseAsset != listedPair.BaseAsset || storedPair.QuoteAsset != listedPair.QuoteAsset {
+		storedPair.BaseAsset, storedPair.QuoteAsset = listedPair.BaseAsset, listedPair.QuoteAsset
+		changed = true
+	}
+	if storedPair.IsActive != listedPair.IsActive {
+		storedPair.IsActive = listedPair.IsActive
+		changed = true
+	}
+	for _, filter := range []struct{ stored, listed **decimal.Decimal }{
+		{&storedPair.TickSize, &listedPair.TickSize},
+		{&storedPair.StepSize, &listedPair.StepSize},
+		{&storedPair.MinQuantity, &listedPair.MinQuantity},
+		{&storedPair.MaxQuantity, &listedPair.MaxQuantity},
+	} {
+		if *filter.listed == nil || (*filter.stored != nil && (*filter.stored).Equal(**filter.listed)) {
+			continue
+		}
+		*filter.stored = *filter.listed
+		changed = true
+	}
+	return changed
+}
+
+func fromExchange(assets *Assets, asset string, value *decimal.Decimal) *decimal.Decimal {
+	if value == nil {
+		return nil
+	}
+	converted := assets.FromExchange(asset, *value)
+	return &converted
+}
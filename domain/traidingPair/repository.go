@@ -12,7 +12,11 @@ type ITradingPairRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*models.TradingPair, error)
 	GetByExchangeAndSymbol(ctx context.Context, exchangeID uuid.UUID, symbol string) (*models.TradingPair, error)
 	GetByExchange(ctx context.Context, exchangeID uuid.UUID, activeOnly bool) (*[]models.TradingPair, error)
+	GetByExchangeAndAssets(ctx context.Context, exchangeID uuid.UUID, baseAsset, quoteAsset string) (*models.TradingPair, error)
+	GetByAssets(ctx context.Context, baseAsset, quoteAsset string) ([]models.TradingPair, error)
+	GetSharedMarkets(ctx context.Context) ([]Market, error)
 	Update(ctx context.Context, pair *models.TradingPair) error
+	UpdateMarketInfo(ctx context.Context, pair *models.TradingPair) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetSymbolsList(ctx context.Context, exchangeID uuid.UUID, activeOnly bool, symbols []string) (*[]models.TradingPair, error)
 }
@@ -54,6 +58,50 @@ func (r *TradingPairRepository) GetByExchangeAndSymbol(ctx context.Context, exch
 	return &pair, nil
 }
 
+// GetByExchangeAndAssets finds a pair by its assets, for matching the same market across exchanges with different symbol formats
+func (r *TradingPairRepository) GetByExchangeAndAssets(ctx context.Context, exchangeID uuid.UUID,
+	baseAsset, quoteAsset string) (*models.TradingPair, error) {
+	var pair models.TradingPair
+	err := r.DB.WithContext(ctx).
+		Where("exchange_id = ? AND upper(base_asset) = upper(?) AND upper(quote_asset) = upper(?) AND is_active = ?",
+			exchangeID, baseAsset, quoteAsset, true).
+		First(&pair).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pair, nil
+}
+
+// GetByAssets finds the active pairs of a market on every exchange, with their exchange
+func (r *TradingPairRepository) GetByAssets(ctx context.Context, baseAsset, quoteAsset string) (
+	[]models.TradingPair, error) {
+	var pairs []models.TradingPair
+	err := r.DB.WithContext(ctx).Preload("Exchange").
+		Where("upper(base_asset) = upper(?) AND upper(quote_asset) = upper(?) AND is_active = ?",
+			baseAsset, quoteAsset, true).
+		Find(&pairs).Error
+	return pairs, err
+}
+
+// Market is a base and quote asset pair independent of any exchange
+type Market struct {
+	BaseAsset  string
+	QuoteAsset string
+}
+
+// GetSharedMarkets returns the markets with active pairs on more than one exchange
+func (r *TradingPairRepository) GetSharedMarkets(ctx context.Context) ([]Market, error) {
+	var markets []Market
+	err := r.DB.WithContext(ctx).
+		Raw(`SELECT upper(base_asset) AS base_asset, upper(quote_asset) AS quote_asset FROM trading_pairs
+			WHERE is_active = true AND deleted_at IS NULL
+			GROUP BY upper(base_asset), upper(quote_asset)
+			HAVING count(DISTINCT exchange_id) > 1
+			ORDER BY 1, 2`).
+		Scan(&markets).Error
+	return markets, err
+}
+
 func (r *TradingPairRepository) GetByExchange(ctx context.Context, exchangeID uuid.UUID, activeOnly bool) (*[]models.TradingPair, error) {
 	var pairs *[]models.TradingPair
 	query := r.DB.WithContext(ctx).Where("exchange_id = ?", exchangeID)
@@ -81,6 +129,13 @@ func (r *TradingPairRepository) Update(ctx context.Context, pair *models.Trading
 	return r.DB.WithContext(ctx).Save(pair).Error
 }
 
+// UpdateMarketInfo stores the assets, filters and listing state of a pair as read from its exchange
+func (r *TradingPairRepository) UpdateMarketInfo(ctx context.Context, pair *models.TradingPair) error {
+	return r.DB.WithContext(ctx).Model(pair).
+		Select("base_asset", "quote_asset", "min_quantity", "max_quantity", "step_size", "tick_size", "is_active").
+		Updates(pair).Error
+}
+
 func (r *TradingPairRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.DB.WithContext(ctx).Delete(&models.TradingPair{}, id).Error
 }
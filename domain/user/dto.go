@@ -30,17 +30,21 @@ type ExchangeCredentialRequest struct {
 	AccessKey    string `json:"access_key,omitempty"`
 	RefreshKey   string `json:"refresh_key,omitempty"`
 	IsTestnet    bool   `json:"is_testnet"`
+	IsDefault    bool   `json:"is_default"`
 }
 
+// ExchangeCredentialUpdateRequest changes the fields it carries. Without an id in the path the credential is found
+// by exchange name and label, falling back to the default one, and Label is not changed
 type ExchangeCredentialUpdateRequest struct {
-	ExchangeName string `json:"exchange_name" validate:"required"`
-	Label        string `json:"label" validate:"required"`
-	APIKey       string `json:"api_key" validate:"required"`
+	ExchangeName string `json:"exchange_name,omitempty"`
+	Label        string `json:"label,omitempty"`
+	APIKey       string `json:"api_key,omitempty"`
 	SecretKey    string `json:"secret_key,omitempty"`
 	AccessKey    string `json:"access_key,omitempty"`
 	RefreshToken string `json:"refresh_key,omitempty"`
 	IsActive     string `json:"is_active,omitempty"`
 	IsTestnet    bool   `json:"is_testnet"`
+	IsDefault    *bool  `json:"is_default,omitempty"`
 }
 
 type ExchangeCredentialResponse struct {
@@ -51,6 +55,7 @@ type ExchangeCredentialResponse struct {
 	RefreshKey *string         `json:"refresh_key,omitempty"`
 	IsActive   bool            `json:"is_active"`
 	IsTestnet  bool            `json:"is_testnet"`
+	IsDefault  bool            `json:"is_default"`
 	AccessKey  *string         `json:"access_key,omitempty"`
 	LastUsed   *time.Time      `json:"last_used,omitempty"`
 	Exchange   models.Exchange `json:"exchange,omitempty"`
@@ -60,6 +65,11 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+var ErrCredentialNotFound = &ErrorResponse{Error: "Exchange Credentials Not Found"}
+
+var ErrCredentialHasOpenOrders = &ErrorResponse{
+	Error: "Exchange Credentials still have open orders, cancel them or wait for them to close before deleting"}
+
 type MessageResponse struct {
 	Message string `json:"message"`
 }
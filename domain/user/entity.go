@@ -6,16 +6,19 @@ import (
 	"github.com/google/uuid"
 	"github.com/rzabhd80/eye-on/domain/exchange"
 	"github.com/rzabhd80/eye-on/domain/exchangeCredentials"
+	"github.com/rzabhd80/eye-on/domain/order"
 	"github.com/rzabhd80/eye-on/internal/database/models"
 	envCofig "github.com/rzabhd80/eye-on/internal/envConfig"
 	"github.com/rzabhd80/eye-on/internal/helpers"
 	"strconv"
+	"strings"
 )
 
 type User struct {
 	UserRepo         *UserRepository
 	ExchangeRepo     *exchange.ExchangeRepository
 	ExchangeCredRepo *exchangeCredentials.ExchangeCredentialRepository
+	OrderRepo        *order.OrderRepository
 	JwtParser        *helpers.JWTParser
 	EnvConf          *envCofig.AppConfig
 }
@@ -74,6 +77,32 @@ func (user *User) Login(ctx context.Context, request LoginRequest) (*AuthRespons
 
 }
 
+// ListExchangeCredentials returns every credential of the user, api keys are masked
+func (user *User) ListExchangeCredentials(ctx context.Context, userId uuid.UUID) (
+	[]ExchangeCredentialResponse, *ErrorResponse) {
+	creds, err := user.ExchangeCredRepo.ListByUser(ctx, userId)
+	if err != nil {
+		return nil, &ErrorResponse{Error: "Internal Server Error"}
+	}
+	response := make([]ExchangeCredentialResponse, 0, len(creds))
+	for i := range creds {
+		response = append(response, user.maskedCredentialResponse(&creds[i]))
+	}
+	return response, nil
+}
+
+// GetExchangeCredential returns one credential of the user, its api key is masked
+func (user *User) GetExchangeCredential(ctx context.Context, userId, credentialId uuid.UUID) (
+	*ExchangeCredentialResponse, *ErrorResponse) {
+	cred, err := user.ExchangeCredRepo.GetByUserAndID(ctx, userId, credentialId)
+	if err != nil {
+		return nil, ErrCredentialNotFound
+	}
+	response := user.maskedCredentialResponse(cred)
+	return &response, nil
+}
+
+// CreateExchangeCredential stores another labeled credential, the first one of an exchange becomes its default
 func (user *User) CreateExchangeCredential(ctx context.Context, request ExchangeCredentialRequest, userId uuid.UUID) (
 	*ExchangeCredentialResponse, *ErrorResponse) {
 
@@ -81,9 +110,22 @@ func (user *User) CreateExchangeCredential(ctx context.Context, request Exchange
 	if err != nil || exchangeReg == nil {
 		return nil, &ErrorResponse{Error: "Exchange Not Found "}
 	}
-	existingCredentials, err := user.ExchangeCredRepo.GetByUserAndExchange(ctx, userId, exchangeReg.ID)
-	if err == nil && existingCredentials != nil {
-		return nil, &ErrorResponse{Error: "Exchange Credentials Already Exists "}
+	if request.Label == "" {
+		request.Label = "Default"
+	}
+	existing, err := user.ExchangeCredRepo.ListByUser(ctx, userId)
+	if err != nil {
+		return nil, &ErrorResponse{Error: "Internal Server Error"}
+	}
+	hasDefault := false
+	for _, cred := range existing {
+		if cred.ExchangeID != exchangeReg.ID {
+			continue
+		}
+		if cred.Label == request.Label {
+			return nil, &ErrorResponse{Error: "Exchange Credentials With This Label Already Exist"}
+		}
+		hasDefault = hasDefault || cred.IsDefault
 	}
 	ecryptionKey := user.EnvConf.EncryptionKey
 	encryptedApiKey, err := helpers.EncryptAPIKey(request.APIKey, ecryptionKey)
@@ -106,111 +148,115 @@ func (user *User) CreateExchangeCredential(ctx context.Context, request Exchange
 		}
 	}
 	credential := models.ExchangeCredential{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
 		UserID:     userId,
 		ExchangeID: exchangeReg.ID,
 		Label:      request.Label,
 		APIKey:     encryptedApiKey,
 		SecretKey:  encryptedSecretKey,
+		AccessKey:  encryptedAccKey,
+		RefreshKey: encyptedRefreshKey,
 		IsActive:   true,
 		IsTestnet:  request.IsTestnet,
 	}
-	if request.AccessKey != "" {
-		credential.AccessKey = encryptedAccKey
-	}
-	if request.RefreshKey != "" {
-		credential.RefreshKey = encyptedRefreshKey
-	}
-	err = user.ExchangeCredRepo.Update(ctx, &credential)
-	if err != nil {
+	if err := user.ExchangeCredRepo.Create(ctx, &credential); err != nil {
 		return nil, &ErrorResponse{Error: "Internal Server Error"}
 	}
-	response := &ExchangeCredentialResponse{
-		ID:         credential.ID,
-		ExchangeID: exchangeReg.ID,
-		Label:      request.Label,
-		APIKey:     request.APIKey,
-		IsActive:   true,
-		IsTestnet:  false,
-		LastUsed:   nil,
-		Exchange:   *exchangeReg,
+	if request.IsDefault || !hasDefault {
+		if err := user.ExchangeCredRepo.SetDefault(ctx, &credential); err != nil {
+			return nil, &ErrorResponse{Error: "Internal Server Error"}
+		}
 	}
+	credential.Exchange = *exchangeReg
+	response := newExchangeCredentialResponse(&credential, request.APIKey)
 	if request.RefreshKey != "" {
 		response.RefreshKey = &request.RefreshKey
 	}
 	if request.AccessKey != "" {
 		response.AccessKey = &request.AccessKey
 	}
-	return response, nil
+	return &response, nil
 }
 
-func (user *User) UpdateExchangeCredential(ctx context.Context, request ExchangeCredentialUpdateRequest, userId uuid.UUID) (
-	*ExchangeCredentialResponse, *ErrorResponse) {
-
-	exchangeReg, err := user.ExchangeRepo.GetByName(ctx, request.ExchangeName)
-	if err != nil || exchangeReg == nil {
-		return nil, &ErrorResponse{Error: "Exchange Not Found "}
+// UpdateExchangeCredential changes a credential of the user. A nil credentialId finds it by exchange name and
+// label, a request without a label changes the default credential of the exchange
+func (user *User) UpdateExchangeCredential(ctx context.Context, request ExchangeCredentialUpdateRequest, userId uuid.UUID,
+	credentialId uuid.UUID) (*ExchangeCredentialResponse, *ErrorResponse) {
+	renamed := credentialId != uuid.Nil && request.Label != ""
+	if credentialId == uuid.Nil {
+		exchangeReg, err := user.ExchangeRepo.GetByName(ctx, request.ExchangeName)
+		if err != nil || exchangeReg == nil {
+			return nil, &ErrorResponse{Error: "Exchange Not Found "}
+		}
+		found, err := user.ExchangeCredRepo.GetByUserAndExchange(
+			exchangeCredentials.WithSelector(ctx, exchangeCredentials.Selector{Label: request.Label}), userId, exchangeReg.ID)
+		if err != nil {
+			return nil, ErrCredentialNotFound
+		}
+		credentialId = found.ID
+	}
+	// the stored row keeps its keys encrypted, so saving it back never writes a plain key
+	existingCredentials, err := user.ExchangeCredRepo.GetByUserAndID(ctx, userId, credentialId)
+	if err != nil {
+		return nil, ErrCredentialNotFound
 	}
-	existingCredentials, err := user.ExchangeCredRepo.GetByUserAndExchange(ctx, userId, exchangeReg.ID)
-	if err != nil || existingCredentials == nil {
-		return nil, &ErrorResponse{Error: "Exchange Credentials Not Found "}
+	if renamed && request.Label != existingCredentials.Label {
+		siblings, err := user.ExchangeCredRepo.ListByUser(ctx, userId)
+		if err != nil {
+			return nil, &ErrorResponse{Error: "Internal Server Error"}
+		}
+		for _, sibling := range siblings {
+			if sibling.ExchangeID == existingCredentials.ExchangeID && sibling.Label == request.Label {
+				return nil, &ErrorResponse{Error: "Exchange Credentials With This Label Already Exist"}
+			}
+		}
+		existingCredentials.Label = request.Label
 	}
 	ecryptionKey := user.EnvConf.EncryptionKey
-	encryptedApiKey, err := helpers.EncryptAPIKey(request.APIKey, ecryptionKey)
-	if err != nil {
-		return nil, &ErrorResponse{Error: "Internal Server Error "}
+	if request.APIKey != "" {
+		existingCredentials.APIKey, err = helpers.EncryptAPIKey(request.APIKey, ecryptionKey)
+		if err != nil {
+			return nil, &ErrorResponse{Error: "Internal Server Error "}
+		}
 	}
-	var encryptedSecretKey string
 	if request.SecretKey != "" {
-		encryptedSecretKey = hex.EncodeToString([]byte(request.SecretKey))
+		existingCredentials.SecretKey = hex.EncodeToString([]byte(request.SecretKey))
 	}
-
-	encryptedAccKey := ""
 	if request.AccessKey != "" {
-		encryptedAccKey, err = helpers.EncryptAPIKey(request.AccessKey, ecryptionKey)
+		existingCredentials.AccessKey, err = helpers.EncryptAPIKey(request.AccessKey, ecryptionKey)
 		if err != nil {
 			return nil, &ErrorResponse{Error: "Internal Server Error "}
 		}
 	}
-	var active bool
-	if request.IsActive != "" {
-		active, err = strconv.ParseBool(request.IsActive)
-	}
-	var refreshTokenEnc string
 	if request.RefreshToken != "" {
-		refreshTokenEnc, err = helpers.EncryptAPIKey(request.RefreshToken, ecryptionKey)
+		existingCredentials.RefreshKey, err = helpers.EncryptAPIKey(request.RefreshToken, ecryptionKey)
 		if err != nil {
 			return nil, &ErrorResponse{Error: err.Error()}
 		}
 	}
-	if request.APIKey != "" {
-		existingCredentials.APIKey = encryptedApiKey
-	}
-	if request.AccessKey != "" {
-		existingCredentials.AccessKey = encryptedAccKey
-	}
 	if request.IsActive != "" {
+		active, err := strconv.ParseBool(request.IsActive)
+		if err != nil {
+			return nil, &ErrorResponse{Error: "is_active must be true or false"}
+		}
 		existingCredentials.IsActive = active
 	}
-	if request.SecretKey != "" {
-		existingCredentials.SecretKey = encryptedSecretKey
+	// a credential only becomes the default through SetDefault, which clears the flag on the previous one
+	if request.IsDefault != nil && !*request.IsDefault {
+		existingCredentials.IsDefault = false
 	}
-	if request.RefreshToken != "" {
-		existingCredentials.RefreshKey = refreshTokenEnc
-	}
-	err = user.ExchangeCredRepo.Update(ctx, existingCredentials)
-	if err != nil {
+	if err := user.ExchangeCredRepo.Update(ctx, existingCredentials); err != nil {
 		return nil, &ErrorResponse{Error: "Internal Server Error"}
 	}
+	if request.IsDefault != nil && *request.IsDefault {
+		if err := user.ExchangeCredRepo.SetDefault(ctx, existingCredentials); err != nil {
+			return nil, &ErrorResponse{Error: "Internal Server Error"}
+		}
+	}
 
-	response := &ExchangeCredentialResponse{
-		ID:         existingCredentials.ID,
-		ExchangeID: exchangeReg.ID,
-		Label:      request.Label,
-		APIKey:     request.APIKey,
-		IsActive:   existingCredentials.IsActive,
-		IsTestnet:  false,
-		LastUsed:   nil,
-		Exchange:   *exchangeReg,
+	response := user.maskedCredentialResponse(existingCredentials)
+	if request.APIKey != "" {
+		response.APIKey = request.APIKey
 	}
 	if request.AccessKey != "" {
 		response.AccessKey = &request.AccessKey
@@ -218,5 +264,54 @@ func (user *User) UpdateExchangeCredential(ctx context.Context, request Exchange
 	if request.RefreshToken != "" {
 		response.RefreshKey = &request.RefreshToken
 	}
-	return response, nil
+	return &response, nil
+}
+
+// DeleteExchangeCredential removes a credential of the user, the next one takes over as default. A credential with
+// open orders is kept, the sync worker and cancels still need it to reach those orders
+func (user *User) DeleteExchangeCredential(ctx context.Context, userId, credentialId uuid.UUID) *ErrorResponse {
+	if _, err := user.ExchangeCredRepo.GetByUserAndID(ctx, userId, credentialId); err != nil {
+		return ErrCredentialNotFound
+	}
+	openOrders, err := user.OrderRepo.GetOpenOrders(ctx, userId, credentialId)
+	if err != nil {
+		return &ErrorResponse{Error: "Internal Server Error"}
+	}
+	if len(openOrders) > 0 {
+		return ErrCredentialHasOpenOrders
+	}
+	if err := user.ExchangeCredRepo.Delete(ctx, credentialId); err != nil {
+		return &ErrorResponse{Error: "Internal Server Error"}
+	}
+	return nil
+}
+
+// maskedCredentialResponse describes a stored credential showing only the last characters of its api key
+func (user *User) maskedCredentialResponse(cred *models.ExchangeCredential) ExchangeCredentialResponse {
+	apiKey, err := helpers.DecryptAPIKey(cred.APIKey, user.EnvConf.EncryptionKey)
+	if err != nil {
+		apiKey = ""
+	}
+	return newExchangeCredentialResponse(cred, maskKey(apiKey))
+}
+
+func newExchangeCredentialResponse(cred *models.ExchangeCredential, apiKey string) ExchangeCredentialResponse {
+	return ExchangeCredentialResponse{
+		ID:         cred.ID,
+		ExchangeID: cred.ExchangeID,
+		Label:      cred.Label,
+		APIKey:     apiKey,
+		IsActive:   cred.IsActive,
+		IsTestnet:  cred.IsTestnet,
+		IsDefault:  cred.IsDefault,
+		LastUsed:   cred.LastUsed,
+		Exchange:   cred.Exchange,
+	}
+}
+
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
 }
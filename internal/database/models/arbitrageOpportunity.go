@@ -0,0 +1,31 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// ArbitrageOpportunity is a market whose top of book on one exchange can be bought below what it sells for on
+// another after taker fees. It is detected once and kept up to date while it lasts
+type ArbitrageOpportunity struct {
+	BaseModel
+	Symbol          string          `gorm:"size:20;not null;index:idx_arbitrage_opportunities_symbol_last_seen" json:"symbol"`
+	BaseAsset       string          `gorm:"size:10;not null" json:"base_asset"`
+	QuoteAsset      string          `gorm:"size:10;not null" json:"quote_asset"`
+	BuyExchangeID   uuid.UUID       `gorm:"type:uuid;not null" json:"buy_exchange_id"`
+	SellExchangeID  uuid.UUID       `gorm:"type:uuid;not null" json:"sell_exchange_id"`
+	BuyPrice        decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"buy_price"`
+	SellPrice       decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"sell_price"`
+	Quantity        decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"quantity"`
+	BuyFee          decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"buy_fee"`
+	SellFee         decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"sell_fee"`
+	ProfitRate      decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"profit_rate"`      // net of fees, per unit of quote spent
+	EstimatedProfit decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"estimated_profit"` // in the quote asset for Quantity
+	DetectedAt      time.Time       `gorm:"not null" json:"detected_at"`
+	LastSeenAt      time.Time       `gorm:"not null;index:idx_arbitrage_opportunities_symbol_last_seen" json:"last_seen_at"`
+
+	// Relationships
+	BuyExchange  Exchange `gorm:"foreignKey:BuyExchangeID;constraint:OnDelete:CASCADE" json:"buy_exchange,omitempty"`
+	SellExchange Exchange `gorm:"foreignKey:SellExchangeID;constraint:OnDelete:CASCADE" json:"sell_exchange,omitempty"`
+}
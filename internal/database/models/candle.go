@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Candle is an OHLCV bar of a trading pair. Prices come from the order book mid price and the fills of orders
+// placed through Eye-On, volume and trade count from those fills only
+type Candle struct {
+	BaseModel
+	ExchangeID    uuid.UUID       `gorm:"type:uuid;not null" json:"exchange_id"`
+	TradingPairID uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:ux_candles_pair_interval_open_time" json:"trading_pair_id"`
+	Interval      string          `gorm:"size:3;not null;uniqueIndex:ux_candles_pair_interval_open_time" json:"interval"`
+	OpenTime      time.Time       `gorm:"not null;uniqueIndex:ux_candles_pair_interval_open_time" json:"open_time"`
+	Open          decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"open"`
+	High          decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"high"`
+	Low           decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"low"`
+	Close         decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"close"`
+	Volume        decimal.Decimal `gorm:"type:numeric(30,10);not null;default:0" json:"volume"`
+	TradeCount    int             `gorm:"not null;default:0" json:"trade_count"`
+
+	// Relationships
+	Exchange    Exchange    `gorm:"foreignKey:ExchangeID;constraint:OnDelete:CASCADE" json:"exchange,omitempty"`
+	TradingPair TradingPair `gorm:"foreignKey:TradingPairID;constraint:OnDelete:CASCADE" json:"trading_pair,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CanonicalSymbol is an exchange independent market written as BASE/QUOTE, such as BTC/IRT. The trading pairs
+// of every exchange listing the market link to it
+type CanonicalSymbol struct {
+	BaseModel
+	Symbol     string `gorm:"size:21;not null;uniqueIndex:ux_canonical_symbols_symbol,where:deleted_at IS NULL" json:"symbol"`
+	BaseAsset  string `gorm:"size:10;not null" json:"base_asset"`
+	QuoteAsset string `gorm:"size:10;not null" json:"quote_asset"`
+
+	// Relationships
+	TradingPairs []TradingPair `gorm:"foreignKey:CanonicalSymbolID;constraint:OnDelete:SET NULL" json:"trading_pairs,omitempty"`
+}
+
+// ExchangeAsset maps a canonical asset to the code an exchange uses for it. Amounts on the exchange are the
+// canonical amount multiplied by Scale, Nobitex for instance counts IRT in rials
+type ExchangeAsset struct {
+	BaseModel
+	ExchangeID uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:ux_exchange_assets_exchange_asset,where:deleted_at IS NULL" json:"exchange_id"`
+	Asset      string          `gorm:"size:10;not null;uniqueIndex:ux_exchange_assets_exchange_asset,where:deleted_at IS NULL" json:"asset"`
+	Code       string          `gorm:"size:20;not null" json:"code"`
+	Scale      decimal.Decimal `gorm:"type:numeric(30,10);not null;default:1" json:"scale"`
+
+	// Relationships
+	Exchange Exchange `gorm:"foreignKey:ExchangeID;constraint:OnDelete:CASCADE" json:"exchange,omitempty"`
+}
@@ -15,6 +15,7 @@ type ExchangeCredential struct {
 	RefreshKey string     `gorm:"type:text;" json:"refresh_key"`
 	AccessKey  string     `gorm:"type:text" json:"access_key,omitempty"`
 	IsActive   bool       `gorm:"not null;default:true" json:"is_active"`
+	IsDefault  bool       `gorm:"not null;default:false" json:"is_default"`
 	IsTestnet  bool       `gorm:"not null;default:false" json:"is_testnet"`
 	LastUsed   *time.Time `json:"last_used,omitempty"`
 
@@ -23,6 +24,7 @@ type ExchangeCredential struct {
 	Exchange       Exchange       `gorm:"foreignKey:ExchangeID;constraint:OnDelete:CASCADE" json:"exchange,omitempty"`
 	OrderHistories []OrderHistory `gorm:"foreignKey:ExchangeCredentialID;constraint:OnDelete:CASCADE" json:"order_histories,omitempty"`
 
-	// Unique constraint
+	// Unique constraints, labels per user and exchange and one default credential per user and exchange
 	_ struct{} `gorm:"uniqueIndex:ux_exchange_credentials_user_exchange_label,unique"`
+	_ struct{} `gorm:"uniqueIndex:ux_exchange_credentials_user_exchange_default,unique"`
 }
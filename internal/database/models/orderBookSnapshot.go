@@ -2,6 +2,7 @@ package models
 
 import (
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"time"
 )
 
@@ -13,6 +14,11 @@ type OrderBookSnapshot struct {
 	Bids          JSONB     `gorm:"type:jsonb;not null" json:"bids"` // [[price, qty], ...]
 	Asks          JSONB     `gorm:"type:jsonb;not null" json:"asks"`
 	SnapshotTime  time.Time `gorm:"not null;default:now()" json:"snapshot_time"`
+	// set on deltas, whose bids and asks only hold the levels that differ from this keyframe, a zero quantity
+	// removing the level
+	BaseSnapshotID *uuid.UUID       `gorm:"type:uuid;index" json:"base_snapshot_id,omitempty"`
+	BestBid        *decimal.Decimal `gorm:"type:numeric(30,10)" json:"best_bid,omitempty"`
+	BestAsk        *decimal.Decimal `gorm:"type:numeric(30,10)" json:"best_ask,omitempty"`
 
 	// Relationships
 	Exchange    Exchange    `gorm:"foreignKey:ExchangeID;constraint:OnDelete:CASCADE" json:"exchange,omitempty"`
@@ -1,20 +1,27 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
 
 type OrderHistory struct {
 	BaseModel
-	UserID               uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	ExchangeCredentialID uuid.UUID `gorm:"type:uuid;not null" json:"exchange_credential_id"`
-	ExchangeID           uuid.UUID `gorm:"type:uuid;not null" json:"exchange_id"`
-	TradingPairID        uuid.UUID `gorm:"type:uuid;not null" json:"trading_pair_id"`
-	ClientOrderID        string    `gorm:"size:100;not null;index:idx_order_histories_client_order_id" json:"client_order_id"`
-	ExchangeOrderID      string    `gorm:"size:100;not null;index:idx_order_histories_order_id" json:"exchange_order_id"`
-	Side                 string    `gorm:"size:10;not null" json:"side"` // buy/sell
-	Type                 string    `gorm:"size:10;not null" json:"type"` // limit/market
-	Quantity             float64   `gorm:"type:decimal(20,8);not null" json:"quantity"`
-	Price                *float64  `gorm:"type:decimal(20,8)" json:"price,omitempty"`
-	Status               string    `gorm:"size:20;not null" json:"status"`
+	UserID               uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:ux_order_histories_user_client_order_id,where:deleted_at IS NULL" json:"user_id"`
+	ExchangeCredentialID uuid.UUID        `gorm:"type:uuid;not null" json:"exchange_credential_id"`
+	ExchangeID           uuid.UUID        `gorm:"type:uuid;not null" json:"exchange_id"`
+	TradingPairID        uuid.UUID        `gorm:"type:uuid;not null" json:"trading_pair_id"`
+	ClientOrderID        string           `gorm:"size:100;not null;uniqueIndex:ux_order_histories_user_client_order_id,where:deleted_at IS NULL" json:"client_order_id"`
+	ExchangeOrderID      string           `gorm:"size:100;not null;index:idx_order_histories_order_id" json:"exchange_order_id"`
+	Side                 string           `gorm:"size:10;not null" json:"side"` // buy/sell
+	Type                 string           `gorm:"size:10;not null" json:"type"` // limit/market
+	Quantity             decimal.Decimal  `gorm:"type:numeric(30,10);not null" json:"quantity"`
+	Price                *decimal.Decimal `gorm:"type:numeric(30,10)" json:"price,omitempty"`
+	Status               string           `gorm:"size:20;not null" json:"status"`
+	ExecutedQty          decimal.Decimal  `gorm:"type:numeric(30,10);not null;default:0" json:"executed_qty"`
+	ExecutedPrice        *decimal.Decimal `gorm:"type:numeric(30,10)" json:"executed_price,omitempty"`
+	Commission           decimal.Decimal  `gorm:"type:numeric(30,10);not null;default:0" json:"commission"`
+	ParentOrderID        *uuid.UUID       `gorm:"type:uuid;index:idx_order_histories_parent_order_id" json:"parent_order_id,omitempty"` // routed order this is a child of
 	// Relationships
 	User               User               `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
 	ExchangeCredential ExchangeCredential `gorm:"foreignKey:ExchangeCredentialID;constraint:OnDelete:CASCADE" json:"exchange_credential,omitempty"`
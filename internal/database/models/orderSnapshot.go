@@ -2,17 +2,18 @@ package models
 
 import (
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"time"
 )
 
 type BalanceSnapshot struct {
 	BaseModel
-	UserID       uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	ExchangeID   uuid.UUID `gorm:"type:uuid;not null" json:"exchange_id"`
-	Currency     string    `gorm:"size:10;not null" json:"currency"`
-	Total        float64   `gorm:"type:numeric(30,10);not null" json:"total"`
-	Available    float64   `gorm:"type:numeric(30,10);not null" json:"available"`
-	SnapshotTime time.Time `gorm:"not null;default:now()" json:"snapshot_time"`
+	UserID       uuid.UUID       `gorm:"type:uuid;not null" json:"user_id"`
+	ExchangeID   uuid.UUID       `gorm:"type:uuid;not null" json:"exchange_id"`
+	Currency     string          `gorm:"size:10;not null" json:"currency"`
+	Total        decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"total"`
+	Available    decimal.Decimal `gorm:"type:numeric(30,10);not null" json:"available"`
+	SnapshotTime time.Time       `gorm:"not null;default:now()" json:"snapshot_time"`
 
 	// Relationships
 	User     User     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
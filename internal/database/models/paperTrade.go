@@ -0,0 +1,39 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PaperBalance is a virtual wallet of the papertrade exchange
+type PaperBalance struct {
+	BaseModel
+	UserID    uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:ux_paper_balances_user_currency,where:deleted_at IS NULL" json:"user_id"`
+	Currency  string          `gorm:"size:10;not null;uniqueIndex:ux_paper_balances_user_currency,where:deleted_at IS NULL" json:"currency"`
+	Available decimal.Decimal `gorm:"type:numeric(30,10);not null;default:0" json:"available"`
+	Locked    decimal.Decimal `gorm:"type:numeric(30,10);not null;default:0" json:"locked"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+// PaperOrder is the exchange side record of an order placed on the papertrade exchange
+type PaperOrder struct {
+	BaseModel
+	UserID        uuid.UUID        `gorm:"type:uuid;not null;index:idx_paper_orders_user_status" json:"user_id"`
+	TradingPairID uuid.UUID        `gorm:"type:uuid;not null" json:"trading_pair_id"`
+	ClientOrderID string           `gorm:"size:100;not null" json:"client_order_id"`
+	Side          string           `gorm:"size:10;not null" json:"side"`
+	Type          string           `gorm:"size:10;not null" json:"type"`
+	Quantity      decimal.Decimal  `gorm:"type:numeric(30,10);not null" json:"quantity"`
+	Price         *decimal.Decimal `gorm:"type:numeric(30,10)" json:"price,omitempty"`
+	ExecutedQty   decimal.Decimal  `gorm:"type:numeric(30,10);not null;default:0" json:"executed_qty"`
+	ExecutedQuote decimal.Decimal  `gorm:"type:numeric(30,10);not null;default:0" json:"executed_quote"`
+	Commission    decimal.Decimal  `gorm:"type:numeric(30,10);not null;default:0" json:"commission"`
+	LockedAmount  decimal.Decimal  `gorm:"type:numeric(30,10);not null;default:0" json:"locked_amount"` // funds still held for the open part
+	Status        string           `gorm:"size:20;not null;index:idx_paper_orders_user_status" json:"status"`
+
+	// Relationships
+	User        User        `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	TradingPair TradingPair `gorm:"foreignKey:TradingPairID;constraint:OnDelete:RESTRICT" json:"trading_pair,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// RoutedOrder is an order for a canonical market split by the smart order router into child orders on several
+// exchanges. Its status and executed quantity are derived from the children
+type RoutedOrder struct {
+	BaseModel
+	UserID        uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:ux_routed_orders_user_client_order_id,where:deleted_at IS NULL" json:"user_id"`
+	ClientOrderID string           `gorm:"size:100;not null;uniqueIndex:ux_routed_orders_user_client_order_id,where:deleted_at IS NULL" json:"client_order_id"`
+	Symbol        string           `gorm:"size:20;not null" json:"symbol"`
+	BaseAsset     string           `gorm:"size:10;not null" json:"base_asset"`
+	QuoteAsset    string           `gorm:"size:10;not null" json:"quote_asset"`
+	Side          string           `gorm:"size:10;not null" json:"side"`
+	Type          string           `gorm:"size:10;not null" json:"type"`
+	Quantity      decimal.Decimal  `gorm:"type:numeric(30,10);not null" json:"quantity"`
+	Price         *decimal.Decimal `gorm:"type:numeric(30,10)" json:"price,omitempty"`
+	Status        string           `gorm:"size:20;not null" json:"status"`
+	ExecutedQty   decimal.Decimal  `gorm:"type:numeric(30,10);not null;default:0" json:"executed_qty"`
+
+	// Relationships
+	User        User           `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	ChildOrders []OrderHistory `gorm:"foreignKey:ParentOrderID;constraint:OnDelete:SET NULL" json:"child_orders,omitempty"`
+}
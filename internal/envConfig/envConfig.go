@@ -4,6 +4,7 @@ import (
 	"github.com/caarlos0/env/v10"
 	"github.com/joho/godotenv"
 	"os"
+	"time"
 )
 
 func CheckFileExists(filePath string) bool {
@@ -17,6 +18,14 @@ func CheckFileExists(filePath string) bool {
 type AppConfig struct {
 	DatabaseConfig
 	RedisConfig
+	WorkerConfig
+	PaperTradeConfig
+	ArbitrageConfig
+	MaintenanceConfig
+	CacheConfig
+	RateLimitConfig
+	RetryConfig
+	BitpinConfig
 	AppName       string `env:"APP_NAME" envDefault:"eye on"`
 	AppVersion    string `env:"APP_VERSION" envDefault:"0.0.1"`
 	HOST          string `env:"HOST" envDefault:"0.0.0.0"`
@@ -32,6 +41,68 @@ type RedisConfig struct {
 	RedisDB       int    `env:"REDIS_DB" json:"redis-db"`
 }
 
+type CacheConfig struct {
+	OrderBookCacheTTL             time.Duration `env:"ORDERBOOK_CACHE_TTL" envDefault:"2s"`
+	OrderBookCacheStale           time.Duration `env:"ORDERBOOK_CACHE_STALE" envDefault:"10s"`
+	OrderBookCacheRefreshInterval time.Duration `env:"ORDERBOOK_CACHE_REFRESH_INTERVAL" envDefault:"1s"`
+	OrderBookCacheHotWindow       time.Duration `env:"ORDERBOOK_CACHE_HOT_WINDOW" envDefault:"1m"`
+}
+
+type RateLimitConfig struct {
+	ExchangeRateLimits   map[string]int `env:"EXCHANGE_RATE_LIMITS" envDefault:"bitpin:120,nobitex:120"`
+	CredentialRateLimits map[string]int `env:"CREDENTIAL_RATE_LIMITS" envDefault:"bitpin:60,nobitex:60"`
+	RateLimitMaxWait     time.Duration  `env:"RATE_LIMIT_MAX_WAIT" envDefault:"5s"`
+}
+
+type RetryConfig struct {
+	RetryMaxAttempts        int           `env:"RETRY_MAX_ATTEMPTS" envDefault:"3"`
+	RetryBaseDelay          time.Duration `env:"RETRY_BASE_DELAY" envDefault:"200ms"`
+	RetryMaxDelay           time.Duration `env:"RETRY_MAX_DELAY" envDefault:"2s"`
+	BreakerFailureThreshold int           `env:"BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	BreakerCooldown         time.Duration `env:"BREAKER_COOLDOWN" envDefault:"30s"`
+}
+
+type BitpinConfig struct {
+	BitpinTokenRefreshBefore   time.Duration `env:"BITPIN_TOKEN_REFRESH_BEFORE" envDefault:"2m"`
+	BitpinTokenRefreshInterval time.Duration `env:"BITPIN_TOKEN_REFRESH_INTERVAL" envDefault:"1m"`
+}
+
+type WorkerConfig struct {
+	OrderSyncInterval       time.Duration `env:"ORDER_SYNC_INTERVAL" envDefault:"30s"`
+	OrderReservationTimeout time.Duration `env:"ORDER_RESERVATION_TIMEOUT" envDefault:"5m"`
+	BalanceSnapshotInterval time.Duration `env:"BALANCE_SNAPSHOT_INTERVAL" envDefault:"15m"`
+	OrderBookStreamInterval time.Duration `env:"ORDERBOOK_STREAM_INTERVAL" envDefault:"1s"`
+	SymbolSyncInterval      time.Duration `env:"SYMBOL_SYNC_INTERVAL" envDefault:"6h"`
+	CandleBuildInterval     time.Duration `env:"CANDLE_BUILD_INTERVAL" envDefault:"1m"`
+	CandleBackfill          time.Duration `env:"CANDLE_BACKFILL" envDefault:"24h"`
+}
+
+type PaperTradeConfig struct {
+	PaperTradeSourceExchange  string            `env:"PAPERTRADE_SOURCE_EXCHANGE" envDefault:"nobitex"`
+	PaperTradeFeeRate         string            `env:"PAPERTRADE_FEE_RATE" envDefault:"0.001"`
+	PaperTradeInitialBalances map[string]string `env:"PAPERTRADE_INITIAL_BALANCES" envDefault:"USDT:10000,IRT:1000000000"`
+	PaperTradeMaxBookAge      time.Duration     `env:"PAPERTRADE_MAX_BOOK_AGE" envDefault:"2m"`
+}
+
+type ArbitrageConfig struct {
+	ArbitrageInterval        time.Duration     `env:"ARBITRAGE_INTERVAL" envDefault:"30s"`
+	ArbitrageMinProfitRate   string            `env:"ARBITRAGE_MIN_PROFIT_RATE" envDefault:"0.002"`
+	ArbitrageTakerFees       map[string]string `env:"ARBITRAGE_TAKER_FEES" envDefault:"bitpin:0.002,nobitex:0.0025"`
+	ArbitrageDefaultTakerFee string            `env:"ARBITRAGE_DEFAULT_TAKER_FEE" envDefault:"0.003"`
+}
+
+type MaintenanceConfig struct {
+	MaintenanceInterval       time.Duration `env:"MAINTENANCE_INTERVAL" envDefault:"1h"`
+	OrderBookSnapshotDepth    int           `env:"ORDERBOOK_SNAPSHOT_DEPTH" envDefault:"20"`
+	OrderBookKeyframeInterval int           `env:"ORDERBOOK_KEYFRAME_INTERVAL" envDefault:"30"`
+	OrderBookDownsampleAfter  time.Duration `env:"ORDERBOOK_DOWNSAMPLE_AFTER" envDefault:"48h"`
+	OrderBookDownsampleBucket time.Duration `env:"ORDERBOOK_DOWNSAMPLE_BUCKET" envDefault:"5m"`
+	OrderBookRetention        time.Duration `env:"ORDERBOOK_RETENTION" envDefault:"720h"`
+	BalanceDownsampleAfter    time.Duration `env:"BALANCE_DOWNSAMPLE_AFTER" envDefault:"720h"`
+	BalanceDownsampleBucket   time.Duration `env:"BALANCE_DOWNSAMPLE_BUCKET" envDefault:"24h"`
+	BalanceRetention          time.Duration `env:"BALANCE_RETENTION" envDefault:"0"`
+}
+
 type DatabaseConfig struct {
 	DbHost     string `env:"DB_HOST" envDefault:"postgres"`
 	DbPort     string `env:"DB_PORT" envDefault:"5432"`
@@ -0,0 +1,382 @@
+package exchangefake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BitpinOrder is an order as the fake Bitpin API stores and returns it
+type BitpinOrder struct {
+	ID                int64      `json:"id"`
+	Symbol            string     `json:"symbol"`
+	Type              string     `json:"type"`
+	Side              string     `json:"side"`
+	Price             string     `json:"price"`
+	BaseAmount        string     `json:"base_amount"`
+	QuoteAmount       string     `json:"quote_amount"`
+	Identifier        *string    `json:"identifier"`
+	State             string     `json:"state"`
+	ClosedAt          *time.Time `json:"closed_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	DealedBaseAmount  string     `json:"dealed_base_amount"`
+	DealedQuoteAmount string     `json:"dealed_quote_amount"`
+	ReqToCancel       bool       `json:"req_to_cancel"`
+	Commission        string     `json:"commission"`
+}
+
+// BitpinMarket is a market as the fake Bitpin API lists it
+type BitpinMarket struct {
+	Symbol              string `json:"symbol"`
+	Base                string `json:"base"`
+	Quote               string `json:"quote"`
+	Tradable            bool   `json:"tradable"`
+	PricePrecision      int32  `json:"price_precision"`
+	BaseAmountPrecision int32  `json:"base_amount_precision"`
+	MinBaseAmount       string `json:"min_base_amount,omitempty"`
+	MaxBaseAmount       string `json:"max_base_amount,omitempty"`
+}
+
+type bitpinWallet struct {
+	ID      int    `json:"id"`
+	Asset   string `json:"asset"`
+	Balance string `json:"balance"`
+	Frozen  string `json:"frozen"`
+	Service string `json:"service"`
+}
+
+type book struct {
+	Bids [][]string `json:"bids"`
+	Asks [][]string `json:"asks"`
+}
+
+// BitpinServer fakes the Bitpin REST API: bearer access tokens, token refresh, wallets, markets, order book and orders
+type BitpinServer struct {
+	*httptest.Server
+	recorder
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	tokenCounter int
+	wallets      []bitpinWallet
+	markets      []BitpinMarket
+	books        map[string]book
+	orders       map[int64]*BitpinOrder
+	nextOrderID  int64
+}
+
+// NewBitpinServer starts a fake Bitpin API, close it with Close
+func NewBitpinServer() *BitpinServer {
+	fake := &BitpinServer{
+		accessToken:  "bitpin-access-1",
+		refreshToken: "bitpin-refresh",
+		tokenCounter: 1,
+		books:        make(map[string]book),
+		orders:       make(map[int64]*BitpinOrder),
+		nextOrderID:  1000,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/usr/refresh_token/", fake.handleRefresh)
+	mux.HandleFunc("/api/v1/wlt/wallets/", fake.authorized(fake.handleWallets))
+	mux.HandleFunc("/api/v1/mkt/markets/", fake.handleMarkets)
+	mux.HandleFunc("/api/v1/mth/orderbook/", fake.handleOrderBook)
+	mux.HandleFunc("/api/v1/odr/orders/", fake.authorized(fake.handleOrders))
+	fake.Server = httptest.NewServer(fake.logged(mux))
+	return fake
+}
+
+// AccessToken is the token the fake currently accepts
+func (fake *BitpinServer) AccessToken() string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.accessToken
+}
+
+// RefreshToken is the token the fake accepts on /usr/refresh_token/
+func (fake *BitpinServer) RefreshToken() string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.refreshToken
+}
+
+// ExpireAccessToken rotates the access token so requests carrying the old one get 401
+func (fake *BitpinServer) ExpireAccessToken() {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.rotateAccessToken()
+}
+
+func (fake *BitpinServer) rotateAccessToken() {
+	fake.tokenCounter++
+	fake.accessToken = "bitpin-access-" + strconv.Itoa(fake.tokenCounter)
+}
+
+// SetWallet sets the balance and frozen amount of asset
+func (fake *BitpinServer) SetWallet(asset, balance, frozen string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for i := range fake.wallets {
+		if fake.wallets[i].Asset == asset {
+			fake.wallets[i].Balance, fake.wallets[i].Frozen = balance, frozen
+			return
+		}
+	}
+	fake.wallets = append(fake.wallets, bitpinWallet{
+		ID: len(fake.wallets) + 1, Asset: asset, Balance: balance, Frozen: frozen, Service: "main",
+	})
+}
+
+// SetMarkets sets the markets listed
+func (fake *BitpinServer) SetMarkets(markets ...BitpinMarket) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.markets = markets
+}
+
+// SetOrderBook sets the levels served for symbol, each level is [price, quantity]
+func (fake *BitpinServer) SetOrderBook(symbol string, bids, asks [][]string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.books[symbol] = book{Bids: bids, Asks: asks}
+}
+
+// Order returns a copy of a stored order
+func (fake *BitpinServer) Order(id int64) (BitpinOrder, bool) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	stored, found := fake.orders[id]
+	if !found {
+		return BitpinOrder{}, false
+	}
+	return *stored, true
+}
+
+// Fill marks dealed base amount of an order as executed, closing it once fully dealt
+func (fake *BitpinServer) Fill(id int64, dealedBaseAmount string) error {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	stored, found := fake.orders[id]
+	if !found {
+		return fmt.Errorf("order %d not found", id)
+	}
+	stored.DealedBaseAmount = dealedBaseAmount
+	dealed, _ := strconv.ParseFloat(dealedBaseAmount, 64)
+	total, _ := strconv.ParseFloat(stored.BaseAmount, 64)
+	price, _ := strconv.ParseFloat(stored.Price, 64)
+	stored.DealedQuoteAmount = strconv.FormatFloat(dealed*price, 'f', 8, 64)
+	if dealed >= total {
+		now := time.Now()
+		stored.State = "closed"
+		stored.ClosedAt = &now
+	}
+	return nil
+}
+
+func (fake *BitpinServer) logged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		if fake.record(w, r, body) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized rejects requests that do not carry the current bearer access token, like Bitpin does for expired JWTs
+func (fake *BitpinServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		expected := "Bearer " + fake.accessToken
+		fake.mu.Unlock()
+		if r.Header.Get("Authorization") != expected {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"detail": "Given token not valid for any token type",
+				"code":   "token_not_valid",
+			})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (fake *BitpinServer) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"detail": "Method not allowed."})
+		return
+	}
+	var payload struct {
+		Refresh string `json:"refresh"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"detail": "malformed body"})
+		return
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if payload.Refresh != fake.refreshToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"detail": "Token is invalid or expired",
+			"code": "token_not_valid"})
+		return
+	}
+	fake.rotateAccessToken()
+	writeJSON(w, http.StatusOK, map[string]string{"access": fake.accessToken, "refresh": fake.refreshToken})
+}
+
+func (fake *BitpinServer) handleWallets(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	writeJSON(w, http.StatusOK, fake.wallets)
+}
+
+func (fake *BitpinServer) handleMarkets(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	writeJSON(w, http.StatusOK, fake.markets)
+}
+
+func (fake *BitpinServer) handleOrderBook(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/mth/orderbook/"), "/")
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	levels, found := fake.books[symbol]
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+		return
+	}
+	writeJSON(w, http.StatusOK, levels)
+}
+
+func (fake *BitpinServer) handleOrders(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/odr/orders/"), "/")
+	switch {
+	case rest == "" && r.Method == http.MethodPost:
+		fake.createOrder(w, r)
+	case rest == "" && r.Method == http.MethodGet:
+		fake.listOrders(w, r)
+	case rest != "":
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+			return
+		}
+		fake.orderByID(w, r, id)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"detail": "Method not allowed."})
+	}
+}
+
+func (fake *BitpinServer) createOrder(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Symbol      string  `json:"symbol"`
+		Type        string  `json:"type"`
+		Side        string  `json:"side"`
+		Price       string  `json:"price"`
+		BaseAmount  string  `json:"base_amount"`
+		QuoteAmount string  `json:"quote_amount"`
+		Identifier  *string `json:"identifier"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"detail": "malformed body"})
+		return
+	}
+	fieldErrors := map[string][]string{}
+	if payload.Symbol == "" {
+		fieldErrors["symbol"] = []string{"This field is required."}
+	}
+	if payload.Side != "buy" && payload.Side != "sell" {
+		fieldErrors["side"] = []string{fmt.Sprintf("\"%s\" is not a valid choice.", payload.Side)}
+	}
+	if payload.Type != "limit" && payload.Type != "market" {
+		fieldErrors["type"] = []string{fmt.Sprintf("\"%s\" is not a valid choice.", payload.Type)}
+	}
+	if payload.Type == "limit" && payload.Price == "" {
+		fieldErrors["price"] = []string{"This field is required for limit orders."}
+	}
+	if payload.BaseAmount == "" {
+		fieldErrors["base_amount"] = []string{"This field is required."}
+	}
+	if len(fieldErrors) > 0 {
+		writeJSON(w, http.StatusBadRequest, fieldErrors)
+		return
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if _, found := fake.books[payload.Symbol]; !found && len(fake.books) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string][]string{"symbol": {"Invalid symbol."}})
+		return
+	}
+	fake.nextOrderID++
+	created := &BitpinOrder{
+		ID:                fake.nextOrderID,
+		Symbol:            payload.Symbol,
+		Type:              payload.Type,
+		Side:              payload.Side,
+		Price:             payload.Price,
+		BaseAmount:        payload.BaseAmount,
+		QuoteAmount:       payload.QuoteAmount,
+		Identifier:        payload.Identifier,
+		State:             "active",
+		CreatedAt:         time.Now(),
+		DealedBaseAmount:  "0",
+		DealedQuoteAmount: "0",
+		Commission:        "0",
+	}
+	fake.orders[created.ID] = created
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (fake *BitpinServer) listOrders(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	symbol := r.URL.Query().Get("symbol")
+	identifier := r.URL.Query().Get("identifier")
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	orders := make([]BitpinOrder, 0, len(fake.orders))
+	for _, stored := range fake.orders {
+		if state != "" && stored.State != state {
+			continue
+		}
+		if symbol != "" && stored.Symbol != symbol {
+			continue
+		}
+		if identifier != "" && (stored.Identifier == nil || *stored.Identifier != identifier) {
+			continue
+		}
+		orders = append(orders, *stored)
+	}
+	writeJSON(w, http.StatusOK, orders)
+}
+
+func (fake *BitpinServer) orderByID(w http.ResponseWriter, r *http.Request, id int64) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	stored, found := fake.orders[id]
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, stored)
+	case http.MethodDelete:
+		if stored.State != "active" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"detail": "Order is not active."})
+			return
+		}
+		now := time.Now()
+		stored.State = "canceled"
+		stored.ReqToCancel = true
+		stored.ClosedAt = &now
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"detail": "Method not allowed."})
+	}
+}
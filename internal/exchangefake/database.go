@@ -0,0 +1,60 @@
+package exchangefake
+
+import (
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"testing"
+)
+
+// postgresDefaults rewrites the postgres column defaults of the models into their sqlite equivalents
+var postgresDefaults = strings.NewReplacer(
+	"gen_random_uuid()", "(lower(hex(randomblob(16))))",
+	"now()", "CURRENT_TIMESTAMP",
+)
+
+// NewDatabase opens an in-memory sqlite database holding the tables the exchange adapters use. It is closed when
+// the test ends
+func NewDatabase(t testing.TB) *gorm.DB {
+	t.Helper()
+	gormDb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDb, err := gormDb.DB()
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// every connection to :memory: is a database of its own
+	sqlDb.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDb.Close() })
+
+	err = gormDb.Callback().Raw().Before("gorm:raw").Register("exchangefake:sqlite_defaults", func(tx *gorm.DB) {
+		rewritten := postgresDefaults.Replace(tx.Statement.SQL.String())
+		tx.Statement.SQL.Reset()
+		tx.Statement.SQL.WriteString(rewritten)
+	})
+	if err != nil {
+		t.Fatalf("register sqlite callback: %v", err)
+	}
+	err = gormDb.AutoMigrate(
+		&models.User{},
+		&models.Exchange{},
+		&models.CanonicalSymbol{},
+		&models.TradingPair{},
+		&models.ExchangeAsset{},
+		&models.ExchangeCredential{},
+		&models.OrderHistory{},
+		&models.OrderEvent{},
+		&models.OrderBookSnapshot{},
+		&models.BalanceSnapshot{},
+		&models.PaperBalance{},
+		&models.PaperOrder{},
+	)
+	if err != nil {
+		t.Fatalf("migrate sqlite: %v", err)
+	}
+	return gormDb
+}
@@ -0,0 +1,94 @@
+// Package exchangefake serves in-process fakes of the exchange HTTP APIs the adapters talk to,
+// so the adapters can be driven end to end without reaching a production exchange.
+package exchangefake
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Fault is a canned response returned instead of the normal handler
+type Fault struct {
+	Status int
+	Body   string
+}
+
+// RecordedRequest is a request the fake received, kept for assertions
+type RecordedRequest struct {
+	Method        string
+	Path          string
+	Authorization string
+	Body          string
+}
+
+// recorder keeps the request log and the queued faults shared by every fake
+type recorder struct {
+	mu       sync.Mutex
+	requests []RecordedRequest
+	faults   map[string][]Fault
+}
+
+func faultKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// FailNext makes the next call to method path answer with status and body instead of being handled
+func (rec *recorder) FailNext(method, path string, status int, body string) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.faults == nil {
+		rec.faults = make(map[string][]Fault)
+	}
+	key := faultKey(method, path)
+	rec.faults[key] = append(rec.faults[key], Fault{Status: status, Body: body})
+}
+
+// Requests returns the requests received so far
+func (rec *recorder) Requests() []RecordedRequest {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]RecordedRequest(nil), rec.requests...)
+}
+
+// Count returns how many times method path was called
+func (rec *recorder) Count(method, path string) int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	count := 0
+	for _, request := range rec.requests {
+		if request.Method == strings.ToUpper(method) && request.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+// record logs the request and writes a queued fault for it when there is one
+func (rec *recorder) record(w http.ResponseWriter, r *http.Request, body []byte) bool {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, RecordedRequest{
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Authorization: r.Header.Get("Authorization"),
+		Body:          string(body),
+	})
+	key := faultKey(r.Method, r.URL.Path)
+	queued := rec.faults[key]
+	if len(queued) == 0 {
+		return false
+	}
+	rec.faults[key] = queued[1:]
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(queued[0].Status)
+	_, _ = w.Write([]byte(queued[0].Body))
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,414 @@
+package exchangefake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NobitexOrder is an order as the fake Nobitex API stores and returns it
+type NobitexOrder struct {
+	Type            string    `json:"type"`
+	Execution       string    `json:"execution"`
+	SrcCurrency     string    `json:"srcCurrency"`
+	DstCurrency     string    `json:"dstCurrency"`
+	Price           string    `json:"price"`
+	Amount          string    `json:"amount"`
+	TotalPrice      string    `json:"totalPrice"`
+	TotalOrderPrice string    `json:"totalOrderPrice"`
+	MatchedAmount   string    `json:"matchedAmount"`
+	UnmatchedAmount string    `json:"unmatchedAmount"`
+	ClientOrderID   string    `json:"clientOrderId"`
+	IsMyOrder       bool      `json:"isMyOrder"`
+	ID              int64     `json:"id"`
+	Status          string    `json:"status"`
+	Partial         bool      `json:"partial"`
+	Fee             string    `json:"fee"`
+	CreatedAt       time.Time `json:"created_at"`
+	Market          string    `json:"market"`
+	AveragePrice    string    `json:"averagePrice"`
+}
+
+// NobitexMarket is the listing of a market in the fake Nobitex options, amounts are in the base currency
+type NobitexMarket struct {
+	AmountPrecision string
+	PricePrecision  string
+	MinOrderAmount  string
+	MaxOrderAmount  string
+}
+
+// NobitexServer fakes the Nobitex REST API: token auth, options, wallet balances, order book and orders
+type NobitexServer struct {
+	*httptest.Server
+	recorder
+
+	mu          sync.Mutex
+	apiKey      string
+	balances    map[string]string
+	markets     map[string]NobitexMarket
+	books       map[string]book
+	orders      map[int64]*NobitexOrder
+	nextOrderID int64
+}
+
+// NewNobitexServer starts a fake Nobitex API accepting "Token apiKey", close it with Close
+func NewNobitexServer(apiKey string) *NobitexServer {
+	fake := &NobitexServer{
+		apiKey:      apiKey,
+		balances:    make(map[string]string),
+		markets:     make(map[string]NobitexMarket),
+		books:       make(map[string]book),
+		orders:      make(map[int64]*NobitexOrder),
+		nextOrderID: 5000,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/options", fake.handleOptions)
+	mux.HandleFunc("/v3/orderbook/", fake.handleOrderBook)
+	mux.HandleFunc("/users/wallets/balance", fake.authorized(fake.handleBalance))
+	mux.HandleFunc("/users/wallets/list", fake.authorized(fake.handleWallets))
+	mux.HandleFunc("/market/orders/add", fake.authorized(fake.handleAdd))
+	mux.HandleFunc("/market/orders/update-status", fake.authorized(fake.handleUpdateStatus))
+	mux.HandleFunc("/market/orders/cancel-old", fake.authorized(fake.handleCancelOld))
+	mux.HandleFunc("/market/orders/status", fake.authorized(fake.handleStatus))
+	mux.HandleFunc("/market/orders/list", fake.authorized(fake.handleList))
+	fake.Server = httptest.NewServer(fake.logged(mux))
+	return fake
+}
+
+// SetBalance sets the wallet balance of currency, e.g. "usdt"
+func (fake *NobitexServer) SetBalance(currency, balance string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.balances[strings.ToLower(currency)] = balance
+}
+
+// SetMarket lists the market symbol (e.g. "BTCUSDT") in the options
+func (fake *NobitexServer) SetMarket(symbol string, market NobitexMarket) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.markets[strings.ToUpper(symbol)] = market
+}
+
+// SetOrderBook sets the levels served for symbol (e.g. "BTCUSDT"), each level is [price, quantity]
+func (fake *NobitexServer) SetOrderBook(symbol string, bids, asks [][]string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.books[strings.ToUpper(symbol)] = book{Bids: bids, Asks: asks}
+}
+
+// Order returns a copy of a stored order
+func (fake *NobitexServer) Order(id int64) (NobitexOrder, bool) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	stored, found := fake.orders[id]
+	if !found {
+		return NobitexOrder{}, false
+	}
+	return *stored, true
+}
+
+// Fill marks matchedAmount of an order as executed, setting it to Done once fully matched
+func (fake *NobitexServer) Fill(id int64, matchedAmount string) error {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	stored, found := fake.orders[id]
+	if !found {
+		return fmt.Errorf("order %d not found", id)
+	}
+	matched, _ := strconv.ParseFloat(matchedAmount, 64)
+	total, _ := strconv.ParseFloat(stored.Amount, 64)
+	stored.MatchedAmount = matchedAmount
+	stored.UnmatchedAmount = strconv.FormatFloat(total-matched, 'f', 8, 64)
+	stored.AveragePrice = stored.Price
+	stored.Partial = matched > 0 && matched < total
+	if matched >= total {
+		stored.Status = "Done"
+	}
+	return nil
+}
+
+func (fake *NobitexServer) logged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		if fake.record(w, r, body) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized rejects requests without the configured "Token <apiKey>" header
+func (fake *NobitexServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token "+fake.apiKey {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"detail": "توکن غیر مجاز"})
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"detail": "Method not allowed."})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func failed(w http.ResponseWriter, code, message string) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "failed", "code": code, "message": message})
+}
+
+func (fake *NobitexServer) handleOptions(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	amountPrecisions, pricePrecisions := map[string]string{}, map[string]string{}
+	minOrderAmounts, maxOrderAmounts := map[string]string{}, map[string]string{}
+	for symbol, market := range fake.markets {
+		amountPrecisions[symbol], pricePrecisions[symbol] = market.AmountPrecision, market.PricePrecision
+		if market.MinOrderAmount != "" {
+			minOrderAmounts[symbol] = market.MinOrderAmount
+		}
+		if market.MaxOrderAmount != "" {
+			maxOrderAmounts[symbol] = market.MaxOrderAmount
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"nobitex": map[string]interface{}{
+			"amountPrecisions": amountPrecisions,
+			"pricePrecisions":  pricePrecisions,
+			"minOrderAmounts":  minOrderAmounts,
+			"maxOrderAmounts":  maxOrderAmounts,
+		},
+	})
+}
+
+func (fake *NobitexServer) handleOrderBook(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v3/orderbook/"), "/"))
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	levels, found := fake.books[symbol]
+	if !found {
+		failed(w, "InvalidSymbol", "symbol "+symbol+" is not supported")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     "ok",
+		"lastUpdate": time.Now().UnixMilli(),
+		"asks":       levels.Asks,
+		"bids":       levels.Bids,
+	})
+}
+
+func (fake *NobitexServer) handleBalance(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Currency string `json:"currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		failed(w, "ParseError", "malformed body")
+		return
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	balance, found := fake.balances[strings.ToLower(payload.Currency)]
+	if !found {
+		balance = "0"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "balance": balance})
+}
+
+func (fake *NobitexServer) handleWallets(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	wallets := make([]map[string]interface{}, 0, len(fake.balances))
+	id := 0
+	for currency, balance := range fake.balances {
+		id++
+		wallets = append(wallets, map[string]interface{}{
+			"id": id, "currency": currency, "balance": balance, "blockedBalance": "0", "activeBalance": balance,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "wallets": wallets})
+}
+
+func (fake *NobitexServer) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Type          string `json:"type"`
+		Execution     string `json:"execution"`
+		SrcCurrency   string `json:"srcCurrency"`
+		DstCurrency   string `json:"dstCurrency"`
+		Amount        string `json:"amount"`
+		Price         string `json:"price"`
+		ClientOrderID string `json:"clientOrderId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		failed(w, "ParseError", "malformed body")
+		return
+	}
+	if payload.Type != "buy" && payload.Type != "sell" {
+		failed(w, "InvalidOrderType", "type must be buy or sell")
+		return
+	}
+	amount, err := strconv.ParseFloat(payload.Amount, 64)
+	if err != nil || amount <= 0 {
+		failed(w, "InvalidAmount", "amount is invalid")
+		return
+	}
+	execution := payload.Execution
+	if execution == "" {
+		execution = "limit"
+		if payload.Price == "" {
+			execution = "market"
+		}
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, stored := range fake.orders {
+		if payload.ClientOrderID != "" && stored.ClientOrderID == payload.ClientOrderID {
+			failed(w, "DuplicateClientOrderId", "clientOrderId is already used")
+			return
+		}
+	}
+	fake.nextOrderID++
+	price := payload.Price
+	if price == "" {
+		price = "0"
+	}
+	created := &NobitexOrder{
+		Type:            payload.Type,
+		Execution:       strings.ToUpper(execution[:1]) + execution[1:],
+		SrcCurrency:     payload.SrcCurrency,
+		DstCurrency:     payload.DstCurrency,
+		Price:           price,
+		Amount:          payload.Amount,
+		TotalPrice:      "0",
+		TotalOrderPrice: "0",
+		MatchedAmount:   "0",
+		UnmatchedAmount: payload.Amount,
+		ClientOrderID:   payload.ClientOrderID,
+		IsMyOrder:       true,
+		ID:              fake.nextOrderID,
+		Status:          "Active",
+		Fee:             "0",
+		CreatedAt:       time.Now(),
+		Market:          strings.ToUpper(payload.SrcCurrency) + "-" + strings.ToUpper(payload.DstCurrency),
+		AveragePrice:    "0",
+	}
+	fake.orders[created.ID] = created
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "order": created})
+}
+
+func (fake *NobitexServer) handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Order  int64  `json:"order"`
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		failed(w, "ParseError", "malformed body")
+		return
+	}
+	if payload.Status != "canceled" {
+		failed(w, "InvalidStatus", "status must be canceled")
+		return
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	stored, found := fake.orders[payload.Order]
+	if !found {
+		failed(w, "NotFound", "order not found")
+		return
+	}
+	if stored.Status != "Active" {
+		failed(w, "InvalidOrderStatus", "order is "+stored.Status)
+		return
+	}
+	stored.Status = "Canceled"
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "updatedStatus": "Canceled"})
+}
+
+func (fake *NobitexServer) handleCancelOld(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Execution   string  `json:"execution"`
+		SrcCurrency string  `json:"srcCurrency"`
+		DstCurrency string  `json:"dstCurrency"`
+		Hours       float64 `json:"hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		failed(w, "ParseError", "malformed body")
+		return
+	}
+	cutoff := time.Now().Add(-time.Duration(payload.Hours * float64(time.Hour)))
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, stored := range fake.orders {
+		if stored.Status != "Active" || !strings.EqualFold(stored.SrcCurrency, payload.SrcCurrency) ||
+			!strings.EqualFold(stored.DstCurrency, payload.DstCurrency) {
+			continue
+		}
+		if payload.Execution != "" && !strings.EqualFold(stored.Execution, payload.Execution) {
+			continue
+		}
+		if payload.Hours > 0 && stored.CreatedAt.After(cutoff) {
+			continue
+		}
+		stored.Status = "Canceled"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "updatedStatus": "Canceled"})
+}
+
+func (fake *NobitexServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ID            int64  `json:"id"`
+		ClientOrderID string `json:"clientOrderId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		failed(w, "ParseError", "malformed body")
+		return
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	stored, found := fake.orders[payload.ID]
+	for _, candidate := range fake.orders {
+		if payload.ClientOrderID != "" && candidate.ClientOrderID == payload.ClientOrderID {
+			stored, found = candidate, true
+		}
+	}
+	if !found {
+		failed(w, "NotFound", "order not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "order": stored})
+}
+
+func (fake *NobitexServer) handleList(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Status      string `json:"status"`
+		SrcCurrency string `json:"srcCurrency"`
+		DstCurrency string `json:"dstCurrency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		failed(w, "ParseError", "malformed body")
+		return
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	orders := make([]NobitexOrder, 0, len(fake.orders))
+	for _, stored := range fake.orders {
+		if payload.Status == "open" && stored.Status != "Active" {
+			continue
+		}
+		if payload.SrcCurrency != "" && !strings.EqualFold(stored.SrcCurrency, payload.SrcCurrency) {
+			continue
+		}
+		if payload.DstCurrency != "" && !strings.EqualFold(stored.DstCurrency, payload.DstCurrency) {
+			continue
+		}
+		orders = append(orders, *stored)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "orders": orders})
+}
@@ -0,0 +1,149 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("exchange is unavailable")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type probeKey struct{}
+
+// CircuitBreakers keeps one breaker per exchange base url. A breaker opens after Threshold calls in a row failed
+// with a 5xx or a transport error and rejects calls for Cooldown. The first call after that runs the probe of the
+// exchange, or goes through as the probe when there is none, and closes the breaker again when it succeeds.
+// A Threshold of 0 disables the breakers
+type CircuitBreakers struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+type circuitBreaker struct {
+	name     string
+	probe    func(ctx context.Context) error
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+// SetProbe names the exchange behind baseURL and sets the health check run while its breaker is half open
+func (breakers *CircuitBreakers) SetProbe(baseURL, name string, probe func(ctx context.Context) error) {
+	breaker := breakers.get(baseURL)
+	if breaker == nil {
+		return
+	}
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.name, breaker.probe = name, probe
+}
+
+func (breakers *CircuitBreakers) get(baseURL string) *circuitBreaker {
+	if breakers == nil || breakers.Threshold <= 0 {
+		return nil
+	}
+	breakers.mu.Lock()
+	defer breakers.mu.Unlock()
+	if breakers.breakers == nil {
+		breakers.breakers = make(map[string]*circuitBreaker)
+	}
+	breaker, found := breakers.breakers[baseURL]
+	if !found {
+		breaker = &circuitBreaker{name: baseURL}
+		breakers.breakers[baseURL] = breaker
+	}
+	return breaker
+}
+
+// allow fails with ErrCircuitOpen while the breaker is open. Calls made by the probe itself always go through.
+// probing is true when the call goes through as the probe of an exchange without one, its outcome must then be
+// recorded or the probe abandoned
+func (breakers *CircuitBreakers) allow(ctx context.Context, baseURL string) (probing bool, err error) {
+	breaker := breakers.get(baseURL)
+	if breaker == nil || isProbe(ctx) {
+		return false, nil
+	}
+	breaker.mu.Lock()
+	switch breaker.state {
+	case breakerHalfOpen:
+		breaker.mu.Unlock()
+		return false, fmt.Errorf("%w: %s is being probed", ErrCircuitOpen, breaker.name)
+	case breakerOpen:
+		if wait := breakers.Cooldown - time.Since(breaker.openedAt); wait > 0 {
+			breaker.mu.Unlock()
+			return false, fmt.Errorf("%w: %s, retry in %s", ErrCircuitOpen, breaker.name, wait.Round(time.Second))
+		}
+		breaker.state = breakerHalfOpen
+		probe := breaker.probe
+		breaker.mu.Unlock()
+		if probe == nil {
+			// this call is the probe, record decides on its outcome
+			return true, nil
+		}
+		err := probe(context.WithValue(ctx, probeKey{}, true))
+		if errors.Is(err, ErrRateLimited) || ctx.Err() != nil {
+			// the health check never reached the exchange, the next call probes again
+			breakers.abandonProbe(baseURL)
+			return false, err
+		}
+		breakers.record(baseURL, err == nil)
+		if err != nil {
+			return false, fmt.Errorf("%w: %s failed its health check: %w", ErrCircuitOpen, breaker.name, err)
+		}
+		return false, nil
+	default:
+		breaker.mu.Unlock()
+		return false, nil
+	}
+}
+
+// abandonProbe opens the half open breaker again without counting a failure, for a probing call that never reached
+// the exchange. Its cooldown is already over, so the next call probes
+func (breakers *CircuitBreakers) abandonProbe(baseURL string) {
+	breaker := breakers.get(baseURL)
+	if breaker == nil {
+		return
+	}
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if breaker.state == breakerHalfOpen {
+		breaker.state = breakerOpen
+	}
+}
+
+// record counts the outcome of a call, a failure while half open opens the breaker again
+func (breakers *CircuitBreakers) record(baseURL string, success bool) {
+	breaker := breakers.get(baseURL)
+	if breaker == nil {
+		return
+	}
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if success {
+		breaker.state, breaker.failures = breakerClosed, 0
+		return
+	}
+	breaker.failures++
+	if breaker.state == breakerHalfOpen || breaker.failures >= breakers.Threshold {
+		breaker.state, breaker.openedAt = breakerOpen, time.Now()
+	}
+}
+
+func isProbe(ctx context.Context) bool {
+	probe, _ := ctx.Value(probeKey{}).(bool)
+	return probe
+}
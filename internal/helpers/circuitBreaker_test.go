@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const testBaseURL = "https://exchange.test"
+
+// breakerStep is one event applied to a breaker: a call asking to go through, a recorded outcome, an abandoned
+// probe or the cooldown running out
+type breakerStep struct {
+	action      string // allow, success, failure, abandon or cooldown
+	wantProbing bool
+	wantOpen    bool  // allow is rejected with ErrCircuitOpen
+	wantErr     error // allow fails with this error instead
+}
+
+// probeResults returns a probe answering with results in order, then succeeding
+func probeResults(results ...error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if len(results) == 0 {
+			return nil
+		}
+		result := results[0]
+		results = results[1:]
+		return result
+	}
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	allowed := breakerStep{action: "allow"}
+	probing := breakerStep{action: "allow", wantProbing: true}
+	rejected := breakerStep{action: "allow", wantOpen: true}
+	failure := breakerStep{action: "failure"}
+	success := breakerStep{action: "success"}
+	cooldown := breakerStep{action: "cooldown"}
+	abandon := breakerStep{action: "abandon"}
+	limited := breakerStep{action: "allow", wantErr: ErrRateLimited}
+
+	tests := []struct {
+		name      string
+		threshold int
+		probe     func(ctx context.Context) error
+		steps     []breakerStep
+	}{
+		{
+			name:      "failures below the threshold keep it closed",
+			threshold: 3,
+			steps:     []breakerStep{failure, failure, allowed, success, failure, failure, allowed},
+		},
+		{
+			name:      "threshold failures in a row open it until the cooldown",
+			threshold: 2,
+			steps:     []breakerStep{failure, failure, rejected, rejected},
+		},
+		{
+			name:      "without a probe the first call after the cooldown probes and its success closes it",
+			threshold: 1,
+			steps:     []breakerStep{failure, cooldown, probing, rejected, success, allowed},
+		},
+		{
+			name:      "a failed probing call opens it again",
+			threshold: 1,
+			steps:     []breakerStep{failure, cooldown, probing, failure, rejected},
+		},
+		{
+			name:      "an abandoned probe lets the next call probe",
+			threshold: 1,
+			steps:     []breakerStep{failure, cooldown, probing, abandon, probing, success, allowed},
+		},
+		{
+			name:      "a healthy probe closes it before the call",
+			threshold: 1,
+			probe:     func(ctx context.Context) error { return nil },
+			steps:     []breakerStep{failure, cooldown, allowed, allowed},
+		},
+		{
+			name:      "a failing probe keeps it open",
+			threshold: 1,
+			probe:     func(ctx context.Context) error { return errors.New("down") },
+			steps:     []breakerStep{failure, cooldown, rejected, rejected},
+		},
+		{
+			name:      "a probe held back by the rate limit is abandoned",
+			threshold: 1,
+			probe:     probeResults(fmt.Errorf("%w: retry in 1s", ErrRateLimited)),
+			steps:     []breakerStep{failure, cooldown, limited, allowed, allowed},
+		},
+		{
+			name:      "a zero threshold disables it",
+			threshold: 0,
+			steps:     []breakerStep{failure, failure, failure, allowed},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breakers := &CircuitBreakers{Threshold: test.threshold, Cooldown: time.Hour}
+			if test.probe != nil {
+				breakers.SetProbe(testBaseURL, "exchange", test.probe)
+			}
+			for i, step := range test.steps {
+				switch step.action {
+				case "allow":
+					probing, err := breakers.allow(context.Background(), testBaseURL)
+					if gotOpen := errors.Is(err, ErrCircuitOpen); gotOpen != step.wantOpen {
+						t.Fatalf("step %d: got error %v, want open %v", i, err, step.wantOpen)
+					}
+					if step.wantErr != nil && !errors.Is(err, step.wantErr) {
+						t.Fatalf("step %d: got error %v, want %v", i, err, step.wantErr)
+					}
+					if probing != step.wantProbing {
+						t.Fatalf("step %d: got probing %v, want %v", i, probing, step.wantProbing)
+					}
+				case "success", "failure":
+					breakers.record(testBaseURL, step.action == "success")
+				case "abandon":
+					breakers.abandonProbe(testBaseURL)
+				case "cooldown":
+					if breaker := breakers.get(testBaseURL); breaker != nil {
+						breaker.mu.Lock()
+						breaker.openedAt = breaker.openedAt.Add(-breakers.Cooldown)
+						breaker.mu.Unlock()
+					}
+				}
+			}
+		})
+	}
+}
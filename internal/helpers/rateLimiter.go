@@ -0,0 +1,195 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("rate limit exhausted")
+
+// burstWindow is how many seconds' worth of budget a bucket holds, exchanges ban keys that spend a minute of budget
+// at once
+const burstWindow = 5 * time.Second
+
+// redisRetryAfter is how long buckets stay local after Redis fails, so a Redis outage does not slow every call
+const redisRetryAfter = 10 * time.Second
+
+// RateLimit is the outbound request budget of one exchange, per minute across every instance and per credential.
+// A budget of 0 is not enforced
+type RateLimit struct {
+	Exchange            string
+	PerMinute           int
+	PerCredentialMinute int
+}
+
+// takeTokens refills every bucket in KEYS from the Redis clock and takes a token from all of them, or from none and
+// returns how many milliseconds to wait for one. ARGV holds the capacity and refill rate per millisecond of each key
+var takeTokens = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local buckets = {}
+local wait = 0
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 2 - 1])
+	local rate = tonumber(ARGV[i * 2])
+	local state = redis.call('HMGET', key, 'tokens', 'at')
+	local tokens = tonumber(state[1]) or capacity
+	local at = tonumber(state[2]) or now
+	tokens = math.min(capacity, tokens + math.max(0, now - at) * rate)
+	if tokens < 1 then
+		wait = math.max(wait, math.ceil((1 - tokens) / rate))
+	end
+	buckets[i] = {key, tokens, capacity, rate}
+end
+for _, bucket in ipairs(buckets) do
+	local tokens = bucket[2]
+	if wait == 0 then
+		tokens = tokens - 1
+	end
+	redis.call('HSET', bucket[1], 'tokens', tostring(tokens), 'at', now)
+	redis.call('PEXPIRE', bucket[1], math.ceil(bucket[3] / bucket[4]) + 1000)
+end
+return wait
+`)
+
+// RateLimiter hands out request tokens from buckets shared through Redis, or from buckets of this process when
+// there is no Redis or it fails. Calls wait up to MaxWait for a token and are rejected after that
+type RateLimiter struct {
+	Client  *redis.Client
+	MaxWait time.Duration
+
+	mu          sync.Mutex
+	local       map[string]*localBucket
+	limits      map[string]RateLimit // by base url
+	redisFailed time.Time
+}
+
+type localBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+type bucket struct {
+	key      string
+	capacity float64
+	rate     float64 // tokens per millisecond
+}
+
+func NewRateLimiter(client *redis.Client, maxWait time.Duration) *RateLimiter {
+	return &RateLimiter{Client: client, MaxWait: maxWait}
+}
+
+// SetLimit applies a budget to every request sent to baseURL
+func (limiter *RateLimiter) SetLimit(baseURL string, limit RateLimit) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if limiter.limits == nil {
+		limiter.limits = make(map[string]RateLimit)
+	}
+	limiter.limits[baseURL] = limit
+}
+
+// Wait takes a token for a request to baseURL, made with credentialID when it is not empty, waiting while the
+// budget refills. It fails with ErrRateLimited when no token frees up within MaxWait or before ctx is done
+func (limiter *RateLimiter) Wait(ctx context.Context, baseURL, credentialID string) error {
+	if limiter == nil {
+		return nil
+	}
+	limiter.mu.Lock()
+	limit, found := limiter.limits[baseURL]
+	limiter.mu.Unlock()
+	if !found {
+		return nil
+	}
+	var buckets []bucket
+	if limit.PerMinute > 0 {
+		buckets = append(buckets, newBucket("ratelimit:exchange:"+limit.Exchange, limit.PerMinute))
+	}
+	if limit.PerCredentialMinute > 0 && credentialID != "" {
+		buckets = append(buckets, newBucket("ratelimit:credential:"+credentialID, limit.PerCredentialMinute))
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(limiter.MaxWait)
+	for {
+		wait := limiter.take(ctx, buckets)
+		if wait <= 0 {
+			return nil
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("%w for %s, retry in %s", ErrRateLimited, limit.Exchange, wait.Round(time.Millisecond))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w for %s: %w", ErrRateLimited, limit.Exchange, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func newBucket(key string, perMinute int) bucket {
+	rate := float64(perMinute) / float64(time.Minute.Milliseconds())
+	return bucket{key: key, capacity: math.Max(1, math.Floor(rate*float64(burstWindow.Milliseconds()))), rate: rate}
+}
+
+// take takes a token from every bucket or returns how long to wait for one
+func (limiter *RateLimiter) take(ctx context.Context, buckets []bucket) time.Duration {
+	limiter.mu.Lock()
+	useRedis := limiter.Client != nil && time.Since(limiter.redisFailed) > redisRetryAfter
+	limiter.mu.Unlock()
+	if useRedis {
+		keys := make([]string, 0, len(buckets))
+		args := make([]interface{}, 0, len(buckets)*2)
+		for _, b := range buckets {
+			keys = append(keys, b.key)
+			args = append(args, b.capacity, b.rate)
+		}
+		wait, err := takeTokens.Run(ctx, limiter.Client, keys, args...).Int64()
+		if err == nil {
+			return time.Duration(wait) * time.Millisecond
+		}
+		limiter.mu.Lock()
+		limiter.redisFailed = time.Now()
+		limiter.mu.Unlock()
+	}
+	return limiter.takeLocal(buckets)
+}
+
+// takeLocal is take on buckets of this process only
+func (limiter *RateLimiter) takeLocal(buckets []bucket) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if limiter.local == nil {
+		limiter.local = make(map[string]*localBucket)
+	}
+	now := time.Now()
+	var wait float64
+	for _, b := range buckets {
+		state, found := limiter.local[b.key]
+		if !found {
+			state = &localBucket{tokens: b.capacity, at: now}
+			limiter.local[b.key] = state
+		}
+		state.tokens = math.Min(b.capacity, state.tokens+float64(now.Sub(state.at))/float64(time.Millisecond)*b.rate)
+		state.at = now
+		if state.tokens < 1 {
+			wait = math.Max(wait, math.Ceil((1-state.tokens)/b.rate))
+		}
+	}
+	if wait > 0 {
+		return time.Duration(wait) * time.Millisecond
+	}
+	for _, b := range buckets {
+		limiter.local[b.key].tokens--
+	}
+	return 0
+}
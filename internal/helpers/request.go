@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/rzabhd80/eye-on/domain/order"
 	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
 	"io"
 	"net/http"
 	"strconv"
@@ -24,7 +25,9 @@ const (
 
 type Request struct {
 	client           *http.Client
-	rateLimiter      chan struct{}
+	Limiter          *RateLimiter // local to this process until it is given a Redis client
+	Retry            RetryPolicy
+	Breakers         *CircuitBreakers
 	symbolMap        map[string]string
 	reverseSymbolMap map[string]string
 }
@@ -34,10 +37,52 @@ func NewRequest(timeout time.Duration) *Request {
 		client: &http.Client{
 			Timeout: timeout,
 		},
+		Limiter:  NewRateLimiter(nil, 0),
+		Breakers: &CircuitBreakers{},
 	}
 }
 
+// MakeRequest sends a call to an exchange within its rate limit and circuit breaker. Idempotent calls are retried
+// according to Retry, others are sent exactly once
 func (n *Request) MakeRequest(ctx context.Context, method, endpoint string, body []byte,
+	creds *models.ExchangeCredential, baseURL string, addBearer bool, addTokenPhrase bool, apiKey AuthToken) (*http.Response, []byte, error) {
+	attempts := n.Retry.attempts(ctx, method)
+	for attempt := 1; ; attempt++ {
+		probing, err := n.Breakers.allow(ctx, baseURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, respBody, err := n.send(ctx, method, endpoint, body, creds, baseURL, addBearer, addTokenPhrase, apiKey)
+		if errors.Is(err, ErrRateLimited) || ctx.Err() != nil {
+			// nothing reached the exchange, or the caller gave up, so the call tells nothing about its health
+			if probing {
+				n.Breakers.abandonProbe(baseURL)
+			}
+			return resp, respBody, err
+		}
+		healthy := err == nil && resp.StatusCode < http.StatusInternalServerError
+		if !isProbe(ctx) {
+			n.Breakers.record(baseURL, healthy)
+		}
+		if attempt >= attempts || !retryable(resp, err) {
+			return resp, respBody, err
+		}
+		delay := n.Retry.backoff(attempt, resp)
+		if delay < 0 {
+			return resp, respBody, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, respBody, err
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt of a call
+func (n *Request) send(ctx context.Context, method, endpoint string, body []byte,
 	creds *models.ExchangeCredential, baseURL string, addBearer bool, addTokenPhrase bool, apiKey AuthToken) (*http.Response, []byte, error) {
 	url := baseURL + endpoint
 	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(body))
@@ -73,7 +118,13 @@ func (n *Request) MakeRequest(ctx context.Context, method, endpoint string, body
 		req.Header.Set("Authorization", authToken)
 
 	}
-	fmt.Printf("request body: %s\n", string(body))
+	credentialID := ""
+	if creds != nil {
+		credentialID = creds.ID.String()
+	}
+	if err := n.Limiter.Wait(ctx, baseURL, credentialID); err != nil {
+		return nil, nil, err
+	}
 	resp, err := n.client.Do(req)
 	if err != nil {
 		return nil, nil, fmt.Errorf("request failed: %w", err)
@@ -81,7 +132,6 @@ func (n *Request) MakeRequest(ctx context.Context, method, endpoint string, body
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
-	fmt.Printf("response body: %s\n", string(respBody))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read response: %w", err)
 	}
@@ -99,144 +149,103 @@ func (h *OrderCalculationHelper) ValidateOrderRequestForNobitex(req *order.Stand
 	}
 
 	if req.Side != order.OrderSideBuy && req.Side != order.OrderSideSell {
-		return fmt.Errorf("invalid side: must be 'buy' or 'sell'")
+		return order.NewValidationError(order.CodeInvalidSide, "side", "invalid side: must be 'buy' or 'sell'")
 	}
 
 	if req.Type != order.OrderTypeMarket && req.Type != order.OrderTypeLimit {
-		return fmt.Errorf("invalid type: must be 'market' or 'limit'")
+		return order.NewValidationError(order.CodeInvalidType, "type", "invalid type: must be 'market' or 'limit'")
 	}
 
 	// For limit orders, price is required
 	if req.Type == order.OrderTypeLimit && req.Price == nil {
-		return fmt.Errorf("price is required for limit orders")
+		return order.NewValidationError(order.CodePriceRequired, "price", "price is required for limit orders")
 	}
 	if req.BaseCurrency == "" || req.QuoteCurrency == "" {
 		return fmt.Errorf("nobitex expects src and dest currencies'")
 	}
 
+	// currencies arrive as Nobitex codes, the adapter translates canonical assets through the symbol registry
 	req.BaseCurrency, req.QuoteCurrency = strings.ToLower(req.BaseCurrency), strings.ToLower(req.QuoteCurrency)
-	if req.BaseCurrency == "irt" {
-		req.BaseCurrency = "rls"
-	}
-	if req.QuoteCurrency == "irt" {
-		req.QuoteCurrency = "rls"
-	}
-
 	if req.QuoteCurrency != "rls" && req.QuoteCurrency != "usdt" {
 		return fmt.Errorf("nobitex only supports buying/selling with usdt and rials as quote currencies")
 	}
 
 	// Must have either Quantity OR (BaseAmount/QuoteAmount)
-	hasQuantity := req.Quantity != nil && *req.Quantity > 0
-	hasBaseAmount := req.BaseAmount != nil && *req.BaseAmount > 0
-	hasQuoteAmount := req.QuoteAmount != nil && *req.QuoteAmount > 0
+	hasQuantity := req.Quantity != nil && req.Quantity.IsPositive()
+	hasBaseAmount := req.BaseAmount != nil && req.BaseAmount.IsPositive()
+	hasQuoteAmount := req.QuoteAmount != nil && req.QuoteAmount.IsPositive()
 
 	if !hasQuantity && !hasBaseAmount && !hasQuoteAmount {
-		return fmt.Errorf("must specify either quantity or base_amount/quote_amount")
+		return order.NewValidationError(order.CodeQuantityRequired, "quantity",
+			"must specify either quantity or base_amount/quote_amount")
 	}
 
 	return nil
 }
 
 // GetQuantityForExchange calculates the appropriate quantity for exchanges that need single quantity
-func (h *OrderCalculationHelper) GetQuantityForExchange(req *order.StandardOrderRequest) (float64, error) {
+func (h *OrderCalculationHelper) GetQuantityForExchange(req *order.StandardOrderRequest) (decimal.Decimal, error) {
 	// If quantity is directly specified, use it
-	if req.Quantity != nil && *req.Quantity > 0 {
+	if req.Quantity != nil && req.Quantity.IsPositive() {
 		return *req.Quantity, nil
 	}
 
 	// If base_amount is specified, use it as quantity (most common case)
-	if req.BaseAmount != nil && *req.BaseAmount > 0 {
+	if req.BaseAmount != nil && req.BaseAmount.IsPositive() {
 		return *req.BaseAmount, nil
 	}
 
 	// If only quote_amount is specified and we have price, calculate base amount
-	if req.QuoteAmount != nil && *req.QuoteAmount > 0 && req.Price != nil && *req.Price > 0 {
-		return *req.QuoteAmount / *req.Price, nil
+	if req.QuoteAmount != nil && req.QuoteAmount.IsPositive() && req.Price != nil && req.Price.IsPositive() {
+		return req.QuoteAmount.Div(*req.Price), nil
 	}
 
-	return 0, fmt.Errorf("cannot determine quantity from provided amounts")
+	return decimal.Zero, fmt.Errorf("cannot determine quantity from provided amounts")
 }
 
 // GetBaseAmountForExchange calculates base amount for exchanges that need it separately
-func (h *OrderCalculationHelper) GetBaseAmountForExchange(req *order.StandardOrderRequest) (float64, error) {
+func (h *OrderCalculationHelper) GetBaseAmountForExchange(req *order.StandardOrderRequest) (decimal.Decimal, error) {
 	// If base_amount is directly specified, use it
-	if req.BaseAmount != nil && *req.BaseAmount > 0 {
+	if req.BaseAmount != nil && req.BaseAmount.IsPositive() {
 		return *req.BaseAmount, nil
 	}
 
 	// If quantity is specified, use it as base amount
-	if req.Quantity != nil && *req.Quantity > 0 {
+	if req.Quantity != nil && req.Quantity.IsPositive() {
 		return *req.Quantity, nil
 	}
 
 	// If only quote_amount is specified and we have price, calculate base amount
-	if req.QuoteAmount != nil && *req.QuoteAmount > 0 && req.Price != nil && *req.Price > 0 {
-		return *req.QuoteAmount / *req.Price, nil
+	if req.QuoteAmount != nil && req.QuoteAmount.IsPositive() && req.Price != nil && req.Price.IsPositive() {
+		return req.QuoteAmount.Div(*req.Price), nil
 	}
 
-	return 0, fmt.Errorf("cannot determine base amount from provided amounts")
+	return decimal.Zero, fmt.Errorf("cannot determine base amount from provided amounts")
 }
 
 // GetQuoteAmountForExchange calculates quote amount for exchanges that need it separately
-func (h *OrderCalculationHelper) GetQuoteAmountForExchange(req *order.StandardOrderRequest) (float64, error) {
+func (h *OrderCalculationHelper) GetQuoteAmountForExchange(req *order.StandardOrderRequest) (decimal.Decimal, error) {
 	// If quote_amount is directly specified, use it
-	if req.QuoteAmount != nil && *req.QuoteAmount > 0 {
+	if req.QuoteAmount != nil && req.QuoteAmount.IsPositive() {
 		return *req.QuoteAmount, nil
 	}
 
 	// Calculate from base amount and price
-	var baseAmount float64
+	var baseAmount decimal.Decimal
 
-	if req.BaseAmount != nil && *req.BaseAmount > 0 {
+	if req.BaseAmount != nil && req.BaseAmount.IsPositive() {
 		baseAmount = *req.BaseAmount
-	} else if req.Quantity != nil && *req.Quantity > 0 {
+	} else if req.Quantity != nil && req.Quantity.IsPositive() {
 		baseAmount = *req.Quantity
 	} else {
-		return 0, fmt.Errorf("cannot determine quote amount without base amount or quantity")
-	}
-
-	if req.Price == nil || *req.Price <= 0 {
-		return 0, fmt.Errorf("cannot determine quote amount without price")
-	}
-
-	return baseAmount * *req.Price, nil
-}
-
-func (h *OrderCalculationHelper) ParseSymbolParts(symbol string) (base, quote string, err error) {
-	// Handle different symbol formats
-	if strings.Contains(symbol, "_") {
-		parts := strings.Split(symbol, "_")
-		if len(parts) != 2 {
-			return "", "", fmt.Errorf("invalid symbol format: %s", symbol)
-		}
-		return strings.ToUpper(parts[0]), strings.ToUpper(parts[1]), nil
+		return decimal.Zero, fmt.Errorf("cannot determine quote amount without base amount or quantity")
 	}
 
-	if strings.Contains(symbol, "-") {
-		parts := strings.Split(symbol, "-")
-		if len(parts) != 2 {
-			return "", "", fmt.Errorf("invalid symbol format: %s", symbol)
-		}
-		return strings.ToUpper(parts[0]), strings.ToUpper(parts[1]), nil
-	}
-
-	// For symbols like BTCUSDT, try to parse common patterns
-	symbol = strings.ToUpper(symbol)
-
-	// Common quote currencies to try
-	quoteCurrencies := []string{"USDT", "USDC", "BTC", "ETH", "BNB", "IRT", "RLS"}
-
-	for _, quote := range quoteCurrencies {
-		if strings.HasSuffix(symbol, quote) {
-			base := strings.TrimSuffix(symbol, quote)
-			if len(base) > 0 {
-				return base, quote, nil
-			}
-		}
+	if req.Price == nil || !req.Price.IsPositive() {
+		return decimal.Zero, fmt.Errorf("cannot determine quote amount without price")
 	}
 
-	return "", "", fmt.Errorf("cannot parse symbol: %s", symbol)
+	return baseAmount.Mul(*req.Price), nil
 }
 
 func (h *OrderCalculationHelper) ConvertToNobitexFormat(req *order.StandardOrderRequest) (map[string]interface{}, error) {
@@ -258,7 +267,7 @@ func (h *OrderCalculationHelper) ConvertToNobitexFormat(req *order.StandardOrder
 	}
 	orderData := map[string]interface{}{
 		"type":          orderType,
-		"amount":        fmt.Sprintf("%.8f", quantity),
+		"amount":        quantity.StringFixed(8),
 		"clientOrderId": req.ClientOrderId,
 	}
 
@@ -266,7 +275,7 @@ func (h *OrderCalculationHelper) ConvertToNobitexFormat(req *order.StandardOrder
 	orderData["dstCurrency"] = strings.ToLower(req.QuoteCurrency)
 
 	if req.Type == order.OrderTypeLimit && req.Price != nil {
-		orderData["price"] = fmt.Sprintf("%.8f", *req.Price)
+		orderData["price"] = req.Price.StringFixed(8)
 	}
 
 	return orderData, nil
@@ -278,25 +287,26 @@ func (h *OrderCalculationHelper) ValidateOrderRequestForBitpin(req *order.Standa
 	}
 
 	if req.Side != order.OrderSideBuy && req.Side != order.OrderSideSell {
-		return fmt.Errorf("invalid side: must be 'buy' or 'sell'")
+		return order.NewValidationError(order.CodeInvalidSide, "side", "invalid side: must be 'buy' or 'sell'")
 	}
 
 	if req.Type != order.OrderTypeMarket && req.Type != order.OrderTypeLimit {
-		return fmt.Errorf("invalid type: must be 'market' or 'limit'")
+		return order.NewValidationError(order.CodeInvalidType, "type", "invalid type: must be 'market' or 'limit'")
 	}
 
 	// For limit orders, price is required
 	if req.Type == order.OrderTypeLimit && req.Price == nil {
-		return fmt.Errorf("price is required for limit orders")
+		return order.NewValidationError(order.CodePriceRequired, "price", "price is required for limit orders")
 	}
 
 	// Must have either Quantity OR (BaseAmount/QuoteAmount)
-	hasQuantity := req.Quantity != nil && *req.Quantity > 0
-	hasBaseAmount := req.BaseAmount != nil && *req.BaseAmount > 0
-	hasQuoteAmount := req.QuoteAmount != nil && *req.QuoteAmount > 0
+	hasQuantity := req.Quantity != nil && req.Quantity.IsPositive()
+	hasBaseAmount := req.BaseAmount != nil && req.BaseAmount.IsPositive()
+	hasQuoteAmount := req.QuoteAmount != nil && req.QuoteAmount.IsPositive()
 
 	if !hasQuantity && !hasBaseAmount && !hasQuoteAmount {
-		return fmt.Errorf("must specify either quantity or base_amount/quote_amount")
+		return order.NewValidationError(order.CodeQuantityRequired, "quantity",
+			"must specify either quantity or base_amount/quote_amount")
 	}
 
 	return nil
@@ -318,22 +328,20 @@ func (h *OrderCalculationHelper) ConvertToBitpinFormat(req *order.StandardOrderR
 		return nil, err
 	}
 
-	// Convert symbol to Bitpin format (e.g., BTCUSDT -> BTC_USDT)
-
 	orderData := map[string]interface{}{
 		"symbol":       req.Symbol,
 		"type":         strings.ToLower(string(req.Type)),
 		"side":         strings.ToLower(string(req.Side)),
-		"base_amount":  fmt.Sprintf("%.8f", baseAmount),
-		"quote_amount": fmt.Sprintf("%.8f", quoteAmount),
+		"base_amount":  baseAmount.StringFixed(8),
+		"quote_amount": quoteAmount.StringFixed(8),
 	}
 
 	if req.Type == order.OrderTypeLimit && req.Price != nil {
-		orderData["price"] = fmt.Sprintf("%.8f", *req.Price)
+		orderData["price"] = req.Price.StringFixed(8)
 	}
 
 	if req.StopPrice != nil {
-		orderData["stop_price"] = fmt.Sprintf("%.8f", *req.StopPrice)
+		orderData["stop_price"] = req.StopPrice.StringFixed(8)
 	}
 
 	if req.ClientOrderId != "" {
@@ -342,3 +350,79 @@ func (h *OrderCalculationHelper) ConvertToBitpinFormat(req *order.StandardOrderR
 
 	return orderData, nil
 }
+
+// ApplyTradingPairFilters rounds price to the pair tick size and quantity down to its step size,
+// then rejects orders outside the pair min/max quantity before anything is sent to the exchange
+func (h *OrderCalculationHelper) ApplyTradingPairFilters(req *order.StandardOrderRequest, pair *models.TradingPair) error {
+	if !pair.IsActive {
+		return order.NewValidationError(order.CodeSymbolInactive, "symbol", "%s is not tradable", pair.Symbol)
+	}
+	if req.Side != order.OrderSideBuy && req.Side != order.OrderSideSell {
+		return order.NewValidationError(order.CodeInvalidSide, "side", "invalid side: must be 'buy' or 'sell'")
+	}
+	if req.Type != order.OrderTypeMarket && req.Type != order.OrderTypeLimit {
+		return order.NewValidationError(order.CodeInvalidType, "type", "invalid type: must be 'market' or 'limit'")
+	}
+	if req.Type == order.OrderTypeLimit && req.Price == nil {
+		return order.NewValidationError(order.CodePriceRequired, "price", "price is required for limit orders")
+	}
+
+	if req.Price != nil {
+		if !req.Price.IsPositive() {
+			return order.NewValidationError(order.CodeInvalidPrice, "price", "price must be positive")
+		}
+		if pair.TickSize != nil && pair.TickSize.IsPositive() {
+			price := roundToIncrement(*req.Price, *pair.TickSize, decimal.Decimal.Round)
+			if !price.IsPositive() {
+				return order.NewValidationError(order.CodeInvalidPrice, "price",
+					"price %s is below tick size %s", req.Price, pair.TickSize)
+			}
+			req.Price = &price
+		}
+	}
+
+	quantity, err := h.GetBaseAmountForExchange(req)
+	if err != nil {
+		// quote only market orders are sized by the exchange
+		if req.QuoteAmount != nil && req.QuoteAmount.IsPositive() {
+			return nil
+		}
+		return order.NewValidationError(order.CodeQuantityRequired, "quantity",
+			"must specify either quantity or base_amount/quote_amount")
+	}
+	if pair.StepSize != nil && pair.StepSize.IsPositive() {
+		quantity = roundToIncrement(quantity, *pair.StepSize, decimal.Decimal.RoundFloor)
+		if !quantity.IsPositive() {
+			return order.NewValidationError(order.CodeBelowStepSize, "quantity",
+				"quantity is smaller than step size %s", pair.StepSize)
+		}
+	}
+	if pair.MinQuantity != nil && quantity.LessThan(*pair.MinQuantity) {
+		return order.NewValidationError(order.CodeQuantityTooSmall, "quantity",
+			"quantity %s is below minimum %s for %s", quantity, pair.MinQuantity, pair.Symbol)
+	}
+	if pair.MaxQuantity != nil && pair.MaxQuantity.IsPositive() && quantity.GreaterThan(*pair.MaxQuantity) {
+		return order.NewValidationError(order.CodeQuantityTooLarge, "quantity",
+			"quantity %s is above maximum %s for %s", quantity, pair.MaxQuantity, pair.Symbol)
+	}
+
+	// write the rounded amount back so every exchange format sends the same quantity
+	hasQuantity := req.Quantity != nil && req.Quantity.IsPositive()
+	hasBaseAmount := req.BaseAmount != nil && req.BaseAmount.IsPositive()
+	if hasQuantity {
+		req.Quantity = &quantity
+	}
+	if hasBaseAmount || !hasQuantity {
+		req.BaseAmount = &quantity
+	}
+	if !hasQuantity && !hasBaseAmount {
+		quoteAmount := quantity.Mul(*req.Price)
+		req.QuoteAmount = &quoteAmount
+	}
+	return nil
+}
+
+// roundToIncrement snaps value onto a whole multiple of increment
+func roundToIncrement(value, increment decimal.Decimal, round func(decimal.Decimal, int32) decimal.Decimal) decimal.Decimal {
+	return round(value.Div(increment), 0).Mul(increment)
+}
@@ -0,0 +1,276 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"github.com/rzabhd80/eye-on/domain/order"
+	"github.com/rzabhd80/eye-on/internal/database/models"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer answers every call with the statuses in order, repeating the last one, and counts the calls
+func newTestServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(calls.Add(1))
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(statuses[min(call, len(statuses))-1])
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestMakeRequestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		idempotent bool
+		header     http.Header
+		statuses   []int
+		wantCalls  int32
+		wantStatus int
+	}{
+		{name: "get is retried on 5xx", method: http.MethodGet, statuses: []int{503, 502, 200},
+			wantCalls: 3, wantStatus: 200},
+		{name: "get gives up after max attempts", method: http.MethodGet, statuses: []int{500},
+			wantCalls: 3, wantStatus: 500},
+		{name: "get is not retried on 4xx", method: http.MethodGet, statuses: []int{400},
+			wantCalls: 1, wantStatus: 400},
+		{name: "post is sent once", method: http.MethodPost, statuses: []int{503, 200},
+			wantCalls: 1, wantStatus: 503},
+		{name: "idempotent post is retried", method: http.MethodPost, idempotent: true, statuses: []int{503, 200},
+			wantCalls: 2, wantStatus: 200},
+		{name: "429 waiting longer than max delay is not retried", method: http.MethodGet,
+			header: http.Header{"Retry-After": {"60"}}, statuses: []int{429, 200}, wantCalls: 1, wantStatus: 429},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, calls := newTestServer(t, test.header, test.statuses...)
+			request := NewRequest(5 * time.Second)
+			request.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+			ctx := context.Background()
+			if test.idempotent {
+				ctx = Idempotent(ctx)
+			}
+
+			resp, _, err := request.MakeRequest(ctx, test.method, "/", nil, nil, server.URL, false, false, ApiKeyAuth)
+			if err != nil {
+				t.Fatalf("MakeRequest: %v", err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if got := calls.Load(); got != test.wantCalls {
+				t.Errorf("exchange received %d calls, want %d", got, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMakeRequestOpensBreaker(t *testing.T) {
+	server, calls := newTestServer(t, nil, 500, 500, 200)
+	request := NewRequest(5 * time.Second)
+	request.Breakers.Threshold = 2
+	request.Breakers.Cooldown = time.Hour
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := request.MakeRequest(ctx, http.MethodGet, "/", nil, nil, server.URL, false, false,
+			ApiKeyAuth); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if _, _, err := request.MakeRequest(ctx, http.MethodGet, "/", nil, nil, server.URL, false, false,
+		ApiKeyAuth); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v with the breaker open, want %v", err, ErrCircuitOpen)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("exchange received %d calls, want 2", got)
+	}
+}
+
+func TestMakeRequestAbandonsProbeThatWasNotSent(t *testing.T) {
+	server, calls := newTestServer(t, nil, 500, 200)
+	request := NewRequest(5 * time.Second)
+	request.Breakers.Threshold = 1
+	request.Breakers.Cooldown = time.Hour
+	request.Limiter.SetLimit(server.URL, RateLimit{Exchange: "exchange", PerMinute: 1})
+	ctx := context.Background()
+
+	// the failure opens the breaker and spends the only token of the minute
+	if _, _, err := request.MakeRequest(ctx, http.MethodGet, "/", nil, nil, server.URL, false, false,
+		ApiKeyAuth); err != nil {
+		t.Fatal(err)
+	}
+	breaker := request.Breakers.get(server.URL)
+	breaker.mu.Lock()
+	breaker.openedAt = breaker.openedAt.Add(-time.Hour)
+	breaker.mu.Unlock()
+
+	// the call taking the probe is held back by the rate limit and never reaches the exchange
+	if _, _, err := request.MakeRequest(ctx, http.MethodGet, "/", nil, nil, server.URL, false, false,
+		ApiKeyAuth); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v, want %v", err, ErrRateLimited)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("exchange received %d calls, want 1", got)
+	}
+	probing, err := request.Breakers.allow(ctx, server.URL)
+	if err != nil || !probing {
+		t.Errorf("next call got probing %v and error %v, want to probe", probing, err)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       *RateLimit
+		credentials []string
+		wantLimited []bool
+	}{
+		{
+			name:        "no limit set",
+			credentials: []string{"", "", "", ""},
+			wantLimited: []bool{false, false, false, false},
+		},
+		{
+			name:        "exchange budget holds five seconds of calls",
+			limit:       &RateLimit{Exchange: "exchange", PerMinute: 60},
+			credentials: []string{"", "", "", "", "", ""},
+			wantLimited: []bool{false, false, false, false, false, true},
+		},
+		{
+			name:        "every credential has a budget of its own",
+			limit:       &RateLimit{Exchange: "exchange", PerCredentialMinute: 12},
+			credentials: []string{"a", "a", "b", ""},
+			wantLimited: []bool{false, true, false, false},
+		},
+		{
+			name:        "a call takes from the exchange and the credential budget",
+			limit:       &RateLimit{Exchange: "exchange", PerMinute: 12, PerCredentialMinute: 60},
+			credentials: []string{"a", "b"},
+			wantLimited: []bool{false, true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := NewRateLimiter(nil, 0)
+			if test.limit != nil {
+				limiter.SetLimit(testBaseURL, *test.limit)
+			}
+			for i, credentialID := range test.credentials {
+				err := limiter.Wait(context.Background(), testBaseURL, credentialID)
+				if limited := errors.Is(err, ErrRateLimited); limited != test.wantLimited[i] {
+					t.Errorf("call %d: got %v, want limited %v", i, err, test.wantLimited[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tooMany := func(retryAfter string) *http.Response {
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {retryAfter}}}
+	}
+	tests := []struct {
+		name     string
+		attempt  int
+		resp     *http.Response
+		min, max time.Duration
+	}{
+		{name: "first retry waits up to the base delay", attempt: 1, max: 100 * time.Millisecond},
+		{name: "the ceiling doubles every attempt", attempt: 3, max: 400 * time.Millisecond},
+		{name: "the ceiling stops at max delay", attempt: 10, max: time.Second},
+		{name: "retry after is honoured", attempt: 1, resp: tooMany("1"), min: time.Second, max: time.Second},
+		{name: "retry after past max delay gives up", attempt: 1, resp: tooMany("5"), min: -1, max: -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if delay := policy.backoff(test.attempt, test.resp); delay < test.min || delay > test.max {
+					t.Fatalf("got delay %s, want between %s and %s", delay, test.min, test.max)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyTradingPairFilters(t *testing.T) {
+	decPtr := func(value string) *decimal.Decimal {
+		if value == "" {
+			return nil
+		}
+		d := decimal.RequireFromString(value)
+		return &d
+	}
+	tests := []struct {
+		name                    string
+		orderType               order.OrderType
+		price, quantity, quote  string
+		inactive                bool
+		min, max                string
+		wantCode                order.ValidationCode
+		wantPrice, wantQuantity string
+	}{
+		{name: "price is rounded to the tick and quantity down to the step", orderType: order.OrderTypeLimit,
+			price: "100.126", quantity: "1.23456", wantPrice: "100.13", wantQuantity: "1.234"},
+		{name: "a price rounding to zero is rejected", orderType: order.OrderTypeLimit, price: "0.004",
+			quantity: "1", wantCode: order.CodeInvalidPrice},
+		{name: "a quantity below one step is rejected", orderType: order.OrderTypeLimit, price: "100",
+			quantity: "0.0004", wantCode: order.CodeBelowStepSize},
+		{name: "a quantity under the minimum is rejected", orderType: order.OrderTypeLimit, price: "100",
+			quantity: "0.0059", min: "0.006", wantCode: order.CodeQuantityTooSmall},
+		{name: "a quantity rounded onto the minimum passes", orderType: order.OrderTypeLimit, price: "100",
+			quantity: "0.0065", min: "0.006", wantPrice: "100", wantQuantity: "0.006"},
+		{name: "a quantity over the maximum is rejected", orderType: order.OrderTypeLimit, price: "100",
+			quantity: "200", max: "100", wantCode: order.CodeQuantityTooLarge},
+		{name: "an inactive pair is rejected", orderType: order.OrderTypeLimit, price: "100", quantity: "1",
+			inactive: true, wantCode: order.CodeSymbolInactive},
+		{name: "a quote only market order is left to the exchange", orderType: order.OrderTypeMarket, quote: "50"},
+		{name: "a market order without any amount is rejected", orderType: order.OrderTypeMarket,
+			wantCode: order.CodeQuantityRequired},
+	}
+	helper := &OrderCalculationHelper{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pair := &models.TradingPair{Symbol: "BTC_USDT", IsActive: !test.inactive, TickSize: decPtr("0.01"),
+				StepSize: decPtr("0.001"), MinQuantity: decPtr(test.min), MaxQuantity: decPtr(test.max)}
+			req := &order.StandardOrderRequest{Symbol: "BTC_USDT", Side: order.OrderSideBuy, Type: test.orderType,
+				Price: decPtr(test.price), Quantity: decPtr(test.quantity), QuoteAmount: decPtr(test.quote)}
+
+			err := helper.ApplyTradingPairFilters(req, pair)
+			if test.wantCode != "" {
+				var validationErr *order.ValidationError
+				if !errors.As(err, &validationErr) || validationErr.Code != test.wantCode {
+					t.Fatalf("got %v, want a %s validation error", err, test.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyTradingPairFilters: %v", err)
+			}
+			if test.wantPrice != "" && !req.Price.Equal(decimal.RequireFromString(test.wantPrice)) {
+				t.Errorf("got price %s, want %s", req.Price, test.wantPrice)
+			}
+			if test.wantQuantity == "" {
+				if req.Quantity != nil || req.BaseAmount != nil {
+					t.Errorf("got quantity %v base amount %v, want the order left unsized", req.Quantity,
+						req.BaseAmount)
+				}
+				return
+			}
+			if req.Quantity == nil || !req.Quantity.Equal(decimal.RequireFromString(test.wantQuantity)) {
+				t.Errorf("got quantity %v, want %s", req.Quantity, test.wantQuantity)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package helpers
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type idempotentKey struct{}
+
+// RetryPolicy retries idempotent calls that failed with a transport error, a 5xx or a 429, waiting an exponential
+// backoff with full jitter between attempts. MaxAttempts counts the first attempt, 1 or less disables retries
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Idempotent marks the calls made with ctx as safe to retry although their method is not, e.g. POST endpoints that
+// only read. Never use it for calls that place or cancel orders
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(ctx context.Context, method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+	return idempotent
+}
+
+// attempts is how many times a call may be sent
+func (policy RetryPolicy) attempts(ctx context.Context, method string) int {
+	if policy.MaxAttempts <= 1 || isProbe(ctx) || !isIdempotent(ctx, method) {
+		return 1
+	}
+	return policy.MaxAttempts
+}
+
+// backoff is the wait before retrying after the given attempt, at least what a 429 asked for in Retry-After. It is
+// negative when the exchange asked to wait longer than MaxDelay, the call is not retried then
+func (policy RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	ceiling := policy.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || (policy.MaxDelay > 0 && ceiling > policy.MaxDelay) {
+		ceiling = policy.MaxDelay
+	}
+	var delay time.Duration
+	if ceiling > 0 {
+		delay = time.Duration(rand.Int63n(int64(ceiling) + 1))
+	}
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter := time.Duration(seconds) * time.Second
+			if policy.MaxDelay > 0 && retryAfter > policy.MaxDelay {
+				return -1
+			}
+			delay = max(delay, retryAfter)
+		}
+	}
+	return delay
+}
+
+// retryable reports whether a failed attempt may succeed when sent again
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
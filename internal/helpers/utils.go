@@ -1,5 +1,9 @@
 package helpers
 
-func FloatPointer(f float64) *float64 {
-	return &f
+import "github.com/shopspring/decimal"
+
+// DecimalPointer is used for the literal filters of the static symbol tables
+func DecimalPointer(f float64) *decimal.Decimal {
+	d := decimal.NewFromFloat(f)
+	return &d
 }
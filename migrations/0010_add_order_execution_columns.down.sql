@@ -0,0 +1,4 @@
+ALTER TABLE order_histories
+    DROP COLUMN IF EXISTS executed_qty,
+    DROP COLUMN IF EXISTS executed_price,
+    DROP COLUMN IF EXISTS commission;
\ No newline at end of file
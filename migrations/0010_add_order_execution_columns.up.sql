@@ -0,0 +1,4 @@
+ALTER TABLE order_histories
+    ADD COLUMN executed_qty   DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    ADD COLUMN executed_price DECIMAL(20, 8),
+    ADD COLUMN commission     DECIMAL(20, 8) NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- The original status spellings cannot be restored, only the constraint is dropped.
+ALTER TABLE order_histories
+    DROP CONSTRAINT IF EXISTS chk_order_histories_status;
\ No newline at end of file
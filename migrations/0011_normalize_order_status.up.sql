@@ -0,0 +1,21 @@
+-- Map the legacy status spellings written by the adapters onto the canonical order statuses.
+-- Adapters used to store an exchange side 'filled' order as 'failed'.
+UPDATE order_histories
+SET status = CASE lower(status)
+                 WHEN 'pending' THEN 'new'
+                 WHEN 'partial' THEN 'partially_filled'
+                 WHEN 'partially' THEN 'partially_filled'
+                 WHEN 'failed' THEN 'filled'
+                 WHEN 'cancelled' THEN 'canceled'
+                 ELSE lower(status)
+    END;
+
+-- Any other spelling is of an order whose outcome is not known. Closing it as rejected keeps the sync worker from
+-- polling it and cancels from reaching it, reopening it could act on an order that is long gone.
+UPDATE order_histories
+SET status = 'rejected'
+WHERE status NOT IN ('new', 'partially_filled', 'filled', 'canceled', 'rejected');
+
+ALTER TABLE order_histories
+    ADD CONSTRAINT chk_order_histories_status
+        CHECK (status IN ('new', 'partially_filled', 'filled', 'canceled', 'rejected'));
@@ -0,0 +1,13 @@
+DELETE
+FROM order_histories
+WHERE status = 'reserved';
+
+ALTER TABLE order_histories
+    DROP CONSTRAINT IF EXISTS chk_order_histories_status;
+ALTER TABLE order_histories
+    ADD CONSTRAINT chk_order_histories_status
+        CHECK (status IN ('new', 'partially_filled', 'filled', 'canceled', 'rejected'));
+
+DROP INDEX IF EXISTS ux_order_histories_user_client_order_id;
+CREATE INDEX idx_order_histories_client_order_id
+    ON order_histories (client_order_id);
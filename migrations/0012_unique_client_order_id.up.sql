@@ -0,0 +1,20 @@
+-- Older nobitex orders reused one client_order_id per user, make those unique before indexing.
+UPDATE order_histories o
+SET client_order_id = o.id::text
+WHERE EXISTS (SELECT 1
+              FROM order_histories d
+              WHERE d.user_id = o.user_id
+                AND d.client_order_id = o.client_order_id
+                AND d.id <> o.id);
+
+DROP INDEX IF EXISTS idx_order_histories_client_order_id;
+-- Placement reserves the client_order_id here before the exchange is called.
+CREATE UNIQUE INDEX ux_order_histories_user_client_order_id
+    ON order_histories (user_id, client_order_id)
+    WHERE deleted_at IS NULL;
+
+ALTER TABLE order_histories
+    DROP CONSTRAINT IF EXISTS chk_order_histories_status;
+ALTER TABLE order_histories
+    ADD CONSTRAINT chk_order_histories_status
+        CHECK (status IN ('reserved', 'new', 'partially_filled', 'filled', 'canceled', 'rejected'));
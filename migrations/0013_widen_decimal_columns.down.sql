@@ -0,0 +1,16 @@
+ALTER TABLE trading_pairs
+    ALTER COLUMN min_quantity TYPE DECIMAL(20, 8),
+    ALTER COLUMN max_quantity TYPE DECIMAL(20, 8),
+    ALTER COLUMN step_size TYPE DECIMAL(20, 8),
+    ALTER COLUMN tick_size TYPE DECIMAL(20, 8);
+
+ALTER TABLE order_events
+    ALTER COLUMN filled_qty TYPE DECIMAL(20, 8),
+    ALTER COLUMN remaining_qty TYPE DECIMAL(20, 8);
+
+ALTER TABLE order_histories
+    ALTER COLUMN quantity TYPE DECIMAL(20, 8),
+    ALTER COLUMN price TYPE DECIMAL(20, 8),
+    ALTER COLUMN executed_qty TYPE DECIMAL(20, 8),
+    ALTER COLUMN executed_price TYPE DECIMAL(20, 8),
+    ALTER COLUMN commission TYPE DECIMAL(20, 8);
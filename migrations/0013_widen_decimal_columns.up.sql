@@ -0,0 +1,17 @@
+-- IRT notional values overflow DECIMAL(20, 8), use the same precision as the balance snapshots.
+ALTER TABLE order_histories
+    ALTER COLUMN quantity TYPE NUMERIC(30, 10),
+    ALTER COLUMN price TYPE NUMERIC(30, 10),
+    ALTER COLUMN executed_qty TYPE NUMERIC(30, 10),
+    ALTER COLUMN executed_price TYPE NUMERIC(30, 10),
+    ALTER COLUMN commission TYPE NUMERIC(30, 10);
+
+ALTER TABLE order_events
+    ALTER COLUMN filled_qty TYPE NUMERIC(30, 10),
+    ALTER COLUMN remaining_qty TYPE NUMERIC(30, 10);
+
+ALTER TABLE trading_pairs
+    ALTER COLUMN min_quantity TYPE NUMERIC(30, 10),
+    ALTER COLUMN max_quantity TYPE NUMERIC(30, 10),
+    ALTER COLUMN step_size TYPE NUMERIC(30, 10),
+    ALTER COLUMN tick_size TYPE NUMERIC(30, 10);
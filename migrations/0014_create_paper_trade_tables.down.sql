@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS paper_orders;
+DROP TABLE IF EXISTS paper_balances;
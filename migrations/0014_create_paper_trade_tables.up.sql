@@ -0,0 +1,38 @@
+CREATE TABLE paper_balances
+(
+    id         UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id    UUID            NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    currency   VARCHAR(10)     NOT NULL,
+    available  NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    locked     NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ,
+    CONSTRAINT chk_paper_balances_non_negative CHECK (available >= 0 AND locked >= 0)
+);
+CREATE UNIQUE INDEX ux_paper_balances_user_currency
+    ON paper_balances (user_id, currency)
+    WHERE deleted_at IS NULL;
+
+CREATE TABLE paper_orders
+(
+    id              UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id         UUID            NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    trading_pair_id UUID            NOT NULL REFERENCES trading_pairs (id) ON DELETE RESTRICT,
+    client_order_id VARCHAR(100)    NOT NULL,
+    side            VARCHAR(10)     NOT NULL,
+    type            VARCHAR(10)     NOT NULL,
+    quantity        NUMERIC(30, 10) NOT NULL,
+    price           NUMERIC(30, 10),
+    executed_qty    NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    executed_quote  NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    commission      NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    locked_amount   NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    status          VARCHAR(20)     NOT NULL,
+    created_at      TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    deleted_at      TIMESTAMPTZ
+);
+CREATE INDEX idx_paper_orders_user_status
+    ON paper_orders (user_id, status);
+
@@ -0,0 +1,25 @@
+CREATE TABLE arbitrage_opportunities
+(
+    id               UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    symbol           VARCHAR(20)     NOT NULL,
+    base_asset       VARCHAR(10)     NOT NULL,
+    quote_asset      VARCHAR(10)     NOT NULL,
+    buy_exchange_id  UUID            NOT NULL REFERENCES exchanges (id) ON DELETE CASCADE,
+    sell_exchange_id UUID            NOT NULL REFERENCES exchanges (id) ON DELETE CASCADE,
+    buy_price        NUMERIC(30, 10) NOT NULL,
+    sell_price       NUMERIC(30, 10) NOT NULL,
+    quantity         NUMERIC(30, 10) NOT NULL,
+    buy_fee          NUMERIC(30, 10) NOT NULL,
+    sell_fee         NUMERIC(30, 10) NOT NULL,
+    profit_rate      NUMERIC(30, 10) NOT NULL,
+    estimated_profit NUMERIC(30, 10) NOT NULL,
+    detected_at      TIMESTAMPTZ     NOT NULL,
+    last_seen_at     TIMESTAMPTZ     NOT NULL,
+    created_at       TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    deleted_at       TIMESTAMPTZ
+);
+CREATE INDEX idx_arbitrage_opportunities_last_seen
+    ON arbitrage_opportunities (last_seen_at DESC);
+CREATE INDEX idx_arbitrage_opportunities_symbol_last_seen
+    ON arbitrage_opportunities (symbol, last_seen_at DESC);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_order_histories_parent_order_id;
+ALTER TABLE order_histories
+    DROP COLUMN IF EXISTS parent_order_id;
+DROP TABLE IF EXISTS routed_orders;
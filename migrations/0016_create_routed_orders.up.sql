@@ -0,0 +1,26 @@
+CREATE TABLE routed_orders
+(
+    id              UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id         UUID            NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_order_id VARCHAR(100)    NOT NULL,
+    symbol          VARCHAR(20)     NOT NULL,
+    base_asset      VARCHAR(10)     NOT NULL,
+    quote_asset     VARCHAR(10)     NOT NULL,
+    side            VARCHAR(10)     NOT NULL,
+    type            VARCHAR(10)     NOT NULL,
+    quantity        NUMERIC(30, 10) NOT NULL,
+    price           NUMERIC(30, 10),
+    status          VARCHAR(20)     NOT NULL,
+    executed_qty    NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    deleted_at      TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX ux_routed_orders_user_client_order_id
+    ON routed_orders (user_id, client_order_id)
+    WHERE deleted_at IS NULL;
+
+ALTER TABLE order_histories
+    ADD COLUMN parent_order_id UUID REFERENCES routed_orders (id) ON DELETE SET NULL;
+CREATE INDEX idx_order_histories_parent_order_id
+    ON order_histories (parent_order_id);
@@ -0,0 +1,10 @@
+UPDATE routed_orders
+SET symbol = base_asset || '_' || quote_asset;
+UPDATE arbitrage_opportunities
+SET symbol = base_asset || '_' || quote_asset;
+
+DROP INDEX IF EXISTS idx_trading_pairs_canonical_symbol_id;
+ALTER TABLE trading_pairs
+    DROP COLUMN IF EXISTS canonical_symbol_id;
+DROP TABLE IF EXISTS exchange_assets;
+DROP TABLE IF EXISTS canonical_symbols;
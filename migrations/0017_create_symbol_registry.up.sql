@@ -0,0 +1,49 @@
+CREATE TABLE canonical_symbols
+(
+    id          UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    symbol      VARCHAR(21) NOT NULL,
+    base_asset  VARCHAR(10) NOT NULL,
+    quote_asset VARCHAR(10) NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at  TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX ux_canonical_symbols_symbol
+    ON canonical_symbols (symbol)
+    WHERE deleted_at IS NULL;
+
+CREATE TABLE exchange_assets
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    exchange_id UUID            NOT NULL REFERENCES exchanges (id) ON DELETE CASCADE,
+    asset       VARCHAR(10)     NOT NULL,
+    code        VARCHAR(20)     NOT NULL,
+    scale       NUMERIC(30, 10) NOT NULL DEFAULT 1,
+    created_at  TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    deleted_at  TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX ux_exchange_assets_exchange_asset
+    ON exchange_assets (exchange_id, asset)
+    WHERE deleted_at IS NULL;
+
+ALTER TABLE trading_pairs
+    ADD COLUMN canonical_symbol_id UUID REFERENCES canonical_symbols (id) ON DELETE SET NULL;
+CREATE INDEX idx_trading_pairs_canonical_symbol_id
+    ON trading_pairs (canonical_symbol_id);
+
+INSERT INTO canonical_symbols (symbol, base_asset, quote_asset)
+SELECT DISTINCT upper(base_asset) || '/' || upper(quote_asset), upper(base_asset), upper(quote_asset)
+FROM trading_pairs
+WHERE deleted_at IS NULL;
+
+UPDATE trading_pairs
+SET canonical_symbol_id = canonical_symbols.id
+FROM canonical_symbols
+WHERE canonical_symbols.symbol = upper(trading_pairs.base_asset) || '/' || upper(trading_pairs.quote_asset)
+  AND canonical_symbols.deleted_at IS NULL;
+
+UPDATE arbitrage_opportunities
+SET symbol = base_asset || '/' || quote_asset;
+UPDATE routed_orders
+SET symbol = base_asset || '/' || quote_asset;
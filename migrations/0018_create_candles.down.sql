@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_order_events_event_time;
+DROP INDEX IF EXISTS idx_ob_snapshots_time;
+DROP TABLE IF EXISTS candles;
@@ -0,0 +1,24 @@
+CREATE TABLE candles
+(
+    id              UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    exchange_id     UUID            NOT NULL REFERENCES exchanges (id) ON DELETE CASCADE,
+    trading_pair_id UUID            NOT NULL REFERENCES trading_pairs (id) ON DELETE CASCADE,
+    interval        VARCHAR(3)      NOT NULL,
+    open_time       TIMESTAMPTZ     NOT NULL,
+    open            NUMERIC(30, 10) NOT NULL,
+    high            NUMERIC(30, 10) NOT NULL,
+    low             NUMERIC(30, 10) NOT NULL,
+    close           NUMERIC(30, 10) NOT NULL,
+    volume          NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    trade_count     INTEGER         NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ     NOT NULL DEFAULT now(),
+    deleted_at      TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX ux_candles_pair_interval_open_time
+    ON candles (trading_pair_id, interval, open_time);
+
+CREATE INDEX idx_ob_snapshots_time
+    ON order_book_snapshots (snapshot_time);
+CREATE INDEX idx_order_events_event_time
+    ON order_events (event_time);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_balance_snapshots_time;
+
+-- deltas can not be read without their keyframe logic, only keyframes are kept
+DELETE FROM order_book_snapshots WHERE base_snapshot_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_ob_snapshots_base_snapshot_id;
+ALTER TABLE order_book_snapshots
+    DROP COLUMN IF EXISTS best_ask,
+    DROP COLUMN IF EXISTS best_bid,
+    DROP COLUMN IF EXISTS base_snapshot_id;
@@ -0,0 +1,15 @@
+ALTER TABLE order_book_snapshots
+    ADD COLUMN base_snapshot_id UUID REFERENCES order_book_snapshots (id) ON DELETE CASCADE,
+    ADD COLUMN best_bid         NUMERIC(30, 10),
+    ADD COLUMN best_ask         NUMERIC(30, 10);
+CREATE INDEX idx_ob_snapshots_base_snapshot_id
+    ON order_book_snapshots (base_snapshot_id);
+
+UPDATE order_book_snapshots
+SET best_bid = (SELECT max((level ->> 'price')::numeric)
+                FROM jsonb_array_elements(CASE jsonb_typeof(bids -> 'data') WHEN 'array' THEN bids -> 'data' END) level),
+    best_ask = (SELECT min((level ->> 'price')::numeric)
+                FROM jsonb_array_elements(CASE jsonb_typeof(asks -> 'data') WHEN 'array' THEN asks -> 'data' END) level);
+
+CREATE INDEX idx_balance_snapshots_time
+    ON balance_snapshots (snapshot_time);
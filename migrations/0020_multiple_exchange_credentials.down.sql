@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS ux_exchange_credentials_user_exchange_default;
+DROP INDEX IF EXISTS ux_exchange_credentials_user_exchange_label;
+ALTER TABLE exchange_credentials
+    DROP COLUMN IF EXISTS is_default;
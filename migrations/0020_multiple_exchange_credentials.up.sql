@@ -0,0 +1,30 @@
+ALTER TABLE exchange_credentials
+    ADD COLUMN is_default BOOLEAN NOT NULL DEFAULT false;
+
+-- labels only had to be unique once a user could hold several credentials on one exchange
+UPDATE exchange_credentials
+SET label = ranked.label || ' ' || ranked.position
+FROM (SELECT id,
+             label,
+             row_number() OVER (PARTITION BY user_id, exchange_id, label ORDER BY updated_at DESC) AS position
+      FROM exchange_credentials
+      WHERE deleted_at IS NULL) ranked
+WHERE exchange_credentials.id = ranked.id
+  AND ranked.position > 1;
+
+-- the credential picked so far, the most recently updated active one, becomes the default
+UPDATE exchange_credentials
+SET is_default = true
+FROM (SELECT DISTINCT ON (user_id, exchange_id) id
+      FROM exchange_credentials
+      WHERE deleted_at IS NULL
+        AND is_active
+      ORDER BY user_id, exchange_id, updated_at DESC) latest
+WHERE exchange_credentials.id = latest.id;
+
+CREATE UNIQUE INDEX ux_exchange_credentials_user_exchange_label
+    ON exchange_credentials (user_id, exchange_id, label)
+    WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX ux_exchange_credentials_user_exchange_default
+    ON exchange_credentials (user_id, exchange_id)
+    WHERE is_default AND deleted_at IS NULL;